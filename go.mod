@@ -5,6 +5,7 @@ go 1.24
 require (
 	github.com/gobwas/glob v0.2.3
 	github.com/zeebo/blake3 v0.2.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require github.com/klauspost/cpuid/v2 v2.0.12 // indirect
@@ -3,14 +3,24 @@ package build
 
 import (
 	"bytes"
+	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"text/template"
 
+	"mitl/internal/build/imagelock"
+	"mitl/internal/build/templates"
 	det "mitl/internal/detector"
 )
 
+// GeneratorVersion identifies the Dockerfile templates. It is stamped on every
+// capsule and part of the cache key, so bump it whenever generated output
+// changes in a way that should invalidate existing capsules.
+const GeneratorVersion = "11"
+
 // DockerfileGenerator creates optimized Dockerfiles based on project detection
 type DockerfileGenerator struct {
 	Detector *det.ProjectDetector
@@ -72,6 +82,9 @@ func (dg *LegacyDockerfileGenerator) OptimizationHints() []string {
 	return dg.inner.OptimizationHints()
 }
 
+// TemplateData proxies to internal generator
+func (dg *LegacyDockerfileGenerator) TemplateData() map[string]any { return dg.inner.TemplateData() }
+
 // Conversions between main types and internal/detector types
 func toInternalLanguages(xs []det.Language) []det.Language {
 	out := make([]det.Language, 0, len(xs))
@@ -88,6 +101,7 @@ func toInternalDependencies(d *det.Dependencies) det.Dependencies {
 	return det.Dependencies{
 		PHP: det.PHPDependencies{
 			Version:     d.PHP.Version,
+			Source:      d.PHP.Source,
 			Extensions:  append([]string(nil), d.PHP.Extensions...),
 			Composer:    d.PHP.Composer,
 			ComposerVer: d.PHP.ComposerVer,
@@ -95,46 +109,130 @@ func toInternalDependencies(d *det.Dependencies) det.Dependencies {
 		},
 		Node: det.NodeDependencies{
 			Version:        d.Node.Version,
+			Source:         d.Node.Source,
 			PackageManager: d.Node.PackageManager,
+			ManagerVersion: d.Node.ManagerVersion,
+			YarnBerry:      d.Node.YarnBerry,
+			YarnPnP:        d.Node.YarnPnP,
+			Policy:         d.Node.Policy,
 			GlobalPackages: append([]string(nil), d.Node.GlobalPackages...),
 			BuildTools:     d.Node.BuildTools,
 		},
 		Python: det.PythonDependencies{
-			Version:    d.Python.Version,
-			UsesPoetry: d.Python.UsesPoetry,
-			UsesPipenv: d.Python.UsesPipenv,
-			UsesVenv:   d.Python.UsesVenv,
-			SystemDeps: append([]string(nil), d.Python.SystemDeps...),
+			Version:     d.Python.Version,
+			Source:      d.Python.Source,
+			Installer:   d.Python.Installer,
+			Lockfile:    d.Python.Lockfile,
+			UsesPoetry:  d.Python.UsesPoetry,
+			UsesPipenv:  d.Python.UsesPipenv,
+			UsesVenv:    d.Python.UsesVenv,
+			SystemDeps:  append([]string(nil), d.Python.SystemDeps...),
+			RuntimeDeps: append([]string(nil), d.Python.RuntimeDeps...),
+			Servers:     append([]string(nil), d.Python.Servers...),
+			AppModule:   d.Python.AppModule,
+		},
+		Ruby: det.RubyDependencies{
+			Version:      d.Ruby.Version,
+			Source:       d.Ruby.Source,
+			Bundler:      d.Ruby.Bundler,
+			Locked:       d.Ruby.Locked,
+			Assets:       d.Ruby.Assets,
+			BuildDeps:    append([]string(nil), d.Ruby.BuildDeps...),
+			RuntimeDeps:  append([]string(nil), d.Ruby.RuntimeDeps...),
+			NodeRequired: d.Ruby.NodeRequired,
+		},
+		Rust: det.RustDependencies{
+			Version:    d.Rust.Version,
+			Source:     d.Rust.Source,
+			Channel:    d.Rust.Channel,
+			Workspace:  d.Rust.Workspace,
+			Members:    append([]string(nil), d.Rust.Members...),
+			Binaries:   append([]string(nil), d.Rust.Binaries...),
+			Locked:     d.Rust.Locked,
+			SystemDeps: append([]string(nil), d.Rust.SystemDeps...),
+		},
+		Go: det.GoDependencies{
+			Version:    d.Go.Version,
+			Source:     d.Go.Source,
+			Module:     d.Go.Module,
+			Mains:      append([]string(nil), d.Go.Mains...),
+			Target:     d.Go.Target,
+			Cgo:        d.Go.Cgo,
+			SystemDeps: append([]string(nil), d.Go.SystemDeps...),
 		},
+		Java:   d.Java,
 		System: append([]string(nil), d.System...),
 	}
 }
 
-// Generate creates the complete Dockerfile
+// Generate creates the complete Dockerfile: the project's template from
+// .mitl/templates or ~/.mitl/templates when there is one, otherwise the
+// built-in template for its type
 func (dg *DockerfileGenerator) Generate() (string, error) {
+	return dg.render(dg.stack())
+}
+
+// TemplateData returns the data the project's template is rendered with.
+// User templates see exactly these fields.
+func (dg *DockerfileGenerator) TemplateData() map[string]any {
+	_, data := dg.stack()
+	return data
+}
+
+// stack returns the built-in template for the project type and its data
+func (dg *DockerfileGenerator) stack() (string, map[string]any) {
 	switch dg.Detector.Type {
 	case det.TypePHPLaravel:
-		return dg.GenerateLaravel()
+		return laravelTemplate, dg.prepareTemplateData()
 	case det.TypePHPSymfony, det.TypePHPGeneric:
-		// Symfony and generic PHP default to generic Dockerfile for now
-		return dg.GenerateGeneric()
+		return phpTemplate, dg.phpData()
 	case det.TypeNodeNext, det.TypeNodeNuxt, det.TypeNodeGeneric:
-		return dg.GenerateNode()
+		return nodeTemplateFor(dg.Detector.Dependencies.Node), dg.prepareNodeData()
 	case det.TypeGoModule:
-		return dg.GenerateGo()
-	case det.TypePythonDjango, det.TypePythonFlask, det.TypePythonGeneric:
-		return dg.GeneratePython()
-	case det.TypeRubyRails, det.TypeRubyGeneric, det.TypeStatic, det.TypeUnknown:
-		// Fallbacks handled by generic generator
-		return dg.GenerateGeneric()
+		return goTemplate, dg.goData()
+	case det.TypeRustCargo:
+		return rustTemplate, dg.rustData()
+	case det.TypeJavaMaven, det.TypeJavaGradle:
+		return javaTemplate, dg.javaData()
+	case det.TypePythonDjango, det.TypePythonFlask, det.TypePythonFastAPI, det.TypePythonGeneric:
+		return pythonTemplate, dg.pythonData()
+	case det.TypeRubyRails, det.TypeRubyGeneric:
+		return rubyTemplate, dg.rubyData()
 	default:
-		return dg.GenerateGeneric()
+		// Static, unknown and anything else
+		return genericTemplate, dg.genericData()
 	}
 }
 
-// GenerateLaravel creates an opinionated Laravel Dockerfile (alpine-based)
-func (dg *DockerfileGenerator) GenerateLaravel() (string, error) {
-	tmpl := `# syntax=docker/dockerfile:1.4
+// render executes the user template for the project type, if any,
+// otherwise builtin, and pins base images recorded in the project's mitl.lock
+func (dg *DockerfileGenerator) render(builtin string, data map[string]any) (string, error) {
+	name, text := "dockerfile", builtin
+	if path, ok := templates.Find(dg.Detector.Root, string(dg.Detector.Type)); ok {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read template %s: %w", path, err)
+		}
+		name, text = path, string(b)
+	}
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parse template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render template: %w", err)
+	}
+	lock, err := imagelock.Load(dg.Detector.Root)
+	if err != nil {
+		return "", err
+	}
+	return lock.Pin(buf.String()), nil
+}
+
+// laravelTemplate builds vendor/ with the composer image and serves the app
+// with php-fpm
+const laravelTemplate = `# syntax=docker/dockerfile:1.4
 # Auto-generated by Mitl for Laravel project
 # Optimized for: PHP {{.PHPVersion}}, {{.Platform}}
 
@@ -151,18 +249,7 @@ RUN composer dump-autoload --optimize --classmap-authoritative
 
 {{if .HasNodeDeps}}
 # Stage 2: Node dependencies
-FROM node:{{.NodeVersion}}-alpine AS node-deps
-WORKDIR /app
-COPY package*.json ./
-{{if eq .PackageManager "pnpm"}}COPY pnpm-lock.yaml* ./{{end}}
-{{if eq .PackageManager "yarn"}}COPY yarn.lock* ./{{end}}
-RUN --mount=type=cache,target=/root/.npm \
-    --mount=type=cache,target=/root/.pnpm-store \
-    --mount=type=cache,target=/root/.yarn \
-    {{.PackageManager}} {{if eq .PackageManager "npm"}}ci{{else}}install --frozen-lockfile{{end}}
-COPY . .
-RUN {{.PackageManager}} run build || echo "no build script"
-{{end}}
+{{.NodeStage}}{{end}}
 
 # Stage 3: Final image
 FROM php:{{.PHPVersion}}-fpm-alpine{{.AlpineVersion}}
@@ -182,109 +269,37 @@ COPY . .
 EXPOSE 9000
 CMD ["php-fpm"]
 `
-	data := dg.prepareTemplateData()
-	var buf bytes.Buffer
-	t := template.Must(template.New("dockerfile").Parse(tmpl))
-	err := t.Execute(&buf, data)
-	return buf.String(), err
+
+// GenerateLaravel creates an opinionated Laravel Dockerfile (alpine-based)
+func (dg *DockerfileGenerator) GenerateLaravel() (string, error) {
+	return dg.render(laravelTemplate, dg.prepareTemplateData())
 }
 
-// GenerateNode creates an optimized Node.js Dockerfile
+// GenerateNode creates an optimized Node.js Dockerfile for the project's
+// resolved package manager
 func (dg *DockerfileGenerator) GenerateNode() (string, error) {
-	tmpl := `# syntax=docker/dockerfile:1.4
-# Auto-generated by Mitl for Node.js project
-# Node: {{.NodeVersion}} PM: {{.PackageManager}}
-
-FROM node:{{.NodeVersion}}-alpine AS deps
-WORKDIR /app
-COPY package*.json ./
-COPY pnpm-lock.yaml* ./
-RUN corepack enable && corepack prepare pnpm@latest --activate
-RUN --mount=type=cache,target=/root/.npm \
-    --mount=type=cache,target=/root/.local/share/pnpm/store \
-    --mount=type=cache,target=/root/.yarn \
-    pnpm install --frozen-lockfile || pnpm install
-
-FROM node:{{.NodeVersion}}-alpine AS builder
-WORKDIR /app
-COPY --from=deps /app/node_modules ./node_modules
-COPY . .
-RUN corepack enable && corepack prepare pnpm@latest --activate
-{{if .HasBuildScript}}RUN pnpm run build{{end}}
-
-FROM node:{{.NodeVersion}}-alpine
-WORKDIR /app
-RUN apk add --no-cache dumb-init
-RUN addgroup -g 1001 -S nodejs && adduser -S nodejs -u 1001
-COPY --chown=nodejs:nodejs --from=builder /app/{{if .HasBuildScript}}dist{{else}}.{{end}} ./
-{{if not .HasBuildScript}}COPY --chown=nodejs:nodejs --from=deps /app/node_modules ./node_modules{{end}}
-USER nodejs
-EXPOSE {{.Port}}
-ENTRYPOINT ["dumb-init", "--"]
-CMD ["node", "{{.EntryPoint}}"]
-`
-	data := dg.prepareNodeData()
-	var buf bytes.Buffer
-	t := template.Must(template.New("dockerfile").Parse(tmpl))
-	err := t.Execute(&buf, data)
-	return buf.String(), err
+	return dg.render(nodeTemplateFor(dg.Detector.Dependencies.Node), dg.prepareNodeData())
 }
 
-// GenerateGo creates a Go multi-stage build Dockerfile
-func (dg *DockerfileGenerator) GenerateGo() (string, error) {
-	tmpl := `# syntax=docker/dockerfile:1.4
-FROM golang:1-alpine AS build
-WORKDIR /src
-ENV GOTOOLCHAIN=auto
-COPY go.mod .
-RUN --mount=type=cache,target=/go/pkg/mod go mod download
-COPY . .
-RUN CGO_ENABLED=0 go build -o /out/app ./...
-
+// genericTemplate copies the project into a plain Alpine image
+const genericTemplate = `# syntax=docker/dockerfile:1.4
 FROM alpine:3
 WORKDIR /app
-COPY --from=build /out/app /usr/local/bin/app
-CMD ["/usr/local/bin/app"]
-`
-	var buf bytes.Buffer
-	t := template.Must(template.New("dockerfile").Parse(tmpl))
-	err := t.Execute(&buf, map[string]any{})
-	return buf.String(), err
-}
-
-// GeneratePython creates a Python Dockerfile
-func (dg *DockerfileGenerator) GeneratePython() (string, error) {
-	tmpl := `# syntax=docker/dockerfile:1.4
-FROM python:{{.PyVersion}}-alpine
-WORKDIR /app
-COPY requirements.txt* ./
-RUN --mount=type=cache,target=/root/.cache/pip pip install -r requirements.txt || true
-COPY . .
-EXPOSE 8000
-CMD ["python", "-m", "http.server", "8000"]
+{{if .SystemPackages}}RUN apk add --no-cache {{.SystemPackages}}
+{{end}}COPY . .
+CMD ["/bin/sh"]
 `
-	var buf bytes.Buffer
-	t := template.Must(template.New("dockerfile").Parse(tmpl))
-	py := dg.Detector.Dependencies.Python.Version
-	if py == "" {
-		py = "3.11"
-	}
-	err := t.Execute(&buf, map[string]any{"PyVersion": py})
-	return buf.String(), err
-}
 
 // GenerateGeneric creates a minimal Alpine Dockerfile
 func (dg *DockerfileGenerator) GenerateGeneric() (string, error) {
-	tmpl := `# syntax=docker/dockerfile:1.4
-FROM alpine:3
-WORKDIR /app
-COPY . .
-CMD ["/bin/sh"]
-`
-	var buf bytes.Buffer
-	t := template.Must(template.New("dockerfile").Parse(tmpl))
-	err := t.Execute(&buf, map[string]any{})
-	return buf.String(), err
+	return dg.render(genericTemplate, dg.genericData())
+}
+
+// genericData returns the data genericTemplate is rendered with
+func (dg *DockerfileGenerator) genericData() map[string]any {
+	return map[string]any{
+		"SystemPackages": strings.Join(dg.Detector.Dependencies.System, " "),
+	}
 }
 
 // prepareTemplateData creates data map for PHP-based templates
@@ -296,13 +311,15 @@ func (dg *DockerfileGenerator) prepareTemplateData() map[string]any {
 		data["HasRedis"] = det.ContainsString(dg.Detector.Dependencies.PHP.Extensions, "redis")
 		data["HasImagick"] = det.ContainsString(dg.Detector.Dependencies.PHP.Extensions, "imagick")
 	} else {
-		data["PHPVersion"] = "8.3"
+		data["PHPVersion"] = det.DefaultPHP
 		data["PHPExtensions"] = "pdo pdo_mysql"
 	}
 	// Node presence
 	if dg.Detector.Dependencies.Node.Version != "" {
+		tc := toolchainFor(dg.Detector)
 		data["NodeVersion"] = dg.Detector.Dependencies.Node.Version
-		data["PackageManager"] = dg.Detector.Dependencies.Node.PackageManager
+		data["PackageManager"] = tc.Manager
+		data["NodeStage"] = laravelNodeStage(tc)
 		data["HasNodeDeps"] = true
 	}
 	data["Platform"] = dg.Platform
@@ -313,10 +330,8 @@ func (dg *DockerfileGenerator) prepareTemplateData() map[string]any {
 
 // prepareNodeData creates data map for Node templates
 func (dg *DockerfileGenerator) prepareNodeData() map[string]any {
-	// Enforce pnpm for all Node projects
-	pkg := "pnpm"
 	entry := "index.js"
-	port := "3000"
+	port := dg.primaryPort("3000")
 	hasBuild := false
 	if m, ok := dg.Detector.Metadata["package.json"].(map[string]interface{}); ok {
 		if scripts, ok := m["scripts"].(map[string]interface{}); ok {
@@ -328,13 +343,51 @@ func (dg *DockerfileGenerator) prepareNodeData() map[string]any {
 			}
 		}
 	}
-	return map[string]any{
-		"NodeVersion":    dg.Detector.Dependencies.Node.Version,
-		"PackageManager": pkg,
+	nd := dg.Detector.Dependencies.Node
+	return nodeTemplateData(toolchainFor(dg.Detector), map[string]any{
+		"NodeVersion":    nd.Version,
+		"YarnPnP":        nd.YarnPnP,
 		"HasBuildScript": hasBuild,
 		"EntryPoint":     entry,
 		"Port":           port,
+		"SystemPackages": strings.Join(dg.Detector.Dependencies.System, " "),
+	})
+}
+
+// primaryPort returns the first port inferred by the detector, or def
+func (dg *DockerfileGenerator) primaryPort(def string) string {
+	if ports := dg.Detector.InferPorts(); len(ports) > 0 {
+		return strconv.Itoa(ports[0])
 	}
+	return def
+}
+
+// fileExists reports whether name exists under the project root
+func fileExists(root, name string) bool {
+	_, err := os.Stat(filepath.Join(root, name))
+	return err == nil
+}
+
+// projectFiles returns the names that exist under root, in order. Templates
+// COPY only these so optional files never break the build.
+func projectFiles(root string, names ...string) []string {
+	var out []string
+	for _, n := range names {
+		if fileExists(root, n) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// languageVersion returns the version recorded for a language, if any
+func (dg *DockerfileGenerator) languageVersion(name string) string {
+	for _, l := range dg.Detector.Languages {
+		if l.Name == name && l.Version != "" {
+			return l.Version
+		}
+	}
+	return ""
 }
 
 // OptimizationHints returns suggestions for the generated Dockerfile
@@ -0,0 +1,117 @@
+package build
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	det "mitl/internal/detector"
+)
+
+// goMajorSuffix matches the /vN suffix of a major-version module path
+var goMajorSuffix = regexp.MustCompile(`^v\d+$`)
+
+// goTemplate downloads modules from go.mod/go.sum alone so the layer
+// survives source edits, then builds the selected main package. The module
+// and build caches sit where the go-mod and go-build volumes are mounted at
+// run time, and the final stage keeps the toolchain for `mitl run go ...`.
+// A main package also gets a runtime stage holding only its binary, built
+// with `mitl hydrate --target runtime`: scratch for static binaries, Alpine
+// with the libraries it links against for cgo.
+const goTemplate = `# syntax=docker/dockerfile:1.4
+# Auto-generated by Mitl for Go project
+# Go: {{.GoVersion}}{{if .Target}}, main package {{.Target}}{{end}}{{if .Cgo}}, cgo{{end}}
+
+FROM golang:{{.GoVersion}}-alpine AS base
+{{if .Packages}}RUN apk add --no-cache {{.Packages}}
+{{end}}ENV GOTOOLCHAIN=auto CGO_ENABLED={{if .Cgo}}1{{else}}0{{end}}
+WORKDIR /app
+
+FROM base AS build
+{{if and .Manifests (not .Vendored)}}COPY {{.Manifests}} ./
+RUN --mount=type=cache,target=/go/pkg/mod \
+    go mod download
+{{end}}COPY . .
+RUN --mount=type=cache,target=/go/pkg/mod \
+    --mount=type=cache,target=/root/.cache/go-build \
+    {{if .Binary}}go build -o /out/{{.Binary}} {{.Target}}{{else}}go build ./... && mkdir -p /out{{end}}
+{{if .Binary}}{{if .Cgo}}
+FROM alpine:3 AS runtime
+RUN apk add --no-cache ca-certificates tzdata{{if .RuntimePackages}} {{.RuntimePackages}}{{end}} && \
+    adduser -D -u 1001 app
+USER app
+{{else}}
+FROM scratch AS runtime
+COPY --from=base /etc/ssl/certs/ca-certificates.crt /etc/ssl/certs/
+USER 1001
+{{end}}COPY --from=build /out/{{.Binary}} /usr/local/bin/{{.Binary}}
+CMD ["/usr/local/bin/{{.Binary}}"]
+{{end}}
+FROM base
+COPY --from=build /out/ /usr/local/bin/
+COPY . .
+{{if .Binary}}CMD ["/usr/local/bin/{{.Binary}}"]{{else}}CMD ["/bin/sh"]{{end}}
+`
+
+// GenerateGo creates a Go Dockerfile building the module's main package:
+// the root package, cmd/<module name> or the first cmd/ package unless
+// mitl.yaml picks one with go.target
+func (dg *DockerfileGenerator) GenerateGo() (string, error) {
+	return dg.render(goTemplate, dg.goData())
+}
+
+// goData returns the data goTemplate is rendered with
+func (dg *DockerfileGenerator) goData() map[string]any {
+	gd := dg.Detector.Dependencies.Go
+	goVersion := gd.Version
+	if v := dg.languageVersion("go"); v != "" {
+		goVersion = v
+	}
+	if goVersion == "" {
+		goVersion = "1"
+	}
+	var packages, runtimePackages []string
+	if gd.Cgo {
+		packages = append([]string{"build-base"}, gd.SystemDeps...)
+		for _, dep := range gd.SystemDeps {
+			runtimePackages = append(runtimePackages, strings.TrimSuffix(dep, "-dev"))
+		}
+	}
+	packages = det.UniqueStrings(append(packages, dg.Detector.Dependencies.System...))
+	runtimePackages = det.UniqueStrings(append(runtimePackages, dg.Detector.Dependencies.System...))
+
+	target, binary := "", ""
+	if gd.Target != "" {
+		target = "./" + path.Clean(gd.Target)
+		if gd.Target == "." {
+			target = "."
+		}
+		binary = goBinaryName(gd.Module, gd.Target)
+	}
+
+	return map[string]any{
+		"GoVersion":       goVersion,
+		"Target":          target,
+		"Binary":          binary,
+		"Cgo":             gd.Cgo,
+		"Packages":        strings.Join(packages, " "),
+		"RuntimePackages": strings.Join(runtimePackages, " "),
+		"Manifests":       strings.Join(projectFiles(dg.Detector.Root, "go.mod", "go.sum"), " "),
+		"Vendored":        fileExists(dg.Detector.Root, "vendor/modules.txt"),
+	}
+}
+
+// goBinaryName names the binary after its directory, or after the module
+// for the root package: "cmd/api" -> "api", "example.com/svc/v2" -> "svc"
+func goBinaryName(module, target string) string {
+	if target != "." {
+		return path.Base(target)
+	}
+	parts := strings.Split(module, "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] != "" && !(i > 0 && goMajorSuffix.MatchString(parts[i])) {
+			return parts[i]
+		}
+	}
+	return "app"
+}
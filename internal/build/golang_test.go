@@ -0,0 +1,61 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/detector"
+)
+
+func TestGenerateGo(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/svc/v2\n\ngo 1.22\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "go.sum"), []byte(""), 0o644)
+	d := detector.NewProjectDetector(dir)
+	d.Type = detector.TypeGoModule
+	d.Dependencies.Go = detector.GoDependencies{
+		Version: "1.22", Module: "example.com/svc/v2",
+		Mains: []string{"cmd/api", "cmd/worker"}, Target: "cmd/worker",
+	}
+	df, err := NewDockerfileGenerator(d).Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	for _, want := range []string{
+		"FROM golang:1.22-alpine AS base",
+		"CGO_ENABLED=0",
+		"COPY go.mod go.sum ./",
+		"--mount=type=cache,target=/go/pkg/mod",
+		"--mount=type=cache,target=/root/.cache/go-build",
+		"go build -o /out/worker ./cmd/worker",
+		"FROM scratch AS runtime\nCOPY --from=base /etc/ssl/certs/ca-certificates.crt /etc/ssl/certs/\nUSER 1001\n",
+		"COPY --from=build /out/worker /usr/local/bin/worker\n",
+		`CMD ["/usr/local/bin/worker"]`,
+	} {
+		if !strings.Contains(df, want) {
+			t.Fatalf("expected %q in:\n%s", want, df)
+		}
+	}
+	if final := df[strings.LastIndex(df, "FROM "):]; !strings.HasPrefix(final, "FROM base\n") || !strings.Contains(final, "COPY . .") {
+		t.Fatalf("the default capsule must keep the toolchain and source:\n%s", final)
+	}
+
+	d.Dependencies.Go.Target = "."
+	d.Dependencies.Go.Cgo = true
+	d.Dependencies.Go.SystemDeps = []string{"vips-dev"}
+	df, _ = NewDockerfileGenerator(d).Generate()
+	for _, want := range []string{"apk add --no-cache build-base vips-dev", "FROM alpine:3 AS runtime\nRUN apk add --no-cache ca-certificates tzdata vips &&", "CGO_ENABLED=1", "go build -o /out/svc .\n"} {
+		if !strings.Contains(df, want) {
+			t.Fatalf("expected %q in:\n%s", want, df)
+		}
+	}
+
+	// a library: compile everything, no binary to run
+	d.Dependencies.Go = detector.GoDependencies{Version: "1.22"}
+	df, _ = NewDockerfileGenerator(d).Generate()
+	if !strings.Contains(df, "go build ./...") || !strings.Contains(df, `CMD ["/bin/sh"]`) || strings.Contains(df, "AS runtime") {
+		t.Fatalf("expected a library build, got:\n%s", df)
+	}
+}
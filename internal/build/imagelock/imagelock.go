@@ -0,0 +1,203 @@
+// Package imagelock pins the base images of a capsule's Dockerfile to
+// content digests. `mitl lock` resolves every image reference through the
+// container runtime and records it in a committed mitl.lock; Dockerfiles are
+// then built from image@sha256:... so the same project digest always
+// produces the same capsule.
+package imagelock
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"mitl/internal/build/lint"
+
+	e "mitl/pkg/errors"
+)
+
+// FileName is the lock file in the project root
+const FileName = "mitl.lock"
+
+// CurrentVersion is the newest lock file schema
+const CurrentVersion = 1
+
+// header heads every written lock file
+const header = "# Base image digests for mitl capsules. Commit this file; refresh with `mitl lock --update`.\n"
+
+// digestPattern matches an image content digest
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// Lock maps image references to the digests they resolved to
+type Lock struct {
+	Version int               `yaml:"version"`
+	Images  map[string]string `yaml:"images"` // "node:20-alpine" -> "sha256:..."
+
+	// Path is the file the lock was loaded from
+	Path string `yaml:"-"`
+}
+
+// New returns an empty lock
+func New() *Lock {
+	return &Lock{Version: CurrentVersion, Images: map[string]string{}}
+}
+
+// Load reads mitl.lock from root. It returns (nil, nil) when there is none.
+func Load(root string) (*Lock, error) {
+	path := filepath.Join(root, FileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, e.Wrap(err, e.ErrPermissionDenied, "Failed to read "+FileName).WithContext("file", path)
+	}
+	l := New()
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if derr := dec.Decode(l); derr != nil {
+		return nil, e.New(e.ErrInvalidConfig, "Invalid "+FileName+": "+derr.Error()).
+			WithContext("file", path).WithSuggestion("Delete it and run: mitl lock")
+	}
+	if l.Version > CurrentVersion {
+		return nil, e.New(e.ErrInvalidConfig, FileName+" version "+strconv.Itoa(l.Version)+" is newer than this mitl").
+			WithContext("file", path).WithSuggestion("Upgrade mitl")
+	}
+	for ref, d := range l.Images {
+		if !digestPattern.MatchString(d) {
+			return nil, e.New(e.ErrInvalidConfig, "Invalid "+FileName+": "+ref+" has malformed digest "+strconv.Quote(d)).
+				WithContext("file", path).WithSuggestion("Delete it and run: mitl lock")
+		}
+	}
+	if l.Images == nil {
+		l.Images = map[string]string{}
+	}
+	l.Path = path
+	return l, nil
+}
+
+// Save writes the lock to root/mitl.lock
+func (l *Lock) Save(root string) error {
+	l.Version = CurrentVersion
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return e.Wrap(err, e.ErrUnknown, "Failed to encode "+FileName)
+	}
+	path := filepath.Join(root, FileName)
+	if werr := os.WriteFile(path, append([]byte(header), data...), 0o644); werr != nil {
+		return e.Wrap(werr, e.ErrPermissionDenied, "Failed to write "+FileName).WithContext("file", path)
+	}
+	l.Path = path
+	return nil
+}
+
+// Images returns the registry images a Dockerfile builds on: FROM images
+// and COPY --from sources that are not earlier stages, scratch or ARG
+// expansions. Images already pinned to a digest are left out.
+func Images(dockerfile string) []string {
+	df := lint.Parse([]byte(dockerfile))
+	seen := map[string]bool{}
+	var images []string
+	add := func(ref string, stages []lint.Stage) {
+		if !lockable(ref, stages) || seen[ref] {
+			return
+		}
+		seen[ref] = true
+		images = append(images, ref)
+	}
+	for i, st := range df.Stages {
+		add(st.Image, df.Stages[:i])
+	}
+	for _, in := range df.Instructions {
+		if in.Cmd != "COPY" && in.Cmd != "ADD" {
+			continue
+		}
+		if from, ok := in.Flag("from"); ok && in.Stage >= 0 {
+			add(from, df.Stages[:in.Stage+1])
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// lockable reports whether ref names a registry image rather than an
+// earlier stage (by name or index), scratch, an ARG or a pinned image
+func lockable(ref string, stages []lint.Stage) bool {
+	if ref == "" || ref == "scratch" || strings.ContainsAny(ref, "$@") {
+		return false
+	}
+	if _, err := strconv.Atoi(ref); err == nil {
+		return false
+	}
+	for _, st := range stages {
+		if st.Name != "" && strings.EqualFold(st.Name, ref) {
+			return false
+		}
+	}
+	return true
+}
+
+// Pin rewrites the Dockerfile's locked images to image@digest
+func (l *Lock) Pin(dockerfile string) string {
+	if l == nil || len(l.Images) == 0 {
+		return dockerfile
+	}
+	pinned := map[string]bool{}
+	for _, ref := range Images(dockerfile) {
+		if _, ok := l.Images[ref]; ok {
+			pinned[ref] = true
+		}
+	}
+	if len(pinned) == 0 {
+		return dockerfile
+	}
+	df := lint.Parse([]byte(dockerfile))
+	lines := strings.Split(dockerfile, "\n")
+	for _, in := range df.Instructions {
+		var pattern *regexp.Regexp
+		var ref string
+		switch in.Cmd {
+		case "FROM":
+			ref = df.Stages[in.Stage].Image
+			pattern = regexp.MustCompile(`^(\s*FROM\s+(?:--\S+\s+)*)` + regexp.QuoteMeta(ref) + `(\s|$)`)
+		case "COPY", "ADD":
+			ref, _ = in.Flag("from")
+			pattern = regexp.MustCompile(`(--from=)` + regexp.QuoteMeta(ref) + `(\s|$)`)
+		}
+		if ref == "" || !pinned[ref] {
+			continue
+		}
+		i := in.Line - 1
+		lines[i] = pattern.ReplaceAllString(lines[i], "${1}"+ref+"@"+l.Images[ref]+"${2}")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unpin reverses Pin, so the Dockerfile's references can be resolved again
+func (l *Lock) Unpin(dockerfile string) string {
+	if l == nil {
+		return dockerfile
+	}
+	for ref, d := range l.Images {
+		dockerfile = strings.ReplaceAll(dockerfile, ref+"@"+d, ref)
+	}
+	return dockerfile
+}
+
+// Stale compares the lock with a Dockerfile that has been through Pin. It
+// returns the images the lock does not pin and the entries nothing uses.
+func (l *Lock) Stale(pinnedDockerfile string) (missing, unused []string) {
+	missing = Images(pinnedDockerfile)
+	for ref, d := range l.Images {
+		if !strings.Contains(pinnedDockerfile, ref+"@"+d) {
+			unused = append(unused, ref)
+		}
+	}
+	sort.Strings(unused)
+	return missing, unused
+}
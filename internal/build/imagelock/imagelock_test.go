@@ -0,0 +1,140 @@
+package imagelock
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	nodeDigest     = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	composerDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+const sample = `# syntax=docker/dockerfile:1.4
+ARG BASE=alpine:3
+FROM --platform=linux/amd64 node:20-alpine AS deps
+COPY --from=composer:2 /usr/bin/composer /usr/bin/composer
+FROM deps AS build
+COPY --from=deps /app /app
+FROM ${BASE}
+COPY --from=0 /app /app
+FROM scratch
+`
+
+func TestImages(t *testing.T) {
+	if got := strings.Join(Images(sample), " "); got != "composer:2 node:20-alpine" {
+		t.Fatalf("Images = %q", got)
+	}
+}
+
+func TestPin(t *testing.T) {
+	l := New()
+	l.Images["node:20-alpine"] = nodeDigest
+	l.Images["composer:2"] = composerDigest
+	l.Images["python:3.12-alpine"] = composerDigest
+
+	pinned := l.Pin(sample)
+	for _, want := range []string{
+		"FROM --platform=linux/amd64 node:20-alpine@" + nodeDigest + " AS deps\n",
+		"COPY --from=composer:2@" + composerDigest + " /usr/bin/composer",
+		"COPY --from=deps /app /app",
+	} {
+		if !strings.Contains(pinned, want) {
+			t.Fatalf("expected %q in:\n%s", want, pinned)
+		}
+	}
+	if l.Unpin(pinned) != sample {
+		t.Fatalf("Unpin did not restore the Dockerfile:\n%s", l.Unpin(pinned))
+	}
+
+	missing, unused := l.Stale(pinned)
+	if len(missing) != 0 || strings.Join(unused, ",") != "python:3.12-alpine" {
+		t.Fatalf("Stale = %v, %v", missing, unused)
+	}
+	delete(l.Images, "composer:2")
+	if missing, _ := l.Stale(l.Pin(sample)); strings.Join(missing, ",") != "composer:2" {
+		t.Fatalf("expected composer:2 to be missing, got %v", missing)
+	}
+
+	var none *Lock
+	if none.Pin(sample) != sample {
+		t.Fatalf("a nil lock must leave the Dockerfile alone")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	if l, err := Load(dir); l != nil || err != nil {
+		t.Fatalf("expected no lock, got %v, %v", l, err)
+	}
+	l := New()
+	l.Images["node:20-alpine"] = nodeDigest
+	if err := l.Save(dir); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := Load(dir)
+	if err != nil || got.Images["node:20-alpine"] != nodeDigest {
+		t.Fatalf("load: %+v, %v", got, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, FileName), []byte("version: 1\nimages:\n  node:20: latest\n"), 0o644)
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "malformed digest") {
+		t.Fatalf("expected malformed digest error, got %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	old := execCommand
+	defer func() { execCommand = old }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if args[0] == "pull" {
+			return exec.Command("true")
+		}
+		return exec.Command("echo", `[{"RepoDigests":["docker.io/library/alpine@`+composerDigest+`","docker.io/library/node@`+nodeDigest+`"]}]`)
+	}
+	d, err := Resolve("docker", "node:20-alpine")
+	if err != nil || d != nodeDigest {
+		t.Fatalf("Resolve = %q, %v", d, err)
+	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd { return exec.Command("false") }
+	if _, err := Resolve("docker", "node:20-alpine"); err == nil {
+		t.Fatalf("expected a pull failure")
+	}
+}
+
+func TestRepoDigest_RefusesUnrelatedOrAmbiguous(t *testing.T) {
+	tests := []struct {
+		name    string
+		digests []string
+		want    string
+	}{
+		{"own repository", []string{"node@" + nodeDigest}, nodeDigest},
+		{"same digest twice", []string{"node@" + nodeDigest, "docker.io/library/node@" + nodeDigest}, nodeDigest},
+		{"other repository only", []string{"docker.io/library/alpine@" + composerDigest}, ""},
+		{"two digests for the repository", []string{"node@" + nodeDigest, "node@" + composerDigest}, ""},
+	}
+	for _, tt := range tests {
+		if got := repoDigest("node:20-alpine", tt.digests); got != tt.want {
+			t.Errorf("%s: repoDigest = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRepo(t *testing.T) {
+	tests := map[string]string{
+		"node":                         "docker.io/library/node",
+		"mlocati/php-extension":        "docker.io/mlocati/php-extension",
+		"ghcr.io/astral-sh/uv":         "ghcr.io/astral-sh/uv",
+		"index.docker.io/library/node": "docker.io/library/node",
+		"localhost/app":                "localhost/app",
+	}
+	for in, want := range tests {
+		if got := normalizeRepo(in); got != want {
+			t.Fatalf("normalizeRepo(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
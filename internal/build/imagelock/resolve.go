@@ -0,0 +1,85 @@
+package imagelock
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+
+	"mitl/internal/cache"
+
+	e "mitl/pkg/errors"
+)
+
+// execCommand enables test stubbing for command execution
+var execCommand = exec.Command
+
+// Resolve pulls ref with the runtime CLI and returns its content digest,
+// the registry digest of the tag (a multi-platform index where the image
+// has one), so the pin holds on every architecture
+func Resolve(runtime, ref string) (string, error) {
+	if out, err := execCommand(runtime, "pull", ref).CombinedOutput(); err != nil {
+		return "", e.Wrap(err, e.ErrRegistryUnreachable, "Failed to pull "+ref).
+			WithDetails(strings.TrimSpace(string(out))).WithContext("runtime", runtime)
+	}
+	out, err := execCommand(runtime, "image", "inspect", ref, "--format", "{{json .}}").Output()
+	if err != nil {
+		return "", e.Wrap(err, e.ErrRuntimeNotFound, "Failed to inspect "+ref).WithContext("runtime", runtime)
+	}
+	var details cache.ImageDetails
+	if jerr := json.Unmarshal(out, &details); jerr != nil {
+		// Some runtimes print a one-element array
+		var list []cache.ImageDetails
+		if json.Unmarshal(out, &list) != nil || len(list) == 0 {
+			return "", e.Wrap(jerr, e.ErrUnknown, "Unexpected inspect output for "+ref)
+		}
+		details = list[0]
+	}
+	if d := repoDigest(ref, details.RepoDigests); d != "" {
+		return d, nil
+	}
+	return "", e.New(e.ErrUnknown, "No single registry digest for "+ref).
+		WithSuggestion("Only images pulled from a registry by tag can be locked; pin "+ref+" by hand with @sha256:<digest>").
+		WithContext("repo_digests", strings.Join(details.RepoDigests, " "))
+}
+
+// repoDigest picks the digest recorded for ref's repository. Runtimes
+// report repositories fully qualified (docker.io/library/node) or short
+// (node), so both sides are normalized before comparing. A digest of
+// another repository, or several for this one, may be a single platform's
+// manifest rather than the tag's index, so those give "" and no pin.
+func repoDigest(ref string, repoDigests []string) string {
+	want := normalizeRepo(repository(ref))
+	found := ""
+	for _, rd := range repoDigests {
+		repo, d, ok := strings.Cut(rd, "@")
+		if !ok || !digestPattern.MatchString(d) || normalizeRepo(repo) != want {
+			continue
+		}
+		if found != "" && found != d {
+			return ""
+		}
+		found = d
+	}
+	return found
+}
+
+// repository strips the tag from an image reference
+func repository(ref string) string {
+	ref, _, _ = strings.Cut(ref, "@")
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i]
+	}
+	return ref
+}
+
+// normalizeRepo expands Docker Hub shorthands: node -> docker.io/library/node
+func normalizeRepo(repo string) string {
+	first, _, hasSlash := strings.Cut(repo, "/")
+	if !hasSlash {
+		return "docker.io/library/" + repo
+	}
+	if !strings.ContainsAny(first, ".:") && first != "localhost" {
+		return "docker.io/" + repo
+	}
+	return strings.TrimPrefix(repo, "index.")
+}
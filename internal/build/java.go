@@ -0,0 +1,156 @@
+package build
+
+import (
+	"strings"
+
+	det "mitl/internal/detector"
+)
+
+// jvmToolchain describes how Maven or Gradle resolve and package a project.
+// Checked-in wrappers run on the plain Temurin JDK; otherwise the official
+// maven/gradle images provide the tool for the same JDK.
+type jvmToolchain struct {
+	Label    string // Maven or Gradle, for the header comment
+	Image    string // JDK image with the build tool available
+	Tool     string // ./mvnw, mvn, ./gradlew or gradle
+	Manifest string // COPY lines for the files dependency resolution needs
+	CacheDir string // BuildKit cache mount target for the tool's repository
+	Resolve  string // warms the dependency cache from the manifests alone
+	Package  string // builds the application without running tests
+	Output   string // directory the build tool writes artifacts to
+}
+
+// javaToolchainFor returns the toolchain for the detected build tool. Only
+// files present in the project are copied into the dependency layer.
+func javaToolchainFor(root string, jd det.JavaDependencies) jvmToolchain {
+	copyIfPresent := func(b *strings.Builder, names ...string) {
+		if present := projectFiles(root, names...); len(present) > 0 {
+			b.WriteString("COPY " + strings.Join(present, " ") + " ./\n")
+		}
+	}
+	var manifest strings.Builder
+	if jd.BuildTool == det.BuildToolGradle {
+		tc := jvmToolchain{
+			Label:    "Gradle",
+			Image:    "gradle:jdk" + jd.Version + "-alpine",
+			Tool:     "gradle",
+			CacheDir: "/root/.gradle",
+			Output:   "build",
+		}
+		if jd.Wrapper {
+			tc.Image = "eclipse-temurin:" + jd.Version + "-jdk-alpine"
+			tc.Tool = "./gradlew"
+			copyIfPresent(&manifest, "gradlew")
+		}
+		copyIfPresent(&manifest, "settings.gradle.kts", "settings.gradle", "build.gradle.kts", "build.gradle", "gradle.properties", "gradle.lockfile")
+		if fileExists(root, "gradle") {
+			// wrapper jar/properties and the libs.versions.toml catalog
+			manifest.WriteString("COPY gradle ./gradle\n")
+		}
+		tc.Manifest = manifest.String()
+		tc.Resolve = tc.Tool + " --no-daemon -q dependencies"
+		tc.Package = tc.Tool + " --no-daemon -x test assemble"
+		return tc
+	}
+	tc := jvmToolchain{
+		Label:    "Maven",
+		Image:    "maven:3-eclipse-temurin-" + jd.Version + "-alpine",
+		Tool:     "mvn",
+		CacheDir: "/root/.m2",
+		Output:   "target",
+	}
+	if jd.Wrapper {
+		tc.Image = "eclipse-temurin:" + jd.Version + "-jdk-alpine"
+		tc.Tool = "./mvnw"
+		copyIfPresent(&manifest, "mvnw")
+		if fileExists(root, ".mvn") {
+			manifest.WriteString("COPY .mvn ./.mvn\n")
+		}
+	}
+	copyIfPresent(&manifest, "pom.xml")
+	tc.Manifest = manifest.String()
+	tc.Resolve = tc.Tool + " -B -q dependency:go-offline"
+	tc.Package = tc.Tool + " -B -DskipTests package"
+	return tc
+}
+
+// javaTemplate resolves dependencies from the build manifests alone so the
+// layer survives source edits, then packages with the tool's repository on a
+// cache mount. Multi-module builds need every module's manifest to resolve,
+// so they copy the whole tree up front. The final stage keeps the JDK and
+// build tool so `mitl run mvn test`/`mitl run gradle test` work against the
+// ~/.m2 and ~/.gradle volumes.
+const javaTemplate = `# syntax=docker/dockerfile:1.4
+# Auto-generated by Mitl for Java ({{.Label}}) project
+# JDK: {{.JDK}}{{if .Kotlin}}, Kotlin{{end}}{{if .Framework}}, {{.Framework}}{{end}}
+
+FROM {{.Image}} AS deps
+WORKDIR /app
+{{if .MultiModule}}COPY . .
+{{else}}{{.Manifest}}{{end}}RUN --mount=type=cache,target={{.CacheDir}} \
+    {{.Resolve}}
+
+FROM deps AS builder
+COPY . .
+RUN --mount=type=cache,target={{.CacheDir}} \
+    {{.Package}} && \
+    mkdir -p /out && \
+{{- if .Quarkus}}
+    cp -r "$(find . -type d -path '*/{{.Output}}/quarkus-app' | head -n1)" /out/quarkus-app
+{{- else}}
+    cp "$(find . -path '*/{{.Output}}/*.jar' ! -name '*-sources.jar' ! -name '*-javadoc.jar' ! -name '*-plain.jar' ! -path '*/quarkus-app/*' -exec ls -S {} + | head -n1)" /out/app.jar
+{{- end}}
+
+FROM {{.Image}}
+WORKDIR /app
+{{if .SystemPackages}}RUN apk add --no-cache {{.SystemPackages}}
+{{end}}COPY --from=builder /out/ /opt/app/
+COPY . .
+EXPOSE {{.Port}}
+CMD ["java", "-jar", "/opt/app/{{if .Quarkus}}quarkus-app/quarkus-run.jar{{else}}app.jar{{end}}"]
+`
+
+// GenerateJava creates a multi-stage Dockerfile for Maven and Gradle
+// projects, Java or Kotlin
+func (dg *DockerfileGenerator) GenerateJava() (string, error) {
+	return dg.render(javaTemplate, dg.javaData())
+}
+
+// javaData returns the data javaTemplate is rendered with
+func (dg *DockerfileGenerator) javaData() map[string]any {
+	jd := dg.Detector.Dependencies.Java
+	if v := dg.languageVersion("java"); v != "" {
+		jd.Version = v
+	}
+	if jd.Version == "" {
+		jd.Version = det.DefaultJDK
+	}
+	if jd.BuildTool == "" {
+		jd.BuildTool = det.BuildToolMaven
+		if dg.Detector.Type == det.TypeJavaGradle {
+			jd.BuildTool = det.BuildToolGradle
+		}
+	}
+	tc := javaToolchainFor(dg.Detector.Root, jd)
+	output := tc.Output
+	if jd.BuildTool == det.BuildToolGradle && dg.Detector.Framework != "Quarkus" {
+		output = "build/libs"
+	}
+
+	return map[string]any{
+		"Label":          tc.Label,
+		"JDK":            jd.Version,
+		"Kotlin":         jd.Kotlin,
+		"Framework":      dg.Detector.Framework,
+		"Quarkus":        dg.Detector.Framework == "Quarkus",
+		"MultiModule":    jd.MultiModule,
+		"Image":          tc.Image,
+		"Manifest":       tc.Manifest,
+		"CacheDir":       tc.CacheDir,
+		"Resolve":        tc.Resolve,
+		"Package":        tc.Package,
+		"Output":         output,
+		"Port":           dg.primaryPort("8080"),
+		"SystemPackages": strings.Join(dg.Detector.Dependencies.System, " "),
+	}
+}
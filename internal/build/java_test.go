@@ -0,0 +1,70 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/detector"
+)
+
+func TestGenerateJava_MavenWrapper(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"pom.xml", "mvnw", ".mvn/wrapper/maven-wrapper.properties"} {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, f)), 0o755)
+		os.WriteFile(filepath.Join(dir, f), nil, 0o644)
+	}
+	d := detector.NewProjectDetector(dir)
+	d.Type = detector.TypeJavaMaven
+	d.Framework = "Spring Boot"
+	d.Dependencies.Java = detector.JavaDependencies{Version: "17", BuildTool: detector.BuildToolMaven, Wrapper: true}
+	df, err := NewDockerfileGenerator(d).Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	for _, want := range []string{
+		"FROM eclipse-temurin:17-jdk-alpine AS deps",
+		"COPY mvnw ./\nCOPY .mvn ./.mvn\nCOPY pom.xml ./\n",
+		"--mount=type=cache,target=/root/.m2",
+		"./mvnw -B -q dependency:go-offline",
+		"./mvnw -B -DskipTests package",
+		"'*/target/*.jar'",
+		"EXPOSE 8080",
+		`CMD ["java", "-jar", "/opt/app/app.jar"]`,
+	} {
+		if !strings.Contains(df, want) {
+			t.Fatalf("expected %q in:\n%s", want, df)
+		}
+	}
+}
+
+func TestGenerateJava_GradleQuarkus(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "build.gradle.kts"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "settings.gradle.kts"), nil, 0o644)
+	d := detector.NewProjectDetector(dir)
+	d.Type = detector.TypeJavaGradle
+	d.Framework = "Quarkus"
+	d.Dependencies.Java = detector.JavaDependencies{Version: "21", BuildTool: detector.BuildToolGradle}
+	df, err := NewDockerfileGenerator(d).Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	for _, want := range []string{
+		"FROM gradle:jdk21-alpine AS deps",
+		"COPY settings.gradle.kts build.gradle.kts ./\n",
+		"--mount=type=cache,target=/root/.gradle",
+		"gradle --no-daemon -q dependencies",
+		"gradle --no-daemon -x test assemble",
+		"'*/build/quarkus-app'",
+		`CMD ["java", "-jar", "/opt/app/quarkus-app/quarkus-run.jar"]`,
+	} {
+		if !strings.Contains(df, want) {
+			t.Fatalf("expected %q in:\n%s", want, df)
+		}
+	}
+	if strings.Contains(df, "COPY gradle ./gradle") {
+		t.Fatalf("gradle/ copied although absent:\n%s", df)
+	}
+}
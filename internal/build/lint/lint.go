@@ -0,0 +1,304 @@
+// Package lint checks Dockerfiles for common mistakes: unpinned base
+// images, dependency installs that defeat layer or BuildKit caching,
+// masked failures and capsules running as root. It runs on generated
+// Dockerfiles, user templates and project Dockerfiles alike.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Severity ranks a finding
+type Severity string
+
+// Severities from most to least serious
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// rank orders severities; higher is more serious
+var rank = map[Severity]int{SeverityInfo: 1, SeverityWarning: 2, SeverityError: 3}
+
+// ParseSeverity accepts "error", "warning" or "info"
+func ParseSeverity(s string) (Severity, error) {
+	sev := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := rank[sev]; !ok {
+		return "", fmt.Errorf("unknown severity %q (expected error, warning or info)", s)
+	}
+	return sev, nil
+}
+
+// AtLeast reports whether s is as serious as min or more
+func (s Severity) AtLeast(min Severity) bool {
+	return rank[s] >= rank[min]
+}
+
+// Finding is one problem found in a Dockerfile
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Line     int      `json:"line"`
+	Message  string   `json:"message"`
+}
+
+// String formats a finding as "line 3: warning: message (rule)"
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: %s: %s (%s)", f.Line, f.Severity, f.Message, f.Rule)
+}
+
+// Rule is a single check
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+	check       func(df *Dockerfile, report func(line int, msg string))
+}
+
+// Rules lists every check in the order findings are reported for a line
+var Rules = []Rule{
+	{"unpinned-base", SeverityError, "FROM without a tag or digest", checkUnpinnedBase},
+	{"latest-tag", SeverityError, "FROM an image's latest tag", checkLatestTag},
+	{"copy-before-install", SeverityWarning, "COPY of the whole context before installing dependencies", checkCopyBeforeInstall},
+	{"masked-failure", SeverityWarning, "|| true hiding a failing command", checkMaskedFailure},
+	{"apk-no-cache", SeverityWarning, "apk add without --no-cache or a cache mount", checkApkNoCache},
+	{"missing-cache-mount", SeverityInfo, "dependency install without a BuildKit cache mount", checkMissingCacheMount},
+	{"root-user", SeverityInfo, "final stage runs as root", checkRootUser},
+}
+
+// Lint runs every rule over a Dockerfile and returns the findings ordered
+// by line
+func Lint(content []byte) []Finding {
+	df := Parse(content)
+	var findings []Finding
+	for _, r := range Rules {
+		r.check(df, func(line int, msg string) {
+			findings = append(findings, Finding{Rule: r.ID, Severity: r.Severity, Line: line, Message: msg})
+		})
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Line < findings[j].Line })
+	return findings
+}
+
+// Count returns how many findings are at least as serious as min
+func Count(findings []Finding, min Severity) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity.AtLeast(min) {
+			n++
+		}
+	}
+	return n
+}
+
+var (
+	// installPattern matches commands that download dependencies
+	installPattern = regexp.MustCompile(`\b(npm (ci|install|i)|pnpm (install|i|fetch)|yarn install|bun install|` +
+		`pip3? install|poetry install|pipenv (install|sync)|uv (sync|pip install)|pdm (install|sync)|` +
+		`composer install|bundle install|go mod download|cargo (fetch|chef cook)|dependency:go-offline|gradlew? .*\bdependencies\b)`)
+	// globalInstallPattern matches installs of tools rather than project dependencies
+	globalInstallPattern = regexp.MustCompile(`\b(npm (install|i) (-g|--global)|pip3? install( -U| --upgrade)? (pip|poetry|pipenv|uv|pdm)\b)`)
+	maskedPattern        = regexp.MustCompile(`\|\|\s*(true|:|exit 0)\b`)
+	// commandSeparator splits a RUN into the shell commands it chains
+	commandSeparator = regexp.MustCompile(`&&|\|\||[;|\n]`)
+)
+
+// baseImage splits a FROM image into name, tag and digest
+func baseImage(image string) (name, tag, digest string) {
+	name, digest, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+	return name, tag, digest
+}
+
+// externalBase reports whether a stage builds on a registry image rather
+// than scratch, an earlier stage or an ARG
+func externalBase(df *Dockerfile, i int) bool {
+	img := df.Stages[i].Image
+	if img == "" || img == "scratch" || strings.Contains(img, "$") {
+		return false
+	}
+	for _, prev := range df.Stages[:i] {
+		if prev.Name != "" && strings.EqualFold(prev.Name, img) {
+			return false
+		}
+	}
+	return true
+}
+
+func checkUnpinnedBase(df *Dockerfile, report func(int, string)) {
+	for i, st := range df.Stages {
+		if !externalBase(df, i) {
+			continue
+		}
+		if _, tag, digest := baseImage(st.Image); tag == "" && digest == "" {
+			report(st.Line, fmt.Sprintf("base image %s has no tag; pin a version such as %s:<version>", st.Image, st.Image))
+		}
+	}
+}
+
+func checkLatestTag(df *Dockerfile, report func(int, string)) {
+	for i, st := range df.Stages {
+		if !externalBase(df, i) {
+			continue
+		}
+		if name, tag, digest := baseImage(st.Image); tag == "latest" && digest == "" {
+			report(st.Line, fmt.Sprintf("base image %s uses the moving latest tag; pin a version of %s", st.Image, name))
+		}
+	}
+}
+
+// copiesContext reports a COPY or ADD of the whole build context
+func copiesContext(in Instruction) bool {
+	if in.Cmd != "COPY" && in.Cmd != "ADD" {
+		return false
+	}
+	if _, ok := in.Flag("from"); ok {
+		return false
+	}
+	fields := strings.Fields(in.Args)
+	if len(fields) < 2 {
+		return false
+	}
+	for _, src := range fields[:len(fields)-1] {
+		if src == "." || src == "./" {
+			return true
+		}
+	}
+	return false
+}
+
+// installs reports a RUN downloading project dependencies
+func installs(in Instruction) bool {
+	if in.Cmd != "RUN" {
+		return false
+	}
+	args := globalInstallPattern.ReplaceAllString(in.Args, "")
+	return installPattern.MatchString(args + " ")
+}
+
+func checkCopyBeforeInstall(df *Dockerfile, report func(int, string)) {
+	copied := map[int]int{} // stage -> line of its first context COPY
+	reported := map[int]bool{}
+	for _, in := range df.Instructions {
+		if copiesContext(in) {
+			if _, seen := copied[in.Stage]; !seen {
+				copied[in.Stage] = in.Line
+			}
+			continue
+		}
+		if line, seen := copied[in.Stage]; seen && !reported[in.Stage] && installs(in) {
+			report(line, fmt.Sprintf("the whole context is copied before the install on line %d, so any source edit reinstalls dependencies; copy the manifests and lockfiles first", in.Line))
+			reported[in.Stage] = true
+		}
+	}
+}
+
+func checkMaskedFailure(df *Dockerfile, report func(int, string)) {
+	for _, in := range df.Instructions {
+		if in.Cmd == "RUN" && maskedPattern.MatchString(in.Args) {
+			report(in.Line, "|| true hides failures of the preceding command; handle the expected failure explicitly")
+		}
+	}
+}
+
+// cacheMountTargets returns the targets of a RUN's cache mounts
+func cacheMountTargets(in Instruction) []string {
+	var targets []string
+	for _, f := range in.Flags {
+		spec, ok := strings.CutPrefix(f, "--mount=")
+		if !ok {
+			continue
+		}
+		cache, target := false, ""
+		for _, kv := range strings.Split(spec, ",") {
+			k, v, _ := strings.Cut(kv, "=")
+			switch k {
+			case "type":
+				cache = v == "cache"
+			case "target", "dst", "destination":
+				target = v
+			}
+		}
+		if cache {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// apkAddWithoutNoCache reports an apk add in a RUN that is not passed
+// --no-cache itself; --no-cache-dir or a flag of another command does not count
+func apkAddWithoutNoCache(in Instruction) bool {
+	for _, cmd := range commandSeparator.Split(in.Args, -1) {
+		fields := strings.Fields(cmd)
+		if len(fields) == 0 || fields[0] != "apk" || !slices.Contains(fields, "add") {
+			continue
+		}
+		if !slices.Contains(fields, "--no-cache") {
+			return true
+		}
+	}
+	return false
+}
+
+func checkApkNoCache(df *Dockerfile, report func(int, string)) {
+	for _, in := range df.Instructions {
+		if in.Cmd != "RUN" || !apkAddWithoutNoCache(in) {
+			continue
+		}
+		mounted := false
+		for _, t := range cacheMountTargets(in) {
+			if strings.HasPrefix(t, "/var/cache/apk") || strings.HasPrefix(t, "/etc/apk/cache") {
+				mounted = true
+			}
+		}
+		if !mounted {
+			report(in.Line, "apk add without --no-cache leaves the package index in the image")
+		}
+	}
+}
+
+func checkMissingCacheMount(df *Dockerfile, report func(int, string)) {
+	for _, in := range df.Instructions {
+		if !installs(in) || len(cacheMountTargets(in)) > 0 || strings.Contains(in.Args, "--no-cache-dir") {
+			continue
+		}
+		report(in.Line, "dependency install without a cache mount downloads everything again on each rebuild; add RUN --mount=type=cache,target=<cache dir>")
+	}
+}
+
+// checkRootUser follows the final stage back through the stages it is
+// built FROM, since USER carries over
+func checkRootUser(df *Dockerfile, report func(int, string)) {
+	if len(df.Stages) == 0 {
+		return
+	}
+	final := len(df.Stages) - 1
+	user := ""
+	for stage := final; stage >= 0 && user == ""; {
+		for _, in := range df.Instructions {
+			if in.Stage == stage && in.Cmd == "USER" {
+				user = strings.TrimSpace(in.Args)
+			}
+		}
+		parent := -1
+		for i := stage - 1; i >= 0; i-- {
+			if df.Stages[i].Name != "" && strings.EqualFold(df.Stages[i].Name, df.Stages[stage].Image) {
+				parent = i
+				break
+			}
+		}
+		stage = parent
+	}
+	name, _, _ := strings.Cut(user, ":")
+	if user == "" || name == "root" || name == "0" {
+		report(df.Stages[final].Line, "the final stage runs as root; add a USER for images you ship")
+	}
+}
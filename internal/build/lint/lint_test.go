@@ -0,0 +1,79 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint_Rules(t *testing.T) {
+	tests := []struct {
+		name, dockerfile, rule string
+		line                   int
+	}{
+		{"untagged base", "FROM node\nUSER node\n", "unpinned-base", 1},
+		{"latest tag", "FROM python:latest\nUSER app\n", "latest-tag", 1},
+		{"copy before install", "FROM node:20\nCOPY . .\nRUN --mount=type=cache,target=/root/.npm npm ci\nUSER node\n", "copy-before-install", 2},
+		{"masked failure", "FROM alpine:3.20\nRUN make test || true\nUSER app\n", "masked-failure", 2},
+		{"apk index kept", "FROM alpine:3.20\nRUN apk add git\nUSER app\n", "apk-no-cache", 2},
+		{"no-cache of another command", "FROM alpine:3.20\nRUN apk add py3-pip && pip install --no-cache-dir poetry\nUSER app\n", "apk-no-cache", 2},
+		{"no cache mount", "FROM python:3.12\nCOPY requirements.txt .\nRUN pip install -r requirements.txt\nUSER app\n", "missing-cache-mount", 3},
+		{"root", "FROM alpine:3.20 AS base\nUSER app\nFROM base\nUSER root\n", "root-user", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := Lint([]byte(tt.dockerfile))
+			if len(findings) != 1 || findings[0].Rule != tt.rule || findings[0].Line != tt.line {
+				t.Fatalf("expected %s on line %d, got %v", tt.rule, tt.line, findings)
+			}
+		})
+	}
+}
+
+func TestLint_Clean(t *testing.T) {
+	clean := `# syntax=docker/dockerfile:1.4
+ARG BASE=node:20-alpine
+FROM ${BASE} AS deps
+WORKDIR /app
+RUN apk add --no-cache git
+RUN set -eux; apk --no-cache add curl && pip install --no-cache-dir poetry
+RUN npm install -g pnpm
+COPY package.json pnpm-lock.yaml ./
+RUN --mount=type=cache,target=/root/.local/share/pnpm/store pnpm install --frozen-lockfile
+COPY . .
+RUN pnpm build
+
+FROM scratch AS assets
+COPY --from=deps /app/dist /dist
+
+FROM node:20-alpine@sha256:0123456789abcdef
+COPY --from=deps /app /app
+USER node
+`
+	if findings := Lint([]byte(clean)); len(findings) != 0 {
+		t.Fatalf("expected no findings, got %v", findings)
+	}
+}
+
+func TestLint_PinningIsAnError(t *testing.T) {
+	for _, f := range Lint([]byte("FROM node\nFROM python:latest\nRUN apk add git\nUSER app\n")) {
+		want := SeverityWarning
+		if f.Rule == "unpinned-base" || f.Rule == "latest-tag" {
+			want = SeverityError
+		}
+		if f.Severity != want {
+			t.Errorf("%s: severity %s, want %s", f.Rule, f.Severity, want)
+		}
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	if s, err := ParseSeverity("Warning"); err != nil || s != SeverityWarning {
+		t.Fatalf("ParseSeverity = %q, %v", s, err)
+	}
+	if _, err := ParseSeverity("fatal"); err == nil || !strings.Contains(err.Error(), "fatal") {
+		t.Fatalf("expected error for unknown severity, got %v", err)
+	}
+	if !SeverityError.AtLeast(SeverityWarning) || SeverityInfo.AtLeast(SeverityWarning) {
+		t.Fatalf("unexpected severity order")
+	}
+}
@@ -0,0 +1,158 @@
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// Instruction is one Dockerfile instruction
+type Instruction struct {
+	Line  int      // line of the keyword, 1-based
+	Cmd   string   // upper-cased keyword, e.g. RUN
+	Flags []string // leading flags, e.g. --mount=type=cache,target=/root/.npm
+	Args  string   // the rest, continuation lines joined with a space
+	Stage int      // index of the FROM stage it belongs to; -1 before the first FROM
+}
+
+// Flag returns the value of a leading --name=value flag
+func (in Instruction) Flag(name string) (string, bool) {
+	for _, f := range in.Flags {
+		if v, ok := strings.CutPrefix(f, "--"+name+"="); ok {
+			return v, true
+		}
+		if f == "--"+name {
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// Stage is one FROM section of a Dockerfile
+type Stage struct {
+	Line  int    // line of the FROM
+	Image string // base image or earlier stage name
+	Name  string // AS name, if any
+}
+
+// Dockerfile is a parsed Dockerfile
+type Dockerfile struct {
+	Instructions []Instruction
+	Stages       []Stage
+}
+
+var (
+	directivePattern = regexp.MustCompile(`^#\s*([a-zA-Z]+)\s*=\s*(\S+)\s*$`)
+	heredocPattern   = regexp.MustCompile(`<<-?["']?([A-Za-z_][A-Za-z0-9_]*)["']?`)
+)
+
+// Parse reads a Dockerfile. Comments are skipped, continuation lines are
+// joined and heredoc bodies are appended to the instruction that opens
+// them. The escape parser directive is honoured. Parse does not fail: lines
+// it cannot make sense of are kept as instructions with an unknown keyword.
+func Parse(content []byte) *Dockerfile {
+	df := &Dockerfile{}
+	escape := `\`
+	directives := true
+	stage := -1
+
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	next := func() (string, bool) {
+		if !sc.Scan() {
+			return "", false
+		}
+		lineNo++
+		return strings.TrimRight(sc.Text(), "\r"), true
+	}
+
+	for {
+		line, ok := next()
+		if !ok {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		if directives {
+			if m := directivePattern.FindStringSubmatch(trimmed); m != nil {
+				if strings.EqualFold(m[1], "escape") && (m[2] == "`" || m[2] == `\`) {
+					escape = m[2]
+				}
+				continue
+			}
+			directives = false
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		start := lineNo
+		var parts []string
+		for {
+			t := strings.TrimSpace(line)
+			if strings.HasSuffix(t, escape) {
+				parts = append(parts, strings.TrimSpace(strings.TrimSuffix(t, escape)))
+				// Comment and blank lines inside a continuation are dropped
+				for {
+					l, more := next()
+					if !more {
+						line = ""
+						break
+					}
+					if lt := strings.TrimSpace(l); lt != "" && !strings.HasPrefix(lt, "#") {
+						line = l
+						break
+					}
+				}
+				if line == "" {
+					break
+				}
+				continue
+			}
+			parts = append(parts, t)
+			break
+		}
+		text := strings.Join(parts, " ")
+
+		cmd, rest, _ := strings.Cut(text, " ")
+		in := Instruction{Line: start, Cmd: strings.ToUpper(cmd)}
+		rest = strings.TrimSpace(rest)
+		for strings.HasPrefix(rest, "--") {
+			flag, after, _ := strings.Cut(rest, " ")
+			in.Flags = append(in.Flags, flag)
+			rest = strings.TrimSpace(after)
+		}
+		in.Args = rest
+
+		if in.Cmd == "RUN" || in.Cmd == "COPY" || in.Cmd == "ADD" {
+			for _, m := range heredocPattern.FindAllStringSubmatch(rest, -1) {
+				var body []string
+				for {
+					l, more := next()
+					if !more || strings.TrimSpace(l) == m[1] {
+						break
+					}
+					body = append(body, l)
+				}
+				in.Args += "\n" + strings.Join(body, "\n")
+			}
+		}
+
+		if in.Cmd == "FROM" {
+			stage++
+			fields := strings.Fields(in.Args)
+			st := Stage{Line: start}
+			if len(fields) > 0 {
+				st.Image = fields[0]
+			}
+			if len(fields) >= 3 && strings.EqualFold(fields[1], "as") {
+				st.Name = fields[2]
+			}
+			df.Stages = append(df.Stages, st)
+		}
+		in.Stage = stage
+		df.Instructions = append(df.Instructions, in)
+	}
+	return df
+}
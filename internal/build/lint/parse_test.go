@@ -0,0 +1,54 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	df := Parse([]byte(`# syntax=docker/dockerfile:1.4
+# a comment
+FROM node:20-alpine AS deps
+WORKDIR /app
+RUN --mount=type=cache,target=/root/.npm \
+    # comments inside a continuation are dropped
+    npm ci && \
+
+    npm run build
+COPY <<EOF /etc/motd
+hello
+EOF
+FROM deps
+USER node
+`))
+	if len(df.Stages) != 2 || df.Stages[0].Name != "deps" || df.Stages[1].Image != "deps" {
+		t.Fatalf("unexpected stages %+v", df.Stages)
+	}
+	var cmds []string
+	for _, in := range df.Instructions {
+		cmds = append(cmds, in.Cmd)
+	}
+	if got := strings.Join(cmds, " "); got != "FROM WORKDIR RUN COPY FROM USER" {
+		t.Fatalf("unexpected instructions %q", got)
+	}
+	run := df.Instructions[2]
+	if run.Line != 5 || run.Stage != 0 || run.Args != "npm ci && npm run build" {
+		t.Fatalf("unexpected RUN %+v", run)
+	}
+	if v, ok := run.Flag("mount"); !ok || v != "type=cache,target=/root/.npm" {
+		t.Fatalf("unexpected mount flag %q", v)
+	}
+	if copyIn := df.Instructions[3]; !strings.HasSuffix(copyIn.Args, "\nhello") {
+		t.Fatalf("expected heredoc body, got %q", copyIn.Args)
+	}
+	if user := df.Instructions[5]; user.Line != 14 || user.Stage != 1 {
+		t.Fatalf("unexpected USER %+v", user)
+	}
+}
+
+func TestParse_EscapeDirective(t *testing.T) {
+	df := Parse([]byte("# escape=`\nFROM mcr.microsoft.com/windows/servercore:ltsc2022\nRUN dir `\n    C:\\\n"))
+	if len(df.Instructions) != 2 || df.Instructions[1].Args != `dir C:\` {
+		t.Fatalf("unexpected instructions %+v", df.Instructions)
+	}
+}
@@ -0,0 +1,38 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mitl/internal/build/lint"
+	"mitl/internal/detector"
+)
+
+func TestGenerate_LintClean(t *testing.T) {
+	projects := map[string]map[string]string{
+		"laravel": {"composer.json": `{"require":{"laravel/framework":"^10.0"}}`, "artisan": "", "package.json": `{"scripts":{"build":"vite build"}}`},
+		"node":    {"package.json": `{"dependencies":{"express":"4"}}`, "package-lock.json": "{}"},
+		"django":  {"requirements.txt": "django\n", "manage.py": ""},
+		"go":      {"go.mod": "module example.com/x\n\ngo 1.22\n", "main.go": "package main\nfunc main(){}\n"},
+		"rust":    {"Cargo.toml": "[package]\nname='x'\n", "Cargo.lock": ""},
+		"maven":   {"pom.xml": "<project></project>"},
+	}
+	for name, files := range projects {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			for f, c := range files {
+				os.WriteFile(filepath.Join(dir, f), []byte(c), 0o644)
+			}
+			d := detector.NewProjectDetector(dir)
+			_ = d.Detect()
+			df, err := NewDockerfileGenerator(d).Generate()
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			if n := lint.Count(lint.Lint([]byte(df)), lint.SeverityWarning); n != 0 {
+				t.Fatalf("generated %s Dockerfile has warnings: %v\n%s", d.Type, lint.Lint([]byte(df)), df)
+			}
+		})
+	}
+}
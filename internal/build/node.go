@@ -0,0 +1,220 @@
+package build
+
+import (
+	"path"
+	"strings"
+
+	det "mitl/internal/detector"
+)
+
+// nodeToolchain describes how a Node package manager installs dependencies
+// inside an image. Templates stay manager-agnostic by rendering these fields.
+type nodeToolchain struct {
+	Manager   string   // npm, yarn, pnpm, bun
+	Image     string   // base image for install/build stages
+	Setup     string   // Dockerfile lines activating the manager, newline-terminated
+	Manifests string   // root COPY sources needed to install
+	Members   []string // workspace member directories with a package.json
+	CacheDir  string   // BuildKit cache mount target for the manager's store
+	Install   string   // install command, frozen to the lockfile when there is one
+	Runtime   string   // program that runs the app (node or bun)
+}
+
+// corepackSetup activates a corepack-managed manager. COREPACK_HOME is shared
+// so the non-root runtime user finds the same prepared binary.
+func corepackSetup(manager, version string) string {
+	if version == "" {
+		version = "latest"
+		if manager == det.PackageManagerYarn {
+			version = "stable"
+		}
+	}
+	return "ENV COREPACK_HOME=/usr/local/share/corepack COREPACK_ENABLE_DOWNLOAD_PROMPT=0\n" +
+		"RUN corepack enable && corepack prepare " + manager + "@" + version + " --activate\n"
+}
+
+// toolchainFor returns the toolchain for the detector's resolved manager.
+// Installs are frozen to the manager's lockfile when the project has one;
+// without one there is nothing to freeze and a plain install resolves.
+func toolchainFor(pd *det.ProjectDetector) nodeToolchain {
+	nd := pd.Dependencies.Node
+	locked := len(projectFiles(pd.Root, det.Lockfiles(nd.PackageManager)...)) > 0
+	install := func(plain, frozen string) string {
+		if locked {
+			return frozen
+		}
+		return plain
+	}
+	nodeImage := "node:" + nd.Version + "-alpine"
+	tc := nodeToolchain{
+		Manager:   det.PackageManagerNPM,
+		Image:     nodeImage,
+		Manifests: "package*.json npm-shrinkwrap.json*",
+		CacheDir:  "/root/.npm",
+		Install:   install("npm install", "npm ci"),
+		Runtime:   "node",
+	}
+	switch {
+	case nd.PackageManager == det.PackageManagerPNPM:
+		tc = nodeToolchain{
+			Manager:   nd.PackageManager,
+			Image:     nodeImage,
+			Setup:     corepackSetup(nd.PackageManager, nd.ManagerVersion),
+			Manifests: "package*.json pnpm-lock.yaml* pnpm-workspace.yaml*",
+			CacheDir:  "/root/.local/share/pnpm/store",
+			Install:   install("pnpm install", "pnpm install --frozen-lockfile"),
+			Runtime:   "node",
+		}
+	case nd.PackageManager == det.PackageManagerYarn && nd.YarnBerry:
+		tc = nodeToolchain{
+			Manager:   nd.PackageManager,
+			Image:     nodeImage,
+			Setup:     corepackSetup(nd.PackageManager, nd.ManagerVersion),
+			Manifests: "package.json yarn.lock* .yarnrc.yml*",
+			CacheDir:  "/root/.yarn/berry/cache",
+			Install:   install("yarn install", "yarn install --immutable"),
+			Runtime:   "node",
+		}
+	case nd.PackageManager == det.PackageManagerYarn:
+		// node images ship Yarn classic
+		tc = nodeToolchain{
+			Manager:   nd.PackageManager,
+			Image:     nodeImage,
+			Manifests: "package.json yarn.lock*",
+			CacheDir:  "/usr/local/share/.cache/yarn",
+			Install:   install("yarn install", "yarn install --frozen-lockfile"),
+			Runtime:   "node",
+		}
+	case nd.PackageManager == det.PackageManagerBun:
+		version := nd.ManagerVersion
+		if version == "" {
+			version = "1"
+		}
+		tc = nodeToolchain{
+			Manager:   nd.PackageManager,
+			Image:     "oven/bun:" + version + "-alpine",
+			Manifests: "package.json bun.lock* bun.lockb*",
+			CacheDir:  "/root/.bun/install/cache",
+			Install:   install("bun install", "bun install --frozen-lockfile"),
+			Runtime:   "bun",
+		}
+	}
+	tc.Members = nodeMembers(pd)
+	return tc
+}
+
+// nodeMembers returns the workspace members with a package.json. A
+// workspace install needs every member's manifest to link the members and
+// to match the lockfile.
+func nodeMembers(pd *det.ProjectDetector) []string {
+	if pd.Workspace == nil {
+		return nil
+	}
+	var dirs []string
+	for _, p := range pd.Workspace.Packages {
+		if p.Path != "" && p.Path != "." && fileExists(pd.Root, path.Join(p.Path, "package.json")) {
+			dirs = append(dirs, p.Path)
+		}
+	}
+	return dirs
+}
+
+// copyManifests renders the COPY lines for the root manifests and each
+// member's package.json, kept at its path
+func (tc nodeToolchain) copyManifests() string {
+	var b strings.Builder
+	b.WriteString("COPY " + tc.Manifests + " ./\n")
+	for _, dir := range tc.Members {
+		b.WriteString("COPY " + dir + "/package.json " + dir + "/\n")
+	}
+	return b.String()
+}
+
+// nodeTemplate builds npm, pnpm, Yarn classic and Bun projects: install
+// against the lockfile, build, then copy the output into a slim runtime
+const nodeTemplate = `# syntax=docker/dockerfile:1.4
+# Auto-generated by Mitl for Node.js project
+# Node: {{.NodeVersion}} PM: {{.PackageManager}}
+
+FROM {{.Image}} AS deps
+WORKDIR /app
+{{.Setup}}{{.CopyManifests}}RUN --mount=type=cache,target={{.CacheDir}} \
+    {{.Install}}
+
+FROM {{.Image}} AS builder
+WORKDIR /app
+{{.Setup}}COPY --from=deps /app/node_modules ./node_modules
+COPY . .
+{{if .HasBuildScript}}RUN {{.PackageManager}} run build{{end}}
+
+FROM {{.Image}}
+WORKDIR /app
+RUN apk add --no-cache dumb-init{{if .SystemPackages}} {{.SystemPackages}}{{end}}
+{{.Setup}}RUN addgroup -g 1001 -S nodejs && adduser -S nodejs -u 1001
+COPY --chown=nodejs:nodejs --from=builder /app/{{if .HasBuildScript}}dist{{else}}.{{end}} ./
+{{if not .HasBuildScript}}COPY --chown=nodejs:nodejs --from=deps /app/node_modules ./node_modules{{end}}
+USER nodejs
+EXPOSE {{.Port}}
+ENTRYPOINT ["dumb-init", "--"]
+CMD ["{{.Runtime}}", "{{.EntryPoint}}"]
+`
+
+// yarnBerryTemplate builds Yarn 2+ projects. Berry needs the whole tree
+// (.yarnrc.yml, .yarn/ plugins and releases, workspaces) to install, and with
+// Plug'n'Play there is no node_modules, so stages share /app wholesale.
+const yarnBerryTemplate = `# syntax=docker/dockerfile:1.4
+# Auto-generated by Mitl for Node.js project
+# Node: {{.NodeVersion}} PM: yarn {{if .YarnPnP}}(berry, pnp){{else}}(berry){{end}}
+
+FROM {{.Image}} AS deps
+WORKDIR /app
+{{.Setup}}COPY . .
+RUN --mount=type=cache,target={{.CacheDir}} \
+    {{.Install}}
+
+FROM deps AS builder
+{{if .HasBuildScript}}RUN yarn run build{{end}}
+
+FROM {{.Image}}
+WORKDIR /app
+RUN apk add --no-cache dumb-init{{if .SystemPackages}} {{.SystemPackages}}{{end}}
+{{.Setup}}RUN addgroup -g 1001 -S nodejs && adduser -S nodejs -u 1001
+COPY --chown=nodejs:nodejs --from=builder /app ./
+USER nodejs
+EXPOSE {{.Port}}
+ENTRYPOINT ["dumb-init", "--"]
+CMD ["node"{{if .YarnPnP}}, "--require", "./.pnp.cjs"{{end}}, "{{.EntryPoint}}"]
+`
+
+// nodeTemplateFor picks the template for a resolved package manager
+func nodeTemplateFor(nd det.NodeDependencies) string {
+	if nd.PackageManager == det.PackageManagerYarn && nd.YarnBerry {
+		return yarnBerryTemplate
+	}
+	return nodeTemplate
+}
+
+// nodeTemplateData flattens toolchain fields into template data
+func nodeTemplateData(tc nodeToolchain, data map[string]any) map[string]any {
+	data["PackageManager"] = tc.Manager
+	data["Image"] = tc.Image
+	data["Setup"] = tc.Setup
+	data["Manifests"] = tc.Manifests
+	data["CopyManifests"] = tc.copyManifests()
+	data["CacheDir"] = tc.CacheDir
+	data["Install"] = tc.Install
+	data["Runtime"] = tc.Runtime
+	return data
+}
+
+// laravelNodeStage renders the asset build stage of Laravel images
+func laravelNodeStage(tc nodeToolchain) string {
+	var b strings.Builder
+	b.WriteString("FROM " + tc.Image + " AS node-deps\nWORKDIR /app\n")
+	b.WriteString(tc.Setup)
+	b.WriteString(tc.copyManifests())
+	b.WriteString("RUN --mount=type=cache,target=" + tc.CacheDir + " \\\n    " + tc.Install + "\n")
+	b.WriteString("COPY . .\n")
+	b.WriteString("RUN " + tc.Manager + ` run build || echo "no build script"` + "\n")
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/detector"
+)
+
+func TestGenerateNode_PackageManagers(t *testing.T) {
+	tests := []struct {
+		name string
+		nd   detector.NodeDependencies
+		lock string
+		want []string
+		not  []string
+	}{
+		{"npm", detector.NodeDependencies{PackageManager: "npm"}, "package-lock.json",
+			[]string{"    npm ci\n", "target=/root/.npm"}, []string{"pnpm", "corepack", "||"}},
+		{"npm without lockfile", detector.NodeDependencies{PackageManager: "npm"}, "",
+			[]string{"    npm install\n"}, []string{"npm ci"}},
+		{"pnpm pinned", detector.NodeDependencies{PackageManager: "pnpm", ManagerVersion: "9.1.0"}, "pnpm-lock.yaml",
+			[]string{"corepack prepare pnpm@9.1.0 --activate", "pnpm install --frozen-lockfile\n"}, []string{"||"}},
+		{"pnpm without lockfile", detector.NodeDependencies{PackageManager: "pnpm"}, "package-lock.json",
+			[]string{"    pnpm install\n"}, []string{"--frozen-lockfile"}},
+		{"yarn classic", detector.NodeDependencies{PackageManager: "yarn"}, "yarn.lock",
+			[]string{"yarn install --frozen-lockfile"}, []string{"corepack"}},
+		{"yarn berry pnp", detector.NodeDependencies{PackageManager: "yarn", YarnBerry: true, YarnPnP: true}, "yarn.lock",
+			[]string{"corepack prepare yarn@stable", "yarn install --immutable", `"--require", "./.pnp.cjs"`}, []string{"/app/node_modules"}},
+		{"bun", detector.NodeDependencies{PackageManager: "bun"}, "bun.lock",
+			[]string{"FROM oven/bun:1-alpine", "bun install --frozen-lockfile", `CMD ["bun"`}, []string{"node:"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.lock != "" {
+				os.WriteFile(filepath.Join(dir, tt.lock), []byte("{}"), 0o644)
+			}
+			d := detector.NewProjectDetector(dir)
+			d.Type = detector.TypeNodeGeneric
+			tt.nd.Version = "20"
+			d.Dependencies.Node = tt.nd
+			df, err := NewDockerfileGenerator(d).Generate()
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(df, w) {
+					t.Fatalf("expected %q in:\n%s", w, df)
+				}
+			}
+			for _, n := range tt.not {
+				if strings.Contains(df, n) {
+					t.Fatalf("unexpected %q in:\n%s", n, df)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateNode_WorkspaceManifests(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name":"root","private":true}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "pnpm-workspace.yaml"), []byte("packages:\n  - packages/*\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), []byte("lockfileVersion: '9.0'\n"), 0o644)
+	for _, name := range []string{"api", "web"} {
+		os.MkdirAll(filepath.Join(dir, "packages", name), 0o755)
+		os.WriteFile(filepath.Join(dir, "packages", name, "package.json"), []byte(`{"name":"`+name+`"}`), 0o644)
+	}
+	d := detector.NewProjectDetector(dir)
+	d.Type = detector.TypeNodeGeneric
+	d.Dependencies.Node = detector.NodeDependencies{Version: "20", PackageManager: "pnpm"}
+	d.Workspace = detector.DiscoverWorkspace(dir)
+	df, err := NewDockerfileGenerator(d).Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	want := "COPY package*.json pnpm-lock.yaml* pnpm-workspace.yaml* ./\n" +
+		"COPY packages/api/package.json packages/api/\n" +
+		"COPY packages/web/package.json packages/web/\n" +
+		"RUN --mount=type=cache,target=/root/.local/share/pnpm/store \\\n    pnpm install --frozen-lockfile\n"
+	if !strings.Contains(df, want) {
+		t.Fatalf("expected member manifests before the install in:\n%s", df)
+	}
+}
@@ -0,0 +1,108 @@
+package build
+
+import (
+	"sort"
+	"strings"
+
+	det "mitl/internal/detector"
+)
+
+// phpBundledExtensions ship compiled into the official php:*-cli-alpine
+// images and need no install step
+var phpBundledExtensions = map[string]bool{
+	"ctype": true, "curl": true, "date": true, "dom": true, "fileinfo": true,
+	"filter": true, "hash": true, "iconv": true, "json": true, "libxml": true,
+	"mbstring": true, "mysqlnd": true, "openssl": true, "pcre": true, "pdo": true,
+	"pdo_sqlite": true, "phar": true, "posix": true, "random": true, "readline": true,
+	"reflection": true, "session": true, "simplexml": true, "sodium": true, "spl": true,
+	"sqlite3": true, "standard": true, "tokenizer": true, "xml": true, "xmlreader": true,
+	"xmlwriter": true, "zlib": true,
+}
+
+// phpTemplate installs extensions with install-php-extensions, which covers
+// core and PECL extensions along with their Alpine libraries, then resolves
+// vendor/ from composer.json/composer.lock alone so the layer survives source
+// edits. COMPOSER_HOME lines the download cache up with the composer cache
+// volume; composer stays in the final stage for `mitl run composer ...`.
+const phpTemplate = `# syntax=docker/dockerfile:1.4
+# Auto-generated by Mitl for {{.Label}} project
+# PHP: {{.PHPVersion}}{{if .Framework}}, {{.Framework}} {{.Version}}{{end}}
+
+FROM php:{{.PHPVersion}}-cli-alpine AS base
+COPY --from=mlocati/php-extension-installer:2 /usr/bin/install-php-extensions /usr/local/bin/
+COPY --from=composer:2 /usr/bin/composer /usr/bin/composer
+RUN apk add --no-cache git unzip{{if .Packages}} {{.Packages}}{{end}}{{if .Extensions}} && \
+    install-php-extensions {{.Extensions}}{{end}}
+ENV COMPOSER_HOME=/root/.composer COMPOSER_ALLOW_SUPERUSER=1
+WORKDIR /app
+{{if .Manifests}}
+FROM base AS vendor
+COPY {{.Manifests}} ./
+RUN --mount=type=cache,target=/root/.composer/cache \
+    composer install --no-interaction --no-scripts --no-autoloader --prefer-dist
+{{end}}
+FROM base
+{{if .Manifests}}COPY --from=vendor /app/vendor ./vendor
+{{end}}COPY . .
+{{if .Manifests}}RUN composer dump-autoload --optimize
+{{end}}{{if .Port}}EXPOSE {{.Port}}
+{{end}}CMD [{{.Command}}]
+`
+
+// GeneratePHP creates a Dockerfile for Symfony and framework-less PHP
+// projects, served by PHP's built-in web server
+func (dg *DockerfileGenerator) GeneratePHP() (string, error) {
+	return dg.render(phpTemplate, dg.phpData())
+}
+
+// phpData returns the data phpTemplate is rendered with
+func (dg *DockerfileGenerator) phpData() map[string]any {
+	pd := dg.Detector.Dependencies.PHP
+	version := pd.Version
+	if v := dg.languageVersion("php"); v != "" {
+		version = v
+	}
+	if version == "" {
+		version = det.DefaultPHP
+	}
+	var extensions []string
+	for _, ext := range pd.Extensions {
+		if !phpBundledExtensions[strings.ToLower(ext)] {
+			extensions = append(extensions, ext)
+		}
+	}
+	sort.Strings(extensions)
+
+	var manifests []string
+	if fileExists(dg.Detector.Root, "composer.json") {
+		manifests = projectFiles(dg.Detector.Root, "composer.json", "composer.lock", "symfony.lock")
+	}
+
+	// A docroot means a web app; otherwise fall back to the interactive shell
+	label := "PHP"
+	if dg.Detector.Type == det.TypePHPSymfony {
+		label = "Symfony"
+	}
+	port := dg.primaryPort("8000")
+	var command string
+	switch {
+	case fileExists(dg.Detector.Root, "public"):
+		command = `"php", "-S", "0.0.0.0:` + port + `", "-t", "public"`
+	case fileExists(dg.Detector.Root, "index.php"):
+		command = `"php", "-S", "0.0.0.0:` + port + `", "-t", "."`
+	default:
+		command, port = `"php", "-a"`, ""
+	}
+
+	return map[string]any{
+		"Label":      label,
+		"PHPVersion": version,
+		"Framework":  dg.Detector.Framework,
+		"Version":    dg.Detector.Version,
+		"Packages":   strings.Join(dg.Detector.Dependencies.System, " "),
+		"Extensions": strings.Join(extensions, " "),
+		"Manifests":  strings.Join(manifests, " "),
+		"Port":       port,
+		"Command":    command,
+	}
+}
@@ -0,0 +1,54 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/detector"
+)
+
+func TestGeneratePHP_Symfony(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "composer.json"), []byte(`{}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "composer.lock"), []byte(`{}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "symfony.lock"), []byte(`{}`), 0o644)
+	os.Mkdir(filepath.Join(dir, "public"), 0o755)
+	d := detector.NewProjectDetector(dir)
+	d.Type = detector.TypePHPSymfony
+	d.Framework, d.Version = "Symfony", "7.1"
+	d.Dependencies.PHP = detector.PHPDependencies{Version: "8.2", Extensions: []string{"pdo", "intl", "redis", "mbstring"}}
+	df, err := NewDockerfileGenerator(d).Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	for _, want := range []string{
+		"FROM php:8.2-cli-alpine AS base",
+		"install-php-extensions intl redis\n",
+		"COPY composer.json composer.lock symfony.lock ./",
+		"--mount=type=cache,target=/root/.composer/cache",
+		"COPY --from=vendor /app/vendor ./vendor",
+		"composer dump-autoload --optimize",
+		"EXPOSE 8000",
+		`CMD ["php", "-S", "0.0.0.0:8000", "-t", "public"]`,
+	} {
+		if !strings.Contains(df, want) {
+			t.Fatalf("expected %q in:\n%s", want, df)
+		}
+	}
+}
+
+func TestGeneratePHP_ScriptHasNoServer(t *testing.T) {
+	dir := t.TempDir()
+	d := detector.NewProjectDetector(dir)
+	d.Type = detector.TypePHPGeneric
+	df, err := NewDockerfileGenerator(d).Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if !strings.Contains(df, "FROM php:"+detector.DefaultPHP+"-cli-alpine") || strings.Contains(df, "EXPOSE") ||
+		strings.Contains(df, "vendor") || !strings.Contains(df, `CMD ["php", "-a"]`) {
+		t.Fatalf("unexpected generic php Dockerfile:\n%s", df)
+	}
+}
@@ -0,0 +1,194 @@
+package build
+
+import (
+	"strings"
+
+	det "mitl/internal/detector"
+)
+
+// uvImage is the uv release copied into uv capsules. It is pinned so a uv
+// release can't change a capsule built from the same inputs; mitl lock pins
+// it further to a digest.
+const uvImage = "ghcr.io/astral-sh/uv:0.8.15"
+
+// pythonToolchain describes how an installer fills /app/.venv, the path the
+// venv volume is mounted on at run time
+type pythonToolchain struct {
+	Setup     string   // Dockerfile lines installing the installer itself
+	Env       string   // extra ENV assignments, space-prefixed
+	Manifests []string // files the install needs; empty copies the tree
+	CacheDir  string   // BuildKit cache mount target for downloads
+	Install   string   // install command honoring the lockfile, if any
+}
+
+// pythonToolchainFor returns the toolchain for the detected installer. Every
+// installer targets the venv at /app/.venv, which is created up front and
+// activated through VIRTUAL_ENV/PATH so Poetry, Pipenv and PDM adopt it.
+func pythonToolchainFor(root string, py det.PythonDependencies) pythonToolchain {
+	locked := py.Lockfile != ""
+	pipInstall := func(tool string) string {
+		return "RUN --mount=type=cache,target=/root/.cache/pip pip install " + tool + "\n"
+	}
+	switch py.Installer {
+	case det.PythonInstallerUV:
+		install := "uv sync --no-install-project"
+		if locked {
+			install = "uv sync --frozen --no-install-project"
+		}
+		return pythonToolchain{
+			Setup:     "COPY --from=" + uvImage + " /uv /uvx /bin/\n",
+			Env:       " UV_PROJECT_ENVIRONMENT=/app/.venv UV_LINK_MODE=copy UV_PYTHON_DOWNLOADS=never",
+			Manifests: projectFiles(root, "pyproject.toml", "uv.lock", ".python-version"),
+			CacheDir:  "/root/.cache/uv",
+			Install:   install,
+		}
+	case det.PythonInstallerPoetry:
+		return pythonToolchain{
+			Setup:     pipInstall("poetry"),
+			Env:       " POETRY_NO_INTERACTION=1",
+			Manifests: projectFiles(root, "pyproject.toml", "poetry.lock"),
+			CacheDir:  "/root/.cache/pypoetry",
+			Install:   "poetry install --no-root",
+		}
+	case det.PythonInstallerPipenv:
+		install := "pipenv install --dev"
+		if locked {
+			install += " --deploy"
+		}
+		return pythonToolchain{
+			Setup:     pipInstall("pipenv"),
+			Manifests: projectFiles(root, "Pipfile", "Pipfile.lock"),
+			CacheDir:  "/root/.cache",
+			Install:   install,
+		}
+	case det.PythonInstallerPDM:
+		install := "pdm install --no-self"
+		if locked {
+			install += " --frozen-lockfile"
+		}
+		return pythonToolchain{
+			Setup:     pipInstall("pdm"),
+			Env:       " PDM_CHECK_UPDATE=false",
+			Manifests: projectFiles(root, "pyproject.toml", "pdm.lock"),
+			CacheDir:  "/root/.cache/pdm",
+			Install:   install,
+		}
+	}
+	tc := pythonToolchain{CacheDir: "/root/.cache/pip"}
+	switch {
+	case fileExists(root, "requirements.txt"):
+		tc.Manifests = []string{"requirements.txt"}
+		tc.Install = "pip install -r requirements.txt"
+	case fileExists(root, "pyproject.toml"):
+		// PEP 621 project without a lockfile: install it editable, which
+		// needs the sources, so the tree is copied (Manifests left empty)
+		tc.Install = "pip install -e ."
+	}
+	return tc
+}
+
+// pythonTemplate installs dependencies into /app/.venv from the manifests
+// alone so the layer survives source edits. build-base stays in the final
+// stage so `mitl run pip install` can build wheels into the venv volume.
+const pythonTemplate = `# syntax=docker/dockerfile:1.4
+# Auto-generated by Mitl for {{.Label}} project
+# Python: {{.PyVersion}} Installer: {{.Installer}}
+
+FROM python:{{.PyVersion}}-alpine AS base
+RUN apk add --no-cache build-base{{if .Packages}} {{.Packages}}{{end}}
+ENV PYTHONDONTWRITEBYTECODE=1 PYTHONUNBUFFERED=1 \
+    VIRTUAL_ENV=/app/.venv PATH=/app/.venv/bin:$PATH{{.Env}}
+WORKDIR /app
+{{.Setup}}
+FROM base AS deps
+{{if .Manifests}}COPY {{.Manifests}} ./
+{{else}}COPY . .
+{{end}}RUN --mount=type=cache,target={{.CacheDir}} \
+    python -m venv /app/.venv{{if .Install}} && {{.Install}}{{end}}
+
+FROM base
+COPY --from=deps /app/.venv /app/.venv
+COPY . .
+{{if .Port}}EXPOSE {{.Port}}
+{{end}}CMD [{{.Command}}]
+`
+
+// GeneratePython creates a Python Dockerfile for the detected installer and
+// framework (Django, Flask, FastAPI)
+func (dg *DockerfileGenerator) GeneratePython() (string, error) {
+	return dg.render(pythonTemplate, dg.pythonData())
+}
+
+// pythonData returns the data pythonTemplate is rendered with
+func (dg *DockerfileGenerator) pythonData() map[string]any {
+	py := dg.Detector.Dependencies.Python
+	version := py.Version
+	if v := dg.languageVersion("python"); v != "" {
+		version = v
+	}
+	if version == "" {
+		version = det.DefaultPython
+	}
+	if py.Installer == "" {
+		py.Installer = det.PythonInstallerPip
+	}
+	tc := pythonToolchainFor(dg.Detector.Root, py)
+
+	packages := append(append([]string{}, py.SystemDeps...), py.RuntimeDeps...)
+	packages = det.UniqueStrings(append(packages, dg.Detector.Dependencies.System...))
+
+	label, command, port := dg.pythonCommand(py)
+
+	return map[string]any{
+		"Label":     label,
+		"PyVersion": version,
+		"Installer": py.Installer,
+		"Packages":  strings.Join(packages, " "),
+		"Setup":     tc.Setup,
+		"Env":       tc.Env,
+		"Manifests": strings.Join(tc.Manifests, " "),
+		"CacheDir":  tc.CacheDir,
+		"Install":   tc.Install,
+		"Port":      port,
+		"Command":   command,
+	}
+}
+
+// pythonCommand picks the CMD for the framework. Declared servers win:
+// gunicorn for Django/Flask, uvicorn for FastAPI; otherwise the framework's
+// own development server.
+func (dg *DockerfileGenerator) pythonCommand(py det.PythonDependencies) (label, command, port string) {
+	quote := func(args ...string) string { return `"` + strings.Join(args, `", "`) + `"` }
+	gunicorn := det.ContainsString(py.Servers, "gunicorn")
+	switch dg.Detector.Type {
+	case det.TypePythonDjango:
+		port = dg.primaryPort("8000")
+		if gunicorn && py.AppModule != "" {
+			return "Django", quote("gunicorn", "--bind", "0.0.0.0:"+port, py.AppModule), port
+		}
+		return "Django", quote("python", "manage.py", "runserver", "0.0.0.0:"+port), port
+	case det.TypePythonFlask:
+		port = dg.primaryPort("5000")
+		app := py.AppModule
+		if app == "" {
+			app = "app:app"
+		}
+		if gunicorn {
+			return "Flask", quote("gunicorn", "--bind", "0.0.0.0:"+port, app), port
+		}
+		return "Flask", quote("flask", "--app", app, "run", "--host", "0.0.0.0", "--port", port), port
+	case det.TypePythonFastAPI:
+		port = dg.primaryPort("8000")
+		app := py.AppModule
+		if app == "" {
+			app = "main:app"
+		}
+		return "FastAPI", quote("uvicorn", app, "--host", "0.0.0.0", "--port", port), port
+	}
+	for _, script := range []string{"main.py", "app.py"} {
+		if fileExists(dg.Detector.Root, script) {
+			return "Python", quote("python", script), dg.primaryPort("")
+		}
+	}
+	return "Python", quote("python"), ""
+}
@@ -0,0 +1,88 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/detector"
+)
+
+func TestGeneratePython_Installers(t *testing.T) {
+	cases := []struct {
+		name  string
+		files []string
+		deps  detector.PythonDependencies
+		want  []string
+	}{
+		{"uv", []string{"pyproject.toml", "uv.lock"},
+			detector.PythonDependencies{Installer: detector.PythonInstallerUV, Lockfile: "uv.lock"},
+			[]string{"COPY --from=ghcr.io/astral-sh/uv:0.8.15 /uv", "COPY pyproject.toml uv.lock ./", "--mount=type=cache,target=/root/.cache/uv", "uv sync --frozen --no-install-project"}},
+		{"poetry", []string{"pyproject.toml", "poetry.lock"},
+			detector.PythonDependencies{Installer: detector.PythonInstallerPoetry, Lockfile: "poetry.lock"},
+			[]string{"pip install poetry", "COPY pyproject.toml poetry.lock ./", "poetry install --no-root"}},
+		{"pipenv", []string{"Pipfile", "Pipfile.lock"},
+			detector.PythonDependencies{Installer: detector.PythonInstallerPipenv, Lockfile: "Pipfile.lock"},
+			[]string{"pip install pipenv", "COPY Pipfile Pipfile.lock ./", "pipenv install --dev --deploy"}},
+		{"pdm", []string{"pyproject.toml"},
+			detector.PythonDependencies{Installer: detector.PythonInstallerPDM},
+			[]string{"pip install pdm", "COPY pyproject.toml ./", "pdm install --no-self\n"}},
+		{"pip", []string{"requirements.txt"},
+			detector.PythonDependencies{Installer: detector.PythonInstallerPip, SystemDeps: []string{"postgresql-dev"}, RuntimeDeps: []string{"libpq"}},
+			[]string{"apk add --no-cache build-base postgresql-dev libpq", "COPY requirements.txt ./", "python -m venv /app/.venv && pip install -r requirements.txt"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range c.files {
+				os.WriteFile(filepath.Join(dir, f), nil, 0o644)
+			}
+			d := detector.NewProjectDetector(dir)
+			d.Type = detector.TypePythonGeneric
+			c.deps.Version = "3.12"
+			d.Dependencies.Python = c.deps
+			df, err := NewDockerfileGenerator(d).Generate()
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			if strings.Contains(df, "|| true") {
+				t.Fatalf("install failures must not be swallowed:\n%s", df)
+			}
+			for _, want := range append(c.want, "FROM python:3.12-alpine AS base", "COPY --from=deps /app/.venv /app/.venv") {
+				if !strings.Contains(df, want) {
+					t.Fatalf("expected %q in:\n%s", want, df)
+				}
+			}
+		})
+	}
+}
+
+func TestGeneratePython_FrameworkCommands(t *testing.T) {
+	cases := []struct {
+		typ  detector.ProjectType
+		deps detector.PythonDependencies
+		want string
+	}{
+		{detector.TypePythonDjango, detector.PythonDependencies{},
+			`CMD ["python", "manage.py", "runserver", "0.0.0.0:8000"]`},
+		{detector.TypePythonDjango, detector.PythonDependencies{Servers: []string{"gunicorn"}, AppModule: "shop.wsgi:application"},
+			`CMD ["gunicorn", "--bind", "0.0.0.0:8000", "shop.wsgi:application"]`},
+		{detector.TypePythonFlask, detector.PythonDependencies{},
+			`CMD ["flask", "--app", "app:app", "run", "--host", "0.0.0.0", "--port", "5000"]`},
+		{detector.TypePythonFastAPI, detector.PythonDependencies{AppModule: "app.main:api"},
+			`CMD ["uvicorn", "app.main:api", "--host", "0.0.0.0", "--port", "8000"]`},
+	}
+	for _, c := range cases {
+		d := detector.NewProjectDetector(t.TempDir())
+		d.Type = c.typ
+		d.Dependencies.Python = c.deps
+		df, err := NewDockerfileGenerator(d).Generate()
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		if !strings.Contains(df, c.want) {
+			t.Fatalf("%s: expected %q in:\n%s", c.typ, c.want, df)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package build
+
+import (
+	"strings"
+
+	det "mitl/internal/detector"
+)
+
+// rubyTemplate installs gems from Gemfile/Gemfile.lock alone so the bundle
+// layer survives source edits; downloaded .gem files live on a cache mount.
+// The final stage keeps build-base so `mitl run bundle install` can compile
+// native gems into the gems volume.
+const rubyTemplate = `# syntax=docker/dockerfile:1.4
+# Auto-generated by Mitl for {{if .Rails}}Rails{{else}}Ruby{{end}} project
+# Ruby: {{.RubyVersion}}{{if .Rails}}, Rails {{.RailsVersion}}{{end}}
+
+FROM ruby:{{.RubyVersion}}-alpine AS base
+RUN apk add --no-cache build-base git tzdata{{if .Packages}} {{.Packages}}{{end}}
+ENV BUNDLE_PATH=/usr/local/bundle BUNDLE_GLOBAL_GEM_CACHE=true
+WORKDIR /app
+
+FROM base AS gems
+COPY {{.Manifests}} ./
+{{if .Bundler}}RUN gem install bundler -v {{.Bundler}}
+{{end}}RUN --mount=type=cache,target=/root/.bundle/cache \
+    {{if .Locked}}BUNDLE_FROZEN=true {{end}}bundle install --jobs 4
+
+FROM base
+COPY --from=gems /usr/local/bundle /usr/local/bundle
+COPY . .
+{{if .Assets}}RUN SECRET_KEY_BASE_DUMMY=1 RAILS_ENV=production bundle exec rails assets:precompile
+{{end}}{{if .Port}}EXPOSE {{.Port}}
+{{end}}CMD [{{.Command}}]
+`
+
+// GenerateRuby creates a Dockerfile for Bundler projects, with asset
+// precompilation for Rails
+func (dg *DockerfileGenerator) GenerateRuby() (string, error) {
+	return dg.render(rubyTemplate, dg.rubyData())
+}
+
+// rubyData returns the data rubyTemplate is rendered with
+func (dg *DockerfileGenerator) rubyData() map[string]any {
+	rd := dg.Detector.Dependencies.Ruby
+	version := rd.Version
+	if v := dg.languageVersion("ruby"); v != "" {
+		version = v
+	}
+	if version == "" {
+		version = det.DefaultRuby
+	}
+	rails := dg.Detector.Type == det.TypeRubyRails
+
+	// Gemfile `ruby file: ".ruby-version"` needs the file to resolve
+	manifests := append([]string{"Gemfile"}, projectFiles(dg.Detector.Root, "Gemfile.lock", ".ruby-version")...)
+
+	packages := append(append([]string{}, rd.BuildDeps...), rd.RuntimeDeps...)
+	if rd.NodeRequired {
+		packages = append(packages, "nodejs", "yarn")
+	}
+	packages = det.UniqueStrings(append(packages, dg.Detector.Dependencies.System...))
+
+	// Rails and rack apps have a known port; scripts and irb don't listen
+	port := dg.primaryPort("")
+	command := `"irb"`
+	switch {
+	case rails:
+		command = `"bundle", "exec", "rails", "server", "-b", "0.0.0.0", "-p", "` + port + `"`
+	case fileExists(dg.Detector.Root, "config.ru"):
+		command = `"bundle", "exec", "rackup", "-o", "0.0.0.0", "-p", "` + port + `"`
+	case fileExists(dg.Detector.Root, "app.rb"):
+		command = `"bundle", "exec", "ruby", "app.rb"`
+	}
+
+	return map[string]any{
+		"RubyVersion":  version,
+		"Rails":        rails,
+		"RailsVersion": dg.Detector.Version,
+		"Packages":     strings.Join(packages, " "),
+		"Manifests":    strings.Join(manifests, " "),
+		"Bundler":      rd.Bundler,
+		"Locked":       rd.Locked,
+		"Assets":       rails && rd.Assets,
+		"Port":         port,
+		"Command":      command,
+	}
+}
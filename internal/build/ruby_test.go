@@ -0,0 +1,57 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/detector"
+)
+
+func TestGenerateRuby_Rails(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Gemfile"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "Gemfile.lock"), nil, 0o644)
+	d := detector.NewProjectDetector(dir)
+	d.Type = detector.TypeRubyRails
+	d.Version = "7.1.3"
+	d.Dependencies.Ruby = detector.RubyDependencies{
+		Version: "3.2.2", Bundler: "2.5.6", Locked: true, Assets: true,
+		BuildDeps: []string{"postgresql-dev"}, RuntimeDeps: []string{"libpq"},
+	}
+	df, err := NewDockerfileGenerator(d).Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	for _, want := range []string{
+		"FROM ruby:3.2.2-alpine AS base",
+		"apk add --no-cache build-base git tzdata postgresql-dev libpq",
+		"COPY Gemfile Gemfile.lock ./",
+		"gem install bundler -v 2.5.6",
+		"--mount=type=cache,target=/root/.bundle/cache",
+		"BUNDLE_FROZEN=true bundle install",
+		"rails assets:precompile",
+		"EXPOSE 3000",
+		`CMD ["bundle", "exec", "rails", "server", "-b", "0.0.0.0", "-p", "3000"]`,
+	} {
+		if !strings.Contains(df, want) {
+			t.Fatalf("expected %q in:\n%s", want, df)
+		}
+	}
+}
+
+func TestGenerateRuby_ScriptHasNoServer(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Gemfile"), nil, 0o644)
+	d := detector.NewProjectDetector(dir)
+	d.Type = detector.TypeRubyGeneric
+	df, err := NewDockerfileGenerator(d).Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if !strings.Contains(df, "FROM ruby:"+detector.DefaultRuby+"-alpine") || strings.Contains(df, "EXPOSE") ||
+		strings.Contains(df, "assets:precompile") || strings.Contains(df, "BUNDLE_FROZEN") {
+		t.Fatalf("unexpected generic ruby Dockerfile:\n%s", df)
+	}
+}
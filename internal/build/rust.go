@@ -0,0 +1,76 @@
+package build
+
+import (
+	"strings"
+
+	det "mitl/internal/detector"
+)
+
+// rustTemplate follows the cargo-chef pattern: the planner reduces the
+// workspace to a dependency recipe so the cook layer is only rebuilt when
+// Cargo.toml/Cargo.lock change. The final stage keeps the toolchain so
+// `mitl run cargo ...` works against the registry and target volumes.
+const rustTemplate = `# syntax=docker/dockerfile:1.4
+# Auto-generated by Mitl for Rust project
+# Rust: {{.RustVersion}}{{if .Channel}} (toolchain {{.Channel}}){{end}}{{if .Workspace}}, workspace{{end}}
+
+FROM rust:{{.RustVersion}}-alpine AS chef
+RUN apk add --no-cache musl-dev{{if .SystemPackages}} {{.SystemPackages}}{{end}}
+RUN --mount=type=cache,target=/usr/local/cargo/registry \
+    cargo install cargo-chef --locked
+WORKDIR /app
+
+FROM chef AS planner
+COPY . .
+RUN cargo chef prepare --recipe-path recipe.json
+
+FROM chef AS builder
+COPY --from=planner /app/recipe.json recipe.json
+{{if .Channel}}COPY rust-toolchain* ./
+{{end}}RUN --mount=type=cache,target=/usr/local/cargo/registry \
+    --mount=type=cache,target=/usr/local/cargo/git \
+    cargo chef cook --release{{if .Locked}} --locked{{end}}{{if .Workspace}} --workspace{{end}} --recipe-path recipe.json
+COPY . .
+RUN --mount=type=cache,target=/usr/local/cargo/registry \
+    --mount=type=cache,target=/usr/local/cargo/git \
+    cargo build --release{{if .Locked}} --locked{{end}}{{if .Workspace}} --workspace{{end}} && \
+    mkdir -p /out && find target/release -maxdepth 1 -type f -perm -u+x -exec cp {} /out/ \;
+
+FROM chef
+WORKDIR /app
+COPY --from=builder /out/ /usr/local/bin/
+COPY . .
+{{if .Binary}}CMD ["/usr/local/bin/{{.Binary}}"]{{else}}CMD ["cargo", "run", "--release"]{{end}}
+`
+
+// GenerateRust creates a cargo-chef multi-stage Dockerfile for Cargo
+// packages and workspaces
+func (dg *DockerfileGenerator) GenerateRust() (string, error) {
+	return dg.render(rustTemplate, dg.rustData())
+}
+
+// rustData returns the data rustTemplate is rendered with
+func (dg *DockerfileGenerator) rustData() map[string]any {
+	rd := dg.Detector.Dependencies.Rust
+	version := rd.Version
+	if v := dg.languageVersion("rust"); v != "" {
+		version = v
+	}
+	if version == "" {
+		version = "1"
+	}
+	binary := ""
+	if len(rd.Binaries) > 0 {
+		binary = rd.Binaries[0]
+	}
+	packages := det.UniqueStrings(append(append([]string{}, rd.SystemDeps...), dg.Detector.Dependencies.System...))
+
+	return map[string]any{
+		"RustVersion":    version,
+		"Channel":        rd.Channel,
+		"Workspace":      rd.Workspace,
+		"Locked":         rd.Locked,
+		"Binary":         binary,
+		"SystemPackages": strings.Join(packages, " "),
+	}
+}
@@ -0,0 +1,33 @@
+package build
+
+import (
+	"strings"
+	"testing"
+
+	"mitl/internal/detector"
+)
+
+func TestGenerateRust(t *testing.T) {
+	d := detector.NewProjectDetector(t.TempDir())
+	d.Type = detector.TypeRustCargo
+	d.Dependencies.Rust = detector.RustDependencies{
+		Version: "1.78", Workspace: true, Locked: true,
+		Binaries: []string{"api"}, SystemDeps: []string{"openssl-dev"},
+	}
+	df, err := NewDockerfileGenerator(d).Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	for _, want := range []string{
+		"FROM rust:1.78-alpine AS chef",
+		"apk add --no-cache musl-dev openssl-dev",
+		"cargo chef prepare --recipe-path recipe.json",
+		"cargo chef cook --release --locked --workspace",
+		"--mount=type=cache,target=/usr/local/cargo/registry",
+		`CMD ["/usr/local/bin/api"]`,
+	} {
+		if !strings.Contains(df, want) {
+			t.Fatalf("expected %q in:\n%s", want, df)
+		}
+	}
+}
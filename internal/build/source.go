@@ -0,0 +1,152 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	e "mitl/pkg/errors"
+)
+
+// Build modes choose what a capsule is built from
+const (
+	ModeAuto       = "auto"       // the project's Dockerfile if it has one, else generate
+	ModeDockerfile = "dockerfile" // the project's Dockerfile; an error when there is none
+	ModeGenerate   = "generate"   // always the generated Dockerfile
+)
+
+// Modes lists the accepted build modes
+var Modes = []string{ModeAuto, ModeDockerfile, ModeGenerate}
+
+// Source handling chooses what identifies a capsule
+const (
+	// SourceMounted capsules are identified by their dependencies alone:
+	// run and shell mount the project over /app, so source edits reuse them
+	SourceMounted = "mounted"
+	// SourceBaked capsules are identified by every project file, for images
+	// that must carry the current source
+	SourceBaked = "baked"
+)
+
+// SourceHandlings lists the accepted build.source values
+var SourceHandlings = []string{SourceMounted, SourceBaked}
+
+// ProjectDockerfiles are the files auto mode looks for in the project root,
+// in lookup order
+var ProjectDockerfiles = []string{"Dockerfile", "Containerfile"}
+
+// Source describes what a capsule is built from: a Dockerfile shipped with
+// the project or the generated one, plus the stage and build arguments
+type Source struct {
+	Dockerfile string            // path relative to the project root; "" when generated
+	Target     string            // stage passed as --target, if any
+	Args       map[string]string // values passed as --build-arg
+}
+
+// Generated reports whether the capsule is built from mitl's Dockerfile
+func (s Source) Generated() bool {
+	return s.Dockerfile == ""
+}
+
+// Label identifies the source in the capsule's cache key: "generated" or
+// the Dockerfile path, followed by "#<target>" and ";args=<hash>" when set.
+// Changing the stage or a build argument therefore rebuilds the capsule.
+func (s Source) Label() string {
+	label := "generated"
+	if !s.Generated() {
+		label = filepath.ToSlash(s.Dockerfile)
+	}
+	if s.Target != "" {
+		label += "#" + s.Target
+	}
+	if len(s.Args) > 0 {
+		keys := make([]string, 0, len(s.Args))
+		for k := range s.Args {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		h := sha256.New()
+		for _, k := range keys {
+			h.Write([]byte(k + "=" + s.Args[k] + "\n"))
+		}
+		label += ";args=" + hex.EncodeToString(h.Sum(nil))[:12]
+	}
+	return label
+}
+
+// BuildArgs returns the --target and --build-arg flags for a build command
+// in a stable order
+func (s Source) BuildArgs() []string {
+	var args []string
+	if s.Target != "" {
+		args = append(args, "--target", s.Target)
+	}
+	keys := make([]string, 0, len(s.Args))
+	for k := range s.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--build-arg", k+"="+s.Args[k])
+	}
+	return args
+}
+
+// FindDockerfile returns the first of ProjectDockerfiles present in root
+func FindDockerfile(root string) (string, bool) {
+	for _, name := range ProjectDockerfiles {
+		if info, err := os.Stat(filepath.Join(root, name)); err == nil && !info.IsDir() {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// ResolveSource picks the capsule's source for a project root. An explicit
+// dockerfile implies dockerfile mode; an empty mode means auto.
+func ResolveSource(root, mode, dockerfile, target string, args map[string]string) (Source, error) {
+	src := Source{Target: target, Args: args}
+	if mode == "" {
+		mode = ModeAuto
+		if dockerfile != "" {
+			mode = ModeDockerfile
+		}
+	}
+	switch mode {
+	case ModeGenerate:
+		return src, nil
+	case ModeAuto, ModeDockerfile:
+	default:
+		return src, e.New(e.ErrInvalidConfig, "Unknown build mode "+mode).
+			WithSuggestion("Use one of: " + strings.Join(Modes, ", "))
+	}
+
+	if dockerfile == "" {
+		name, ok := FindDockerfile(root)
+		if !ok {
+			if mode == ModeAuto {
+				return src, nil
+			}
+			return src, e.New(e.ErrDockerfileNotFound, "No Dockerfile or Containerfile in "+root).
+				WithSuggestion("Add one, set build.dockerfile in mitl.yaml, or build with --generate")
+		}
+		src.Dockerfile = name
+		return src, nil
+	}
+
+	clean := path.Clean(filepath.ToSlash(dockerfile))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return src, e.New(e.ErrInvalidConfig, "Dockerfile "+dockerfile+" is outside the project").
+			WithSuggestion("Use a path relative to the project root")
+	}
+	if info, err := os.Stat(filepath.Join(root, filepath.FromSlash(clean))); err != nil || info.IsDir() {
+		return src, e.New(e.ErrDockerfileNotFound, "Dockerfile not found: "+dockerfile).
+			WithContext("root", root)
+	}
+	src.Dockerfile = clean
+	return src, nil
+}
@@ -0,0 +1,59 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSource(t *testing.T) {
+	dir := t.TempDir()
+
+	src, err := ResolveSource(dir, "", "", "", nil)
+	if err != nil || !src.Generated() || src.Label() != "generated" {
+		t.Fatalf("auto without a Dockerfile should generate, got %+v, %v", src, err)
+	}
+	if _, err := ResolveSource(dir, ModeDockerfile, "", "", nil); err == nil {
+		t.Fatalf("dockerfile mode without a Dockerfile should fail")
+	}
+
+	os.WriteFile(filepath.Join(dir, "Containerfile"), []byte("FROM alpine\n"), 0o644)
+	if src, _ := ResolveSource(dir, "", "", "", nil); src.Dockerfile != "Containerfile" {
+		t.Fatalf("expected Containerfile, got %+v", src)
+	}
+	os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM alpine\n"), 0o644)
+	if src, _ := ResolveSource(dir, ModeAuto, "", "", nil); src.Dockerfile != "Dockerfile" {
+		t.Fatalf("expected Dockerfile to win, got %+v", src)
+	}
+	if src, _ := ResolveSource(dir, ModeGenerate, "", "", nil); !src.Generated() {
+		t.Fatalf("generate mode should ignore the project Dockerfile, got %+v", src)
+	}
+
+	os.MkdirAll(filepath.Join(dir, "docker"), 0o755)
+	os.WriteFile(filepath.Join(dir, "docker", "dev.Dockerfile"), []byte("FROM alpine AS dev\n"), 0o644)
+	src, err = ResolveSource(dir, "", "./docker/dev.Dockerfile", "dev", map[string]string{"B": "2", "A": "1"})
+	if err != nil || src.Dockerfile != "docker/dev.Dockerfile" {
+		t.Fatalf("expected explicit Dockerfile, got %+v, %v", src, err)
+	}
+	if !strings.HasPrefix(src.Label(), "docker/dev.Dockerfile#dev;args=") {
+		t.Fatalf("unexpected label %q", src.Label())
+	}
+	if got := strings.Join(src.BuildArgs(), " "); got != "--target dev --build-arg A=1 --build-arg B=2" {
+		t.Fatalf("unexpected build args %q", got)
+	}
+	changed := src
+	changed.Args = map[string]string{"A": "1", "B": "3"}
+	if changed.Label() == src.Label() {
+		t.Fatalf("changing a build arg must change the label")
+	}
+
+	for _, bad := range []string{"../Dockerfile", "/etc/Dockerfile", "missing.Dockerfile"} {
+		if _, err := ResolveSource(dir, "", bad, "", nil); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+	if _, err := ResolveSource(dir, "always", "", "", nil); err == nil {
+		t.Fatalf("expected error for an unknown mode")
+	}
+}
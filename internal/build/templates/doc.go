@@ -1,4 +0,0 @@
-// Package templates provides Dockerfile templates and build configurations
-// for the mitl tool. This package manages template generation, rendering,
-// and customization for different project types and build environments.
-package templates
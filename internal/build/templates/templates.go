@@ -1,41 +1,67 @@
-// Package templates provides Dockerfile templates for various stacks.
-// Keep templates separate for maintainability.
+// Package templates locates user Dockerfile templates. A template named
+// <project type>.Dockerfile.tmpl in the project's .mitl/templates directory,
+// or else in ~/.mitl/templates, replaces mitl's built-in template for that
+// type and is rendered with the same data.
 package templates
 
-// PHPTemplates for different PHP frameworks
-var PHPTemplates = map[string]string{
-	"generic": PHPGenericTemplate,
-}
-
-// NodeTemplates for different Node frameworks
-var NodeTemplates = map[string]string{
-	"generic": NodeGenericTemplate,
-}
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
 
-// PHPGenericTemplate for generic PHP applications
-const PHPGenericTemplate = `# PHP Generic Application
-FROM php:{{.Version}}-fpm-alpine
+// Suffix follows the project type in a template file name, e.g.
+// node-next.Dockerfile.tmpl
+const Suffix = ".Dockerfile.tmpl"
 
-RUN docker-php-ext-install {{.Extensions}}
+// ProjectDir holds a project's templates, relative to its root
+const ProjectDir = ".mitl/templates"
 
-WORKDIR /app
-COPY . .
+// Dirs returns the template directories for a project root in lookup
+// order: the project's own, then the user's
+func Dirs(root string) []string {
+	dirs := []string{filepath.Join(root, ProjectDir)}
+	if home := homeDir(); home != "" {
+		dirs = append(dirs, filepath.Join(home, ".mitl", "templates"))
+	}
+	return dirs
+}
 
-{{if .UseComposer}}
-COPY --from=composer:2 /usr/bin/composer /usr/bin/composer
-RUN composer install --no-dev --optimize-autoloader
-{{end}}
+// Find returns the template overriding the built-in one for a project
+// type, if there is one
+func Find(root, projectType string) (string, bool) {
+	for _, dir := range Dirs(root) {
+		p := filepath.Join(dir, projectType+Suffix)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p, true
+		}
+	}
+	return "", false
+}
 
-CMD ["php-fpm"]
-`
+// Files returns every template in the lookup directories, keyed by the
+// name the digest records: ".mitl/templates/<file>" for the project's,
+// "~/.mitl/templates/<file>" for the user's. Hashing all of them keeps the
+// capsule tag independent of detection, which runs after the cache check.
+func Files(root string) map[string]string {
+	files := map[string]string{}
+	labels := []string{filepath.ToSlash(ProjectDir), "~/.mitl/templates"}
+	for i, dir := range Dirs(root) {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*"+Suffix))
+		sort.Strings(matches)
+		for _, p := range matches {
+			files[labels[i]+"/"+filepath.Base(p)] = p
+		}
+	}
+	return files
+}
 
-// NodeGenericTemplate for generic Node app (simple)
-const NodeGenericTemplate = `# Node Generic Application
-FROM node:{{.NodeVersion}}-alpine
-WORKDIR /app
-COPY package*.json ./
-RUN npm ci
-COPY . .
-EXPOSE 3000
-CMD ["npm", "start"]
-`
+// homeDir follows HOME like the rest of mitl's configuration
+func homeDir() string {
+	if home := os.Getenv("HOME"); home != "" {
+		return home
+	}
+	home, _ := os.UserHomeDir()
+	return strings.TrimSpace(home)
+}
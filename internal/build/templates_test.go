@@ -0,0 +1,60 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/detector"
+)
+
+func TestGenerate_UserTemplates(t *testing.T) {
+	dir, home := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	d := detector.NewProjectDetector(dir)
+	d.Type = detector.TypeGoModule
+	d.Dependencies.Go = detector.GoDependencies{Version: "1.22", Module: "example.com/api", Target: "."}
+
+	builtin, err := NewDockerfileGenerator(d).Generate()
+	if err != nil || !strings.Contains(builtin, "FROM golang:1.22-alpine") {
+		t.Fatalf("expected the built-in template, got %v:\n%s", err, builtin)
+	}
+
+	write := func(path, content string) {
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(home, ".mitl", "templates", "go.Dockerfile.tmpl"), "FROM home:{{.GoVersion}}\n")
+	if df, _ := NewDockerfileGenerator(d).Generate(); df != "FROM home:1.22\n" {
+		t.Fatalf("expected the user template, got:\n%s", df)
+	}
+	// the project's template wins over the user's
+	write(filepath.Join(dir, ".mitl", "templates", "go.Dockerfile.tmpl"), "FROM project:{{.GoVersion}} # {{.Binary}}\n")
+	if df, _ := NewDockerfileGenerator(d).Generate(); df != "FROM project:1.22 # api\n" {
+		t.Fatalf("expected the project template, got:\n%s", df)
+	}
+	// other types keep their built-in template
+	d.Type = detector.TypeStatic
+	if df, _ := NewDockerfileGenerator(d).Generate(); !strings.Contains(df, "FROM alpine:3") {
+		t.Fatalf("expected the built-in generic template, got:\n%s", df)
+	}
+
+	d.Type = detector.TypeGoModule
+	write(filepath.Join(dir, ".mitl", "templates", "go.Dockerfile.tmpl"), "FROM {{.GoVersion\n")
+	if _, err := NewDockerfileGenerator(d).Generate(); err == nil || !strings.Contains(err.Error(), "go.Dockerfile.tmpl") {
+		t.Fatalf("expected a parse error naming the template, got %v", err)
+	}
+}
+
+func TestTemplateData(t *testing.T) {
+	d := detector.NewProjectDetector(t.TempDir())
+	d.Type = detector.TypeRustCargo
+	d.Dependencies.Rust = detector.RustDependencies{Version: "1.78", Binaries: []string{"api"}}
+	data := NewDockerfileGenerator(d).TemplateData()
+	if data["RustVersion"] != "1.78" || data["Binary"] != "api" {
+		t.Fatalf("unexpected template data %v", data)
+	}
+}
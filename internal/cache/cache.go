@@ -4,6 +4,7 @@ package cache
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -35,10 +36,29 @@ type cacheEntry struct {
 
 // ImageDetails contains basic image metadata
 type ImageDetails struct {
-	Created      string   `json:"Created"`
-	Size         int64    `json:"Size"`
-	Architecture string   `json:"Architecture"`
-	RepoDigests  []string `json:"RepoDigests"`
+	Created      string            `json:"Created"`
+	Size         int64             `json:"Size"`
+	Architecture string            `json:"Architecture"`
+	RepoDigests  []string          `json:"RepoDigests"`
+	Labels       map[string]string `json:"Labels,omitempty"` // podman reports labels at the top level
+	Config       ImageConfig       `json:"Config"`
+}
+
+// ImageConfig holds the subset of the image config mitl reads
+type ImageConfig struct {
+	Labels map[string]string `json:"Labels,omitempty"`
+}
+
+// AllLabels merges labels from the image config and the top level
+func (d ImageDetails) AllLabels() map[string]string {
+	out := make(map[string]string, len(d.Labels)+len(d.Config.Labels))
+	for k, v := range d.Labels {
+		out[k] = v
+	}
+	for k, v := range d.Config.Labels {
+		out[k] = v
+	}
+	return out
 }
 
 // Statistics contains cache statistics
@@ -95,45 +115,84 @@ func (c *CapsuleCache) ExistsWithDetails() (bool, ImageDetails, error) {
 		return exists, ImageDetails{}, err
 	}
 
-	cmd := execCommand(c.runtime, "inspect", c.tag, "--format", "{{json .}}")
+	details, err := inspectImage(c.runtime, c.tag)
+	if err != nil {
+		if errors.Is(err, errInspectFailed) {
+			return false, ImageDetails{}, err
+		}
+		return true, ImageDetails{}, err
+	}
+	return true, details, nil
+}
+
+var errInspectFailed = errors.New("inspect failed")
+
+// inspectImage runs `{runtime} inspect {tag} --format '{{json .}}'`
+func inspectImage(runtime, tag string) (ImageDetails, error) {
+	cmd := execCommand(runtime, "inspect", tag, "--format", "{{json .}}")
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		return false, ImageDetails{}, fmt.Errorf("%s inspect failed: %v", c.runtime, err)
+		return ImageDetails{}, fmt.Errorf("%s %w: %v", runtime, errInspectFailed, err)
 	}
 
 	var details ImageDetails
 	if err := json.Unmarshal(stdout.Bytes(), &details); err != nil {
-		return true, ImageDetails{}, fmt.Errorf("parse inspect output: %w", err)
+		return ImageDetails{}, fmt.Errorf("parse inspect output: %w", err)
 	}
-
-	return true, details, nil
+	return details, nil
 }
 
-// InvalidateCache removes the cache entry to force re-verification
-func (c *CapsuleCache) InvalidateCache() {
-	c.mu.Lock()
-	delete(c.memCache, c.tag)
-	c.mu.Unlock()
+// Labels returns the mitl labels stamped on the capsule
+func (c *CapsuleCache) Labels() (Labels, error) {
+	exists, details, err := c.ExistsWithDetails()
+	if err != nil {
+		return Labels{}, err
+	}
+	if !exists {
+		return Labels{}, fmt.Errorf("capsule %s not found", c.tag)
+	}
+	return ParseLabels(details.AllLabels()), nil
 }
 
-// ValidateDigest checks that the image digest matches expected.
-// This prevents using incorrect capsules when digest changes.
-func (c *CapsuleCache) ValidateDigest(expectedDigest string) bool {
+// ValidateLabels checks that the capsule exists and that its labels match the
+// expected cache key. Capsules without mitl labels (built by older versions
+// or pulled from a registry) fall back to matching RepoDigests.
+func (c *CapsuleCache) ValidateLabels(expected Labels) bool {
 	exists, details, err := c.ExistsWithDetails()
 	if err != nil || !exists {
 		return false
 	}
+	labels := ParseLabels(details.AllLabels())
+	if labels.Digest != "" {
+		return labels.Matches(expected)
+	}
+	if expected.Digest == "" {
+		return false
+	}
 	for _, d := range details.RepoDigests {
-		if strings.Contains(d, expectedDigest) {
+		if strings.Contains(d, expected.Digest) {
 			return true
 		}
 	}
 	return false
 }
 
+// InvalidateCache removes the cache entry to force re-verification
+func (c *CapsuleCache) InvalidateCache() {
+	c.mu.Lock()
+	delete(c.memCache, c.tag)
+	c.mu.Unlock()
+}
+
+// ValidateDigest checks that the image digest matches expected.
+// This prevents using incorrect capsules when digest changes.
+func (c *CapsuleCache) ValidateDigest(expectedDigest string) bool {
+	return c.ValidateLabels(Labels{Digest: expectedDigest})
+}
+
 // validateDigest provides a private version for backward compatibility
 func (c *CapsuleCache) validateDigest(expectedDigest string) bool {
 	return c.ValidateDigest(expectedDigest)
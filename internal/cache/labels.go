@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"sort"
+	"time"
+)
+
+// Label keys stamped on every capsule at build time. They make locally built
+// images self-describing, since RepoDigests is only populated for images that
+// were pushed to or pulled from a registry.
+const (
+	LabelDigest           = "run.mitl.digest"
+	LabelLockfileHash     = "run.mitl.lockfile-hash"
+	LabelGeneratorVersion = "run.mitl.generator-version"
+	LabelBuildSource      = "run.mitl.build-source"
+	LabelDetectorType     = "run.mitl.detector-type"
+	LabelMitlVersion      = "run.mitl.version"
+	LabelBuildTime        = "run.mitl.build-time"
+	LabelProject          = "run.mitl.project"
+
+	// labelOCICreated mirrors the build time in the standard OCI annotation
+	labelOCICreated = "org.opencontainers.image.created"
+)
+
+// Labels describes which project and digest a capsule was built from
+type Labels struct {
+	Digest           string
+	LockfileHash     string
+	GeneratorVersion string
+	BuildSource      string // "generated" or the project Dockerfile, with stage and args
+	DetectorType     string
+	MitlVersion      string
+	Project          string
+	BuildTime        time.Time
+}
+
+// Map returns the labels keyed by label name, omitting empty values
+func (l Labels) Map() map[string]string {
+	m := make(map[string]string)
+	set := func(k, v string) {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	set(LabelDigest, l.Digest)
+	set(LabelLockfileHash, l.LockfileHash)
+	set(LabelGeneratorVersion, l.GeneratorVersion)
+	set(LabelBuildSource, l.BuildSource)
+	set(LabelDetectorType, l.DetectorType)
+	set(LabelMitlVersion, l.MitlVersion)
+	set(LabelProject, l.Project)
+	if !l.BuildTime.IsZero() {
+		ts := l.BuildTime.UTC().Format(time.RFC3339)
+		m[LabelBuildTime] = ts
+		m[labelOCICreated] = ts
+	}
+	return m
+}
+
+// BuildArgs returns --label flags for a build command in a stable order
+func (l Labels) BuildArgs() []string {
+	m := l.Map()
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	args := make([]string, 0, len(keys)*2)
+	for _, k := range keys {
+		args = append(args, "--label", k+"="+m[k])
+	}
+	return args
+}
+
+// ParseLabels extracts mitl labels from an image label map
+func ParseLabels(m map[string]string) Labels {
+	l := Labels{
+		Digest:           m[LabelDigest],
+		LockfileHash:     m[LabelLockfileHash],
+		GeneratorVersion: m[LabelGeneratorVersion],
+		BuildSource:      m[LabelBuildSource],
+		DetectorType:     m[LabelDetectorType],
+		MitlVersion:      m[LabelMitlVersion],
+		Project:          m[LabelProject],
+	}
+	if ts, err := time.Parse(time.RFC3339, m[LabelBuildTime]); err == nil {
+		l.BuildTime = ts
+	}
+	return l
+}
+
+// Matches reports whether l satisfies every non-empty cache key field of
+// expected (digest, lockfile hash, generator version and build source).
+// Descriptive fields such as project path or build time never cause a
+// mismatch.
+func (l Labels) Matches(expected Labels) bool {
+	if l.Digest == "" {
+		return false
+	}
+	pairs := [][2]string{
+		{expected.Digest, l.Digest},
+		{expected.LockfileHash, l.LockfileHash},
+		{expected.GeneratorVersion, l.GeneratorVersion},
+		{expected.BuildSource, l.BuildSource},
+	}
+	for _, p := range pairs {
+		if p[0] != "" && p[0] != p[1] {
+			return false
+		}
+	}
+	return true
+}
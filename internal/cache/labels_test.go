@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLabels_RoundTrip(t *testing.T) {
+	built := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := Labels{
+		Digest:           "abc123",
+		LockfileHash:     "def456",
+		GeneratorVersion: "1",
+		BuildSource:      "Dockerfile#dev",
+		DetectorType:     "node",
+		MitlVersion:      "dev",
+		Project:          "/src/app",
+		BuildTime:        built,
+	}
+	got := ParseLabels(l.Map())
+	if got != l {
+		t.Fatalf("round trip mismatch:\n got: %+v\nwant: %+v", got, l)
+	}
+	if l.Map()[labelOCICreated] != "2024-05-01T12:00:00Z" {
+		t.Fatalf("expected OCI created label, got %v", l.Map())
+	}
+
+	args := strings.Join(Labels{Digest: "abc", Project: "/p"}.BuildArgs(), " ")
+	want := "--label run.mitl.digest=abc --label run.mitl.project=/p"
+	if args != want {
+		t.Fatalf("BuildArgs = %q, want %q", args, want)
+	}
+}
+
+func TestLabels_Matches(t *testing.T) {
+	have := Labels{Digest: "abc", LockfileHash: "lock", GeneratorVersion: "1", BuildSource: "generated", Project: "/a"}
+	tests := []struct {
+		name     string
+		expected Labels
+		want     bool
+	}{
+		{"all match", Labels{Digest: "abc", LockfileHash: "lock", GeneratorVersion: "1"}, true},
+		{"project ignored", Labels{Digest: "abc", Project: "/b"}, true},
+		{"digest differs", Labels{Digest: "xyz"}, false},
+		{"lockfile differs", Labels{Digest: "abc", LockfileHash: "other"}, false},
+		{"generator bumped", Labels{Digest: "abc", GeneratorVersion: "2"}, false},
+		{"project dockerfile", Labels{Digest: "abc", BuildSource: "Dockerfile"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := have.Matches(tt.expected); got != tt.want {
+				t.Fatalf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if (Labels{}).Matches(Labels{}) {
+		t.Fatalf("unlabelled image must never match")
+	}
+}
+
+func TestCapsuleCache_ValidateLabels(t *testing.T) {
+	originalExec := execCommand
+	defer func() { execCommand = originalExec }()
+
+	img := ImageDetails{Config: ImageConfig{Labels: Labels{Digest: "abc", GeneratorVersion: "1"}.Map()}}
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "images" {
+			return mockCmd("sha256:abc", false)
+		}
+		b, _ := json.Marshal(img)
+		return mockCmd(string(b), false)
+	}
+	c := NewCapsuleCache("docker", "mitl-capsule:abc")
+	if !c.ValidateLabels(Labels{Digest: "abc", GeneratorVersion: "1"}) {
+		t.Fatalf("expected labels to validate")
+	}
+	if c.ValidateLabels(Labels{Digest: "abc", GeneratorVersion: "2"}) {
+		t.Fatalf("expected generator version mismatch to invalidate")
+	}
+	l, err := c.Labels()
+	if err != nil || l.Digest != "abc" {
+		t.Fatalf("Labels() = %+v, %v", l, err)
+	}
+}
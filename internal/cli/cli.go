@@ -52,6 +52,8 @@ func (c *CLI) registerCommands() {
 	c.register(NewRunCommand())
 	c.register(NewShellCommand())
 	c.register(NewInspectCommand())
+	c.register(NewLockCommand())
+	c.register(NewDepsCommand())
 	c.register(NewSetupCommand())
 	c.register(NewRuntimeCommand())
 	c.register(NewDoctorCommand())
@@ -59,6 +61,12 @@ func (c *CLI) registerCommands() {
 	c.register(NewVolumesCommand())
 	c.register(NewBenchCommand())
 	c.register(NewCompletionCommand())
+	c.register(NewUpCommand())
+	c.register(NewDownCommand())
+	c.register(NewPsCommand())
+	c.register(NewLogsCommand())
+	c.register(NewRestartCommand())
+	c.register(NewExecCommand())
 }
 
 // Run executes the CLI with given arguments
@@ -131,6 +131,24 @@ func (completionCmd) Run(args []string) error {
 
 func NewCompletionCommand() Command { return completionCmd{} }
 
+// Lock command pins base images in mitl.lock
+type lockCmd struct{}
+
+func (lockCmd) Name() string            { return "lock" }
+func (lockCmd) Description() string     { return "Pin base images to digests in mitl.lock" }
+func (lockCmd) Run(args []string) error { return commands.Lock(args) }
+
+func NewLockCommand() Command { return lockCmd{} }
+
+// Deps command compares lockfile package sets
+type depsCmd struct{}
+
+func (depsCmd) Name() string            { return "deps" }
+func (depsCmd) Description() string     { return "Compare the packages lockfiles pin" }
+func (depsCmd) Run(args []string) error { return commands.Deps(args) }
+
+func NewDepsCommand() Command { return depsCmd{} }
+
 // build command alias for hydrate
 type buildCmd struct{}
 
@@ -139,3 +157,47 @@ func (buildCmd) Description() string     { return "Build project capsule (alias
 func (buildCmd) Run(args []string) error { return commands.Hydrate(args) }
 
 func NewBuildCommand() Command { return buildCmd{} }
+
+// Service mode commands for long-running capsules
+type upCmd struct{}
+
+func (upCmd) Name() string            { return "up" }
+func (upCmd) Description() string     { return "Start project capsule in the background" }
+func (upCmd) Run(args []string) error { return commands.Up(args) }
+
+type downCmd struct{}
+
+func (downCmd) Name() string            { return "down" }
+func (downCmd) Description() string     { return "Stop background capsule" }
+func (downCmd) Run(args []string) error { return commands.Down(args) }
+
+type psCmd struct{}
+
+func (psCmd) Name() string            { return "ps" }
+func (psCmd) Description() string     { return "List background capsules" }
+func (psCmd) Run(args []string) error { return commands.Ps(args) }
+
+type logsCmd struct{}
+
+func (logsCmd) Name() string            { return "logs" }
+func (logsCmd) Description() string     { return "Show background capsule logs" }
+func (logsCmd) Run(args []string) error { return commands.Logs(args) }
+
+type restartCmd struct{}
+
+func (restartCmd) Name() string            { return "restart" }
+func (restartCmd) Description() string     { return "Restart background capsule" }
+func (restartCmd) Run(args []string) error { return commands.Restart(args) }
+
+type execCmd struct{}
+
+func (execCmd) Name() string            { return "exec" }
+func (execCmd) Description() string     { return "Run command in the running capsule" }
+func (execCmd) Run(args []string) error { return commands.Exec(args) }
+
+func NewUpCommand() Command      { return upCmd{} }
+func NewDownCommand() Command    { return downCmd{} }
+func NewPsCommand() Command      { return psCmd{} }
+func NewLogsCommand() Command    { return logsCmd{} }
+func NewRestartCommand() Command { return restartCmd{} }
+func NewExecCommand() Command    { return execCmd{} }
@@ -3,7 +3,11 @@ package commands
 import (
 	"fmt"
 	"os"
+	"path"
 	"strings"
+	"text/tabwriter"
+
+	"mitl/internal/cache"
 )
 
 // Cache handles cache management commands (list, clean, stats).
@@ -26,21 +30,61 @@ func Cache(args []string) error {
 	}
 }
 
-// listCachedCapsules lists all cached mitl capsule images.
+// listCachedCapsules lists all cached mitl capsule images along with the
+// project, detector type and digest recorded in their labels.
 func listCachedCapsules() error {
 	runtime := findBuildCLI()
-	cmd := execCommand(runtime, "images")
-	out, err := cmd.Output()
+	out, err := execCommand(runtime, "images").Output()
 	if err != nil {
 		return fmt.Errorf("failed to list images: %w", err)
 	}
-	fmt.Println("Cached capsules:")
-	for _, line := range strings.Split(string(out), "\n") {
-		if strings.Contains(line, "mitl-capsule") {
-			fmt.Println(line)
+	tags := capsuleTags(string(out))
+	if len(tags) == 0 {
+		fmt.Println("No cached capsules found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TAG\tPROJECT\tTYPE\tDIGEST\tBUILT")
+	for _, tag := range tags {
+		// Images built before labels were introduced list without them
+		l, _ := cache.NewCapsuleCache(runtime, tag).Labels()
+		built := "-"
+		if !l.BuildTime.IsZero() {
+			built = l.BuildTime.Local().Format("2006-01-02 15:04")
 		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", tag, orDash(l.Project), orDash(l.DetectorType), orDash(l.Digest), built)
 	}
-	return nil
+	return w.Flush()
+}
+
+// capsuleTags picks the capsule images from plain `images` output, whose
+// first two columns are the repository and tag on every runtime; filtering
+// here rather than with --filter or --format works on those without them.
+// Podman prefixes local images with localhost/.
+func capsuleTags(images string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(images, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || path.Base(fields[0]) != "mitl-capsule" {
+			continue
+		}
+		tag := fields[0] + ":" + fields[1]
+		if fields[1] == "<none>" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
 }
 
 // cleanOldCapsules removes all cached mitl capsule images.
@@ -3,6 +3,8 @@ package commands
 import (
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -54,3 +56,29 @@ func TestCache_Clean(t *testing.T) {
 		}
 	})
 }
+
+func TestCache_ListShowsLabels(t *testing.T) {
+	// The cache package inspects through the runtime CLI itself
+	cli := filepath.Join(t.TempDir(), "runtime")
+	os.WriteFile(cli, []byte(`#!/bin/sh
+case "$1" in
+images) [ "$2" = "-q" ] && echo id1 || printf 'REPOSITORY TAG IMAGE ID\nmitl-capsule abc id1\nlocalhost/mitl-capsule def id2\nnode 20 id3\nmitl-capsule-old x id4\n' ;;
+inspect) echo '{"Config":{"Labels":{"run.mitl.digest":"abc","run.mitl.project":"/src/app","run.mitl.detector-type":"node"}}}' ;;
+esac
+`), 0o755)
+	t.Setenv("MITL_BUILD_CLI", cli)
+
+	out := captureOut(t, func() {
+		if err := Cache([]string{"list"}); err != nil {
+			t.Fatalf("list: %v", err)
+		}
+	})
+	for _, want := range []string{"mitl-capsule:abc", "localhost/mitl-capsule:def", "/src/app", "node"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "node:20") || strings.Contains(out, "mitl-capsule-old") {
+		t.Fatalf("only capsule images should be listed:\n%s", out)
+	}
+}
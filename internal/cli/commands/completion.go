@@ -47,7 +47,7 @@ _mitl_completions()
 
     local -a commands
     commands=(
-        analyze digest hydrate run shell inspect setup runtime doctor cache volumes bench completion help version
+        analyze digest hydrate run shell exec up down ps logs restart inspect lock deps setup runtime doctor cache volumes bench completion help version
     )
 
     case ${COMP_CWORD} in
@@ -62,6 +62,16 @@ _mitl_completions()
                     COMPREPLY=( $(compgen -W "run compare list export --iterations --category --compare --output --format --parallel --verbose" -- "$cur") ) ;;
                 completion)
                     COMPREPLY=( $(compgen -W "bash zsh" -- "$cur") ) ;;
+                up)
+                    COMPREPLY=( $(compgen -W "-p --publish --" -- "$cur") ) ;;
+                logs)
+                    COMPREPLY=( $(compgen -W "-f --follow --tail" -- "$cur") ) ;;
+                exec)
+                    COMPREPLY=( $(compgen -W "--container" -- "$cur") ) ;;
+                lock)
+                    COMPREPLY=( $(compgen -W "--update --package" -- "$cur") ) ;;
+                deps)
+                    COMPREPLY=( $(compgen -W "diff --format --lockfile" -- "$cur") ) ;;
                 *)
                     COMPREPLY=( $(compgen -W "--verbose --debug" -- "$cur") ) ;;
             esac
@@ -81,7 +91,15 @@ _mitl() {
     'hydrate:Build project capsule'
     'run:Run command in capsule'
     'shell:Open shell in capsule'
+    'exec:Run command in the running capsule'
+    'up:Start project capsule in the background'
+    'down:Stop background capsule'
+    'ps:List background capsules'
+    'logs:Show background capsule logs'
+    'restart:Restart background capsule'
     'inspect:Analyze project and show Dockerfile'
+    'lock:Pin base images to digests in mitl.lock'
+    'deps:Compare the packages lockfiles pin'
     'setup:Setup default runtime'
     'runtime:Runtime info/benchmark/recommend'
     'doctor:System health check'
@@ -106,6 +124,24 @@ _mitl() {
         completion)
           _values 'shell' bash zsh
           ;;
+        up)
+          _values 'options' -p --publish
+          ;;
+        shell)
+          _values 'options' --shell -p --publish -e --env --env-file --no-publish --no-env-file
+          ;;
+        logs)
+          _values 'options' -f --follow --tail
+          ;;
+        exec)
+          _values 'options' --container
+          ;;
+        lock)
+          _values 'options' --update --package
+          ;;
+        deps)
+          _values 'options' diff --format --lockfile
+          ;;
         bench)
           _values 'options' run compare list export --iterations --category --compare --output --format --parallel --verbose
           ;;
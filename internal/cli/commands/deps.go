@@ -0,0 +1,315 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"mitl/internal/digest"
+)
+
+// depsSide is one side of mitl deps diff: lockfile contents by path
+// relative to the current directory
+type depsSide struct {
+	label  string
+	files  map[string][]byte
+	single bool // one lockfile named explicitly, paired whatever its name
+	onDisk bool
+}
+
+// Deps handles mitl deps. Its one subcommand, diff, compares the packages
+// two versions of the project's lockfiles pin.
+func Deps(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		showDepsHelp()
+		return nil
+	}
+	if args[0] != "diff" {
+		return fmt.Errorf("unknown deps subcommand: %s (usage: mitl deps diff <old> [new])", args[0])
+	}
+	return depsDiff(args[1:])
+}
+
+// depsDiff handles mitl deps diff <old> [new] [--format text|json|markdown]
+// [--lockfile path]
+func depsDiff(args []string) error {
+	format, lockfile := "text", ""
+	var specs []string
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "-h" || a == "--help":
+			showDepsHelp()
+			return nil
+		case a == "--format" || a == "--lockfile":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s requires a value", a)
+			}
+			if a == "--format" {
+				format = args[i+1]
+			} else {
+				lockfile = args[i+1]
+			}
+			i++
+		case strings.HasPrefix(a, "--format="):
+			format = strings.TrimPrefix(a, "--format=")
+		case strings.HasPrefix(a, "--lockfile="):
+			lockfile = strings.TrimPrefix(a, "--lockfile=")
+		case !strings.HasPrefix(a, "-") && len(specs) < 2:
+			specs = append(specs, a)
+		default:
+			return fmt.Errorf("unknown deps diff option: %s (usage: mitl deps diff <old> [new] [--format text|json|markdown] [--lockfile path])", a)
+		}
+	}
+	if format != "text" && format != "json" && format != "markdown" {
+		return fmt.Errorf("unsupported format %q (use text, json or markdown)", format)
+	}
+	if len(specs) == 0 {
+		return fmt.Errorf("usage: mitl deps diff <old> [new] [--format text|json|markdown] [--lockfile path]")
+	}
+
+	old, err := loadDepsSide(specs[0], lockfile)
+	if err != nil {
+		return err
+	}
+	newSpec := ""
+	if len(specs) == 2 {
+		newSpec = specs[1]
+	}
+	newSide, err := loadDepsSide(newSpec, lockfile)
+	if err != nil {
+		return err
+	}
+	// Two named lockfiles are compared with each other, whatever their
+	// paths; rev:path alone with the same path in the working tree
+	if old.single && len(specs) == 1 {
+		if old.onDisk {
+			return fmt.Errorf("name the lockfile to compare %s with", specs[0])
+		}
+		if newSide, err = loadDepsSide("", firstPath(old.files)); err != nil {
+			return err
+		}
+	}
+	diffs := diffDepsSides(old, newSide)
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if jerr := enc.Encode(diffs); jerr != nil {
+			return fmt.Errorf("failed to encode lockfile diff: %w", jerr)
+		}
+	case "markdown":
+		fmt.Print(depsMarkdown(old.label, newSide.label, diffs))
+	default:
+		displayDepsDiff(old.label, newSide.label, diffs)
+	}
+	return nil
+}
+
+// loadDepsSide reads one side of a diff. spec is a lockfile, rev:path, or
+// a git revision standing for all lockfiles in the current directory; ""
+// is the working tree. lockfile narrows revisions and the working tree to
+// one path.
+func loadDepsSide(spec, lockfile string) (depsSide, error) {
+	paths := depsLockfileNames()
+	if lockfile != "" {
+		paths = []string{lockfile}
+	}
+	if spec == "" {
+		side := depsSide{label: "working tree", files: map[string][]byte{}}
+		for _, p := range paths {
+			if data, err := os.ReadFile(p); err == nil {
+				side.files[p] = data
+			}
+		}
+		return side, nil
+	}
+	if info, err := os.Stat(spec); err == nil && !info.IsDir() {
+		data, rerr := os.ReadFile(spec)
+		if rerr != nil {
+			return depsSide{}, rerr
+		}
+		return depsSide{label: spec, files: map[string][]byte{spec: data}, single: true, onDisk: true}, nil
+	}
+	if rev, p, ok := strings.Cut(spec, ":"); ok && rev != "" && p != "" {
+		data, err := digest.ReadGitFile(".", rev, p)
+		if err != nil {
+			return depsSide{}, fmt.Errorf("failed to read %s: %w", spec, err)
+		}
+		return depsSide{label: spec, files: map[string][]byte{p: data}, single: true}, nil
+	}
+	files, err := digest.ReadGitFiles(".", spec, paths)
+	if err != nil {
+		return depsSide{}, fmt.Errorf("%s is neither a lockfile nor a git revision: %w", spec, err)
+	}
+	return depsSide{label: spec, files: files}, nil
+}
+
+// depsLockfileNames lists the lockfiles mitl deps diff looks for: those
+// LockfileHasher knows whose package list it can read
+func depsLockfileNames() []string {
+	var names []string
+	for _, name := range digest.LockfileNames() {
+		if digest.LockfileEcosystem(name) != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// firstPath returns the path of a single-file side
+func firstPath(files map[string][]byte) string {
+	for p := range files {
+		return p
+	}
+	return ""
+}
+
+// diffDepsSides compares the lockfiles of two sides, pairing them by path,
+// and returns the changed ones ordered by ecosystem
+func diffDepsSides(old, newSide depsSide) []digest.LockfileDiff {
+	if old.single && newSide.single {
+		name := firstPath(newSide.files)
+		return changedLockfiles([]digest.LockfileDiff{digest.DiffLockfile(name, old.files[firstPath(old.files)], newSide.files[name])})
+	}
+	paths := map[string]bool{}
+	for p := range old.files {
+		paths[p] = true
+	}
+	for p := range newSide.files {
+		paths[p] = true
+	}
+	var diffs []digest.LockfileDiff
+	for p := range paths {
+		diffs = append(diffs, digest.DiffLockfile(p, old.files[p], newSide.files[p]))
+	}
+	return changedLockfiles(diffs)
+}
+
+// changedLockfiles drops unchanged lockfiles and sorts the rest by
+// ecosystem, then path
+func changedLockfiles(diffs []digest.LockfileDiff) []digest.LockfileDiff {
+	changed := []digest.LockfileDiff{}
+	for _, d := range diffs {
+		if d.Status != "unchanged" {
+			changed = append(changed, d)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		if a, b := ecosystemLabel(changed[i]), ecosystemLabel(changed[j]); a != b {
+			return a < b
+		}
+		return changed[i].Lockfile < changed[j].Lockfile
+	})
+	return changed
+}
+
+// ecosystemLabel names the ecosystem of a lockfile diff
+func ecosystemLabel(d digest.LockfileDiff) string {
+	if d.Ecosystem == "" {
+		return "other"
+	}
+	return d.Ecosystem
+}
+
+// displayDepsDiff prints the lockfile changes grouped by ecosystem
+func displayDepsDiff(oldLabel, newLabel string, diffs []digest.LockfileDiff) {
+	fmt.Printf("📦 Dependencies: %s → %s\n", oldLabel, newLabel)
+	if len(diffs) == 0 {
+		fmt.Println("✅ No lockfile changes")
+		return
+	}
+	ecosystem := ""
+	for _, d := range diffs {
+		if label := ecosystemLabel(d); label != ecosystem {
+			ecosystem = label
+			fmt.Printf("\n%s:\n", ecosystem)
+		}
+		fmt.Printf("  %s %s (%s)\n", changeMarker(d.Status), d.Lockfile, d.Status)
+		if d.Packages == nil {
+			fmt.Printf("      %s\n", d.Note)
+			continue
+		}
+		displayPackageDiff(d.Packages, "      ")
+	}
+}
+
+// depsMarkdown renders the lockfile changes as a PR comment: a table of
+// package changes per lockfile under a heading per ecosystem
+func depsMarkdown(oldLabel, newLabel string, diffs []digest.LockfileDiff) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Dependency changes: `%s` → `%s`\n\n", oldLabel, newLabel)
+	if len(diffs) == 0 {
+		b.WriteString("No lockfile changes.\n")
+		return b.String()
+	}
+	ecosystem := ""
+	for _, d := range diffs {
+		if label := ecosystemLabel(d); label != ecosystem {
+			ecosystem = label
+			fmt.Fprintf(&b, "#### %s\n\n", ecosystem)
+		}
+		fmt.Fprintf(&b, "**`%s`** (%s)\n\n", d.Lockfile, d.Status)
+		switch {
+		case d.Packages == nil:
+			fmt.Fprintf(&b, "_%s_\n\n", markdownCell(d.Note))
+			continue
+		case d.Packages.Empty():
+			b.WriteString("_No package versions changed._\n\n")
+			continue
+		}
+		b.WriteString("| Change | Package | From | To |\n| --- | --- | --- | --- |\n")
+		rows := []struct {
+			change string
+			list   []digest.PackageChange
+		}{
+			{"Added", d.Packages.Added},
+			{"Removed", d.Packages.Removed},
+			{"Upgraded", d.Packages.Upgraded},
+			{"Downgraded", d.Packages.Downgraded},
+		}
+		for _, row := range rows {
+			for _, c := range row.list {
+				fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", row.change, markdownCell(c.Name), markdownCell(c.From), markdownCell(c.To))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// markdownCell escapes the characters that would end a table cell
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func showDepsHelp() {
+	fmt.Println(`Usage: mitl deps diff <old> [new] [options]
+
+Compare the packages two versions of the project's lockfiles pin and list
+what was added, removed, upgraded and downgraded, per ecosystem.
+
+Each side is one of:
+    path/to/lockfile        A lockfile on disk
+    <rev>:<path>            A lockfile at a git revision, e.g. main:package-lock.json
+    <rev>                   Every lockfile in the current directory at a git
+                            revision: a branch, tag, commit, HEAD~1, ...
+
+Without new, old is compared with the working tree. Revisions are read from
+.git directly; git need not be installed.
+
+Options:
+    --format text|json|markdown   Output format (default text); markdown
+                                  renders tables for pull request comments
+    --lockfile <path>             Only compare this lockfile
+    -h, --help                    Show this help
+
+Examples:
+    mitl deps diff HEAD                             # Uncommitted lockfile changes
+    mitl deps diff main HEAD --format markdown      # For a pull request comment
+    mitl deps diff v1.2.0 --lockfile go.sum --format json
+    mitl deps diff old/yarn.lock yarn.lock`)
+}
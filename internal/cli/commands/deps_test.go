@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/digest"
+)
+
+const (
+	depsOldLock = `{"lockfileVersion":3,"packages":{"":{"name":"app"},"node_modules/react":{"version":"18.2.0"},"node_modules/left-pad":{"version":"1.3.0"}}}`
+	depsNewLock = `{"lockfileVersion":3,"packages":{"":{"name":"app"},"node_modules/react":{"version":"18.3.1"},"node_modules/zod":{"version":"3.23.8"}}}`
+)
+
+func TestDeps_DiffFiles(t *testing.T) {
+	dir := t.TempDir()
+	oldWd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(oldWd)
+	os.MkdirAll("old", 0o755)
+	os.WriteFile(filepath.Join("old", "package-lock.json"), []byte(depsOldLock), 0o644)
+	os.WriteFile("package-lock.json", []byte(depsNewLock), 0o644)
+	os.WriteFile("go.sum", []byte("a v1.0.0 h1:x=\n"), 0o644)
+
+	out := captureOut(t, func() {
+		if err := Deps([]string{"diff", "old/package-lock.json", "package-lock.json"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, want := range []string{"npm:", "package-lock.json (modified)", "+ zod 3.23.8", "- left-pad 1.3.0", "↑ react 18.2.0 → 18.3.1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+
+	out = captureOut(t, func() {
+		if err := Deps([]string{"diff", "old/package-lock.json", "package-lock.json", "--format", "markdown"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, want := range []string{"#### npm", "| Change | Package | From | To |", "| Upgraded | react | 18.2.0 | 18.3.1 |", "| Added | zod |  | 3.23.8 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+
+	out = captureOut(t, func() {
+		if err := Deps([]string{"diff", "--format=json", "old/package-lock.json", "package-lock.json"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	var diffs []digest.LockfileDiff
+	if err := json.Unmarshal([]byte(out), &diffs); err != nil {
+		t.Fatalf("invalid json %v:\n%s", err, out)
+	}
+	if len(diffs) != 1 || diffs[0].Ecosystem != digest.EcosystemNPM || len(diffs[0].Packages.Upgraded) != 1 {
+		t.Fatalf("diffs = %+v", diffs)
+	}
+
+	if err := Deps([]string{"diff", "package-lock.json"}); err == nil {
+		t.Fatal("a lockfile needs something to compare with")
+	}
+	if err := Deps([]string{"diff", "a", "b", "--format", "yaml"}); err == nil {
+		t.Fatal("expected an unsupported format error")
+	}
+	if err := Deps([]string{"bogus"}); err == nil {
+		t.Fatal("expected an unknown subcommand error")
+	}
+}
+
+func TestDeps_DiffRevisions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	oldWd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(oldWd)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	os.WriteFile("package-lock.json", []byte(depsOldLock), 0o644)
+	os.WriteFile("go.sum", []byte("a v1.0.0 h1:x=\n"), 0o644)
+	git("add", ".")
+	git("commit", "-q", "-m", "one")
+	os.WriteFile("package-lock.json", []byte(depsNewLock), 0o644)
+	os.Remove("go.sum")
+	os.WriteFile("Cargo.lock", []byte("[[package]]\nname = \"serde\"\nversion = \"1.0.203\"\n"), 0o644)
+	git("add", "-A")
+	git("commit", "-q", "-m", "two")
+
+	out := captureOut(t, func() {
+		if err := Deps([]string{"diff", "HEAD~1", "HEAD"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, want := range []string{"cargo:", "Cargo.lock (added)", "+ serde 1.0.203", "go:", "go.sum (removed)", "- a v1.0.0", "npm:", "↑ react 18.2.0 → 18.3.1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "cargo:") > strings.Index(out, "npm:") {
+		t.Errorf("expected ecosystems in order:\n%s", out)
+	}
+
+	// One lockfile at a revision against the working tree
+	os.WriteFile("package-lock.json", []byte(depsOldLock), 0o644)
+	out = captureOut(t, func() {
+		if err := Deps([]string{"diff", "HEAD:package-lock.json"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(out, "↓ react 18.3.1 → 18.2.0") || strings.Contains(out, "Cargo.lock") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	out = captureOut(t, func() {
+		if err := Deps([]string{"diff", "HEAD", "--lockfile", "Cargo.lock"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(out, "No lockfile changes") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if err := Deps([]string{"diff", "no-such-rev"}); err == nil {
+		t.Fatal("expected an unknown revision error")
+	}
+}
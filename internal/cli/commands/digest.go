@@ -13,7 +13,10 @@ import (
 	"strings"
 	"time"
 
+	"mitl/internal/build/templates"
+	"mitl/internal/detector"
 	"mitl/internal/digest"
+	"mitl/internal/manifest"
 )
 
 // DigestCommand provides functionality to calculate and inspect project digests.
@@ -26,9 +29,14 @@ func NewDigestCommand() *DigestCommand {
 	return &DigestCommand{}
 }
 
-// Run executes the digest command with the provided arguments.
-// Supports flags: --verbose, --files, --save, --compare, --lockfiles-only
+// Run executes the digest command with the provided arguments, or the
+// explain subcommand. Supports flags: --verbose, --files, --save, --compare,
+// --lockfiles-only, --no-cache, --git, --package
 func (d *DigestCommand) Run(args []string) error {
+	if len(args) > 0 && args[0] == "explain" {
+		return d.runExplain(args[1:])
+	}
+
 	// Parse command line flags
 	config := d.parseFlags(args)
 
@@ -37,6 +45,15 @@ func (d *DigestCommand) Run(args []string) error {
 		return nil
 	}
 
+	if config.pkg != "" {
+		if err := d.scopeToPackage(&config); err != nil {
+			return err
+		}
+	}
+
+	// User Dockerfile templates are part of the capsule digest
+	config.options.ExtraFiles = templates.Files(config.rootDir)
+
 	// Handle lockfiles-only mode
 	if config.lockfilesOnly {
 		return d.runLockfilesMode(&config)
@@ -51,6 +68,9 @@ func (d *DigestCommand) Run(args []string) error {
 
 	// Display results
 	d.displayResults(projectDigest, &config)
+	if err := d.displayCapsule(&config); err != nil {
+		return err
+	}
 
 	// Handle comparison if requested
 	if config.comparePath != "" {
@@ -79,6 +99,7 @@ type digestConfig struct {
 	comparePath   string
 	lockfilesOnly bool
 	showHelp      bool
+	pkg           string
 	options       digest.Options
 }
 
@@ -88,6 +109,7 @@ func (d *DigestCommand) parseFlags(args []string) digestConfig {
 		rootDir: ".",
 		options: digest.Options{
 			Algorithm: "sha256", // default
+			Mode:      digestMode(),
 		},
 	}
 
@@ -111,6 +133,10 @@ func (d *DigestCommand) parseFlags(args []string) digestConfig {
 			}
 		case "--lockfiles-only":
 			config.lockfilesOnly = true
+		case "--no-cache":
+			config.options.NoCache = true
+		case "--git":
+			config.options.Mode = digest.ModeGit
 		case "--algorithm":
 			if i+1 < len(args) {
 				config.options.Algorithm = args[i+1]
@@ -166,6 +192,11 @@ func (d *DigestCommand) parseFlags(args []string) digestConfig {
 				config.options.ExcludePattern = patterns
 				i++
 			}
+		case "--package":
+			if i+1 < len(args) {
+				config.pkg = args[i+1]
+				i++
+			}
 		case "--root":
 			if i+1 < len(args) {
 				config.rootDir = args[i+1]
@@ -177,6 +208,24 @@ func (d *DigestCommand) parseFlags(args []string) digestConfig {
 	return config
 }
 
+// scopeToPackage points the digest at the workspace enclosing rootDir and
+// restricts it to the package's files: the member, the workspace packages
+// it depends on and root-level files. The capsule tag still covers the
+// whole workspace.
+func (d *DigestCommand) scopeToPackage(config *digestConfig) error {
+	ws := detector.FindWorkspace(config.rootDir)
+	if ws == nil {
+		return fmt.Errorf("no workspace found for --package %s", config.pkg)
+	}
+	pkg, ok := ws.Package(config.pkg)
+	if !ok {
+		return fmt.Errorf("unknown workspace package %q (available: %s)", config.pkg, strings.Join(ws.Names(), ", "))
+	}
+	config.rootDir = ws.Root
+	config.options.Paths = ws.Scope(pkg)
+	return nil
+}
+
 // displayResults shows the digest calculation results.
 func (d *DigestCommand) displayResults(projectDigest *digest.Digest, config *digestConfig) {
 	// Main digest information
@@ -185,9 +234,20 @@ func (d *DigestCommand) displayResults(projectDigest *digest.Digest, config *dig
 
 	if config.verbose {
 		fmt.Printf("Algorithm: %s\n", projectDigest.Algorithm)
+		if projectDigest.Options.Mode == digest.ModeGit {
+			fmt.Println("Mode: git index")
+		} else {
+			fmt.Println("Mode: walk")
+		}
 		fmt.Printf("Full Hash: %s\n", projectDigest.Hash)
 		fmt.Printf("Timestamp: %s\n", projectDigest.Timestamp.Format(time.RFC3339))
 		fmt.Printf("Total Size: %s\n", d.formatFileSize(projectDigest.TotalSize))
+		if c := projectDigest.Cache; c != nil {
+			fmt.Printf("Cache: %d/%d files reused (%.1f%% hit rate, %s saved)\n",
+				c.Hits, c.Hits+c.Misses, c.HitRate()*100, c.Saved.Round(time.Millisecond))
+		} else {
+			fmt.Println("Cache: disabled")
+		}
 
 		// Show file details if requested
 		if config.showFiles {
@@ -196,6 +256,51 @@ func (d *DigestCommand) displayResults(projectDigest *digest.Digest, config *dig
 	}
 }
 
+// displayCapsule shows the dependency digest, which covers only what shapes
+// the capsule image, and the tag the project's capsule gets: the dependency
+// digest, or the project digest with build.source: baked.
+func (d *DigestCommand) displayCapsule(config *digestConfig) error {
+	m, err := manifest.Load(config.rootDir)
+	if err != nil {
+		return err
+	}
+	scope := workspaceScope{root: config.rootDir}
+	pd := detectProjectAt(config.rootDir, m)
+	src, err := scope.builtWith().source(scope.root, m)
+	if err != nil {
+		return err
+	}
+	dockerfile, err := scope.dockerfile(pd, src)
+	if err != nil {
+		return err
+	}
+	deps, err := digest.DependencyDigest(scope.root, scope.digestOptions(), capsuleInputs(pd, src, dockerfile))
+	if err != nil {
+		return fmt.Errorf("failed to calculate dependency digest: %w", err)
+	}
+	fmt.Printf("📦 Dependency digest: %s\n", deps.Hash[:16])
+	fmt.Printf("📁 Dependency inputs: %d\n", deps.FileCount)
+
+	tag, how := deps.Hash[:12], "dependency digest; source mounted at run time"
+	if m.BakedSource() {
+		source, serr := scope.sourceDigest(pd, src, dockerfile)
+		if serr != nil {
+			return serr
+		}
+		tag = source.Hash[:12]
+		how = "project digest; build.source: baked"
+	}
+	fmt.Printf("🏷️  Capsule: mitl-capsule:%s (%s)\n", tag, how)
+
+	if config.verbose && config.showFiles {
+		fmt.Println("\nInputs included in dependency digest:")
+		for _, f := range deps.Files {
+			fmt.Printf("  %s (%s) - %s\n", f.Path, d.formatFileSize(f.Size), f.Hash[:12])
+		}
+	}
+	return nil
+}
+
 // displayFileList shows detailed information about files included in the digest.
 func (d *DigestCommand) displayFileList(files []digest.FileDigest) {
 	fmt.Println("\nFiles included in digest:")
@@ -234,15 +339,9 @@ func (d *DigestCommand) runLockfilesMode(config *digestConfig) error {
 
 	if config.verbose {
 		// Show which lockfiles were found
-		lockfileNames := []string{
-			"composer.lock", "package-lock.json", "pnpm-lock.yaml", "yarn.lock",
-			"go.sum", "go.mod", "Gemfile.lock", "requirements.txt", "poetry.lock",
-			"Pipfile.lock", "cargo.lock",
-		}
-
 		fmt.Println("\nLockfiles found:")
 		found := false
-		for _, name := range lockfileNames {
+		for _, name := range digest.LockfileNames() {
 			path := filepath.Join(config.rootDir, name)
 			if _, err := os.Stat(path); err == nil {
 				fmt.Printf("  ✓ %s\n", name)
@@ -303,6 +402,7 @@ func (d *DigestCommand) showHelp() {
 
 USAGE:
     mitl digest [OPTIONS]
+    mitl digest explain [TAG] [--package NAME]
 
 OPTIONS:
     -h, --help              Show this help message
@@ -311,12 +411,15 @@ OPTIONS:
     --save PATH             Save digest to file for future comparison
     --compare PATH          Compare current digest with saved digest
     --lockfiles-only        Calculate digest of lockfiles only
+    --no-cache              Rehash every file instead of reusing the .mitl/cache index
+    --git                   Take clean tracked files from the git index and honor .gitignore
     --algorithm ALGO        Hash algorithm: sha256 (default), blake3
     --max-size BYTES        Skip files larger than specified size
     --include-hidden        Include hidden files (starting with .)
     --only-ext EXTS         Only include files with specified extensions (comma-separated)
     --exclude-ext EXTS      Exclude files with specified extensions (comma-separated)
     --root DIR              Project root directory (default: current directory)
+    --package NAME          Digest one workspace package and what it depends on
 
 EXAMPLES:
     mitl digest                                    # Calculate basic digest
@@ -324,11 +427,31 @@ EXAMPLES:
     mitl digest --save .mitl/digest.json          # Save digest for later comparison
     mitl digest --compare .mitl/digest.json       # Compare with saved digest
     mitl digest --lockfiles-only                  # Hash only dependency lockfiles
+    mitl digest --git --verbose                   # Skip rereading files git knows are clean
     mitl digest --algorithm blake3 --verbose      # Use Blake3 algorithm
     mitl digest --only-ext .go,.mod --verbose     # Only hash Go files
+    mitl digest --package web --files --verbose   # Files the web package depends on
+    mitl digest explain                           # Why the capsule differs from the last one built
+    mitl digest explain 3f2a9c1b7d4e              # Why that capsule was built
 
 The digest command helps debug cache issues by showing exactly what files
-affect your project's cache key and how changes impact the digest.`)
+affect your project's cache key and how changes impact the digest.
+
+--git (or MITL_DIGEST_MODE=git, which run and hydrate honor too) reads
+.git/index to reuse the hashes of clean tracked files and leaves out
+untracked files .gitignore matches, which the default walk hashes. The
+modes agree only when there are no such files.
+
+Capsules are tagged by the dependency digest: lockfiles and dependency
+manifests, mitl.yaml, the Dockerfile and the detected project settings.
+Source edits don't rebuild them, as run and shell mount the project at
+/app. Set build.source: baked in mitl.yaml to tag them by the project
+digest instead.
+
+mitl hydrate records every capsule it builds in ~/.mitl/digests/<tag>.json.
+explain compares against the project's last recorded capsule, grouping the
+changed lockfiles, manifests, Dockerfile inputs and source files, and lists
+the packages each lockfile added, removed, upgraded or downgraded.`)
 }
 
 // Digest function provides the main entry point for the digest command.
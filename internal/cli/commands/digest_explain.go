@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"mitl/internal/digest"
+)
+
+// explainGroups titles the change groups of mitl digest explain
+var explainGroups = map[string]string{
+	digest.GroupLockfile:   "🔒 Lockfiles",
+	digest.GroupManifest:   "📋 Manifests",
+	digest.GroupDockerfile: "🐳 Dockerfile",
+	digest.GroupSource:     "📝 Source",
+}
+
+// runExplain handles mitl digest explain [tag] [--package name]. Without a
+// tag it explains the capsule the project needs now against the last other
+// capsule hydrate built for it; with one, that capsule against the capsule
+// built before it.
+func (d *DigestCommand) runExplain(args []string) error {
+	tag, pkg := "", ""
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "-h" || a == "--help":
+			d.showHelp()
+			return nil
+		case a == "--package":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--package requires a value")
+			}
+			pkg = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--package="):
+			pkg = strings.TrimPrefix(a, "--package=")
+		case !strings.HasPrefix(a, "-") && tag == "":
+			tag = a
+		default:
+			return fmt.Errorf("unknown explain option: %s (usage: mitl digest explain [tag] [--package name])", a)
+		}
+	}
+	scope, err := resolveScope(pkg)
+	if err != nil {
+		return err
+	}
+	history, err := digest.ProjectHistory(scope.root, scope.pkgName())
+	if err != nil {
+		return err
+	}
+
+	var current *digest.CapsuleRecord
+	if tag != "" {
+		if current, err = digest.LoadCapsuleRecord(scope.root, scope.pkgName(), tag); err != nil {
+			return fmt.Errorf("%w; capsules are recorded when mitl hydrate builds them", err)
+		}
+	} else if current, err = d.currentCapsule(scope); err != nil {
+		return err
+	}
+
+	var previous *digest.CapsuleRecord
+	for _, rec := range history {
+		if rec.Tag != current.Tag && (tag == "" || rec.BuiltAt.Before(current.BuiltAt)) {
+			previous = rec
+			break
+		}
+	}
+	if previous == nil {
+		fmt.Printf("🏷️  Capsule: mitl-capsule:%s\n", current.Tag)
+		fmt.Println("No earlier capsule recorded for this project; run 'mitl hydrate' to record builds.")
+		return nil
+	}
+	d.displayExplanation(digest.Explain(previous, current))
+	return nil
+}
+
+// currentCapsule records the capsule hydrate would use now, with the flags
+// it was last run with, without building or saving it
+func (d *DigestCommand) currentCapsule(scope workspaceScope) (*digest.CapsuleRecord, error) {
+	m, err := scope.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+	src, err := scope.builtWith().source(scope.root, m)
+	if err != nil {
+		return nil, err
+	}
+	c, err := scope.capsule(m, scope.detect(m), src)
+	if err != nil {
+		return nil, err
+	}
+	return digest.NewCapsuleRecord(scope.root, scope.pkgName(), c.digest, c.covered), nil
+}
+
+// displayExplanation prints the changed inputs by group, lockfiles with
+// their package changes
+func (d *DigestCommand) displayExplanation(x *digest.Explanation) {
+	fmt.Printf("🏷️  Capsule: mitl-capsule:%s\n", x.New.Tag)
+	fmt.Printf("   Previous: mitl-capsule:%s (built %s)\n", x.Old.Tag, x.Old.BuiltAt.Local().Format(time.RFC822))
+	if x.Comparison.Identical {
+		fmt.Println("✅ Same inputs; no rebuild needed")
+		return
+	}
+	fmt.Printf("🔄 %s\n", x.Comparison.Summary())
+	if len(x.Comparison.GetAffectedFiles()) == 0 {
+		fmt.Println("   The digest settings changed, not the files")
+	}
+	for _, group := range digest.Groups {
+		changes := x.Changes[group]
+		if len(changes) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s:\n", explainGroups[group])
+		for _, c := range changes {
+			fmt.Printf("  %s %s (%s)\n", changeMarker(c.Change), displayInput(c.Path), c.Change)
+			if c.Packages != nil {
+				displayPackageDiff(c.Packages, "      ")
+			}
+		}
+	}
+}
+
+// changeMarker matches the markers of mitl digest --compare
+func changeMarker(change string) string {
+	switch change {
+	case "added":
+		return "➕"
+	case "removed":
+		return "➖"
+	}
+	return "📝"
+}
+
+// displayInput names the inputs that are not project files
+func displayInput(path string) string {
+	switch path {
+	case "<dockerfile>":
+		return "Dockerfile contents"
+	case "<source>":
+		return "build source (generated or project Dockerfile, target, build args)"
+	case "<detector>":
+		return "detected project settings"
+	}
+	return path
+}
+
+// displayPackageDiff prints a lockfile's package changes, one per line
+func displayPackageDiff(p *digest.PackageDiff, indent string) {
+	if p.Empty() {
+		fmt.Printf("%sno package versions changed\n", indent)
+		return
+	}
+	for _, c := range p.Added {
+		fmt.Printf("%s+ %s %s\n", indent, c.Name, c.To)
+	}
+	for _, c := range p.Removed {
+		fmt.Printf("%s- %s %s\n", indent, c.Name, c.From)
+	}
+	for _, c := range p.Upgraded {
+		fmt.Printf("%s↑ %s %s → %s\n", indent, c.Name, c.From, c.To)
+	}
+	for _, c := range p.Downgraded {
+		fmt.Printf("%s↓ %s %s → %s\n", indent, c.Name, c.From, c.To)
+	}
+}
@@ -1,8 +1,13 @@
 package commands
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"mitl/internal/digest"
 )
 
 func TestDigest_parseFlags_Basic(t *testing.T) {
@@ -20,3 +25,52 @@ func TestDigest_parseFlags_Basic(t *testing.T) {
 		t.Errorf("expected wildcard extensions, got %v", cfg.options.IncludePattern)
 	}
 }
+
+func TestDigest_Explain(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	t.Setenv("MITL_BUILD_CLI", "/bin/echo")
+	proj := filepath.Join(tmp, "proj")
+	os.MkdirAll(proj, 0o755)
+	os.WriteFile(filepath.Join(proj, "package.json"), []byte(`{"name":"app"}`), 0o644)
+	os.WriteFile(filepath.Join(proj, "package-lock.json"), []byte(`{"lockfileVersion":3,"packages":{"node_modules/react":{"version":"18.2.0"}}}`), 0o644)
+	oldWd, _ := os.Getwd()
+	os.Chdir(proj)
+	defer os.Chdir(oldWd)
+	old := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd { return exec.Command("sh", "-c", "true") }
+	defer func() { execCommand = old }()
+
+	cmd := NewDigestCommand()
+	if err := Hydrate(nil); err != nil {
+		t.Fatalf("hydrate: %v", err)
+	}
+	out := captureOut(t, func() { _ = cmd.Run([]string{"explain"}) })
+	if !strings.Contains(out, "No earlier capsule recorded") {
+		t.Fatalf("expected no earlier capsule, got:\n%s", out)
+	}
+
+	os.WriteFile(filepath.Join(proj, "package-lock.json"), []byte(`{"lockfileVersion":3,"packages":{"node_modules/react":{"version":"18.3.1"},"node_modules/zod":{"version":"3.23.8"}}}`), 0o644)
+	out = captureOut(t, func() { _ = cmd.Run([]string{"explain"}) })
+	for _, want := range []string{"🔒 Lockfiles:", "package-lock.json (modified)", "+ zod 3.23.8", "↑ react 18.2.0 → 18.3.1"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	// Once rebuilt, the new capsule is explained against the old one
+	if err := Hydrate(nil); err != nil {
+		t.Fatalf("hydrate: %v", err)
+	}
+	history, _ := digest.ProjectHistory(proj, "")
+	if len(history) != 2 {
+		t.Fatalf("expected two recorded capsules, got %d", len(history))
+	}
+	out = captureOut(t, func() { _ = cmd.Run([]string{"explain", "mitl-capsule:" + history[0].Tag}) })
+	if !strings.Contains(out, "Previous: mitl-capsule:"+history[1].Tag) || !strings.Contains(out, "+ zod 3.23.8") {
+		t.Fatalf("unexpected explanation:\n%s", out)
+	}
+	if err := cmd.Run([]string{"explain", "ffffffffffff"}); err == nil {
+		t.Fatal("expected an error for an unrecorded capsule")
+	}
+}
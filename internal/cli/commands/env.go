@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"mitl/internal/dotenv"
+	"mitl/internal/manifest"
+
+	e "mitl/pkg/errors"
+)
+
+// defaultForwardEnv lists host variables forwarded into capsules when set
+var defaultForwardEnv = []string{"TERM", "COLORTERM", "LANG", "LC_ALL", "TZ"}
+
+// containerEnv is the resolved environment for a capsule command
+type containerEnv struct {
+	vars    []dotenv.Var
+	sources []string // human-readable origins, e.g. ".env (4)"
+}
+
+// resolveEnv merges container environment from, lowest to highest
+// precedence: manifest env, the project's .env, --env-file files, forwarded
+// host variables and -e flags.
+func resolveEnv(opts runOptions, m *manifest.Manifest, root string) (containerEnv, error) {
+	var ce containerEnv
+	index := map[string]int{}
+	set := func(k, v string) {
+		if i, ok := index[k]; ok {
+			ce.vars[i].Value = v
+			return
+		}
+		index[k] = len(ce.vars)
+		ce.vars = append(ce.vars, dotenv.Var{Key: k, Value: v})
+	}
+
+	if m != nil {
+		for _, k := range slices.Sorted(maps.Keys(m.Env)) {
+			set(k, m.Env[k])
+		}
+	}
+
+	// Env files may reference variables set earlier, then host variables
+	lookup := func(k string) (string, bool) {
+		if i, ok := index[k]; ok {
+			return ce.vars[i].Value, true
+		}
+		return os.LookupEnv(k)
+	}
+
+	files := []string{}
+	if opts.dotenv {
+		if p := filepath.Join(root, ".env"); isFile(p) {
+			files = append(files, p)
+		}
+	}
+	files = append(files, opts.envFiles...)
+	for _, f := range files {
+		vars, err := dotenv.ReadFile(f, lookup)
+		if err != nil {
+			code := e.ErrInvalidConfig
+			if os.IsNotExist(err) {
+				code = e.ErrFileNotFound
+			}
+			return ce, e.Wrap(err, code, "Failed to load env file").
+				WithContext("file", f).
+				WithSuggestion("Check the file path and dotenv syntax (KEY=value)")
+		}
+		for _, v := range vars {
+			set(v.Key, v.Value)
+		}
+		name := f
+		if rel, err := filepath.Rel(root, f); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+		ce.sources = append(ce.sources, fmt.Sprintf("%s (%d)", name, len(vars)))
+	}
+
+	forward := append([]string{}, defaultForwardEnv...)
+	if m != nil {
+		forward = append(forward, m.ForwardEnv...)
+	}
+	for _, k := range forward {
+		if v, ok := os.LookupEnv(k); ok {
+			set(k, v)
+		}
+	}
+
+	for _, kv := range opts.env {
+		k, v, hasValue := strings.Cut(kv, "=")
+		if k == "" {
+			return ce, fmt.Errorf("invalid -e value %q (expected KEY=VALUE or KEY)", kv)
+		}
+		if !hasValue {
+			// Like docker: a bare KEY forwards the host value if set
+			hv, ok := os.LookupEnv(k)
+			if !ok {
+				continue
+			}
+			v = hv
+		}
+		set(k, v)
+	}
+	return ce, nil
+}
+
+// args writes the variables to a private env file and returns the
+// --env-file flag passing it to the runtime. Values never appear in argv,
+// shell history or logged command lines, nor in the runtime CLI's own
+// environment, where a project's LD_PRELOAD or DOCKER_CERT_PATH would
+// reconfigure the CLI. Env files hold one line per variable, so multi-line
+// values (PEM keys and the like, which dotenv allows quoted) are passed as a
+// bare -e KEY instead and reach the CLI through cliEnv. cleanup removes the
+// file once the runtime has read it.
+func (ce containerEnv) args() (args []string, cleanup func(), err error) {
+	cleanup = func() {}
+	if len(ce.vars) == 0 {
+		return nil, cleanup, nil
+	}
+	var buf strings.Builder
+	for _, v := range ce.vars {
+		if multiLine(v.Value) {
+			args = append(args, "-e", v.Key)
+			continue
+		}
+		buf.WriteString(v.Key + "=" + v.Value + "\n")
+	}
+	if buf.Len() == 0 {
+		return args, cleanup, nil
+	}
+	// CreateTemp creates the file readable by the owner only (0600)
+	f, err := os.CreateTemp("", "mitl-env-")
+	if err != nil {
+		return nil, cleanup, e.Wrap(err, e.ErrPermissionDenied, "Failed to create env file")
+	}
+	cleanup = func() { os.Remove(f.Name()) }
+	if _, werr := f.WriteString(buf.String()); werr != nil {
+		f.Close()
+		cleanup()
+		return nil, func() {}, e.Wrap(werr, e.ErrPermissionDenied, "Failed to write env file")
+	}
+	if cerr := f.Close(); cerr != nil {
+		cleanup()
+		return nil, func() {}, e.Wrap(cerr, e.ErrPermissionDenied, "Failed to write env file")
+	}
+	return append([]string{"--env-file", f.Name()}, args...), cleanup, nil
+}
+
+// cliEnv returns the runtime CLI's environment: nil, inheriting mitl's own,
+// unless multi-line values are passed as -e KEY, which the CLI reads from
+// its environment. Only those variables are added.
+func (ce containerEnv) cliEnv() []string {
+	var extra []string
+	for _, v := range ce.vars {
+		if multiLine(v.Value) {
+			extra = append(extra, v.Key+"="+v.Value)
+		}
+	}
+	if len(extra) == 0 {
+		return nil
+	}
+	return append(os.Environ(), extra...)
+}
+
+// multiLine reports a value an env file cannot hold
+func multiLine(v string) bool {
+	return strings.ContainsAny(v, "\r\n")
+}
+
+// printSummary reports where variables came from without printing values
+func (ce containerEnv) printSummary() {
+	if len(ce.sources) > 0 {
+		fmt.Printf("\x1b[32m🔐 Loaded env from %s\x1b[0m\n", strings.Join(ce.sources, ", "))
+	}
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"mitl/internal/dotenv"
+	"mitl/internal/manifest"
+)
+
+func TestResolveEnv_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("APP_KEY=base64:secret\nDB=dotenv\nSHARED=dotenv\n"), 0o644)
+	extra := filepath.Join(dir, "ci.env")
+	os.WriteFile(extra, []byte("SHARED=file\nURL=\"postgres://${DB}\"\n"), 0o644)
+	t.Setenv("AWS_PROFILE", "dev")
+	t.Setenv("FROM_HOST", "host")
+
+	m := &manifest.Manifest{Env: map[string]string{"DB": "manifest", "ONLY_MANIFEST": "1"}, ForwardEnv: []string{"AWS_PROFILE"}}
+	opts := runOptions{dotenv: true, envFiles: []string{extra}, env: []string{"SHARED=flag", "FROM_HOST", "UNSET_ON_HOST"}}
+	ce, err := resolveEnv(opts, m, dir)
+	if err != nil {
+		t.Fatalf("resolveEnv: %v", err)
+	}
+	got := dotenv.Map(ce.vars)
+	want := map[string]string{
+		"ONLY_MANIFEST": "1",
+		"DB":            "dotenv",
+		"APP_KEY":       "base64:secret",
+		"SHARED":        "flag",
+		"URL":           "postgres://dotenv",
+		"AWS_PROFILE":   "dev",
+		"FROM_HOST":     "host",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("%s = %q, want %q (all: %v)", k, got[k], v, got)
+		}
+	}
+	if _, ok := got["UNSET_ON_HOST"]; ok {
+		t.Fatalf("bare -e for an unset host variable must be skipped")
+	}
+
+	// Values travel in a private env file, never argv
+	args, cleanup, err := ce.args()
+	if err != nil || len(args) != 2 || args[0] != "--env-file" {
+		t.Fatalf("args = %v, %v", args, err)
+	}
+	info, err := os.Stat(args[1])
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("env file must be private: %v, %v", info, err)
+	}
+	if data, _ := os.ReadFile(args[1]); !strings.Contains(string(data), "APP_KEY=base64:secret\n") {
+		t.Fatalf("expected APP_KEY in the env file:\n%s", data)
+	}
+	cleanup()
+	if _, err := os.Stat(args[1]); !os.IsNotExist(err) {
+		t.Fatalf("cleanup must remove the env file")
+	}
+
+	// A multi-line dotenv value goes by name, its value in the CLI's environment
+	pem := filepath.Join(dir, "pem.env")
+	os.WriteFile(pem, []byte("PEM=\"-----BEGIN KEY-----\nabc\n-----END KEY-----\"\nPLAIN=1\n"), 0o644)
+	multi, err := resolveEnv(runOptions{envFiles: []string{pem}}, nil, dir)
+	if err != nil {
+		t.Fatalf("resolveEnv: %v", err)
+	}
+	args, cleanup, err = multi.args()
+	defer cleanup()
+	if err != nil || len(args) != 4 || args[0] != "--env-file" || args[2] != "-e" || args[3] != "PEM" {
+		t.Fatalf("args = %v, %v", args, err)
+	}
+	if data, _ := os.ReadFile(args[1]); !strings.HasPrefix(string(data), "PLAIN=1\n") || strings.Contains(string(data), "KEY-----") {
+		t.Fatalf("the env file must hold only single-line values:\n%s", data)
+	}
+	cliEnv := multi.cliEnv()
+	if !slices.Contains(cliEnv, "PEM=-----BEGIN KEY-----\nabc\n-----END KEY-----") || slices.Contains(cliEnv, "PLAIN=1") {
+		t.Fatalf("expected only the multi-line value in the CLI environment")
+	}
+	if (containerEnv{vars: []dotenv.Var{{Key: "A", Value: "1"}}}).cliEnv() != nil {
+		t.Fatalf("without multi-line values the CLI inherits mitl's environment")
+	}
+
+	opts.dotenv = false
+	ce, _ = resolveEnv(opts, nil, dir)
+	if _, ok := dotenv.Map(ce.vars)["APP_KEY"]; ok {
+		t.Fatalf("--no-env-file must skip the project .env")
+	}
+}
+
+func TestResolveEnv_MissingFile(t *testing.T) {
+	_, err := resolveEnv(runOptions{envFiles: []string{filepath.Join(t.TempDir(), "nope.env")}}, nil, t.TempDir())
+	if err == nil {
+		t.Fatalf("expected error for missing env file")
+	}
+}
+
+func TestRun_PassesEnvWithoutLeakingValues(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MITL_RUN_CLI", "/bin/echo")
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("DATABASE_URL=postgres://user:hunter2@db/app\nLD_PRELOAD=/tmp/evil.so\n"), 0o644)
+	chdir(t, dir)
+
+	var cmd *exec.Cmd
+	var argv, envFile, envData string
+	old := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		c := exec.Command("sh", "-c", "true")
+		if len(args) > 0 && args[0] == "run" {
+			argv = strings.Join(args, " ")
+			cmd = c
+			for i, a := range args {
+				if a == "--env-file" {
+					envFile = args[i+1]
+					data, _ := os.ReadFile(envFile)
+					envData = string(data)
+				}
+			}
+		}
+		return c
+	}
+	defer func() { execCommand = old }()
+
+	out := captureOut(t, func() {
+		if err := Run([]string{"-e", "EXTRA=1", "env"}); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	})
+	if strings.Contains(argv, "hunter2") || strings.Contains(out, "hunter2") {
+		t.Fatalf("secret leaked: argv=%s out=%s", argv, out)
+	}
+	if !strings.Contains(envData, "DATABASE_URL=postgres://user:hunter2@db/app\n") || !strings.Contains(envData, "EXTRA=1\n") {
+		t.Fatalf("expected the variables in the env file, got %q (argv %s)", envData, argv)
+	}
+	// Project variables must not reconfigure the runtime CLI itself
+	if strings.Contains(strings.Join(cmd.Env, "\n"), "LD_PRELOAD=/tmp/evil.so") || strings.Contains(strings.Join(cmd.Env, "\n"), "hunter2") {
+		t.Fatalf("project variables leaked into the CLI environment")
+	}
+	if _, err := os.Stat(envFile); !os.IsNotExist(err) {
+		t.Fatalf("the env file must be removed after the run")
+	}
+}
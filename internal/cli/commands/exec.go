@@ -0,0 +1,202 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"mitl/internal/detector"
+	"mitl/internal/manifest"
+	"mitl/internal/service"
+	"mitl/pkg/terminal"
+
+	e "mitl/pkg/errors"
+)
+
+const execUsage = "Usage: mitl exec [--container name] <command> [args]"
+
+// isInteractive reports whether both stdin and stdout are terminals. It is a
+// variable so tests can simulate interactive sessions.
+var isInteractive = func() bool {
+	return terminal.IsTTY(os.Stdin) && terminal.IsTTY(os.Stdout)
+}
+
+// execTarget is a running container to exec into
+type execTarget struct {
+	runtime string
+	name    string
+	workdir string // container path matching the current directory, if known
+	project string // host project root, if known
+}
+
+// Exec runs a command inside the project's running capsule container, as
+// started by `mitl up`. When none is running it falls back to a one-off
+// `mitl run` of the command, with the same project scope and environment.
+func Exec(args []string) error {
+	container := ""
+	for len(args) > 0 {
+		if args[0] == "--container" {
+			if len(args) < 2 {
+				fmt.Println(execUsage)
+				return fmt.Errorf("--container requires a name")
+			}
+			container, args = args[1], args[2:]
+			continue
+		}
+		if strings.HasPrefix(args[0], "--container=") {
+			container, args = strings.TrimPrefix(args[0], "--container="), args[1:]
+			continue
+		}
+		if args[0] == "--" {
+			args = args[1:]
+		}
+		break
+	}
+	if len(args) == 0 {
+		fmt.Println(execUsage)
+		return fmt.Errorf("no command specified")
+	}
+
+	target, err := findExecTarget(container)
+	if err != nil {
+		return err
+	}
+	if target.name == "" {
+		fmt.Printf("\x1b[33m💤 No running capsule for this project; starting a one-off container\x1b[0m\n")
+		return Run(append([]string{"--"}, args...))
+	}
+
+	execArgs := []string{"exec"}
+	// -t only with a terminal on both ends so pipes and scripts get clean,
+	// non-CRLF output; -i always so piped stdin reaches the command
+	if isInteractive() {
+		execArgs = append(execArgs, "-it")
+	} else {
+		execArgs = append(execArgs, "-i")
+	}
+	if target.workdir != "" {
+		execArgs = append(execArgs, "-w", target.workdir)
+	}
+	execArgs = append(execArgs, capsuleUserArgs(target.projectType(), args)...)
+	execArgs = append(execArgs, target.name)
+	execArgs = append(execArgs, args...)
+
+	cmd := execCommand(target.runtime, execArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	if err := cmd.Run(); err != nil {
+		return runtimeError(err, target.runtime, "Failed to exec in capsule")
+	}
+	return nil
+}
+
+// findExecTarget resolves the container to exec into: an explicit name,
+// the service registered for this directory or a parent, or a container
+// labelled with the project hash of the nearest such directory, for
+// services the store lost track of. A zero target means none is running;
+// a runtime that cannot list containers is an error.
+func findExecTarget(container string) (execTarget, error) {
+	if container != "" {
+		cli := findRunCLI()
+		if containerStatus(cli, container) != "running" {
+			return execTarget{}, e.New(e.ErrServiceNotFound, "Container "+container+" is not running").
+				WithSuggestion("List running capsules with: mitl ps")
+		}
+		return execTarget{runtime: cli, name: container}, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return execTarget{}, err
+	}
+	if svc, ok := service.NewStore().FindForDir(cwd); ok && containerStatus(svc.Runtime, svc.Name) == "running" {
+		return execTarget{runtime: svc.Runtime, name: svc.Name, workdir: containerWorkdir(svc.Project, cwd), project: svc.Project}, nil
+	}
+
+	cli := findRunCLI()
+	labelled, err := labelledContainers(cli)
+	if err != nil {
+		return execTarget{}, err
+	}
+	for dir := filepath.Clean(cwd); ; dir = filepath.Dir(dir) {
+		if name, ok := labelled[service.ProjectHash(dir)]; ok {
+			return execTarget{runtime: cli, name: name, workdir: containerWorkdir(dir, cwd), project: dir}, nil
+		}
+		if filepath.Dir(dir) == dir {
+			return execTarget{}, nil
+		}
+	}
+}
+
+// labelledContainers lists the running containers carrying a project hash
+// label in one query and maps each hash to a container name. Docker prints
+// Names and Labels as strings ("k=v,k=v"), Podman as a list and a map.
+func labelledContainers(cli string) (map[string]string, error) {
+	var stderr bytes.Buffer
+	cmd := execCommand(cli, "ps", "--filter", "label="+service.LabelProjectHash, "--format", "{{json .}}")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, runtimeError(err, cli, "Failed to list running capsules")
+	}
+	labelled := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		var c struct {
+			Names  json.RawMessage
+			Labels json.RawMessage
+		}
+		if json.Unmarshal([]byte(line), &c) != nil {
+			continue
+		}
+		name, hash := "", ""
+		var names []string
+		if json.Unmarshal(c.Names, &name) != nil && json.Unmarshal(c.Names, &names) == nil && len(names) > 0 {
+			name = names[0]
+		}
+		var labels map[string]string
+		var list string
+		if json.Unmarshal(c.Labels, &labels) == nil {
+			hash = labels[service.LabelProjectHash]
+		} else if json.Unmarshal(c.Labels, &list) == nil {
+			for _, kv := range strings.Split(list, ",") {
+				if k, v, _ := strings.Cut(kv, "="); k == service.LabelProjectHash {
+					hash = v
+				}
+			}
+		}
+		if name != "" && hash != "" {
+			if _, seen := labelled[hash]; !seen {
+				labelled[hash] = name
+			}
+		}
+	}
+	return labelled, nil
+}
+
+// projectType detects the type of the target's project, or of the current
+// directory for a container named with --container
+func (t execTarget) projectType() detector.ProjectType {
+	root := t.project
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+	m, _ := manifest.Load(root)
+	return detectProjectAt(root, m).Type
+}
+
+// containerWorkdir maps a host directory inside root to its /app path
+func containerWorkdir(root, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "/app"
+	}
+	return path.Join("/app", filepath.ToSlash(rel))
+}
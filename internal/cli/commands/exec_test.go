@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/service"
+)
+
+func TestExec_UsesRegisteredServiceFromSubdir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MITL_RUN_CLI", "/bin/echo")
+	project, _ := filepath.EvalSymlinks(t.TempDir())
+	sub := filepath.Join(project, "src", "lib")
+	os.MkdirAll(sub, 0o755)
+	name := service.ContainerName(project)
+	_ = service.NewStore().Put(service.Service{Name: name, Project: project, Runtime: "/bin/echo"})
+	chdir(t, sub)
+
+	for _, tt := range []struct {
+		interactive bool
+		flag        string
+	}{{true, "-it"}, {false, "-i"}} {
+		oldTTY := isInteractive
+		isInteractive = func() bool { return tt.interactive }
+
+		var execArgs string
+		old := execCommand
+		execCommand = func(n string, args ...string) *exec.Cmd {
+			switch args[0] {
+			case "inspect":
+				return exec.Command("sh", "-c", "echo running")
+			case "exec":
+				execArgs = strings.Join(args, " ")
+			}
+			return exec.Command("sh", "-c", "true")
+		}
+		if err := Exec([]string{"ls", "-la"}); err != nil {
+			t.Fatalf("exec: %v", err)
+		}
+		execCommand = old
+		isInteractive = oldTTY
+
+		want := "exec " + tt.flag + " -w /app/src/lib " + name + " ls -la"
+		if execArgs != want {
+			t.Fatalf("exec args = %q, want %q", execArgs, want)
+		}
+	}
+}
+
+func TestExec_InstallsAsRootInNodeCapsule(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MITL_RUN_CLI", "/bin/echo")
+	project, _ := filepath.EvalSymlinks(t.TempDir())
+	os.WriteFile(filepath.Join(project, "package.json"), []byte(`{"name":"web"}`), 0o644)
+	os.WriteFile(filepath.Join(project, "package-lock.json"), []byte(`{"lockfileVersion":3}`), 0o644)
+	name := service.ContainerName(project)
+	_ = service.NewStore().Put(service.Service{Name: name, Project: project, Runtime: "/bin/echo"})
+	chdir(t, project)
+
+	var execArgs string
+	old := execCommand
+	execCommand = func(n string, args ...string) *exec.Cmd {
+		switch args[0] {
+		case "inspect":
+			return exec.Command("sh", "-c", "echo running")
+		case "exec":
+			execArgs = strings.Join(args, " ")
+		}
+		return exec.Command("sh", "-c", "true")
+	}
+	defer func() { execCommand = old }()
+
+	for command, root := range map[string]bool{"npm install": true, "npm test": false} {
+		captureOut(t, func() {
+			if err := Exec(strings.Fields(command)); err != nil {
+				t.Fatalf("exec: %v", err)
+			}
+		})
+		if strings.Contains(execArgs, "--user 0 "+name) != root {
+			t.Fatalf("%s: exec args = %q", command, execArgs)
+		}
+	}
+}
+
+func TestExec_FindsLabelledContainerFromSubdir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MITL_RUN_CLI", "/bin/echo")
+	project, _ := filepath.EvalSymlinks(t.TempDir())
+	sub := filepath.Join(project, "src")
+	os.MkdirAll(sub, 0o755)
+	chdir(t, sub)
+
+	var execArgs string
+	old := execCommand
+	execCommand = func(n string, args ...string) *exec.Cmd {
+		switch args[0] {
+		case "ps":
+			// Docker's line, then Podman's for another project
+			return exec.Command("printf", "%s\n%s\n",
+				`{"Names":"mitl-up","Labels":"a=b,`+service.LabelProjectHash+`=`+service.ProjectHash(project)+`"}`,
+				`{"Names":["other"],"Labels":{"`+service.LabelProjectHash+`":"`+service.ProjectHash(sub)+`x"}}`)
+		case "exec":
+			execArgs = strings.Join(args, " ")
+		}
+		return exec.Command("sh", "-c", "true")
+	}
+	defer func() { execCommand = old }()
+
+	if err := Exec([]string{"ls"}); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if !strings.HasSuffix(execArgs, "-w /app/src mitl-up ls") {
+		t.Fatalf("exec args = %q", execArgs)
+	}
+}
+
+func TestExec_FallsBackToRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MITL_RUN_CLI", "/bin/echo")
+	chdir(t, t.TempDir())
+
+	var verbs []string
+	var runArgs string
+	old := execCommand
+	execCommand = func(n string, args ...string) *exec.Cmd {
+		verbs = append(verbs, args[0])
+		if args[0] == "run" {
+			runArgs = strings.Join(args, " ")
+		}
+		return exec.Command("sh", "-c", "true")
+	}
+	defer func() { execCommand = old }()
+
+	captureOut(t, func() {
+		if err := Exec([]string{"-e", "hi"}); err != nil {
+			t.Fatalf("exec: %v", err)
+		}
+	})
+	if strings.Count(strings.Join(verbs, " "), "ps") != 1 {
+		t.Fatalf("expected one container lookup, got %v", verbs)
+	}
+	if !strings.HasPrefix(runArgs, "run --rm") || !strings.HasSuffix(runArgs, " -e hi") {
+		t.Fatalf("expected a one-off run of the command, got %q", runArgs)
+	}
+}
+
+func TestExec_ReportsRuntimeFailure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MITL_RUN_CLI", "/bin/echo")
+	chdir(t, t.TempDir())
+
+	ran := false
+	old := execCommand
+	execCommand = func(n string, args ...string) *exec.Cmd {
+		if args[0] == "run" {
+			ran = true
+		}
+		return exec.Command("sh", "-c", "echo 'Cannot connect to the Docker daemon' >&2; exit 1")
+	}
+	defer func() { execCommand = old }()
+
+	err := Exec([]string{"ls"})
+	if err == nil || !strings.Contains(err.Error(), "not running") || ran {
+		t.Fatalf("expected the runtime error, got %v (ran %v)", err, ran)
+	}
+}
+
+func TestExec_ExplicitContainerMustBeRunning(t *testing.T) {
+	t.Setenv("MITL_RUN_CLI", "/bin/echo")
+	old := execCommand
+	execCommand = func(n string, args ...string) *exec.Cmd { return exec.Command("sh", "-c", "exit 1") }
+	defer func() { execCommand = old }()
+
+	if err := Exec([]string{"--container", "nope", "ls"}); err == nil {
+		t.Fatalf("expected error for stopped container")
+	}
+	if err := Exec([]string{"--container"}); err == nil {
+		t.Fatalf("expected usage error")
+	}
+}
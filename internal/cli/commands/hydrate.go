@@ -13,10 +13,13 @@ import (
 	"time"
 
 	"mitl/internal/build"
+	"mitl/internal/build/imagelock"
 	"mitl/internal/cache"
 	"mitl/internal/container"
 	"mitl/internal/detector"
 	"mitl/internal/digest"
+	"mitl/internal/manifest"
+	"mitl/pkg/version"
 
 	e "mitl/pkg/errors"
 )
@@ -40,26 +43,66 @@ type Config struct {
 	// LastBuildSeconds stores the duration in seconds of the last successful
 	// build for a given digest key. Used to show time saved on cache hits.
 	LastBuildSeconds map[string]float64 `json:"last_build_seconds,omitempty"`
+	// BuildFlags keeps the hydrate flags a project's capsule was last built
+	// with, by project root and --package, so run, shell and up use it
+	BuildFlags map[string]BuildFlags `json:"build_flags,omitempty"`
 }
 
-// Hydrate builds a Docker image for the current project using a temporary Dockerfile.
-// This command creates an optimized container image (capsule) for the detected project type.
+// BuildFlags are the hydrate flags that choose what a capsule is built from
+type BuildFlags struct {
+	Dockerfile string            `json:"dockerfile,omitempty"`
+	Generate   bool              `json:"generate,omitempty"`
+	Target     string            `json:"target,omitempty"`
+	BuildArgs  map[string]string `json:"build_args,omitempty"`
+}
+
+// Hydrate builds a Docker image for the current project from the project's
+// own Dockerfile or Containerfile when it has one, otherwise from a generated
+// Dockerfile. This command creates an optimized container image (capsule)
+// for the detected project type.
 func Hydrate(args []string) error {
 	start := time.Now()
-	// Use deterministic project digest for capsule tag
-	digestValue, derr := digest.ProjectTag(".", &digest.Options{Algorithm: "sha256"})
-	if derr != nil {
-		return e.Wrap(derr, e.ErrUnknown, "Failed to compute project digest").
-			WithSuggestion("Run 'mitl digest --verbose' for details")
+	opts, perr := parseHydrateFlags(args)
+	if perr != nil {
+		return perr
+	}
+	scope, serr := resolveScope(opts.pkg)
+	if serr != nil {
+		return serr
+	}
+	m, merr := scope.loadManifest()
+	if merr != nil {
+		return merr
+	}
+	src, rerr := opts.source(scope.root, m)
+	if rerr != nil {
+		return rerr
+	}
+	detectorInstance := scope.detect(m)
+	capsule, cerr := scope.capsule(m, detectorInstance, src)
+	if cerr != nil {
+		return cerr
+	}
+	digestValue, tag := capsule.digest, capsule.tag
+	// The lockfile hash is informational when a lockfile can't be parsed;
+	// the capsule digest already covers its raw bytes.
+	lockHash, _ := digest.NewLockfileHasher(scope.root).HashLockfiles()
+	expected := cache.Labels{
+		Digest:       digestValue,
+		LockfileHash: lockHash,
+		BuildSource:  src.Label(),
+	}
+	if src.Generated() {
+		expected.GeneratorVersion = build.GeneratorVersion
 	}
-	tag := fmt.Sprintf("mitl-capsule:%s", digestValue)
 
 	buildCmd := findBuildCLI()
 	capCache := cache.NewCapsuleCache(buildCmd, tag)
 	exists, err := capCache.Exists()
 	if err != nil {
 		fmt.Printf("\x1b[33m⚠️  Cache check failed: %v\x1b[0m\n", err)
-	} else if exists && capCache.ValidateDigest(digestValue) {
+	} else if exists && capCache.ValidateLabels(expected) {
+		scope.saveBuiltWith(opts)
 		elapsed := time.Since(start)
 		cfg := loadConfig()
 		saved := 0.0
@@ -77,15 +120,8 @@ func Hydrate(args []string) error {
 	}
 
 	fmt.Printf("\x1b[33m🔍 Analyzing project structure...\x1b[0m\n")
-	detectorInstance := detector.NewProjectDetector("")
-	if derr := detectorInstance.Detect(); derr != nil {
-		fmt.Printf("\x1b[33m⚠️  Detection failed: %v — using generic Dockerfile\x1b[0m\n", derr)
-	}
-	generator := NewDockerfileGenerator(detectorInstance)
-	dockerfileContent, gerr := generator.Generate()
-	if gerr != nil {
-		fmt.Printf("\x1b[31m❌ Dockerfile generation failed: %v\x1b[0m\n", gerr)
-		return gerr
+	if m != nil {
+		fmt.Printf("\x1b[32m📋 Using manifest: %s\x1b[0m\n", filepath.Base(m.Path))
 	}
 	if detectorInstance.Type != detector.TypeUnknown {
 		fmt.Printf("\x1b[32m📦 Detected: %s\x1b[0m\n", detectorInstance.Type)
@@ -93,9 +129,15 @@ func Hydrate(args []string) error {
 			fmt.Printf("\x1b[32m🚀 Framework: %s %s\x1b[0m\n", detectorInstance.Framework, detectorInstance.Version)
 		}
 	}
-	for _, hint := range generator.OptimizationHints() {
-		fmt.Println(hint)
+	dockerfileContent := capsule.dockerfile
+	if src.Generated() {
+		for _, hint := range NewDockerfileGenerator(detectorInstance).OptimizationHints() {
+			fmt.Println(hint)
+		}
+	} else {
+		fmt.Printf("\x1b[32m📄 Using project Dockerfile: %s\x1b[0m\n", src.Dockerfile)
 	}
+	warnStaleLock(scope.root, string(dockerfileContent))
 
 	fmt.Printf("\x1b[33m🔨 Building optimized capsule: %s\x1b[0m\n", tag)
 	tmpDir, err := mkTempDir("", "mitl-build-")
@@ -103,17 +145,35 @@ func Hydrate(args []string) error {
 		return e.Wrap(err, e.ErrPermissionDenied, "Failed to create temp directory")
 	}
 	defer os.RemoveAll(tmpDir)
+	// A project Dockerfile is copied too: COPY paths are relative to the
+	// context, not the Dockerfile, and the copy carries the env-file rules
 	dockerfilePath := filepath.Join(tmpDir, "Dockerfile")
-	if werr := writeFile(dockerfilePath, []byte(dockerfileContent), 0o644); werr != nil {
+	if werr := writeFile(dockerfilePath, dockerfileContent, 0o644); werr != nil {
 		return e.Wrap(werr, e.ErrPermissionDenied, "Failed to write Dockerfile")
 	}
+	// BuildKit reads <Dockerfile>.dockerignore next to the Dockerfile in
+	// place of the context's .dockerignore
+	if werr := writeFile(dockerfilePath+".dockerignore", buildIgnoreContent(scope.root, src.Dockerfile), 0o644); werr != nil {
+		return e.Wrap(werr, e.ErrPermissionDenied, "Failed to write .dockerignore")
+	}
 	// Determine the target platform. BuildKit can autoselect, but we set explicitly when helpful.
 	platform := resolveBuildPlatform()
 	args = []string{"build", "-t", tag}
 	if platform != "" {
 		args = append(args, "--platform", platform)
 	}
-	args = append(args, "-f", dockerfilePath, ".")
+	labels := expected
+	labels.DetectorType = string(detectorInstance.Type)
+	labels.MitlVersion = version.Version
+	labels.BuildTime = timeNowFn().UTC()
+	if root, aerr := filepath.Abs(scope.root); aerr == nil {
+		labels.Project = root
+	}
+	args = append(args, labels.BuildArgs()...)
+	args = append(args, src.BuildArgs()...)
+	// A workspace package is built from the workspace root so the shared
+	// lockfile and sibling packages are in the context
+	args = append(args, "-f", dockerfilePath, scope.root)
 	cmd := execCommand(buildCmd, args...)
 	// Stream output while also capturing stderr to detect disk-full conditions
 	var errBuf bytes.Buffer
@@ -140,9 +200,199 @@ func Hydrate(args []string) error {
 	}
 	cfg.LastBuildSeconds[digestValue] = buildElapsed.Seconds()
 	saveConfig(cfg)
+	scope.saveBuiltWith(opts)
+
+	// Keep what the capsule was built from for mitl digest explain
+	rec := digest.NewCapsuleRecord(scope.root, scope.pkgName(), digestValue, capsule.covered)
+	if serr := digest.SaveCapsuleRecord(rec); serr != nil {
+		fmt.Printf("\x1b[33m⚠️  Could not record the capsule digest: %v\x1b[0m\n", serr)
+	}
 	return nil
 }
 
+// projectDockerfile reads the project's own Dockerfile with the base images
+// in mitl.lock pinned, as the generator does for generated ones
+func projectDockerfile(root string, src build.Source) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(src.Dockerfile)))
+	if err != nil {
+		return nil, e.Wrap(err, e.ErrDockerfileNotFound, "Failed to read "+src.Dockerfile)
+	}
+	lock, lerr := imagelock.Load(root)
+	if lerr != nil {
+		return nil, lerr
+	}
+	return []byte(lock.Pin(string(data))), nil
+}
+
+// warnStaleLock points out base images mitl.lock does not pin and entries
+// no longer used. Projects without a lock float on tags silently.
+func warnStaleLock(root, dockerfile string) {
+	lock, err := imagelock.Load(root)
+	if err != nil || lock == nil {
+		return
+	}
+	missing, unused := lock.Stale(dockerfile)
+	if len(missing) > 0 {
+		fmt.Printf("\x1b[33m⚠️  %s does not pin %s; run 'mitl lock --update'\x1b[0m\n", imagelock.FileName, strings.Join(missing, ", "))
+	} else if len(unused) > 0 {
+		fmt.Printf("\x1b[33m⚠️  %s pins images no longer used (%s); run 'mitl lock --update'\x1b[0m\n", imagelock.FileName, strings.Join(unused, ", "))
+	}
+}
+
+// hydrateOptions holds hydrate's flags
+type hydrateOptions struct {
+	pkg        string            // --package: workspace member to build
+	dockerfile string            // --dockerfile: project Dockerfile to build from
+	generate   bool              // --generate: ignore any project Dockerfile
+	target     string            // --target: stage to build
+	buildArgs  map[string]string // --build-arg KEY=VAL values
+}
+
+// buildFlagsKey identifies a scope in Config.BuildFlags
+func (s workspaceScope) buildFlagsKey() string {
+	root, err := filepath.Abs(s.root)
+	if err != nil {
+		root = s.root
+	}
+	if name := s.pkgName(); name != "" {
+		return root + "#" + name
+	}
+	return root
+}
+
+// builtWith returns the hydrate flags the scope's capsule was last built
+// with; none when hydrate ran without them
+func (s workspaceScope) builtWith() hydrateOptions {
+	f, ok := loadConfig().BuildFlags[s.buildFlagsKey()]
+	if !ok {
+		return hydrateOptions{}
+	}
+	return hydrateOptions{pkg: s.pkgName(), dockerfile: f.Dockerfile, generate: f.Generate, target: f.Target, buildArgs: f.BuildArgs}
+}
+
+// saveBuiltWith records the flags the scope's capsule was built with for
+// builtWith. Building without flags forgets earlier ones.
+func (s workspaceScope) saveBuiltWith(o hydrateOptions) {
+	f := BuildFlags{Dockerfile: o.dockerfile, Generate: o.generate, Target: o.target, BuildArgs: o.buildArgs}
+	cfg := loadConfig()
+	key := s.buildFlagsKey()
+	if f.Dockerfile == "" && !f.Generate && f.Target == "" && len(f.BuildArgs) == 0 {
+		if _, ok := cfg.BuildFlags[key]; !ok {
+			return
+		}
+		delete(cfg.BuildFlags, key)
+	} else {
+		if cfg.BuildFlags == nil {
+			cfg.BuildFlags = make(map[string]BuildFlags)
+		}
+		cfg.BuildFlags[key] = f
+	}
+	saveConfig(cfg)
+}
+
+// parseHydrateFlags reads hydrate's flags. Unknown arguments are an error,
+// so a mistyped flag doesn't silently build the default capsule.
+func parseHydrateFlags(args []string) (hydrateOptions, error) {
+	opts := hydrateOptions{}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		name, v, inline := strings.Cut(a, "=")
+		switch name {
+		case "--package", "--dockerfile", "--target", "--build-arg":
+		case "--generate":
+			opts.generate = true
+			continue
+		default:
+			return opts, fmt.Errorf("unknown hydrate option: %s (usage: mitl hydrate [--package name] [--dockerfile path | --generate] [--target stage] [--build-arg KEY=VALUE])", a)
+		}
+		if !inline {
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("%s requires a value", name)
+			}
+			v = args[i+1]
+			i++
+		}
+		if v == "" {
+			return opts, fmt.Errorf("%s requires a value", name)
+		}
+		switch name {
+		case "--package":
+			opts.pkg = v
+		case "--dockerfile":
+			opts.dockerfile = v
+		case "--target":
+			opts.target = v
+		case "--build-arg":
+			k, val, ok := strings.Cut(v, "=")
+			if !ok || k == "" {
+				return opts, fmt.Errorf("--build-arg expects KEY=VALUE, got %q", v)
+			}
+			if opts.buildArgs == nil {
+				opts.buildArgs = map[string]string{}
+			}
+			opts.buildArgs[k] = val
+		}
+	}
+	if opts.generate && opts.dockerfile != "" {
+		return opts, fmt.Errorf("--generate and --dockerfile cannot be combined")
+	}
+	return opts, nil
+}
+
+// source resolves what to build from: flags override mitl.yaml's build
+// section, and build arguments from both are merged
+func (o hydrateOptions) source(root string, m *manifest.Manifest) (build.Source, error) {
+	var b manifest.BuildSettings
+	if m != nil {
+		b = m.Build
+	}
+	mode, dockerfile, target := b.Mode, b.Dockerfile, b.Target
+	if o.generate {
+		mode, dockerfile = build.ModeGenerate, ""
+	} else if o.dockerfile != "" {
+		mode, dockerfile = build.ModeDockerfile, o.dockerfile
+	}
+	if o.target != "" {
+		target = o.target
+	}
+	var args map[string]string
+	if len(b.Args)+len(o.buildArgs) > 0 {
+		args = make(map[string]string, len(b.Args)+len(o.buildArgs))
+		for k, v := range b.Args {
+			args[k] = v
+		}
+		for k, v := range o.buildArgs {
+			args[k] = v
+		}
+	}
+	return build.ResolveSource(root, mode, dockerfile, target, args)
+}
+
+// buildIgnoreContent returns the project's .dockerignore extended with rules
+// that keep local env files (and the secrets in them) out of the build
+// context and therefore out of capsule images, and .mitl/, whose digest
+// index changes on every run. A project Dockerfile's own
+// <Dockerfile>.dockerignore takes precedence, as it does in BuildKit.
+func buildIgnoreContent(root, dockerfile string) []byte {
+	var buf bytes.Buffer
+	ignore := filepath.Join(root, ".dockerignore")
+	if dockerfile != "" {
+		own := filepath.Join(root, filepath.FromSlash(dockerfile)) + ".dockerignore"
+		if _, err := os.Stat(own); err == nil {
+			ignore = own
+		}
+	}
+	if data, err := os.ReadFile(ignore); err == nil {
+		buf.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	buf.WriteString("# Added by mitl: local env files stay out of capsules\n.env\n.env.*\n!.env.example\n")
+	buf.WriteString("# Added by mitl: digest cache and templates\n.mitl/\n")
+	return buf.Bytes()
+}
+
 // configPath returns the absolute path to the mitl configuration file. It
 // uses the HOME environment variable if present, otherwise falls back to the
 // current working directory. The file is named .mitl.json.
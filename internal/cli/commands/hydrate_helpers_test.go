@@ -3,6 +3,7 @@ package commands
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -34,3 +35,14 @@ func TestFindBuildAndRunCLI_Env(t *testing.T) {
 		t.Fatalf("findRunCLI env override failed: %s", v)
 	}
 }
+
+func TestBuildIgnoreContent_ExcludesEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	if got := string(buildIgnoreContent(dir, "")); !strings.Contains(got, "\n.env\n.env.*\n!.env.example\n") || !strings.Contains(got, "\n.mitl/\n") {
+		t.Fatalf("expected env exclusions, got:\n%s", got)
+	}
+	os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte("dist"), 0o644)
+	if got := string(buildIgnoreContent(dir, "")); !strings.HasPrefix(got, "dist\n") || !strings.Contains(got, ".env.*") {
+		t.Fatalf("expected project rules to be kept, got:\n%s", got)
+	}
+}
@@ -3,6 +3,8 @@ package commands
 import (
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -22,3 +24,85 @@ func TestHydrate_EchoBuild(t *testing.T) {
 		t.Fatalf("hydrate: %v", err)
 	}
 }
+
+func TestHydrate_ProjectDockerfile(t *testing.T) {
+	tmp := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmp)
+	defer os.Setenv("HOME", oldHome)
+	proj := filepath.Join(tmp, "proj")
+	os.MkdirAll(proj, 0o755)
+	os.WriteFile(filepath.Join(proj, "package.json"), []byte(`{"name":"app"}`), 0o644)
+	os.WriteFile(filepath.Join(proj, "Containerfile"), []byte("FROM node:20 AS dev\n"), 0o644)
+	oldWd, _ := os.Getwd()
+	os.Chdir(proj)
+	defer os.Chdir(oldWd)
+
+	os.Setenv("MITL_BUILD_CLI", "/bin/echo")
+	var buildArgs []string
+	var copied string
+	old := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "build" {
+			buildArgs = args
+			for i, a := range args {
+				if a == "-f" {
+					data, _ := os.ReadFile(args[i+1])
+					copied = string(data)
+				}
+			}
+		}
+		return exec.Command("sh", "-c", "true")
+	}
+	defer func() { execCommand = old }()
+
+	if err := Hydrate([]string{"--target", "dev", "--build-arg", "NODE_ENV=development"}); err != nil {
+		t.Fatalf("hydrate: %v", err)
+	}
+	got := strings.Join(buildArgs, " ")
+	for _, want := range []string{
+		"--label run.mitl.build-source=Containerfile#dev;args=",
+		"--target dev --build-arg NODE_ENV=development",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in build args: %s", want, got)
+		}
+	}
+	if strings.Contains(got, "run.mitl.generator-version") {
+		t.Fatalf("project Dockerfile builds carry no generator version: %s", got)
+	}
+	if copied != "FROM node:20 AS dev\n" {
+		t.Fatalf("expected the project's Containerfile to be built, got:\n%s", copied)
+	}
+
+	buildArgs = nil
+	if err := Hydrate([]string{"--generate"}); err != nil {
+		t.Fatalf("hydrate --generate: %v", err)
+	}
+	if got := strings.Join(buildArgs, " "); !strings.Contains(got, "run.mitl.build-source=generated") {
+		t.Fatalf("expected a generated build, got: %s", got)
+	}
+}
+
+func TestParseHydrateFlags(t *testing.T) {
+	opts, err := parseHydrateFlags([]string{"--package=web", "--dockerfile", "docker/dev.Dockerfile",
+		"--target=dev", "--build-arg", "A=1", "--build-arg=B=x=y"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if opts.pkg != "web" || opts.dockerfile != "docker/dev.Dockerfile" || opts.target != "dev" ||
+		opts.buildArgs["A"] != "1" || opts.buildArgs["B"] != "x=y" {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+	for _, args := range [][]string{
+		{"--target"},
+		{"--build-arg", "NOVALUE"},
+		{"--generate", "--dockerfile", "Dockerfile"},
+		{"--taget", "dev"},
+		{"web"},
+	} {
+		if _, err := parseHydrateFlags(args); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+}
@@ -1,20 +1,88 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"mitl/internal/build/lint"
+	"mitl/internal/build/templates"
 	"mitl/internal/detector"
+
+	e "mitl/pkg/errors"
 )
 
 // Inspect analyzes project and prints summary + generated Dockerfile.
 // This command provides detailed information about the detected project type and shows
-// the Dockerfile that would be generated for the project.
+// the Dockerfile that would be generated for the project, noting when hydrate builds
+// the project's own Dockerfile instead. The Dockerfile hydrate uses is linted; with
+// --lint only the findings are printed (as JSON with --format json), and --fail-on
+// makes findings of that severity or worse an error for CI. With --template-data it
+// prints the data Dockerfile templates are rendered with, as JSON.
 func Inspect(args []string) error {
-	detectorInstance := detector.NewProjectDetector("")
-	_ = detectorInstance.Detect()
+	opts, oerr := parseInspectFlags(args)
+	if oerr != nil {
+		return oerr
+	}
+	m, err := loadManifest()
+	if err != nil {
+		return err
+	}
+	detectorInstance := detectProject(m)
+
+	if opts.templateData {
+		out, jerr := json.MarshalIndent(NewDockerfileGenerator(detectorInstance).TemplateData(), "", "  ")
+		if jerr != nil {
+			return fmt.Errorf("failed to encode template data: %w", jerr)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	generator := NewDockerfileGenerator(detectorInstance)
+	dockerfile, err := generator.Generate()
+	if err != nil {
+		return fmt.Errorf("failed to generate Dockerfile: %w", err)
+	}
+	// Lint what hydrate builds: the Dockerfile it last used for this project
+	scope, _ := resolveScope("")
+	src, serr := scope.builtWith().source(detectorInstance.Root, m)
+	if serr != nil {
+		return serr
+	}
+	used := []byte(dockerfile)
+	if !src.Generated() {
+		data, rerr := projectDockerfile(detectorInstance.Root, src)
+		if rerr != nil {
+			return rerr
+		}
+		used = data
+	}
+	findings := lint.Lint(used)
+
+	if opts.lintOnly {
+		if opts.format == "json" {
+			if findings == nil {
+				findings = []lint.Finding{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.SetEscapeHTML(false)
+			if jerr := enc.Encode(map[string]any{"source": src.Label(), "findings": findings}); jerr != nil {
+				return fmt.Errorf("failed to encode findings: %w", jerr)
+			}
+		} else {
+			printFindings(src.Label(), findings)
+		}
+		return opts.check(findings)
+	}
 
 	fmt.Println("=== Project Analysis ===")
+	if m != nil {
+		fmt.Printf("Manifest: %s\n", filepath.Base(m.Path))
+	}
 	fmt.Printf("Type: %s\n", detectorInstance.Type)
 	if detectorInstance.Framework != "" {
 		fmt.Printf("Framework: %s %s\n", detectorInstance.Framework, detectorInstance.Version)
@@ -22,23 +90,192 @@ func Inspect(args []string) error {
 
 	if detectorInstance.Dependencies.PHP.Version != "" {
 		fmt.Println("\nPHP Dependencies:")
-		fmt.Printf("  Version: %s\n", detectorInstance.Dependencies.PHP.Version)
+		if src := detectorInstance.Dependencies.PHP.Source; src != "" {
+			fmt.Printf("  Version: %s (from %s)\n", detectorInstance.Dependencies.PHP.Version, src)
+		} else {
+			fmt.Printf("  Version: %s\n", detectorInstance.Dependencies.PHP.Version)
+		}
 		fmt.Printf("  Extensions: %s\n", strings.Join(detectorInstance.Dependencies.PHP.Extensions, ", "))
 	}
 
 	if detectorInstance.Dependencies.Node.Version != "" {
 		fmt.Println("\nNode Dependencies:")
-		fmt.Printf("  Version: %s\n", detectorInstance.Dependencies.Node.Version)
-		fmt.Printf("  Package Manager: %s\n", detectorInstance.Dependencies.Node.PackageManager)
+		nd := detectorInstance.Dependencies.Node
+		fmt.Printf("  Version: %s (from %s)\n", nd.Version, nd.Source)
+		pm := nd.PackageManager
+		if nd.ManagerVersion != "" {
+			pm += "@" + nd.ManagerVersion
+		}
+		if nd.YarnPnP {
+			pm += " (berry, pnp)"
+		} else if nd.YarnBerry {
+			pm += " (berry)"
+		}
+		fmt.Printf("  Package Manager: %s (policy: %s)\n", pm, nd.Policy)
 	}
 
-	generator := NewDockerfileGenerator(detectorInstance)
-	dockerfile, err := generator.Generate()
-	if err != nil {
-		return fmt.Errorf("failed to generate Dockerfile: %w", err)
+	if py := detectorInstance.Dependencies.Python; strings.HasPrefix(string(detectorInstance.Type), "python") {
+		fmt.Println("\nPython Dependencies:")
+		fmt.Printf("  Python: %s (from %s)\n", py.Version, py.Source)
+		installer := py.Installer
+		if py.Lockfile != "" {
+			installer += " (" + py.Lockfile + ")"
+		}
+		fmt.Printf("  Installer: %s\n", installer)
+		if py.AppModule != "" {
+			fmt.Printf("  App: %s\n", py.AppModule)
+		}
+		if len(py.SystemDeps) > 0 {
+			fmt.Printf("  Native build deps: %s\n", strings.Join(py.SystemDeps, ", "))
+		}
+	}
+
+	if gd := detectorInstance.Dependencies.Go; detectorInstance.Type == detector.TypeGoModule {
+		fmt.Println("\nGo Dependencies:")
+		fmt.Printf("  Go: %s (from %s)\n", gd.Version, gd.Source)
+		if len(gd.Mains) > 0 {
+			fmt.Printf("  Main packages: %s (building %s)\n", strings.Join(gd.Mains, ", "), gd.Target)
+		}
+		if gd.Cgo {
+			fmt.Println("  cgo: yes")
+		}
+	}
+
+	if rd := detectorInstance.Dependencies.Ruby; strings.HasPrefix(string(detectorInstance.Type), "ruby") {
+		fmt.Println("\nRuby Dependencies:")
+		fmt.Printf("  Ruby: %s (from %s)\n", rd.Version, rd.Source)
+		if rd.Bundler != "" {
+			fmt.Printf("  Bundler: %s\n", rd.Bundler)
+		}
+		if len(rd.BuildDeps) > 0 {
+			fmt.Printf("  Native build deps: %s\n", strings.Join(rd.BuildDeps, ", "))
+		}
+	}
+
+	if rd := detectorInstance.Dependencies.Rust; detectorInstance.Type == detector.TypeRustCargo {
+		fmt.Println("\nRust Dependencies:")
+		toolchain := rd.Version + " (from " + rd.Source + ")"
+		if rd.Channel != "" {
+			toolchain = rd.Version + " (" + rd.Channel + " via " + rd.Source + ")"
+		}
+		fmt.Printf("  Toolchain: %s\n", toolchain)
+		if rd.Workspace {
+			fmt.Printf("  Workspace members: %s\n", strings.Join(rd.Members, ", "))
+		}
+		if len(rd.Binaries) > 0 {
+			fmt.Printf("  Binaries: %s\n", strings.Join(rd.Binaries, ", "))
+		}
+	}
+
+	if jd := detectorInstance.Dependencies.Java; strings.HasPrefix(string(detectorInstance.Type), "java") {
+		fmt.Println("\nJava Dependencies:")
+		fmt.Printf("  JDK: %s (from %s)\n", jd.Version, jd.Source)
+		tool := jd.BuildTool
+		if jd.Wrapper {
+			tool += " (wrapper)"
+		}
+		fmt.Printf("  Build tool: %s\n", tool)
+		if jd.Kotlin {
+			fmt.Println("  Kotlin: yes")
+		}
+		if jd.MultiModule {
+			fmt.Println("  Multi-module: yes")
+		}
+	}
+
+	if ws := detectorInstance.Workspace; ws != nil {
+		fmt.Println("\nWorkspace:")
+		fmt.Printf("  Tools: %s\n", strings.Join(ws.Tools, ", "))
+		for _, p := range ws.Packages {
+			fmt.Printf("  %s: %s\n", p.Name, p.Path)
+		}
+	}
+
+	if path, ok := templates.Find(detectorInstance.Root, string(detectorInstance.Type)); ok {
+		fmt.Printf("\nTemplate: %s\n", path)
 	}
+	if !src.Generated() {
+		fmt.Printf("\nBuild: %s (the generated Dockerfile below is not used)\n", src.Label())
+	}
+
+	fmt.Println()
+	printFindings(src.Label(), findings)
 
 	fmt.Println("\n=== Generated Dockerfile ===")
 	fmt.Println(dockerfile)
+	return opts.check(findings)
+}
+
+// inspectOptions holds inspect's flags
+type inspectOptions struct {
+	templateData bool          // --template-data: print template data as JSON
+	lintOnly     bool          // --lint: print only the Dockerfile findings
+	format       string        // --format: text or json, for --lint
+	failOn       lint.Severity // --fail-on: fail on findings this serious; "" never fails
+}
+
+// parseInspectFlags reads inspect's flags. --format json implies --lint.
+// Unknown arguments are an error, so a mistyped --fail-on cannot pass CI.
+func parseInspectFlags(args []string) (inspectOptions, error) {
+	opts := inspectOptions{format: "text"}
+	for i := 0; i < len(args); i++ {
+		name, v, inline := strings.Cut(args[i], "=")
+		switch name {
+		case "--template-data":
+			opts.templateData = true
+			continue
+		case "--lint":
+			opts.lintOnly = true
+			continue
+		case "--format", "--fail-on":
+		default:
+			return opts, fmt.Errorf("unknown inspect option: %s (usage: mitl inspect [--lint] [--format text|json] [--fail-on error|warning|info] [--template-data])", args[i])
+		}
+		if !inline {
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("%s requires a value", name)
+			}
+			v = args[i+1]
+			i++
+		}
+		if name == "--format" {
+			if v != "text" && v != "json" {
+				return opts, fmt.Errorf("unknown format %q (expected text or json)", v)
+			}
+			opts.format = v
+			opts.lintOnly = opts.lintOnly || v == "json"
+			continue
+		}
+		sev, err := lint.ParseSeverity(v)
+		if err != nil {
+			return opts, fmt.Errorf("--fail-on: %w", err)
+		}
+		opts.failOn = sev
+	}
+	return opts, nil
+}
+
+// check fails when --fail-on is set and a finding reaches it
+func (o inspectOptions) check(findings []lint.Finding) error {
+	if o.failOn == "" {
+		return nil
+	}
+	if n := lint.Count(findings, o.failOn); n > 0 {
+		return e.New(e.ErrInvalidDockerfile, fmt.Sprintf("Dockerfile lint found %d problem(s) at %s or above", n, o.failOn)).
+			WithSuggestion("Fix the findings listed by 'mitl inspect --lint'")
+	}
 	return nil
 }
+
+// printFindings lists lint findings for the Dockerfile a build uses
+func printFindings(source string, findings []lint.Finding) {
+	fmt.Printf("=== Dockerfile Lint (%s) ===\n", source)
+	if len(findings) == 0 {
+		fmt.Println("No problems found")
+		return
+	}
+	icons := map[lint.Severity]string{lint.SeverityError: "❌", lint.SeverityWarning: "⚠️ ", lint.SeverityInfo: "💡"}
+	for _, f := range findings {
+		fmt.Printf("%s %s\n", icons[f.Severity], f)
+	}
+}
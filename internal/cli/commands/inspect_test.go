@@ -1,7 +1,68 @@
 package commands
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mitl/internal/build/lint"
+
+	e "mitl/pkg/errors"
+)
 
 func TestInspect(t *testing.T) {
 	_ = Inspect([]string{}) // exercise path; ignore error since project may be unknown
 }
+
+func TestInspect_LintFailOn(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM node:latest\nRUN apk add git || true\n"), 0o644)
+	oldWd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(oldWd)
+
+	if err := Inspect([]string{"--format", "json"}); err != nil {
+		t.Fatalf("findings alone must not fail: %v", err)
+	}
+	err := Inspect([]string{"--lint", "--fail-on=warning"})
+	if me, ok := err.(*e.MitlError); !ok || me.Code != e.ErrInvalidDockerfile {
+		t.Fatalf("expected INVALID_DOCKERFILE, got %v", err)
+	}
+	if err := Inspect([]string{"--lint", "--fail-on", "error"}); err == nil {
+		t.Fatal("latest base image must fail --fail-on error")
+	}
+
+	os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM node:20\nRUN apk add git || true\n"), 0o644)
+	if err := Inspect([]string{"--lint", "--fail-on", "error"}); err != nil {
+		t.Fatalf("no error-level findings expected: %v", err)
+	}
+}
+
+func TestParseInspectFlags(t *testing.T) {
+	opts, err := parseInspectFlags([]string{"--format=json", "--fail-on", "info"})
+	if err != nil || !opts.lintOnly || opts.format != "json" || opts.failOn != lint.SeverityInfo {
+		t.Fatalf("unexpected options %+v, %v", opts, err)
+	}
+	for _, args := range [][]string{{"--format", "yaml"}, {"--fail-on", "fatal"}, {"--fail-on"}, {"--failon", "error"}, {"--fail_on=error"}, {"lint"}} {
+		if _, err := parseInspectFlags(args); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+}
+
+func TestInspect_LintsDockerfileHydrateUsed(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM node:20\nUSER node\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "Dockerfile.ci"), []byte("FROM node:latest\nUSER node\n"), 0o644)
+	chdir(t, dir)
+
+	if err := Inspect([]string{"--lint", "--fail-on", "error"}); err != nil {
+		t.Fatalf("the root Dockerfile is clean: %v", err)
+	}
+	scope, _ := resolveScope("")
+	scope.saveBuiltWith(hydrateOptions{dockerfile: "Dockerfile.ci"})
+	if err := Inspect([]string{"--lint", "--fail-on", "error"}); err == nil {
+		t.Fatal("expected the Dockerfile hydrate built with to be linted")
+	}
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"mitl/internal/build/imagelock"
+)
+
+// resolveImage enables test stubbing of registry lookups
+var resolveImage = imagelock.Resolve
+
+// Lock pins the base images of the capsule's Dockerfile, generated or the
+// project's own, to content digests in mitl.lock. Images already locked keep
+// their digest unless --update is given; entries no longer used are dropped.
+func Lock(args []string) error {
+	update, pkg := false, ""
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "--update":
+			update = true
+		case a == "--package":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--package requires a value")
+			}
+			pkg = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--package="):
+			pkg = strings.TrimPrefix(a, "--package=")
+		default:
+			return fmt.Errorf("unknown lock option: %s (usage: mitl lock [--update] [--package name])", a)
+		}
+	}
+	scope, serr := resolveScope(pkg)
+	if serr != nil {
+		return serr
+	}
+	m, merr := scope.loadManifest()
+	if merr != nil {
+		return merr
+	}
+	src, rerr := hydrateOptions{}.source(scope.root, m)
+	if rerr != nil {
+		return rerr
+	}
+	old, lerr := imagelock.Load(scope.root)
+	if lerr != nil {
+		return lerr
+	}
+
+	dockerfile, derr := scope.dockerfile(scope.detect(m), src)
+	if derr != nil {
+		return derr
+	}
+	refs := imagelock.Images(old.Unpin(string(dockerfile)))
+	if len(refs) == 0 {
+		fmt.Println("No base images to lock.")
+		return nil
+	}
+
+	runtime := findBuildCLI()
+	lock := imagelock.New()
+	resolved := 0
+	for _, ref := range refs {
+		if old != nil && !update {
+			if d, ok := old.Images[ref]; ok {
+				lock.Images[ref] = d
+				continue
+			}
+		}
+		fmt.Printf("\x1b[33m🔍 Resolving %s...\x1b[0m\n", ref)
+		d, err := resolveImage(runtime, ref)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("   %s@%s\n", ref, d)
+		lock.Images[ref] = d
+		resolved++
+	}
+	if err := lock.Save(scope.root); err != nil {
+		return err
+	}
+	fmt.Printf("\x1b[32m🔒 Locked %d base image(s) in %s (%d resolved)\x1b[0m\n", len(lock.Images), imagelock.FileName, resolved)
+	return nil
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/build/imagelock"
+)
+
+func TestLock(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.22\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+	oldWd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(oldWd)
+	t.Setenv("MITL_BUILD_CLI", "/bin/echo")
+
+	digests := []string{
+		"sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		"sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+	}
+	var resolved []string
+	old := resolveImage
+	resolveImage = func(runtime, ref string) (string, error) {
+		resolved = append(resolved, ref)
+		return digests[len(resolved)-1], nil
+	}
+	defer func() { resolveImage = old }()
+
+	if err := Lock(nil); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	if strings.Join(resolved, ",") != "golang:1.22-alpine" {
+		t.Fatalf("unexpected resolved images %v", resolved)
+	}
+	generated, err := NewDockerfileGenerator(detectProject(nil)).Generate()
+	if err != nil || !strings.Contains(generated, "FROM golang:1.22-alpine@"+digests[0]+" AS base") {
+		t.Fatalf("expected a pinned base image, got %v:\n%s", err, generated)
+	}
+
+	if err := Lock(nil); err != nil || len(resolved) != 1 {
+		t.Fatalf("locked images should be kept, resolved %v, err %v", resolved, err)
+	}
+	if err := Lock([]string{"--update"}); err != nil || len(resolved) != 2 {
+		t.Fatalf("--update should resolve again, resolved %v, err %v", resolved, err)
+	}
+	l, _ := imagelock.Load(".")
+	if l.Images["golang:1.22-alpine"] != digests[1] {
+		t.Fatalf("expected the refreshed digest, got %v", l.Images)
+	}
+	if err := Lock([]string{"--bogus"}); err == nil {
+		t.Fatalf("expected an error for an unknown option")
+	}
+}
@@ -0,0 +1,228 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"mitl/internal/build"
+	"mitl/internal/build/templates"
+	"mitl/internal/detector"
+	"mitl/internal/digest"
+	"mitl/internal/manifest"
+
+	e "mitl/pkg/errors"
+)
+
+// loadManifest reads and validates the optional mitl.yaml in the current
+// directory. A missing manifest is not an error and yields nil.
+func loadManifest() (*manifest.Manifest, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Load(cwd)
+}
+
+// detectProject runs project detection in the current directory and
+// overlays manifest values on top of the detector defaults.
+func detectProject(m *manifest.Manifest) *detector.ProjectDetector {
+	return detectProjectAt("", m)
+}
+
+// detectProjectAt is detectProject for an explicit project root
+func detectProjectAt(root string, m *manifest.Manifest) *detector.ProjectDetector {
+	pd := detector.NewProjectDetector(root)
+	_ = pd.Detect()
+	m.Apply(pd)
+	policy, err := m.PackageManagerPolicy()
+	if err != nil {
+		fmt.Printf("\x1b[33m⚠️  %v; respecting the project's package manager\x1b[0m\n", err)
+	}
+	pd.ApplyPackageManagerPolicy(policy)
+	return pd
+}
+
+// workspaceScope is what a command targets: the project in the current
+// directory or, with --package, one member of the enclosing workspace. A
+// workspace shares one capsule and one set of dependency volumes, built and
+// mounted from its root, so its digest covers every member's manifests
+// whichever package a command targets.
+type workspaceScope struct {
+	root string                     // digest root; "." for the current directory
+	pkg  *detector.WorkspacePackage // nil without --package
+}
+
+// resolveScope returns the scope for a --package value ("" for none)
+func resolveScope(name string) (workspaceScope, error) {
+	if name == "" {
+		return workspaceScope{root: "."}, nil
+	}
+	ws := detector.FindWorkspace(".")
+	if ws == nil {
+		return workspaceScope{}, e.New(e.ErrInvalidConfig, "No workspace found for --package "+name).
+			WithSuggestion("Run from a monorepo with pnpm/npm/Yarn workspaces, go.work, a Cargo workspace, Composer path repositories or Nx projects")
+	}
+	pkg, ok := ws.Package(name)
+	if !ok {
+		return workspaceScope{}, e.New(e.ErrInvalidConfig, fmt.Sprintf("Unknown workspace package %q", name)).
+			WithContext("workspace", ws.Root).
+			WithSuggestion("Available packages: " + strings.Join(ws.Names(), ", "))
+	}
+	return workspaceScope{root: ws.Root, pkg: &pkg}, nil
+}
+
+// loadManifest reads the manifest at the workspace root for --package,
+// otherwise in the current directory
+func (s workspaceScope) loadManifest() (*manifest.Manifest, error) {
+	if s.pkg == nil {
+		return loadManifest()
+	}
+	return manifest.Load(s.root)
+}
+
+// detect runs project detection at the scope's root
+func (s workspaceScope) detect(m *manifest.Manifest) *detector.ProjectDetector {
+	if s.pkg == nil {
+		return detectProject(m)
+	}
+	return detectProjectAt(s.root, m)
+}
+
+// capsule is the image a command runs in
+type capsule struct {
+	tag        string // mitl-capsule:<digest>
+	digest     string
+	dockerfile []byte         // what it is built from, base images pinned
+	covered    *digest.Digest // the full digest, recorded by hydrate
+}
+
+// capsule resolves the scope's capsule for pd: the Dockerfile src selects
+// and the digest that tags it. The digest covers only what shapes the image
+// (lockfiles and dependency manifests, mitl.yaml, the Dockerfile and the
+// detector's view of the project), since run and shell mount the source
+// over /app. With build.source: baked it covers every project file instead.
+func (s workspaceScope) capsule(m *manifest.Manifest, pd *detector.ProjectDetector, src build.Source) (capsule, error) {
+	dockerfile, derr := s.dockerfile(pd, src)
+	if derr != nil {
+		return capsule{}, derr
+	}
+	var d *digest.Digest
+	var err error
+	if m.BakedSource() {
+		d, err = s.sourceDigest(pd, src, dockerfile)
+	} else {
+		d, err = s.dependencyDigest(pd, src, dockerfile)
+	}
+	if err != nil {
+		return capsule{}, err
+	}
+	value := d.Hash[:12]
+	return capsule{tag: "mitl-capsule:" + value, digest: value, dockerfile: dockerfile, covered: d}, nil
+}
+
+// capsuleTag returns the tag of the capsule mitl hydrate last built for the
+// scope: mitl.yaml's build settings with the hydrate flags it was built with
+func (s workspaceScope) capsuleTag(m *manifest.Manifest, pd *detector.ProjectDetector) (string, error) {
+	src, err := s.builtWith().source(s.root, m)
+	if err != nil {
+		return "", err
+	}
+	c, err := s.capsule(m, pd, src)
+	if err != nil {
+		return "", err
+	}
+	return c.tag, nil
+}
+
+// dockerfile returns the Dockerfile the capsule is built from: the project's
+// own or the one generated for pd, with mitl.lock's base images pinned
+func (s workspaceScope) dockerfile(pd *detector.ProjectDetector, src build.Source) ([]byte, error) {
+	if !src.Generated() {
+		return projectDockerfile(s.root, src)
+	}
+	generated, err := NewDockerfileGenerator(pd).Generate()
+	if err != nil {
+		return nil, e.Wrap(err, e.ErrBuildFailed, "Dockerfile generation failed")
+	}
+	return []byte(generated), nil
+}
+
+// sourceDigest hashes every project file in scope along with the
+// Dockerfile, the build source and the detector output, so a baked capsule
+// is rebuilt for another --target or build argument. User Dockerfile
+// templates count as project files.
+func (s workspaceScope) sourceDigest(pd *detector.ProjectDetector, src build.Source, dockerfile []byte) (*digest.Digest, error) {
+	d, err := digest.NewProjectCalculator(s.root, s.digestOptions()).Calculate(context.Background())
+	if err != nil {
+		return nil, e.Wrap(err, e.ErrUnknown, "Failed to compute project digest").
+			WithSuggestion("Run 'mitl digest --verbose' for details")
+	}
+	d.AddInputs(capsuleInputs(pd, src, dockerfile))
+	return d, nil
+}
+
+// dependencyDigest hashes the dependency files in scope along with the
+// Dockerfile, the build source and the detector output
+func (s workspaceScope) dependencyDigest(pd *detector.ProjectDetector, src build.Source, dockerfile []byte) (*digest.Digest, error) {
+	d, err := digest.DependencyDigest(s.root, s.digestOptions(), capsuleInputs(pd, src, dockerfile))
+	if err != nil {
+		return nil, e.Wrap(err, e.ErrUnknown, "Failed to compute dependency digest").
+			WithSuggestion("Run 'mitl digest --verbose' for details")
+	}
+	return d, nil
+}
+
+// pkgName returns the --package name, "" for the whole project
+func (s workspaceScope) pkgName() string {
+	if s.pkg == nil {
+		return ""
+	}
+	return s.pkg.Name
+}
+
+// digestOptions hashes the whole project or workspace
+func (s workspaceScope) digestOptions() *digest.Options {
+	return &digest.Options{
+		Algorithm:  "sha256",
+		Mode:       digestMode(),
+		ExtraFiles: templates.Files(s.root),
+	}
+}
+
+// digestMode returns the digest mode set by MITL_DIGEST_MODE ("walk" or
+// "git"). Git mode leaves out untracked files .gitignore matches, so the
+// modes only agree when there are none.
+func digestMode() string {
+	return os.Getenv("MITL_DIGEST_MODE")
+}
+
+// capsuleInputs are the parts of the dependency digest that are not
+// project files. The detector output leaves out Root, which differs between
+// checkouts, and the workspace layout, which the files already cover.
+func capsuleInputs(pd *detector.ProjectDetector, src build.Source, dockerfile []byte) map[string][]byte {
+	detected, _ := json.Marshal(struct {
+		Type         detector.ProjectType
+		Languages    []detector.Language
+		Framework    string
+		Version      string
+		Dependencies detector.Dependencies
+	}{pd.Type, pd.Languages, pd.Framework, pd.Version, pd.Dependencies})
+	return map[string][]byte{
+		"dockerfile": dockerfile,
+		"detector":   detected,
+		"source":     []byte(src.Label()),
+	}
+}
+
+// workdir is the container working directory: the package's directory
+// under /app for --package
+func (s workspaceScope) workdir() string {
+	if s.pkg == nil {
+		return "/app"
+	}
+	return path.Join("/app", s.pkg.Path)
+}
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"mitl/internal/manifest"
+)
+
+func TestCapsuleTag_DependencyIdentity(t *testing.T) {
+	proj := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(proj, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("package.json", `{"name":"app","dependencies":{"express":"^4.19.0"}}`)
+	write("package-lock.json", `{"packages":{"node_modules/express":{"version":"4.19.2"}}}`)
+	write("index.js", "console.log('v1')")
+	tag := func() string {
+		m, err := manifest.Load(proj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := workspaceScope{root: proj}.capsuleTag(m, detectProjectAt(proj, m))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	base := tag()
+	write("index.js", "console.log('v2')")
+	if got := tag(); got != base {
+		t.Fatalf("a source edit must reuse the capsule: %s -> %s", base, got)
+	}
+	write("package-lock.json", `{"packages":{"node_modules/express":{"version":"4.19.3"}}}`)
+	locked := tag()
+	if locked == base {
+		t.Fatal("a lockfile change must rebuild the capsule")
+	}
+
+	write("mitl.yaml", "build:\n  source: baked\n")
+	baked := tag()
+	if baked == locked {
+		t.Fatal("build.source: baked must tag by the project digest")
+	}
+	write("index.js", "console.log('v3')")
+	if got := tag(); got == baked {
+		t.Fatal("baked capsules must rebuild on source edits")
+	}
+}
+
+func TestCapsuleTag_FollowsHydrateFlags(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	t.Setenv("MITL_BUILD_CLI", "/bin/echo")
+	proj := filepath.Join(tmp, "proj")
+	os.MkdirAll(proj, 0o755)
+	os.WriteFile(filepath.Join(proj, "package.json"), []byte(`{"name":"app"}`), 0o644)
+	os.WriteFile(filepath.Join(proj, "Containerfile"), []byte("FROM node:20 AS dev\nFROM dev AS prod\n"), 0o644)
+	oldWd, _ := os.Getwd()
+	os.Chdir(proj)
+	defer os.Chdir(oldWd)
+	built := ""
+	old := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 2 && args[0] == "build" {
+			built = args[2]
+		}
+		return exec.Command("sh", "-c", "true")
+	}
+	defer func() { execCommand = old }()
+	tag := func() string {
+		m, err := manifest.Load(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := workspaceScope{root: "."}.capsuleTag(m, detectProject(m))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	if err := Hydrate([]string{"--target", "dev", "--build-arg", "A=1"}); err != nil {
+		t.Fatal(err)
+	}
+	dev := built
+	if got := tag(); got != dev {
+		t.Fatalf("run must use the capsule hydrate built: %s, want %s", got, dev)
+	}
+	if rec, err := NewDigestCommand().currentCapsule(workspaceScope{root: "."}); err != nil || "mitl-capsule:"+rec.Tag != dev {
+		t.Fatalf("digest explain must explain the capsule hydrate built: %+v, %v", rec, err)
+	}
+	if err := Hydrate(nil); err != nil {
+		t.Fatal(err)
+	}
+	if built == dev || tag() != built {
+		t.Fatalf("hydrate without flags must switch back: built %s, tag %s", built, tag())
+	}
+
+	// Baked capsules cover the build source as well as the files
+	os.WriteFile("mitl.yaml", []byte("build:\n  source: baked\n"), 0o644)
+	Hydrate([]string{"--target", "dev"})
+	bakedDev := built
+	Hydrate([]string{"--target", "prod"})
+	if built == bakedDev || tag() != built {
+		t.Fatalf("another target must rebuild a baked capsule: %s, %s, tag %s", bakedDev, built, tag())
+	}
+}
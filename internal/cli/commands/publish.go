@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+
+	"mitl/internal/detector"
+	"mitl/internal/manifest"
+	"mitl/internal/ports"
+)
+
+// resolvePublish combines explicit flags, manifest ports and (when neither
+// is given) detector-inferred ports, then moves any busy host port to a free
+// one.
+func resolvePublish(opts runOptions, m *manifest.Manifest, pd *detector.ProjectDetector) ([]ports.Mapping, error) {
+	specs := append([]string{}, opts.publish...)
+	if m != nil {
+		specs = append(specs, m.Ports...)
+	}
+	if len(specs) == 0 && opts.autoPorts && pd != nil {
+		for _, p := range pd.InferPorts() {
+			specs = append(specs, strconv.Itoa(p))
+		}
+	}
+	var mappings []ports.Mapping
+	for _, s := range specs {
+		mp, err := ports.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port mapping %q: %w", s, err)
+		}
+		mappings = append(mappings, mp)
+	}
+	return ports.Resolve(mappings), nil
+}
+
+// publishArgs renders mappings as runtime -p flags
+func publishArgs(mappings []ports.Mapping) []string {
+	args := make([]string, 0, len(mappings)*2)
+	for _, spec := range publishedSpecs(mappings) {
+		args = append(args, "-p", spec)
+	}
+	return args
+}
+
+// printPublished prints the URL for each TCP mapping, noting remapped ports
+func printPublished(mappings []ports.Mapping) {
+	for _, mp := range mappings {
+		if mp.Proto == "udp" {
+			continue
+		}
+		if mp.Remapped() {
+			want := mp.Requested
+			if want == 0 {
+				want = mp.Container
+			}
+			fmt.Printf("\x1b[33m⚠️  Port %d is in use; using %d instead\x1b[0m\n", want, mp.Host)
+		}
+		fmt.Printf("   🌐 %s\n", mp.URL())
+	}
+}
+
+// publishedSpecs returns the resolved mappings as strings for display
+func publishedSpecs(mappings []ports.Mapping) []string {
+	out := make([]string, 0, len(mappings))
+	for _, mp := range mappings {
+		out = append(out, mp.String())
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/ports"
+)
+
+func TestRun_PublishesInferredPortOnRequest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MITL_RUN_CLI", "/bin/echo")
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"dependencies":{"next":"14"}}`), 0o644)
+	chdir(t, dir)
+
+	origAvail := ports.Available
+	ports.Available = func(_ string, p int) bool { return p != 3000 }
+	defer func() { ports.Available = origAvail }()
+
+	var runArgs string
+	old := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 0 && args[0] == "run" {
+			runArgs = strings.Join(args, " ")
+		}
+		return exec.Command("sh", "-c", "true")
+	}
+	defer func() { execCommand = old }()
+
+	if err := Run([]string{"pnpm", "build"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if strings.Contains(runArgs, "-p ") {
+		t.Fatalf("run publishes inferred ports only with -P, got %s", runArgs)
+	}
+
+	out := captureOut(t, func() {
+		if err := Run([]string{"-P", "pnpm", "dev"}); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	})
+	if !strings.Contains(runArgs, "-p 3001:3000") {
+		t.Fatalf("expected remapped publish flag, got %s", runArgs)
+	}
+	if !strings.Contains(out, "http://localhost:3001") {
+		t.Fatalf("expected URL in output:\n%s", out)
+	}
+
+	if err := Shell([]string{"--no-publish"}); err != nil {
+		t.Fatalf("shell: %v", err)
+	}
+	if strings.Contains(runArgs, "-p ") {
+		t.Fatalf("expected no publish flags with --no-publish, got %s", runArgs)
+	}
+}
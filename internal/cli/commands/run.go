@@ -8,85 +8,166 @@ import (
 
 	"mitl/internal/container"
 	"mitl/internal/detector"
-	"mitl/internal/digest"
+	"mitl/internal/manifest"
+	"mitl/internal/ports"
 	"mitl/internal/volume"
 
 	e "mitl/pkg/errors"
 )
 
+const runUsage = "Usage: mitl run [--package name] [-p host:container]... [-e KEY=VAL]... [--env-file file] [-P] [--no-env-file] <command> [args]"
+
 // Run executes the given command inside the capsule Docker image.
 // This command allows running any command within the project's container environment.
 func Run(args []string) error {
+	// One-off commands rarely serve; -P publishes the inferred ports
+	opts, args, perr := parseRunFlags(args, false)
+	if perr != nil {
+		fmt.Println(runUsage)
+		return perr
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
 	if len(args) == 0 {
-		fmt.Println("Usage: mitl run <command> [args]")
+		fmt.Println(runUsage)
 		return fmt.Errorf("no command specified")
 	}
 
-	// Use deterministic project digest for capsule tag
-	digestValue, derr := digest.ProjectTag(".", &digest.Options{Algorithm: "sha256"})
-	if derr != nil {
-		return e.Wrap(derr, e.ErrUnknown, "Failed to compute project digest").
-			WithSuggestion("Run 'mitl digest --verbose' for details")
+	scope, serr := resolveScope(opts.pkg)
+	if serr != nil {
+		return serr
+	}
+	m, merr := scope.loadManifest()
+	if merr != nil {
+		return merr
 	}
-	tag := fmt.Sprintf("mitl-capsule:%s", digestValue)
 
 	// Detect project type for proper volume mounting and pnpm enforcement
-	detectorInstance := detector.NewProjectDetector("")
-	_ = detectorInstance.Detect()
+	detectorInstance := scope.detect(m)
+	tag, terr := scope.capsuleTag(m, detectorInstance)
+	if terr != nil {
+		return terr
+	}
 
 	// Initialize volume manager
 	cli := findRunCLI()
-	vm := volume.NewManager(cli, "")
+	vm := newVolumeManager(cli, detectorInstance)
 
-	// Intercept: npm/yarn converted to pnpm for Node projects
-	if detectorInstance.Type == detector.TypeNodeGeneric || strings.HasPrefix(string(detectorInstance.Type), "node") {
+	// Intercept: other managers' commands rewritten per the package manager policy
+	if strings.HasPrefix(string(detectorInstance.Type), "node") {
 		args = vm.InterceptNodeCommand(args)
-		pnpm := volume.NewPnpmManager("", vm)
-		_ = pnpm.ConvertToUsingPnpm()
+		if nd := detectorInstance.Dependencies.Node; nd.Policy.Rewrites() && nd.PackageManager == detector.PackageManagerPNPM {
+			pnpm := volume.NewPnpmManager("", vm)
+			_ = pnpm.ConvertToUsingPnpm()
+		}
+	}
+
+	mappings, perr := resolvePublish(opts, m, detectorInstance)
+	if perr != nil {
+		return perr
+	}
+	env, eerr := resolveEnv(opts, m, detectorInstance.Root)
+	if eerr != nil {
+		return eerr
 	}
 
 	// Build container args with mounts
-	containerArgs := []string{"run", "--rm"}
-	containerArgs = append(containerArgs, vm.GetMounts(detectorInstance.Type)...)
-	// If performing package installs in Node containers, run as root to avoid permission issues on mounted volumes
-	joined := strings.Join(args, " ")
-	if strings.HasPrefix(string(detectorInstance.Type), "node") {
-		if strings.Contains(joined, "install") || strings.Contains(joined, "add") || strings.Contains(joined, "ci") {
-			containerArgs = append(containerArgs, "--user", "0")
-		}
+	shared, cleanup, aerr := capsuleArgs(vm, detectorInstance, m, env, mappings)
+	if aerr != nil {
+		return aerr
 	}
-	containerArgs = append(containerArgs, "-w", "/app", tag)
+	defer cleanup()
+	containerArgs := []string{"run", "--rm"}
+	containerArgs = append(containerArgs, shared...)
+	containerArgs = append(containerArgs, capsuleUserArgs(detectorInstance.Type, args)...)
+	containerArgs = append(containerArgs, "-w", scope.workdir(), tag)
 	containerArgs = append(containerArgs, args...)
 
+	env.printSummary()
+	printPublished(mappings)
 	cmd := execCommand(cli, containerArgs...)
+	cmd.Env = env.cliEnv()
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
-	err := cmd.Run()
+	if err := cmd.Run(); err != nil {
+		return runtimeError(err, cli, "Failed to run command")
+	}
+	return nil
+}
+
+// newVolumeManager returns a volume manager for the project's root,
+// resolved Node package manager and workspace members
+func newVolumeManager(cli string, pd *detector.ProjectDetector) *volume.Manager {
+	vm := volume.NewManager(cli, pd.Root)
+	vm.SetNodeDependencies(pd.Dependencies.Node)
+	vm.SetWorkspace(pd.Workspace)
+	return vm
+}
+
+// capsuleArgs returns the mount, env and publish flags shared by run, shell
+// and up: the project source plus dependency volumes, manifest mounts, the
+// env file and -p mappings. cleanup removes the env file after the runtime
+// has started the container.
+func capsuleArgs(vm *volume.Manager, pd *detector.ProjectDetector, m *manifest.Manifest, env containerEnv, mappings []ports.Mapping) ([]string, func(), error) {
+	envArgs, cleanup, err := env.args()
 	if err != nil {
-		// Map common runtime issues to MitlError with guidance
-		msg := strings.ToLower(err.Error())
-		// docker daemon not running / connection issues
-		if strings.Contains(msg, "docker daemon is not running") ||
-			strings.Contains(msg, "cannot connect to the docker daemon") ||
-			strings.Contains(msg, "error during connect") ||
-			strings.Contains(msg, "dial unix") ||
-			strings.Contains(msg, "connect: connection refused") {
-			return e.New(e.ErrRuntimeNotRunning, "Container runtime is not running").WithContext("runtime", cli)
-		}
-		// permission denied on socket
-		if strings.Contains(msg, "permission denied") {
-			return e.New(e.ErrRuntimePermission, "Permission denied when accessing runtime").WithContext("runtime", cli).WithCause(err)
-		}
-		// runtime binary missing
-		if strings.Contains(msg, "executable file not found") || strings.Contains(msg, "file not found") {
-			return e.New(e.ErrRuntimeNotFound, "No container runtime found").WithContext("runtime", cli).WithCause(err)
+		return nil, cleanup, err
+	}
+	args := vm.GetMounts(pd.Type)
+	args = append(args, m.MountArgs(pd.Root)...)
+	args = append(args, envArgs...)
+	args = append(args, publishArgs(mappings)...)
+	return args, cleanup, nil
+}
+
+// capsuleUserArgs returns the --user flag run, shell and exec give a
+// command. Generated Node capsules run as the unprivileged nodejs user, which
+// cannot write the mounted node_modules and store volumes, so package
+// installs (install, i, add, ci) run as root; everything else keeps the
+// image's user. Other generated capsules already run as root. A nil command
+// is an interactive shell, which deliberately gets root in Node capsules
+// too: a shell is where packages get installed by hand.
+func capsuleUserArgs(t detector.ProjectType, command []string) []string {
+	if !strings.HasPrefix(string(t), "node") {
+		return nil
+	}
+	if command == nil {
+		return []string{"--user", "0"}
+	}
+	for _, a := range command {
+		switch a {
+		case "install", "i", "add", "ci":
+			return []string{"--user", "0"}
 		}
-		return e.Wrap(err, e.ErrUnknown, "Failed to run command").WithContext("runtime", cli)
 	}
 	return nil
 }
 
+// runtimeError maps common container runtime failures to MitlErrors with
+// guidance, falling back to an UNKNOWN error carrying msg.
+func runtimeError(err error, cli, msg string) error {
+	lower := strings.ToLower(err.Error())
+	// docker daemon not running / connection issues
+	if strings.Contains(lower, "docker daemon is not running") ||
+		strings.Contains(lower, "cannot connect to the docker daemon") ||
+		strings.Contains(lower, "error during connect") ||
+		strings.Contains(lower, "dial unix") ||
+		strings.Contains(lower, "connect: connection refused") {
+		return e.New(e.ErrRuntimeNotRunning, "Container runtime is not running").WithContext("runtime", cli)
+	}
+	// permission denied on socket
+	if strings.Contains(lower, "permission denied") {
+		return e.New(e.ErrRuntimePermission, "Permission denied when accessing runtime").WithContext("runtime", cli).WithCause(err)
+	}
+	// runtime binary missing
+	if strings.Contains(lower, "executable file not found") || strings.Contains(lower, "file not found") {
+		return e.New(e.ErrRuntimeNotFound, "No container runtime found").WithContext("runtime", cli).WithCause(err)
+	}
+	return e.Wrap(err, e.ErrUnknown, msg).WithContext("runtime", cli)
+}
+
 // findRunCLI attempts to locate a suitable container run CLI. The logic
 // mirrors findBuildCLI but allows override via MITL_RUN_CLI. In practice,
 // the same binary can be used for building and running, but having two
@@ -1,9 +1,37 @@
 package commands
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"mitl/internal/detector"
+)
 
 func TestRun_NoArgs(t *testing.T) {
 	if err := Run(nil); err == nil {
 		t.Fatalf("expected error when no args provided")
 	}
 }
+
+func TestCapsuleUserArgs(t *testing.T) {
+	tests := []struct {
+		typ     detector.ProjectType
+		command []string
+		root    bool
+	}{
+		{detector.TypeNodeGeneric, []string{"pnpm", "install"}, true},
+		{detector.TypeNodeGeneric, []string{"npm", "ci"}, true},
+		{detector.TypeNodeGeneric, []string{"yarn", "add", "zod"}, true},
+		{detector.TypeNodeGeneric, []string{"npm", "run", "lint:ci-check"}, false},
+		{detector.TypeNodeGeneric, []string{"node", "specific.js"}, false},
+		{detector.TypeNodeGeneric, nil, true},
+		{detector.TypeGoModule, []string{"go", "install", "./..."}, false},
+		{detector.TypeGoModule, nil, false},
+	}
+	for _, tt := range tests {
+		got := strings.Join(capsuleUserArgs(tt.typ, tt.command), " ")
+		if (got == "--user 0") != tt.root {
+			t.Errorf("capsuleUserArgs(%s, %v) = %q, want root %v", tt.typ, tt.command, got, tt.root)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+)
+
+// runOptions holds the container flags shared by run, shell and up
+type runOptions struct {
+	publish   []string // explicit -p/--publish values
+	autoPorts bool     // publish detector-inferred ports when nothing else is set
+	env       []string // -e/--env values, KEY=VAL or KEY (forwarded from host)
+	envFiles  []string // --env-file paths
+	dotenv    bool     // load the project's .env automatically
+	pkg       string   // --package: workspace member to target
+}
+
+// parseRunFlags consumes leading mitl flags (-p, -P, --no-publish, -e,
+// --env-file, --no-env-file, --package) and returns the remaining arguments untouched,
+// including a leading "--". autoPorts is the command's default for publishing
+// inferred ports, which -P and --no-publish override.
+func parseRunFlags(args []string, autoPorts bool) (runOptions, []string, error) {
+	opts := runOptions{autoPorts: autoPorts, dotenv: true}
+	value := func(i int) (string, error) {
+		if i+1 >= len(args) {
+			return "", fmt.Errorf("%s requires a value", args[i])
+		}
+		return args[i+1], nil
+	}
+	i := 0
+	for ; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-p" || a == "--publish":
+			v, err := value(i)
+			if err != nil {
+				return opts, nil, err
+			}
+			opts.publish = append(opts.publish, v)
+			i++
+		case strings.HasPrefix(a, "--publish="):
+			opts.publish = append(opts.publish, strings.TrimPrefix(a, "--publish="))
+		case a == "-P" || a == "--publish-all":
+			opts.autoPorts = true
+		case a == "--no-publish":
+			opts.autoPorts = false
+		case a == "-e" || a == "--env":
+			v, err := value(i)
+			if err != nil {
+				return opts, nil, err
+			}
+			opts.env = append(opts.env, v)
+			i++
+		case strings.HasPrefix(a, "--env="):
+			opts.env = append(opts.env, strings.TrimPrefix(a, "--env="))
+		case a == "--env-file":
+			v, err := value(i)
+			if err != nil {
+				return opts, nil, err
+			}
+			opts.envFiles = append(opts.envFiles, v)
+			i++
+		case strings.HasPrefix(a, "--env-file="):
+			opts.envFiles = append(opts.envFiles, strings.TrimPrefix(a, "--env-file="))
+		case a == "--no-env-file":
+			opts.dotenv = false
+		case a == "--package":
+			v, err := value(i)
+			if err != nil {
+				return opts, nil, err
+			}
+			opts.pkg = v
+			i++
+		case strings.HasPrefix(a, "--package="):
+			opts.pkg = strings.TrimPrefix(a, "--package=")
+		default:
+			return opts, args[i:], nil
+		}
+	}
+	return opts, args[i:], nil
+}
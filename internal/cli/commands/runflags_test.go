@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRunFlags(t *testing.T) {
+	args := []string{"-p", "3000", "--publish=8080:80", "--no-publish", "-e", "A=1", "--env=B", "--env-file", ".env.test", "--no-env-file", "npm", "run", "dev", "-p", "1"}
+	opts, rest, err := parseRunFlags(args, true)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if strings.Join(opts.publish, ",") != "3000,8080:80" || opts.autoPorts {
+		t.Fatalf("unexpected publish options %+v", opts)
+	}
+	if strings.Join(opts.env, ",") != "A=1,B" || strings.Join(opts.envFiles, ",") != ".env.test" || opts.dotenv {
+		t.Fatalf("unexpected env options %+v", opts)
+	}
+	if strings.Join(rest, " ") != "npm run dev -p 1" {
+		t.Fatalf("flags after the command must be left alone, got %v", rest)
+	}
+	if opts, _, _ := parseRunFlags([]string{"-P", "ls"}, false); !opts.autoPorts {
+		t.Fatalf("expected -P to publish inferred ports, got %+v", opts)
+	}
+	if opts, _, _ := parseRunFlags([]string{"--package", "web", "ls"}, false); opts.pkg != "web" {
+		t.Fatalf("expected --package web, got %+v", opts)
+	}
+	if opts, _, _ := parseRunFlags([]string{"--package=apps/api", "ls"}, false); opts.pkg != "apps/api" {
+		t.Fatalf("expected --package=apps/api, got %+v", opts)
+	}
+	for _, bad := range [][]string{{"-p"}, {"-e"}, {"--env-file"}, {"--package"}} {
+		if _, _, err := parseRunFlags(bad, false); err == nil {
+			t.Fatalf("expected error for %v", bad)
+		}
+	}
+}
@@ -3,30 +3,127 @@ package commands
 import (
 	"fmt"
 	"os"
+	"strings"
+)
 
-	"mitl/internal/digest"
+const shellUsage = "Usage: mitl shell [--shell path] [--package name] [-p host:container]... [-e KEY=VAL]... [--env-file file] [--no-publish] [--no-env-file]"
 
-	e "mitl/pkg/errors"
-)
+// shellCandidates are probed in order when neither --shell nor the manifest
+// picks one. Generated capsules are Alpine-based and usually only have ash.
+var shellCandidates = []string{"bash", "zsh", "ash", "sh"}
 
-// Shell opens an interactive shell inside the capsule Docker image.
+// Shell opens an interactive shell inside the capsule Docker image with the
+// same volumes, environment, ports and working directory as `mitl run`.
 func Shell(args []string) error {
-	// Use deterministic project digest for capsule tag
-	digestValue, derr := digest.ProjectTag(".", &digest.Options{Algorithm: "sha256"})
-	if derr != nil {
-		return e.Wrap(derr, e.ErrUnknown, "Failed to compute project digest").
-			WithSuggestion("Run 'mitl digest --verbose' for details")
+	shell, args, serr := extractShellFlag(args)
+	if serr != nil {
+		fmt.Println(shellUsage)
+		return serr
+	}
+	opts, rest, perr := parseRunFlags(args, true)
+	if perr != nil {
+		fmt.Println(shellUsage)
+		return perr
+	}
+	if len(rest) > 0 {
+		fmt.Println(shellUsage)
+		return fmt.Errorf("unknown shell argument: %s", rest[0])
+	}
+	scope, serr := resolveScope(opts.pkg)
+	if serr != nil {
+		return serr
+	}
+	m, merr := scope.loadManifest()
+	if merr != nil {
+		return merr
 	}
-	tag := fmt.Sprintf("mitl-capsule:%s", digestValue)
-	cwd, err := os.Getwd()
-	if err != nil {
-		return err
+	pd := scope.detect(m)
+	tag, terr := scope.capsuleTag(m, pd)
+	if terr != nil {
+		return terr
 	}
 	cli := findRunCLI()
-	containerArgs := []string{"run", "-it", "--rm", "-v", fmt.Sprintf("%s:/app", cwd), "-w", "/app", tag, "/bin/bash"}
+	vm := newVolumeManager(cli, pd)
+
+	mappings, perr := resolvePublish(opts, m, pd)
+	if perr != nil {
+		return perr
+	}
+	env, eerr := resolveEnv(opts, m, pd.Root)
+	if eerr != nil {
+		return eerr
+	}
+
+	if shell == "" && m != nil {
+		shell = m.Shell
+	}
+	if shell == "" {
+		shell = probeShell(cli, tag)
+	}
+
+	shared, cleanup, aerr := capsuleArgs(vm, pd, m, env, mappings)
+	if aerr != nil {
+		return aerr
+	}
+	defer cleanup()
+	containerArgs := []string{"run", "--rm"}
+	if isInteractive() {
+		containerArgs = append(containerArgs, "-it")
+	} else {
+		containerArgs = append(containerArgs, "-i")
+	}
+	containerArgs = append(containerArgs, shared...)
+	containerArgs = append(containerArgs, capsuleUserArgs(pd.Type, nil)...)
+	// Override the entrypoint so images wrapping commands (dumb-init, custom
+	// scripts) still drop straight into the shell
+	containerArgs = append(containerArgs, "-w", scope.workdir(), "--entrypoint", shell, tag)
+
+	env.printSummary()
+	printPublished(mappings)
 	cmd := execCommand(cli, containerArgs...)
+	cmd.Env = env.cliEnv()
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
-	return cmd.Run()
+	if err := cmd.Run(); err != nil {
+		return runtimeError(err, cli, "Failed to open shell")
+	}
+	return nil
+}
+
+// extractShellFlag removes --shell/--shell=<path> from args
+func extractShellFlag(args []string) (string, []string, error) {
+	shell := ""
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "--shell":
+			if i+1 >= len(args) || args[i+1] == "" {
+				return "", nil, fmt.Errorf("--shell requires a value")
+			}
+			shell = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--shell="):
+			shell = strings.TrimPrefix(a, "--shell=")
+			if shell == "" {
+				return "", nil, fmt.Errorf("--shell requires a value")
+			}
+		default:
+			rest = append(rest, a)
+		}
+	}
+	return shell, rest, nil
+}
+
+// probeShell asks the image which of shellCandidates it has, falling back to
+// /bin/sh when the probe fails (image missing, runtime error)
+func probeShell(cli, tag string) string {
+	script := "for s in " + strings.Join(shellCandidates, " ") + `; do command -v "$s" && exit 0; done; exit 1`
+	out, err := execCommand(cli, "run", "--rm", "--entrypoint", "/bin/sh", tag, "-c", script).Output()
+	if err == nil {
+		if fields := strings.Fields(string(out)); len(fields) > 0 && strings.HasPrefix(fields[0], "/") {
+			return fields[0]
+		}
+	}
+	return "/bin/sh"
 }
@@ -3,6 +3,8 @@ package commands
 import (
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -21,3 +23,78 @@ func TestShell_EchoRun(t *testing.T) {
 		t.Fatalf("shell: %v", err)
 	}
 }
+
+// stubShellRuntime records the interactive run and answers the shell probe
+// with probed; it returns a pointer to the recorded run args and probe count
+func stubShellRuntime(t *testing.T, probed string) (*string, *int) {
+	t.Helper()
+	var runArgs string
+	probes := 0
+	old := execCommand
+	execCommand = func(n string, args ...string) *exec.Cmd {
+		joined := strings.Join(args, " ")
+		switch {
+		case strings.Contains(joined, "--entrypoint /bin/sh") && strings.Contains(joined, "command -v"):
+			probes++
+			return exec.Command("sh", "-c", "echo "+probed)
+		case len(args) > 0 && args[0] == "run":
+			runArgs = joined
+		}
+		return exec.Command("sh", "-c", "true")
+	}
+	t.Cleanup(func() { execCommand = old })
+	return &runArgs, &probes
+}
+
+func TestShell_ProbesImageAndReusesRunMounts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MITL_RUN_CLI", "/bin/echo")
+	project, _ := filepath.EvalSymlinks(t.TempDir())
+	chdir(t, project)
+	oldTTY := isInteractive
+	isInteractive = func() bool { return false }
+	defer func() { isInteractive = oldTTY }()
+
+	runArgs, probes := stubShellRuntime(t, "/bin/ash")
+	if err := Shell([]string{"--no-publish"}); err != nil {
+		t.Fatalf("shell: %v", err)
+	}
+	if *probes != 1 {
+		t.Fatalf("expected one probe, got %d", *probes)
+	}
+	for _, want := range []string{"run --rm -i ", "-v " + project + ":/app", "-w /app --entrypoint /bin/ash mitl-capsule:"} {
+		if !strings.Contains(*runArgs, want) {
+			t.Fatalf("run args %q missing %q", *runArgs, want)
+		}
+	}
+	if strings.Contains(*runArgs, "/bin/bash") {
+		t.Fatalf("shell must not hardcode bash: %q", *runArgs)
+	}
+}
+
+func TestShell_FlagAndManifestSkipProbe(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MITL_RUN_CLI", "/bin/echo")
+	project := t.TempDir()
+	chdir(t, project)
+
+	runArgs, probes := stubShellRuntime(t, "/bin/bash")
+	if err := Shell([]string{"--shell", "/bin/zsh", "--no-publish"}); err != nil {
+		t.Fatalf("shell: %v", err)
+	}
+	if *probes != 0 || !strings.Contains(*runArgs, "--entrypoint /bin/zsh ") {
+		t.Fatalf("--shell not honored (probes=%d): %q", *probes, *runArgs)
+	}
+
+	os.WriteFile(filepath.Join(project, "mitl.yaml"), []byte("shell: ash\n"), 0o644)
+	if err := Shell([]string{"--no-publish"}); err != nil {
+		t.Fatalf("shell: %v", err)
+	}
+	if *probes != 0 || !strings.Contains(*runArgs, "--entrypoint ash ") {
+		t.Fatalf("manifest shell not honored (probes=%d): %q", *probes, *runArgs)
+	}
+
+	if err := Shell([]string{"--shell"}); err == nil {
+		t.Fatalf("expected error for --shell without value")
+	}
+}
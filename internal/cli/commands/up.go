@@ -0,0 +1,294 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"mitl/internal/cache"
+	"mitl/internal/detector"
+	"mitl/internal/manifest"
+	"mitl/internal/service"
+
+	e "mitl/pkg/errors"
+)
+
+const upUsage = "Usage: mitl up [-p host:container]... [-e KEY=VAL]... [--env-file file] [--no-publish] [--no-env-file] [-- command [args]]"
+
+// Up starts the project's capsule as a detached, named container. The
+// container keeps running until `mitl down`, so dev servers survive the
+// terminal that started them.
+func Up(args []string) error {
+	opts, rest, perr := parseRunFlags(args, true)
+	if perr != nil {
+		fmt.Println(upUsage)
+		return perr
+	}
+	if opts.pkg != "" {
+		// one service per project root; a second package would replace it
+		fmt.Println(upUsage)
+		return fmt.Errorf("--package is not supported by up; use mitl run --package")
+	}
+	var command []string
+	if len(rest) > 0 {
+		if rest[0] != "--" {
+			fmt.Println(upUsage)
+			return fmt.Errorf("unknown up argument: %s", rest[0])
+		}
+		command = rest[1:]
+	}
+
+	store := service.NewStore()
+	root, err := serviceRoot(store)
+	if err != nil {
+		return err
+	}
+	cli := findRunCLI()
+	name := service.ContainerName(root)
+
+	if svc, ok := store.Get(root); ok {
+		switch containerStatus(svc.Runtime, svc.Name) {
+		case "running":
+			fmt.Printf("\x1b[32m✅ Already running: %s\x1b[0m\n", svc.Name)
+			return nil
+		case "":
+			// Container was removed outside mitl; start a fresh one
+		default:
+			_ = execCommand(svc.Runtime, "rm", "-f", svc.Name).Run()
+		}
+	}
+
+	m, merr := manifest.Load(root)
+	if merr != nil {
+		return merr
+	}
+	pd := detectProjectAt(root, m)
+	tag, terr := workspaceScope{root: root}.capsuleTag(m, pd)
+	if terr != nil {
+		return terr
+	}
+	// A detached run would try to pull the missing tag and leave nothing
+	// but a logged failure behind
+	if exists, cerr := cache.NewCapsuleCache(cli, tag).Exists(); cerr == nil && !exists {
+		return e.New(e.ErrFileNotFound, "Capsule "+tag+" has not been built").
+			WithContext("project", root).
+			WithSuggestion("Build it first with: mitl hydrate")
+	}
+	vm := newVolumeManager(cli, pd)
+
+	if len(command) == 0 {
+		command = defaultServeCommand(pd)
+	}
+
+	mappings, perr := resolvePublish(opts, m, pd)
+	if perr != nil {
+		return perr
+	}
+	env, eerr := resolveEnv(opts, m, root)
+	if eerr != nil {
+		return eerr
+	}
+
+	runArgs := []string{"run", "-d", "--name", name,
+		"--label", cache.LabelProject + "=" + root,
+		"--label", service.LabelProjectHash + "=" + service.ProjectHash(root)}
+	shared, cleanup, aerr := capsuleArgs(vm, pd, m, env, mappings)
+	if aerr != nil {
+		return aerr
+	}
+	defer cleanup()
+	runArgs = append(runArgs, shared...)
+	runArgs = append(runArgs, "-w", "/app", tag)
+	runArgs = append(runArgs, command...)
+
+	fmt.Printf("\x1b[33m🚀 Starting %s (%s)...\x1b[0m\n", name, tag)
+	env.printSummary()
+	cmd := execCommand(cli, runArgs...)
+	cmd.Env = env.cliEnv()
+	var errBuf bytes.Buffer
+	cmd.Stderr = &errBuf
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(errBuf.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return runtimeError(err, cli, "Failed to start capsule")
+	}
+
+	svc := service.Service{
+		Name:      name,
+		Project:   root,
+		Tag:       tag,
+		Runtime:   cli,
+		Ports:     publishedSpecs(mappings),
+		Command:   command,
+		StartedAt: timeNowFn(),
+	}
+	if err := store.Put(svc); err != nil {
+		fmt.Printf("\x1b[33m⚠️  Could not save service state: %v\x1b[0m\n", err)
+	}
+	fmt.Printf("\x1b[32m✅ Running: %s\x1b[0m\n", name)
+	printPublished(mappings)
+	fmt.Println("   Logs: mitl logs -f   Stop: mitl down")
+	return nil
+}
+
+// Down stops and removes the project's capsule container
+func Down(args []string) error {
+	store := service.NewStore()
+	svc, err := currentService(store)
+	if err != nil {
+		return err
+	}
+	cmd := execCommand(svc.Runtime, "rm", "-f", svc.Name)
+	if err := cmd.Run(); err != nil && containerStatus(svc.Runtime, svc.Name) != "" {
+		return runtimeError(err, svc.Runtime, "Failed to stop capsule")
+	}
+	_ = store.Remove(svc.Project)
+	fmt.Printf("\x1b[32m🛑 Stopped: %s\x1b[0m\n", svc.Name)
+	return nil
+}
+
+// Restart restarts the project's capsule container in place
+func Restart(args []string) error {
+	store := service.NewStore()
+	svc, err := currentService(store)
+	if err != nil {
+		return err
+	}
+	cmd := execCommand(svc.Runtime, "restart", svc.Name)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return runtimeError(err, svc.Runtime, "Failed to restart capsule")
+	}
+	svc.StartedAt = timeNowFn()
+	_ = store.Put(svc)
+	fmt.Printf("\x1b[32m🔄 Restarted: %s\x1b[0m\n", svc.Name)
+	return nil
+}
+
+// Logs prints (or follows with -f) the project's capsule container output
+func Logs(args []string) error {
+	logArgs := []string{"logs"}
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; a {
+		case "-f", "--follow":
+			logArgs = append(logArgs, "-f")
+		case "-n", "--tail":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s requires a line count", a)
+			}
+			logArgs = append(logArgs, "--tail", args[i+1])
+			i++
+		default:
+			fmt.Println("Usage: mitl logs [-f] [--tail N]")
+			return fmt.Errorf("unknown logs argument: %s", a)
+		}
+	}
+	store := service.NewStore()
+	svc, err := currentService(store)
+	if err != nil {
+		return err
+	}
+	logArgs = append(logArgs, svc.Name)
+	cmd := execCommand(svc.Runtime, logArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return runtimeError(err, svc.Runtime, "Failed to read capsule logs")
+	}
+	return nil
+}
+
+// Ps lists capsule containers started with `mitl up` across all projects
+func Ps(args []string) error {
+	services := service.NewStore().List()
+	if len(services) == 0 {
+		fmt.Println("No capsules running. Start one with: mitl up")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPROJECT\tSTATUS\tPORTS\tSTARTED")
+	for _, svc := range services {
+		status := containerStatus(svc.Runtime, svc.Name)
+		if status == "" {
+			status = "missing"
+		}
+		started := "-"
+		if !svc.StartedAt.IsZero() {
+			started = formatSince(timeNowFn().Sub(svc.StartedAt)) + " ago"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", svc.Name, svc.Project, status, orDash(strings.Join(svc.Ports, ",")), started)
+	}
+	return w.Flush()
+}
+
+// serviceRoot returns the project root for `mitl up`: the enclosing project
+// that already has a service, or the current directory.
+func serviceRoot(store *service.Store) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	if svc, ok := store.FindForDir(cwd); ok {
+		return svc.Project, nil
+	}
+	return cwd, nil
+}
+
+// currentService finds the service for the current directory or any parent
+func currentService(store *service.Store) (service.Service, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return service.Service{}, err
+	}
+	svc, ok := store.FindForDir(cwd)
+	if !ok {
+		return service.Service{}, e.New(e.ErrServiceNotFound, "No running capsule for this project").
+			WithContext("project", cwd)
+	}
+	return svc, nil
+}
+
+// containerStatus returns the runtime's state for a container (running,
+// exited, ...) or "" when the container does not exist.
+func containerStatus(runtime, name string) string {
+	out, err := execCommand(runtime, "inspect", "--format", "{{.State.Status}}", name).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// defaultServeCommand picks a dev server for projects whose image CMD is not
+// directly useful on its own (php-fpm needs a web server in front of it).
+// Other project types keep the capsule's default command.
+func defaultServeCommand(pd *detector.ProjectDetector) []string {
+	switch {
+	case pd.Type == detector.TypePHPLaravel:
+		return []string{"php", "artisan", "serve", "--host=0.0.0.0", "--port=8000"}
+	case strings.HasPrefix(string(pd.Type), "php"):
+		docroot := "."
+		if info, err := os.Stat(filepath.Join(pd.Root, "public")); err == nil && info.IsDir() {
+			docroot = "public"
+		}
+		return []string{"php", "-S", "0.0.0.0:8000", "-t", docroot}
+	}
+	return nil
+}
+
+// formatSince renders a duration in the largest whole unit
+func formatSince(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
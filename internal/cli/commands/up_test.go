@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/ports"
+	"mitl/internal/service"
+
+	e "mitl/pkg/errors"
+)
+
+// chdir switches the working directory for the duration of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	old, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(old) })
+}
+
+func TestUp_LifecycleFromSubdirectory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MITL_RUN_CLI", "/bin/echo")
+	project, _ := filepath.EvalSymlinks(t.TempDir())
+	os.WriteFile(filepath.Join(project, "artisan"), []byte("<?php"), 0o644)
+	os.WriteFile(filepath.Join(project, "composer.json"), []byte(`{"require":{"laravel/framework":"^11.0"}}`), 0o644)
+	sub := filepath.Join(project, "app", "Http")
+	os.MkdirAll(sub, 0o755)
+
+	origAvail := ports.Available
+	ports.Available = func(string, int) bool { return true }
+	defer func() { ports.Available = origAvail }()
+
+	var calls [][]string
+	running := false
+	old := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		calls = append(calls, args)
+		if len(args) > 0 && args[0] == "inspect" {
+			if running {
+				return exec.Command("sh", "-c", "echo running")
+			}
+			return exec.Command("sh", "-c", "exit 1")
+		}
+		return exec.Command("sh", "-c", "true")
+	}
+	defer func() { execCommand = old }()
+
+	chdir(t, project)
+	if err := Up([]string{"-p", "8000:8000"}); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	var runArgs string
+	for _, c := range calls {
+		if len(c) > 0 && c[0] == "run" {
+			runArgs = strings.Join(c, " ")
+		}
+	}
+	name := service.ContainerName(project)
+	for _, want := range []string{"-d --name " + name, "-p 8000:8000", "php artisan serve --host=0.0.0.0"} {
+		if !strings.Contains(runArgs, want) {
+			t.Fatalf("expected %q in run args: %s", want, runArgs)
+		}
+	}
+	running = true
+
+	// Second up is a no-op while the container is running
+	calls = nil
+	if err := Up(nil); err != nil {
+		t.Fatalf("second up: %v", err)
+	}
+	for _, c := range calls {
+		if c[0] == "run" {
+			t.Fatalf("expected no new container while running")
+		}
+	}
+
+	// logs/restart/down resolve the project from a subdirectory
+	chdir(t, sub)
+	calls = nil
+	if err := Logs([]string{"-f"}); err != nil {
+		t.Fatalf("logs: %v", err)
+	}
+	if got := strings.Join(calls[len(calls)-1], " "); got != "logs -f "+name {
+		t.Fatalf("unexpected logs args: %s", got)
+	}
+	if err := Restart(nil); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	out := captureOut(t, func() {
+		if err := Ps(nil); err != nil {
+			t.Fatalf("ps: %v", err)
+		}
+	})
+	if !strings.Contains(out, name) || !strings.Contains(out, "running") || !strings.Contains(out, "8000:8000") {
+		t.Fatalf("unexpected ps output:\n%s", out)
+	}
+	if err := Down(nil); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if _, ok := service.NewStore().Get(project); ok {
+		t.Fatalf("expected service state removed after down")
+	}
+}
+
+func TestLogs_NoService(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	chdir(t, t.TempDir())
+	err := Logs(nil)
+	me, ok := err.(*e.MitlError)
+	if !ok || me.Code != e.ErrServiceNotFound {
+		t.Fatalf("expected SERVICE_NOT_FOUND, got %v", err)
+	}
+}
+
+func TestUp_RequiresHydratedCapsule(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// true prints no image IDs: the capsule is missing
+	t.Setenv("MITL_RUN_CLI", "/bin/true")
+	chdir(t, t.TempDir())
+
+	var verbs []string
+	old := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		verbs = append(verbs, args[0])
+		return exec.Command("sh", "-c", "true")
+	}
+	defer func() { execCommand = old }()
+
+	err := Up(nil)
+	me, ok := err.(*e.MitlError)
+	if !ok || !strings.Contains(me.Suggestion, "mitl hydrate") {
+		t.Fatalf("expected a hydrate suggestion, got %v", err)
+	}
+	for _, v := range verbs {
+		if v == "run" {
+			t.Fatalf("up must not start a missing capsule: %v", verbs)
+		}
+	}
+}
+
+func TestUp_UnknownFlag(t *testing.T) {
+	if err := Up([]string{"--bogus"}); err == nil {
+		t.Fatalf("expected error for unknown flag")
+	}
+}
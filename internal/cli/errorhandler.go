@@ -129,6 +129,8 @@ func (h *ErrorHandler) getErrorIcon(code e.ErrorCode) string {
 		e.ErrPermissionDenied:   "🚫",
 		e.ErrNetworkTimeout:     "🌐",
 		e.ErrInvalidConfig:      "⚙️",
+		e.ErrInvalidManifest:    "⚙️",
+		e.ErrServiceNotFound:    "💤",
 		e.ErrUnknown:            "❓",
 	}
 	if ic, ok := icons[code]; ok {
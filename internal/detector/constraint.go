@@ -0,0 +1,84 @@
+package detector
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionClausePattern splits a constraint into operator/version pairs;
+// clauses may be comma (PEP 440, Composer) or space (Poetry, Composer)
+// separated
+var versionClausePattern = regexp.MustCompile(`(===|==|!=|~=|>=|<=|>|<|\^|~)?\s*(\d+(?:\.\d+)*(?:\.\*)?|\*)`)
+
+// versionClause checks a major.minor line against one operator/version
+// pair. "~" follows Poetry ("~3.10" locks the minor); Composer's tilde has
+// PEP 440 "~=" semantics and is mapped to it by the caller.
+func versionClause(version, op, target string) bool {
+	if target == "*" {
+		return true
+	}
+	have := minorVersion(version)
+	want := minorVersion(target)
+	cmp := compareMinor(have, want)
+	patch := strings.Count(target, ".") >= 2 && !strings.HasSuffix(target, ".*")
+	switch op {
+	case ">=":
+		return cmp >= 0
+	case ">":
+		// ">3.10.1" still admits later 3.10 patches, which the 3.10 image has
+		if patch {
+			return cmp >= 0
+		}
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case "<":
+		if patch && !strings.HasSuffix(target, ".0") {
+			return cmp <= 0
+		}
+		return cmp < 0
+	case "!=":
+		return patch || cmp != 0
+	case "^":
+		return cmp >= 0 && have[0] == want[0]
+	case "~=":
+		if strings.Count(target, ".") <= 1 {
+			return cmp >= 0 && have[0] == want[0]
+		}
+		return cmp == 0
+	case "~":
+		if !strings.Contains(target, ".") {
+			return have[0] == want[0]
+		}
+		return cmp == 0
+	case "", "==", "===":
+		if !strings.Contains(target, ".") {
+			return have[0] == want[0]
+		}
+		return cmp == 0
+	}
+	return false
+}
+
+// minorVersion parses "3.10.2" or "3.10.*" into {3, 10}
+func minorVersion(v string) [2]int {
+	var out [2]int
+	parts := strings.Split(strings.TrimSuffix(v, ".*"), ".")
+	for i := 0; i < 2 && i < len(parts); i++ {
+		out[i], _ = strconv.Atoi(parts[i])
+	}
+	return out
+}
+
+func compareMinor(a, b [2]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
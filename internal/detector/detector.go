@@ -18,6 +18,7 @@ type ProjectDetector struct {
 	Framework    string
 	Version      string
 	Dependencies Dependencies
+	Workspace    *Workspace // monorepo members declared in Root, if any
 	Metadata     map[string]interface{}
 }
 
@@ -33,14 +34,40 @@ const (
 	TypeNodeGeneric   ProjectType = "node"
 	TypePythonDjango  ProjectType = "python-django"
 	TypePythonFlask   ProjectType = "python-flask"
+	TypePythonFastAPI ProjectType = "python-fastapi"
 	TypePythonGeneric ProjectType = "python"
 	TypeGoModule      ProjectType = "go"
 	TypeRubyRails     ProjectType = "ruby-rails"
 	TypeRubyGeneric   ProjectType = "ruby"
+	TypeRustCargo     ProjectType = "rust"
+	TypeJavaMaven     ProjectType = "java-maven"
+	TypeJavaGradle    ProjectType = "java-gradle"
 	TypeStatic        ProjectType = "static"
 	TypeUnknown       ProjectType = "unknown"
 )
 
+// KnownTypes lists every project type the detector can report
+var KnownTypes = []ProjectType{
+	TypePHPLaravel, TypePHPSymfony, TypePHPGeneric,
+	TypeNodeNext, TypeNodeNuxt, TypeNodeGeneric,
+	TypePythonDjango, TypePythonFlask, TypePythonFastAPI, TypePythonGeneric,
+	TypeGoModule,
+	TypeRubyRails, TypeRubyGeneric,
+	TypeRustCargo,
+	TypeJavaMaven, TypeJavaGradle,
+	TypeStatic, TypeUnknown,
+}
+
+// IsKnownType reports whether t is one of KnownTypes
+func IsKnownType(t ProjectType) bool {
+	for _, k := range KnownTypes {
+		if k == t {
+			return true
+		}
+	}
+	return false
+}
+
 // Language represents a programming language with version
 type Language struct {
 	Name    string `json:"name"`
@@ -53,12 +80,17 @@ type Dependencies struct {
 	PHP    PHPDependencies    `json:"php,omitempty"`
 	Node   NodeDependencies   `json:"node,omitempty"`
 	Python PythonDependencies `json:"python,omitempty"`
+	Go     GoDependencies     `json:"go,omitempty"`
+	Ruby   RubyDependencies   `json:"ruby,omitempty"`
+	Rust   RustDependencies   `json:"rust,omitempty"`
+	Java   JavaDependencies   `json:"java,omitempty"`
 	System []string           `json:"system,omitempty"` // Alpine packages
 }
 
 // PHPDependencies for PHP projects
 type PHPDependencies struct {
-	Version     string            `json:"version"` // 8.3, 8.2, etc.
+	Version     string            `json:"version"`          // 8.3, 8.2, etc.
+	Source      string            `json:"source,omitempty"` // where Version came from
 	Extensions  []string          `json:"extensions"`
 	Composer    bool              `json:"composer"`
 	ComposerVer string            `json:"composer_version"`
@@ -67,19 +99,30 @@ type PHPDependencies struct {
 
 // NodeDependencies for Node projects
 type NodeDependencies struct {
-	Version        string   `json:"version"`
-	PackageManager string   `json:"package_manager"` // npm, yarn, pnpm
-	GlobalPackages []string `json:"global_packages"`
-	BuildTools     bool     `json:"build_tools"`
+	Version        string               `json:"version"`
+	Source         string               `json:"source,omitempty"`                  // where Version came from
+	PackageManager string               `json:"package_manager"`                   // npm, yarn, pnpm, bun
+	ManagerVersion string               `json:"package_manager_version,omitempty"` // from package.json "packageManager"
+	YarnBerry      bool                 `json:"yarn_berry,omitempty"`              // Yarn 2+ (.yarnrc.yml)
+	YarnPnP        bool                 `json:"yarn_pnp,omitempty"`                // Berry with Plug'n'Play linker
+	Policy         PackageManagerPolicy `json:"package_manager_policy,omitempty"`
+	GlobalPackages []string             `json:"global_packages"`
+	BuildTools     bool                 `json:"build_tools"`
 }
 
 // PythonDependencies for Python projects
 type PythonDependencies struct {
-	Version    string   `json:"version"`
-	UsesPoetry bool     `json:"uses_poetry"`
-	UsesPipenv bool     `json:"uses_pipenv"`
-	UsesVenv   bool     `json:"uses_venv"`
-	SystemDeps []string `json:"system_deps"`
+	Version     string   `json:"version"`
+	Source      string   `json:"source,omitempty"`   // where Version came from
+	Installer   string   `json:"installer"`          // pip, poetry, pipenv, uv, pdm
+	Lockfile    string   `json:"lockfile,omitempty"` // lockfile the installer was picked from
+	UsesPoetry  bool     `json:"uses_poetry"`
+	UsesPipenv  bool     `json:"uses_pipenv"`
+	UsesVenv    bool     `json:"uses_venv"`
+	SystemDeps  []string `json:"system_deps"`            // Alpine -dev packages for native builds
+	RuntimeDeps []string `json:"runtime_deps,omitempty"` // Alpine libraries loaded at runtime
+	Servers     []string `json:"servers,omitempty"`      // gunicorn/uvicorn when declared
+	AppModule   string   `json:"app_module,omitempty"`   // "module:app" for the WSGI/ASGI server
 }
 
 // NewProjectDetector creates a detector for the current directory
@@ -100,6 +143,7 @@ func (pd *ProjectDetector) Detect() error {
 	pd.detectLanguages()
 	pd.analyzeDependencies()
 	pd.detectFrameworkRequirements()
+	pd.Workspace = DiscoverWorkspace(pd.Root)
 	return nil
 }
 
@@ -108,6 +152,9 @@ func (pd *ProjectDetector) DetectPHPExtensions(packages map[string]interface{})
 	return pd.detectPHPExtensions(packages)
 }
 
+// AnalyzeDependencies re-runs dependency analysis, e.g. after Type was overridden
+func (pd *ProjectDetector) AnalyzeDependencies() { pd.analyzeDependencies() }
+
 // ValidatePyProject checks pyproject content for validity
 func (pd *ProjectDetector) ValidatePyProject(path string) bool { return pd.validatePyProject(path) }
 
@@ -126,6 +173,18 @@ func (pd *ProjectDetector) detectLanguages() {
 	if strings.HasPrefix(string(pd.Type), "python") || fileExists(filepath.Join(pd.Root, "requirements.txt")) || fileExists(filepath.Join(pd.Root, "pyproject.toml")) {
 		langs = append(langs, Language{Name: "python", Primary: strings.HasPrefix(string(pd.Type), "python")})
 	}
+	if strings.HasPrefix(string(pd.Type), "ruby") {
+		langs = append(langs, Language{Name: "ruby", Primary: true})
+	}
+	if pd.Type == TypeGoModule {
+		langs = append(langs, Language{Name: "go", Primary: true})
+	}
+	if pd.Type == TypeRustCargo {
+		langs = append(langs, Language{Name: "rust", Primary: true})
+	}
+	if strings.HasPrefix(string(pd.Type), "java") {
+		langs = append(langs, Language{Name: "java", Primary: true})
+	}
 	pd.Languages = langs
 }
 
@@ -151,6 +210,7 @@ func (pd *ProjectDetector) detectProjectType() {
 		{"pyproject.toml", TypePythonGeneric, pd.validatePyProject},
 		{"manage.py", TypePythonDjango, nil},
 		{"app.py", TypePythonFlask, pd.checkFlaskImports},
+		{"Pipfile", TypePythonGeneric, nil},
 
 		// Go
 		{"go.mod", TypeGoModule, nil},
@@ -158,6 +218,16 @@ func (pd *ProjectDetector) detectProjectType() {
 		// Ruby
 		{"Gemfile", TypeRubyGeneric, nil},
 		{"config.ru", TypeRubyRails, nil},
+
+		// Rust
+		{"Cargo.toml", TypeRustCargo, pd.validateCargoToml},
+
+		// JVM
+		{"pom.xml", TypeJavaMaven, nil},
+		{"build.gradle.kts", TypeJavaGradle, nil},
+		{"build.gradle", TypeJavaGradle, nil},
+		{"settings.gradle.kts", TypeJavaGradle, nil},
+		{"settings.gradle", TypeJavaGradle, nil},
 	}
 
 	for _, check := range checks {
@@ -194,7 +264,7 @@ func (pd *ProjectDetector) validateComposerJSON(path string) bool {
 	}
 	pd.Metadata["composer"] = composer
 
-	// Laravel detection and version
+	// Laravel and Symfony detection and version
 	if require, ok := composer["require"].(map[string]interface{}); ok {
 		if v, has := require["laravel/framework"]; has {
 			pd.Type = TypePHPLaravel
@@ -202,6 +272,12 @@ func (pd *ProjectDetector) validateComposerJSON(path string) bool {
 			if s, ok := v.(string); ok {
 				pd.Version = extractVersion(s)
 			}
+		} else if v, has := require["symfony/framework-bundle"]; has {
+			pd.Type = TypePHPSymfony
+			pd.Framework = "Symfony"
+			if s, ok := v.(string); ok {
+				pd.Version = extractVersion(s)
+			}
 		}
 	}
 	return true
@@ -269,8 +345,10 @@ func (pd *ProjectDetector) refineProjectType() {
 	if pd.Type == TypePythonGeneric {
 		if fileExists(filepath.Join(pd.Root, "manage.py")) {
 			pd.Type = TypePythonDjango
-		} else if fileExists(filepath.Join(pd.Root, "app.py")) {
-			// If app.py references Flask, validator sets; otherwise leave generic
+		} else if isFastAPIApp(pd.Root) {
+			pd.Type = TypePythonFastAPI
+		} else if findPythonApp(pd.Root, flaskApp) != "" {
+			pd.Type = TypePythonFlask
 		}
 	}
 	// Node: refine generic to framework by config files
@@ -281,6 +359,10 @@ func (pd *ProjectDetector) refineProjectType() {
 			pd.Type = TypeNodeNuxt
 		}
 	}
+	// Ruby: Rails apps depend on rails/railties or carry config/application.rb
+	if pd.Type == TypeRubyGeneric && isRailsApp(pd.Root, gemfileGems(pd.Root)) {
+		pd.Type = TypeRubyRails
+	}
 }
 
 // analyzeDependencies performs deep analysis of project dependencies
@@ -292,6 +374,14 @@ func (pd *ProjectDetector) analyzeDependencies() {
 		pd.analyzeNodeDependencies()
 	case strings.HasPrefix(string(pd.Type), "python"):
 		pd.analyzePythonDependencies()
+	case strings.HasPrefix(string(pd.Type), "ruby"):
+		pd.analyzeRubyDependencies()
+	case pd.Type == TypeGoModule:
+		pd.analyzeGoDependencies()
+	case pd.Type == TypeRustCargo:
+		pd.analyzeRustDependencies()
+	case strings.HasPrefix(string(pd.Type), "java"):
+		pd.analyzeJavaDependencies()
 	}
 
 	// Mixed stacks: analyze secondary language deps when present
@@ -305,67 +395,38 @@ func (pd *ProjectDetector) analyzeDependencies() {
 	pd.detectSecondaryLanguages()
 }
 
-// analyzePHPDependencies extracts PHP requirements
-func (pd *ProjectDetector) analyzePHPDependencies() {
-	deps := PHPDependencies{
-		Version:    "8.3",
-		Composer:   true,
-		Extensions: []string{},
-		IniSettings: map[string]string{
-			"memory_limit":        "256M",
-			"max_execution_time":  "300",
-			"post_max_size":       "100M",
-			"upload_max_filesize": "100M",
-		},
-	}
-	if composer, ok := pd.Metadata["composer"].(map[string]interface{}); ok {
-		if require, ok := composer["require"].(map[string]interface{}); ok {
-			if phpReq, ok := require["php"].(string); ok {
-				deps.Version = ExtractPHPVersion(phpReq)
-			}
-			deps.Extensions = pd.detectPHPExtensions(require)
-		}
-	}
-	// Add Laravel defaults
-	if pd.Type == TypePHPLaravel {
-		laravelExts := []string{"bcmath", "ctype", "curl", "dom", "fileinfo", "json", "mbstring", "openssl", "pdo", "pdo_mysql", "tokenizer", "xml", "zip"}
-		deps.Extensions = UniqueStrings(append(deps.Extensions, laravelExts...))
-	}
-	// Scan source for usages (best-effort)
-	deps.Extensions = UniqueStrings(append(deps.Extensions, pd.scanPHPFiles()...))
-	pd.Dependencies.PHP = deps
-}
-
 // analyzeNodeDependencies extracts Node requirements
 func (pd *ProjectDetector) analyzeNodeDependencies() {
-	nd := NodeDependencies{Version: "20", PackageManager: "npm"}
-	if pkg, ok := pd.Metadata["package.json"].(map[string]interface{}); ok {
+	nd := NodeDependencies{Version: "20", Source: "default", PackageManager: "npm"}
+	pkg, ok := pd.Metadata["package.json"].(map[string]interface{})
+	if !ok {
+		// Mixed stacks (e.g. Laravel) never ran Node detection
+		if data, err := os.ReadFile(filepath.Join(pd.Root, "package.json")); err == nil && json.Unmarshal(data, &pkg) == nil {
+			ok = true
+		}
+	}
+	pd.detectPackageManager(&nd, pkg)
+	if ok {
 		if engines, ok := pkg["engines"].(map[string]interface{}); ok {
 			if v, ok := engines["node"].(string); ok {
-				nd.Version = ExtractNodeVersion(v)
+				nd.Version, nd.Source = ExtractNodeVersion(v), "engines.node "+v
 			}
 		}
-		// PM detection via lockfiles
-		if fileExists(filepath.Join(pd.Root, "pnpm-lock.yaml")) {
-			nd.PackageManager = "pnpm"
-		} else if fileExists(filepath.Join(pd.Root, "yarn.lock")) {
-			nd.PackageManager = "yarn"
-		} else {
-			nd.PackageManager = "npm"
-		}
 		// Build script present?
 		if scripts, ok := pkg["scripts"].(map[string]interface{}); ok {
 			_, nd.BuildTools = scripts["build"]
 		}
 	}
+	// Version manager and nvm files beat the engines range
+	if v, src := versionFile(pd.Root, "node", ".nvmrc", ".node-version"); v != "" {
+		nd.Version, nd.Source = v, src
+	}
+	if v, src := managedVersion(pd.Root, "node"); v != "" {
+		nd.Version, nd.Source = v, src
+	}
 	pd.Dependencies.Node = nd
 }
 
-// analyzePythonDependencies extracts Python requirements
-func (pd *ProjectDetector) analyzePythonDependencies() {
-	pd.Dependencies.Python = PythonDependencies{Version: "3.11"}
-}
-
 // detectSecondaryLanguages detects mixed stacks (e.g., PHP + Node)
 func (pd *ProjectDetector) detectSecondaryLanguages() {
 	// Simple heuristic: if package.json present, note Node
@@ -497,18 +558,6 @@ func extractVersion(req string) string {
 	return req
 }
 
-func ExtractPHPVersion(req string) string {
-	// Simplify to major.minor if present
-	v := extractVersion(req)
-	// trim constraints like >=, <=, ^, ~
-	v = strings.TrimLeft(v, "=<>^~ ")
-	// Default reasonable fallback
-	if v == "" {
-		return "8.3"
-	}
-	return v
-}
-
 func ExtractNodeVersion(req string) string {
 	v := extractVersion(req)
 	v = strings.TrimSpace(v)
@@ -0,0 +1,193 @@
+package detector
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GoDependencies for Go modules
+type GoDependencies struct {
+	Version    string   `json:"version"`               // Go for the golang image tag, e.g. "1.22" or "1.22.3"
+	Source     string   `json:"source,omitempty"`      // where Version came from
+	Module     string   `json:"module,omitempty"`      // module path from go.mod
+	Mains      []string `json:"mains,omitempty"`       // main packages: "." and cmd/* directories
+	Target     string   `json:"target,omitempty"`      // main package to build; "" builds ./... without a binary
+	Cgo        bool     `json:"cgo,omitempty"`         // a package imports "C" or a module needs cgo
+	SystemDeps []string `json:"system_deps,omitempty"` // Alpine packages cgo modules link against
+}
+
+var (
+	goModToolchain = regexp.MustCompile(`(?m)^toolchain\s+go(\d+\.\d+(?:\.\d+)?)`)
+	goModDirective = regexp.MustCompile(`(?m)^go\s+(\d+\.\d+(?:\.\d+)?)`)
+)
+
+// goCgoModules maps modules that only build with cgo to the Alpine
+// packages they link against (go-sqlite3 bundles SQLite, so needs none)
+var goCgoModules = map[string][]string{
+	"github.com/mattn/go-sqlite3":      nil,
+	"gopkg.in/gographics/imagick.v2":   {"imagemagick-dev"},
+	"gopkg.in/gographics/imagick.v3":   {"imagemagick-dev"},
+	"github.com/h2non/bimg":            {"vips-dev"},
+	"github.com/davidbyttow/govips/v2": {"vips-dev"},
+}
+
+// analyzeGoDependencies resolves the Go version, the main packages and cgo
+// needs. The toolchain line is what the go command switches to, so it beats
+// mise/asdf; the go directive is only a minimum and comes after them.
+func (pd *ProjectDetector) analyzeGoDependencies() {
+	gd := GoDependencies{Version: "1", Source: "default"}
+	gomod := readFileString(filepath.Join(pd.Root, "go.mod"))
+	if m := goModToolchain.FindStringSubmatch(gomod); m != nil {
+		gd.Version, gd.Source = m[1], "go.mod toolchain"
+	} else if v, src := managedVersion(pd.Root, "go"); v != "" {
+		gd.Version, gd.Source = v, src
+	} else if m := goModDirective.FindStringSubmatch(gomod); m != nil {
+		gd.Version, gd.Source = m[1], "go.mod go directive"
+	}
+	if m := goModModule.FindStringSubmatch(gomod); m != nil {
+		gd.Module = m[1]
+	}
+	for _, m := range goModRequire.FindAllStringSubmatch(gomod, -1) {
+		if deps, ok := goCgoModules[m[1]]; ok {
+			gd.Cgo = true
+			gd.SystemDeps = append(gd.SystemDeps, deps...)
+		}
+	}
+	gd.SystemDeps = UniqueStrings(gd.SystemDeps)
+
+	mains, cgo := scanGoPackages(pd.Root)
+	gd.Mains, gd.Cgo = mains, gd.Cgo || cgo
+	gd.Target = defaultGoTarget(gd.Module, mains)
+	pd.Dependencies.Go = gd
+}
+
+// scanGoPackages parses the imports of the module's non-test files. It
+// returns the main packages at the root and under cmd/, and whether any
+// package imports "C". vendor/, testdata/, hidden directories and nested
+// modules are skipped, as the go command does.
+func scanGoPackages(root string) (mains []string, cgo bool) {
+	found := map[string]bool{}
+	fset := token.NewFileSet()
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			if name == "vendor" || name == "testdata" || name == "node_modules" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+				fileExists(filepath.Join(path, "go.mod")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		f, perr := parser.ParseFile(fset, path, nil, parser.ImportsOnly|parser.ParseComments)
+		if perr != nil || !goBuildsOnLinux(name, f) {
+			return nil
+		}
+		for _, imp := range f.Imports {
+			if p, _ := strconv.Unquote(imp.Path.Value); p == "C" {
+				cgo = true
+			}
+		}
+		if f.Name.Name == "main" {
+			rel, _ := filepath.Rel(root, filepath.Dir(path))
+			rel = filepath.ToSlash(rel)
+			if rel == "." || strings.HasPrefix(rel, "cmd/") {
+				found[rel] = true
+			}
+		}
+		return nil
+	})
+	for dir := range found {
+		mains = append(mains, dir)
+	}
+	sort.Strings(mains)
+	return mains, cgo
+}
+
+// goLinuxTags are the build tags satisfied when the capsule builds: Linux on
+// either architecture mitl builds for, with cgo available
+var goLinuxTags = map[string]bool{"linux": true, "unix": true, "amd64": true, "arm64": true, "cgo": true, "gc": true}
+
+// goKnownOS and goKnownArch are the GOOS and GOARCH values a filename
+// suffix such as _windows.go or _darwin_arm64.go restricts a file to
+var (
+	goKnownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+		"illumos": true, "ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true,
+		"openbsd": true, "plan9": true, "solaris": true, "wasip1": true, "windows": true, "zos": true,
+	}
+	goKnownArch = map[string]bool{
+		"386": true, "amd64": true, "arm": true, "arm64": true, "loong64": true, "mips": true,
+		"mipsle": true, "mips64": true, "mips64le": true, "ppc64": true, "ppc64le": true,
+		"riscv64": true, "s390x": true, "wasm": true,
+	}
+)
+
+// goBuildsOnLinux reports whether the go command includes a file when
+// building for Linux: its _GOOS/_GOARCH filename suffix and its //go:build
+// (or // +build) lines must allow it. "//go:build ignore" go:generate
+// helpers and _windows.go cgo shims are left out this way.
+func goBuildsOnLinux(name string, f *ast.File) bool {
+	parts := strings.Split(strings.TrimSuffix(name, ".go"), "_")
+	if n := len(parts); n > 1 {
+		last := parts[n-1]
+		switch {
+		case goKnownOS[last]:
+			if !goLinuxTags[last] {
+				return false
+			}
+		case goKnownArch[last]:
+			if !goLinuxTags[last] || (n > 2 && goKnownOS[parts[n-2]] && !goLinuxTags[parts[n-2]]) {
+				return false
+			}
+		}
+	}
+	satisfied := func(tag string) bool { return goLinuxTags[tag] || strings.HasPrefix(tag, "go1.") }
+	for _, c := range f.Comments {
+		if c.Pos() > f.Package {
+			break
+		}
+		for _, line := range c.List {
+			if !constraint.IsGoBuild(line.Text) && !constraint.IsPlusBuild(line.Text) {
+				continue
+			}
+			if expr, err := constraint.Parse(line.Text); err == nil && !expr.Eval(satisfied) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// defaultGoTarget picks the main package to build: the module root, then
+// cmd/<module name>, then the first cmd/ package
+func defaultGoTarget(module string, mains []string) string {
+	if len(mains) == 0 {
+		return ""
+	}
+	if ContainsString(mains, ".") {
+		return "."
+	}
+	if module != "" {
+		if dir := "cmd/" + module[strings.LastIndex(module, "/")+1:]; ContainsString(mains, dir) {
+			return dir
+		}
+	}
+	return mains[0]
+}
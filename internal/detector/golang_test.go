@@ -0,0 +1,75 @@
+package detector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeGo_MainsAndCgo(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"go.mod":                 "module example.com/shop\n\ngo 1.22\n\nrequire (\n\tgithub.com/mattn/go-sqlite3 v1.14.22\n)\n",
+		"gen.go":                 "//go:build ignore\n\npackage main\n\nfunc main() {}\n",
+		"shop.go":                "package shop\n",
+		"cmd/admin/main.go":      "package main\n\nfunc main() {}\n",
+		"cmd/shop/main.go":       "package main\n\nfunc main() {}\n",
+		"cmd/shop/main_test.go":  "package main_test\n",
+		"internal/tools/main.go": "package main\n\nfunc main() {}\n",
+		"examples/go.mod":        "module example.com/examples\n",
+		"examples/cgo/main.go":   "package main\n\nimport \"C\"\n\nfunc main() {}\n",
+		"vendor/x/y.go":          "package y\n\nimport \"C\"\n",
+	})
+	pd := NewProjectDetector(dir)
+	pd.Type = TypeGoModule
+	pd.analyzeGoDependencies()
+	gd := pd.Dependencies.Go
+	if gd.Module != "example.com/shop" || gd.Version != "1.22" {
+		t.Fatalf("unexpected module/version %+v", gd)
+	}
+	if !reflect.DeepEqual(gd.Mains, []string{"cmd/admin", "cmd/shop"}) {
+		t.Fatalf("unexpected main packages %v", gd.Mains)
+	}
+	if gd.Target != "cmd/shop" {
+		t.Fatalf("expected cmd/<module name> as target, got %q", gd.Target)
+	}
+	if !gd.Cgo {
+		t.Fatal("go-sqlite3 needs cgo")
+	}
+
+	dir = t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"go.mod":  "module example.com/tool\n\ngo 1.22\n",
+		"main.go": "package main\n\n// #include <stdio.h>\nimport \"C\"\n\nfunc main() {}\n",
+	})
+	pd = NewProjectDetector(dir)
+	pd.analyzeGoDependencies()
+	if gd := pd.Dependencies.Go; gd.Target != "." || !gd.Cgo {
+		t.Fatalf("expected the root main package with cgo, got %+v", gd)
+	}
+
+	// cgo only in files the Linux build leaves out
+	dir = t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"go.mod":               "module example.com/tray\n\ngo 1.22\n",
+		"main.go":              "package main\n\nfunc main() {}\n",
+		"tray_windows.go":      "package main\n\nimport \"C\"\n",
+		"tray_darwin_arm64.go": "package main\n\nimport \"C\"\n",
+		"tray_mac.go":          "//go:build darwin && cgo\n\npackage main\n\nimport \"C\"\n",
+		"tray_old.go":          "// +build windows\n\npackage main\n\nimport \"C\"\n",
+		"tray_linux_amd64.go":  "package main\n",
+		"tray_unix.go":         "//go:build unix && !darwin\n\npackage main\n",
+	})
+	pd = NewProjectDetector(dir)
+	pd.analyzeGoDependencies()
+	if gd := pd.Dependencies.Go; gd.Target != "." || gd.Cgo {
+		t.Fatalf("expected no cgo for the Linux build, got %+v", gd)
+	}
+	writeTree(t, dir, map[string]string{
+		"tray_linux.go": "//go:build linux && (amd64 || arm64)\n\npackage main\n\nimport \"C\"\n",
+	})
+	pd = NewProjectDetector(dir)
+	pd.analyzeGoDependencies()
+	if !pd.Dependencies.Go.Cgo {
+		t.Fatal("a Linux cgo file needs cgo")
+	}
+}
@@ -0,0 +1,188 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// JavaDependencies for Maven and Gradle projects
+type JavaDependencies struct {
+	Version     string `json:"version"`                // JDK feature release for the image tag, e.g. "21"
+	Source      string `json:"source,omitempty"`       // where Version came from
+	BuildTool   string `json:"build_tool"`             // maven or gradle
+	Wrapper     bool   `json:"wrapper,omitempty"`      // mvnw/gradlew checked in
+	Kotlin      bool   `json:"kotlin,omitempty"`       // Kotlin sources or plugin
+	MultiModule bool   `json:"multi_module,omitempty"` // <modules> or settings include
+	Locked      bool   `json:"locked,omitempty"`       // gradle.lockfile present
+}
+
+// Build tools reported in JavaDependencies.BuildTool
+const (
+	BuildToolMaven  = "maven"
+	BuildToolGradle = "gradle"
+)
+
+// DefaultJDK is used when neither the build nor .java-version names one
+const DefaultJDK = "21"
+
+// JDK version declarations, most specific first. Gradle toolchains win over
+// source compatibility; Maven's release flag wins over source/target.
+var (
+	gradleJDKPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`JavaLanguageVersion\.of\(\s*["']?(\d+)`),
+		regexp.MustCompile(`jvmToolchain\(\s*(\d+)`),
+		regexp.MustCompile(`(?:sourceCompatibility|targetCompatibility)\s*=\s*(?:JavaVersion\.VERSION_)?["']?((?:1[._])?\d+)`),
+	}
+	mavenJDKPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`<maven\.compiler\.release>\s*(\d+)`),
+		regexp.MustCompile(`<release>\s*(\d+)\s*</release>`),
+		regexp.MustCompile(`<java\.version>\s*((?:1\.)?\d+)`),
+		regexp.MustCompile(`<maven\.compiler\.source>\s*((?:1\.)?\d+)`),
+	}
+	javaVersionFile = regexp.MustCompile(`(?:^|[-_])(?:1\.)?(\d+)`)
+
+	springBootMaven  = regexp.MustCompile(`(?s)<artifactId>\s*spring-boot-starter-parent\s*</artifactId>\s*<version>\s*([^<\s]+)`)
+	springBootGradle = regexp.MustCompile(`org\.springframework\.boot["']?\)?\s*version\s*["']([^"']+)`)
+	quarkusVersion   = regexp.MustCompile(`(?:<quarkus\.platform\.version>|quarkusPlatformVersion\s*=\s*|io\.quarkus["']?\)?\s*version\s*["'])([\w.\-]+)`)
+	gradleInclude    = regexp.MustCompile(`(?m)^\s*include\s*[\("']`)
+)
+
+// gradleBuildFiles returns the contents of the Gradle build and settings
+// scripts, Groovy or Kotlin DSL
+func gradleBuildFiles(root string) (build, settings string) {
+	for _, name := range []string{"build.gradle.kts", "build.gradle"} {
+		if b, err := os.ReadFile(filepath.Join(root, name)); err == nil {
+			build = string(b)
+			break
+		}
+	}
+	for _, name := range []string{"settings.gradle.kts", "settings.gradle"} {
+		if b, err := os.ReadFile(filepath.Join(root, name)); err == nil {
+			settings = string(b)
+			break
+		}
+	}
+	return build, settings
+}
+
+// analyzeJavaDependencies reads pom.xml or the Gradle scripts for the JDK,
+// wrapper, Kotlin and framework (Spring Boot, Quarkus)
+func (pd *ProjectDetector) analyzeJavaDependencies() {
+	jd := JavaDependencies{}
+	var build string
+	var patterns []*regexp.Regexp
+	if pd.Type == TypeJavaMaven {
+		jd.BuildTool = BuildToolMaven
+		b, _ := os.ReadFile(filepath.Join(pd.Root, "pom.xml"))
+		build = string(b)
+		patterns = mavenJDKPatterns
+		jd.Wrapper = fileExists(filepath.Join(pd.Root, "mvnw"))
+		jd.MultiModule = strings.Contains(build, "<modules>")
+		jd.Kotlin = strings.Contains(build, "kotlin-maven-plugin")
+	} else {
+		jd.BuildTool = BuildToolGradle
+		var settings string
+		build, settings = gradleBuildFiles(pd.Root)
+		patterns = gradleJDKPatterns
+		jd.Wrapper = fileExists(filepath.Join(pd.Root, "gradlew"))
+		jd.MultiModule = gradleInclude.MatchString(settings)
+		jd.Kotlin = strings.Contains(build, "org.jetbrains.kotlin") || strings.Contains(build, `kotlin("jvm")`)
+		jd.Locked = fileExists(filepath.Join(pd.Root, "gradle.lockfile"))
+	}
+	if fileExists(filepath.Join(pd.Root, "src", "main", "kotlin")) {
+		jd.Kotlin = true
+	}
+	if jd.Kotlin && !pd.hasLanguage("kotlin") {
+		pd.Languages = append(pd.Languages, Language{Name: "kotlin"})
+	}
+
+	for _, re := range patterns {
+		if m := re.FindStringSubmatch(build); m != nil {
+			jd.Version = normalizeJDK(m[1])
+			jd.Source = filepath.Base(pd.buildFile())
+			break
+		}
+	}
+	if jd.Version == "" {
+		if b, err := os.ReadFile(filepath.Join(pd.Root, ".java-version")); err == nil {
+			if m := javaVersionFile.FindStringSubmatch(strings.TrimSpace(string(b))); m != nil {
+				jd.Version = m[1]
+				jd.Source = ".java-version"
+			}
+		}
+	}
+	if jd.Version == "" {
+		jd.Version = DefaultJDK
+		jd.Source = "default"
+	}
+
+	switch {
+	case strings.Contains(build, "org.springframework.boot"):
+		pd.Framework = "Spring Boot"
+		if m := springBootMaven.FindStringSubmatch(build); m != nil {
+			pd.Version = m[1]
+		} else if m := springBootGradle.FindStringSubmatch(build); m != nil {
+			pd.Version = m[1]
+		}
+	case strings.Contains(build, "io.quarkus"):
+		pd.Framework = "Quarkus"
+		if m := quarkusVersion.FindStringSubmatch(build); m != nil {
+			pd.Version = m[1]
+		} else if b, err := os.ReadFile(filepath.Join(pd.Root, "gradle.properties")); err == nil {
+			if m := quarkusVersion.FindStringSubmatch(string(b)); m != nil {
+				pd.Version = m[1]
+			}
+		}
+	}
+	pd.Dependencies.Java = jd
+}
+
+// buildFile returns the path of the JVM build script that was detected
+func (pd *ProjectDetector) buildFile() string {
+	if pd.Type == TypeJavaMaven {
+		return filepath.Join(pd.Root, "pom.xml")
+	}
+	for _, name := range []string{"build.gradle.kts", "build.gradle", "settings.gradle.kts", "settings.gradle"} {
+		if p := filepath.Join(pd.Root, name); fileExists(p) {
+			return p
+		}
+	}
+	return ""
+}
+
+func (pd *ProjectDetector) hasLanguage(name string) bool {
+	for _, l := range pd.Languages {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeJDK turns legacy "1.8"/"1_8" spellings into the feature release
+func normalizeJDK(v string) string {
+	v = strings.ReplaceAll(v, "_", ".")
+	return strings.TrimPrefix(v, "1.")
+}
+
+// jvmServerPort reads server.port (Spring) or quarkus.http.port from the
+// application config under src/main/resources
+func (pd *ProjectDetector) jvmServerPort() int {
+	key := "server.port"
+	if pd.Framework == "Quarkus" {
+		key = "quarkus.http.port"
+	}
+	b, err := os.ReadFile(filepath.Join(pd.Root, "src", "main", "resources", "application.properties"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		k, v, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(k) == key {
+			return validPort(strings.TrimSpace(v))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package detector
+
+import "testing"
+
+const springPom = `<project>
+  <parent>
+    <groupId>org.springframework.boot</groupId>
+    <artifactId>spring-boot-starter-parent</artifactId>
+    <version>3.2.5</version>
+  </parent>
+  <properties>
+    <java.version>17</java.version>
+  </properties>
+</project>
+`
+
+func TestDetectJava_MavenSpringBoot(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"pom.xml":       springPom,
+		"mvnw":          "#!/bin/sh\n",
+		".java-version": "21\n",
+		"src/main/resources/application.properties": "server.port = 9090\n",
+	})
+	pd := NewProjectDetector(dir)
+	_ = pd.Detect()
+	if pd.Type != TypeJavaMaven {
+		t.Fatalf("expected java-maven, got %s", pd.Type)
+	}
+	jd := pd.Dependencies.Java
+	// the build's java.version wins over .java-version
+	if jd.Version != "17" || jd.Source != "pom.xml" || jd.BuildTool != BuildToolMaven || !jd.Wrapper {
+		t.Fatalf("unexpected java deps %+v", jd)
+	}
+	if pd.Framework != "Spring Boot" || pd.Version != "3.2.5" {
+		t.Fatalf("framework = %q %q", pd.Framework, pd.Version)
+	}
+	if ports := pd.InferPorts(); len(ports) != 1 || ports[0] != 9090 {
+		t.Fatalf("ports = %v", ports)
+	}
+}
+
+func TestDetectJava_GradleKotlinQuarkus(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"build.gradle.kts": "plugins {\n  kotlin(\"jvm\") version \"1.9.23\"\n  id(\"io.quarkus\")\n}\n" +
+			"java {\n  toolchain {\n    languageVersion = JavaLanguageVersion.of(17)\n  }\n}\n",
+		"settings.gradle.kts": "rootProject.name = \"svc\"\ninclude(\"api\", \"core\")\n",
+		"gradle.properties":   "quarkusPlatformVersion=3.8.1\n",
+		"gradle.lockfile":     "empty=\n",
+	})
+	pd := NewProjectDetector(dir)
+	_ = pd.Detect()
+	if pd.Type != TypeJavaGradle {
+		t.Fatalf("expected java-gradle, got %s", pd.Type)
+	}
+	jd := pd.Dependencies.Java
+	if jd.Version != "17" || !jd.Kotlin || !jd.MultiModule || !jd.Locked || jd.Wrapper {
+		t.Fatalf("unexpected java deps %+v", jd)
+	}
+	if pd.Framework != "Quarkus" || pd.Version != "3.8.1" {
+		t.Fatalf("framework = %q %q", pd.Framework, pd.Version)
+	}
+	if !pd.hasLanguage("kotlin") {
+		t.Fatalf("expected kotlin language, got %+v", pd.Languages)
+	}
+	if ports := pd.InferPorts(); len(ports) != 1 || ports[0] != 8080 {
+		t.Fatalf("ports = %v", ports)
+	}
+}
+
+func TestDetectJava_VersionSources(t *testing.T) {
+	cases := []struct {
+		name  string
+		files map[string]string
+		want  string
+		src   string
+	}{
+		{"legacy source compatibility", map[string]string{"build.gradle": "sourceCompatibility = JavaVersion.VERSION_1_8\n"}, "8", "build.gradle"},
+		{"jvm toolchain", map[string]string{"build.gradle.kts": "kotlin {\n  jvmToolchain(21)\n}\n"}, "21", "build.gradle.kts"},
+		{"maven release", map[string]string{"pom.xml": "<project><properties><maven.compiler.release>11</maven.compiler.release></properties></project>"}, "11", "pom.xml"},
+		{"java-version file", map[string]string{"pom.xml": "<project/>", ".java-version": "temurin-17.0.2\n"}, "17", ".java-version"},
+		{"default", map[string]string{"settings.gradle": "rootProject.name = 'x'\n"}, DefaultJDK, "default"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, c.files)
+			pd := NewProjectDetector(dir)
+			_ = pd.Detect()
+			if jd := pd.Dependencies.Java; jd.Version != c.want || jd.Source != c.src {
+				t.Fatalf("got %s from %s, want %s from %s", jd.Version, jd.Source, c.want, c.src)
+			}
+		})
+	}
+}
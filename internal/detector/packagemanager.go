@@ -0,0 +1,168 @@
+package detector
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Node package managers mitl knows how to build and run
+const (
+	PackageManagerNPM  = "npm"
+	PackageManagerYarn = "yarn"
+	PackageManagerPNPM = "pnpm"
+	PackageManagerBun  = "bun"
+)
+
+// PackageManagers lists supported Node package managers
+var PackageManagers = []string{PackageManagerNPM, PackageManagerYarn, PackageManagerPNPM, PackageManagerBun}
+
+// PackageManagerPolicy decides which package manager a Node project uses:
+// "respect" keeps whatever the project's lockfile or packageManager field
+// says, "enforce-pnpm" converts everything to pnpm, and a manager name
+// (npm, yarn, pnpm, bun) pins that manager.
+type PackageManagerPolicy string
+
+const (
+	PolicyRespect     PackageManagerPolicy = "respect"
+	PolicyEnforcePnpm PackageManagerPolicy = "enforce-pnpm"
+)
+
+// PackageManagerEnv overrides the manifest's package manager policy
+const PackageManagerEnv = "MITL_PACKAGE_MANAGER"
+
+// lockfiles maps each package manager to the lockfiles it writes
+var lockfiles = map[string][]string{
+	PackageManagerPNPM: {"pnpm-lock.yaml"},
+	PackageManagerBun:  {"bun.lock", "bun.lockb"},
+	PackageManagerYarn: {"yarn.lock"},
+	PackageManagerNPM:  {"package-lock.json", "npm-shrinkwrap.json"},
+}
+
+// Lockfiles returns the lockfile names written by a package manager
+func Lockfiles(manager string) []string {
+	return lockfiles[manager]
+}
+
+// ParsePackageManagerPolicy validates a policy string. Empty means respect.
+func ParsePackageManagerPolicy(s string) (PackageManagerPolicy, error) {
+	switch p := PackageManagerPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return PolicyRespect, nil
+	case PolicyRespect, PolicyEnforcePnpm:
+		return p, nil
+	default:
+		if ContainsString(PackageManagers, string(p)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown package manager policy %q (expected respect, enforce-pnpm, npm, yarn, pnpm or bun)", s)
+}
+
+// Resolve returns the package manager to use for a project whose own
+// manager was detected as detected
+func (p PackageManagerPolicy) Resolve(detected string) string {
+	switch p {
+	case "", PolicyRespect:
+		return detected
+	case PolicyEnforcePnpm:
+		return PackageManagerPNPM
+	}
+	return string(p)
+}
+
+// Rewrites reports whether commands for other package managers should be
+// translated to the resolved one. Respect leaves commands as typed.
+func (p PackageManagerPolicy) Rewrites() bool {
+	return p != "" && p != PolicyRespect
+}
+
+// ApplyPackageManagerPolicy records the policy and switches the Node
+// package manager accordingly. Yarn Berry details only survive when the
+// project keeps its own Yarn.
+func (pd *ProjectDetector) ApplyPackageManagerPolicy(p PackageManagerPolicy) {
+	nd := &pd.Dependencies.Node
+	nd.Policy = p
+	if nd.PackageManager == "" {
+		return // no package.json
+	}
+	resolved := p.Resolve(nd.PackageManager)
+	if resolved != nd.PackageManager {
+		nd.PackageManager = resolved
+		nd.ManagerVersion = ""
+		nd.YarnBerry = false
+		nd.YarnPnP = false
+	}
+}
+
+// HasLockfile reports whether the project has a lockfile for manager
+func (pd *ProjectDetector) HasLockfile(manager string) bool {
+	for _, f := range lockfiles[manager] {
+		if fileExists(filepath.Join(pd.Root, f)) {
+			return true
+		}
+	}
+	return false
+}
+
+// PresentLockfiles lists the Node lockfiles found in the project root
+func (pd *ProjectDetector) PresentLockfiles() []string {
+	var found []string
+	for _, pm := range PackageManagers {
+		for _, f := range lockfiles[pm] {
+			if fileExists(filepath.Join(pd.Root, f)) {
+				found = append(found, f)
+			}
+		}
+	}
+	return found
+}
+
+// detectPackageManager fills the manager from package.json's corepack
+// "packageManager" field, falling back to lockfiles and then npm
+func (pd *ProjectDetector) detectPackageManager(nd *NodeDependencies, pkg map[string]interface{}) {
+	nd.PackageManager = PackageManagerNPM
+	if field, ok := pkg["packageManager"].(string); ok {
+		name, version, _ := strings.Cut(field, "@")
+		if ContainsString(PackageManagers, name) {
+			nd.PackageManager = name
+			// strip corepack integrity hashes: yarn@4.1.0+sha512.abc
+			nd.ManagerVersion, _, _ = strings.Cut(version, "+")
+		}
+	} else {
+		for _, pm := range []string{PackageManagerPNPM, PackageManagerBun, PackageManagerYarn} {
+			if pd.HasLockfile(pm) {
+				nd.PackageManager = pm
+				break
+			}
+		}
+	}
+	if nd.PackageManager != PackageManagerYarn {
+		return
+	}
+	// Yarn 2+ always has .yarnrc.yml; classic does not read it
+	rc := filepath.Join(pd.Root, ".yarnrc.yml")
+	nd.YarnBerry = fileExists(rc) || (nd.ManagerVersion != "" && !strings.HasPrefix(nd.ManagerVersion, "1."))
+	if nd.YarnBerry {
+		nd.YarnPnP = yarnNodeLinker(rc) == "pnp"
+	}
+}
+
+// yarnNodeLinker reads nodeLinker from .yarnrc.yml; Berry defaults to pnp
+func yarnNodeLinker(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return "pnp"
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		k, v, ok := strings.Cut(sc.Text(), ":")
+		if ok && strings.TrimSpace(k) == "nodeLinker" {
+			return strings.Trim(strings.TrimSpace(v), `"'`)
+		}
+	}
+	return "pnp"
+}
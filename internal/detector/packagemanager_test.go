@@ -0,0 +1,68 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectPackageManager(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		manager string
+		version string
+		berry   bool
+		pnp     bool
+	}{
+		{"npm lock", map[string]string{"package-lock.json": "{}"}, "npm", "", false, false},
+		{"no lock", nil, "npm", "", false, false},
+		{"pnpm lock", map[string]string{"pnpm-lock.yaml": ""}, "pnpm", "", false, false},
+		{"bun lock", map[string]string{"bun.lockb": ""}, "bun", "", false, false},
+		{"yarn classic", map[string]string{"yarn.lock": ""}, "yarn", "", false, false},
+		{"yarn berry pnp", map[string]string{"yarn.lock": "", ".yarnrc.yml": "enableTelemetry: false\n"}, "yarn", "", true, true},
+		{"yarn berry node_modules", map[string]string{"yarn.lock": "", ".yarnrc.yml": "nodeLinker: node-modules\n"}, "yarn", "", true, false},
+		{"packageManager field wins", map[string]string{"package-lock.json": "{}", "package.json": `{"packageManager":"yarn@4.1.0+sha512.abc"}`}, "yarn", "4.1.0", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name":"x"}`), 0o644)
+			for name, content := range tt.files {
+				os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+			}
+			pd := NewProjectDetector(dir)
+			_ = pd.Detect()
+			nd := pd.Dependencies.Node
+			if nd.PackageManager != tt.manager || nd.ManagerVersion != tt.version || nd.YarnBerry != tt.berry || nd.YarnPnP != tt.pnp {
+				t.Fatalf("got %s@%s berry=%v pnp=%v", nd.PackageManager, nd.ManagerVersion, nd.YarnBerry, nd.YarnPnP)
+			}
+		})
+	}
+}
+
+func TestPackageManagerPolicy(t *testing.T) {
+	for in, want := range map[string]string{"": "npm", "respect": "npm", "enforce-pnpm": "pnpm", "Bun": "bun"} {
+		p, err := ParsePackageManagerPolicy(in)
+		if err != nil {
+			t.Fatalf("parse %q: %v", in, err)
+		}
+		pd := NewProjectDetector(t.TempDir())
+		pd.Dependencies.Node = NodeDependencies{PackageManager: "npm"}
+		pd.ApplyPackageManagerPolicy(p)
+		if got := pd.Dependencies.Node.PackageManager; got != want {
+			t.Fatalf("policy %q resolved %s, want %s", in, got, want)
+		}
+	}
+	if _, err := ParsePackageManagerPolicy("deno"); err == nil {
+		t.Fatalf("expected error for unknown manager")
+	}
+
+	// Berry details are dropped when the policy swaps Yarn out
+	pd := NewProjectDetector(t.TempDir())
+	pd.Dependencies.Node = NodeDependencies{PackageManager: "yarn", ManagerVersion: "4.1.0", YarnBerry: true, YarnPnP: true}
+	pd.ApplyPackageManagerPolicy(PolicyEnforcePnpm)
+	if nd := pd.Dependencies.Node; nd.YarnBerry || nd.YarnPnP || nd.ManagerVersion != "" {
+		t.Fatalf("expected yarn details cleared, got %+v", nd)
+	}
+}
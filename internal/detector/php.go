@@ -0,0 +1,160 @@
+package detector
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultPHP is used when composer.json neither requires nor pins PHP
+const DefaultPHP = "8.3"
+
+// phpImageVersions are the php image lines a Composer constraint is resolved
+// against, newest to oldest. DefaultPHP must be one of them.
+var phpImageVersions = []string{"8.4", "8.3", "8.2", "8.1", "8.0", "7.4"}
+
+var (
+	phpVersionNum = regexp.MustCompile(`\d+\.\d+`)
+	// "8.1 - 8.3" is inclusive on both ends at the minor level
+	phpHyphenRange = regexp.MustCompile(`(\d+(?:\.\d+)*)\s+-\s+(\d+(?:\.\d+)*)`)
+)
+
+// analyzePHPDependencies resolves the PHP version and the extensions the
+// project needs. composer.lock's ext-* platform requirements are
+// authoritative; without a lock, package names are matched against known
+// extension users.
+func (pd *ProjectDetector) analyzePHPDependencies() {
+	deps := PHPDependencies{
+		Version:    DefaultPHP,
+		Source:     "default",
+		Composer:   true,
+		Extensions: []string{},
+		IniSettings: map[string]string{
+			"memory_limit":        "256M",
+			"max_execution_time":  "300",
+			"post_max_size":       "100M",
+			"upload_max_filesize": "100M",
+		},
+	}
+	composer, _ := pd.Metadata["composer"].(map[string]interface{})
+	require, _ := composer["require"].(map[string]interface{})
+	var lock map[string]interface{}
+	locked := json.Unmarshal([]byte(readFileString(filepath.Join(pd.Root, "composer.lock"))), &lock) == nil
+
+	// config.platform.php is what Composer resolves against, so it beats the
+	// require constraint
+	config, _ := composer["config"].(map[string]interface{})
+	platform, _ := config["platform"].(map[string]interface{})
+	lockPlatform, _ := lock["platform"].(map[string]interface{})
+	if v, _ := platform["php"].(string); phpVersionNum.MatchString(v) {
+		deps.Version, deps.Source = phpVersionNum.FindString(v), "composer.json config.platform.php"
+	} else if c, _ := require["php"].(string); c != "" {
+		deps.Version, deps.Source = ExtractPHPVersion(c), "composer.json require.php "+c
+	} else if c, _ := lockPlatform["php"].(string); c != "" {
+		deps.Version, deps.Source = ExtractPHPVersion(c), "composer.lock platform.php "+c
+	}
+
+	deps.Extensions = append(deps.Extensions, phpExtRequirements(require)...)
+	if locked {
+		deps.Extensions = append(deps.Extensions, lockExtensions(lock)...)
+	} else {
+		deps.Extensions = append(deps.Extensions, pd.detectPHPExtensions(require)...)
+	}
+	// Add Laravel defaults
+	if pd.Type == TypePHPLaravel {
+		laravelExts := []string{"bcmath", "ctype", "curl", "dom", "fileinfo", "json", "mbstring", "openssl", "pdo", "pdo_mysql", "tokenizer", "xml", "zip"}
+		deps.Extensions = append(deps.Extensions, laravelExts...)
+	}
+	// Scan source for usages (best-effort)
+	deps.Extensions = UniqueStrings(append(deps.Extensions, pd.scanPHPFiles()...))
+	pd.Dependencies.PHP = deps
+}
+
+// lockExtensions collects ext-* requirements from composer.lock: the root
+// platform requirements and those of every locked package
+func lockExtensions(lock map[string]interface{}) []string {
+	var exts []string
+	for _, key := range []string{"platform", "platform-dev"} {
+		reqs, _ := lock[key].(map[string]interface{})
+		exts = append(exts, phpExtRequirements(reqs)...)
+	}
+	for _, key := range []string{"packages", "packages-dev"} {
+		pkgs, _ := lock[key].([]interface{})
+		for _, p := range pkgs {
+			pkg, _ := p.(map[string]interface{})
+			reqs, _ := pkg["require"].(map[string]interface{})
+			exts = append(exts, phpExtRequirements(reqs)...)
+		}
+	}
+	sort.Strings(exts)
+	return UniqueStrings(exts)
+}
+
+// phpExtRequirements returns extension names for the ext-* keys of a
+// Composer require map, e.g. "ext-pdo_pgsql" -> "pdo_pgsql"
+func phpExtRequirements(reqs map[string]interface{}) []string {
+	var exts []string
+	for name := range reqs {
+		if ext, ok := strings.CutPrefix(strings.ToLower(name), "ext-"); ok {
+			// Composer names OPcache after its Zend module
+			if ext == "zend-opcache" {
+				ext = "opcache"
+			}
+			exts = append(exts, ext)
+		}
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// ExtractPHPVersion picks the php image line for a Composer constraint such
+// as "^8.1 || ^8.2", ">=8.1 <8.4" or "~8.2.0". The default wins when it
+// satisfies the constraint, otherwise the newest line that does. A
+// constraint no supported line satisfies, such as "^9.0", gets the newest
+// line; one without a version gets the default.
+func ExtractPHPVersion(req string) string {
+	constraint := phpHyphenRange.ReplaceAllString(req, ">=$1 <=$2")
+	if phpSatisfies(DefaultPHP, constraint) {
+		return DefaultPHP
+	}
+	for _, v := range phpImageVersions {
+		if phpSatisfies(v, constraint) {
+			return v
+		}
+	}
+	if strings.ContainsAny(req, "0123456789") {
+		return phpImageVersions[0]
+	}
+	return DefaultPHP
+}
+
+// phpSatisfies checks a major.minor line against a Composer constraint.
+// Alternatives are separated by "||" (or the legacy "|"); clauses within one
+// are ANDed.
+func phpSatisfies(version, constraint string) bool {
+	for _, alt := range strings.Split(strings.ReplaceAll(constraint, "||", "|"), "|") {
+		clauses := versionClausePattern.FindAllStringSubmatch(alt, -1)
+		if len(clauses) == 0 {
+			continue
+		}
+		ok := true
+		for _, c := range clauses {
+			op := c[1]
+			if op == "~" {
+				// Composer's "~8.1" and "~8" mean >=8.1 <9 and >=8 <9,
+				// like PEP 440's "~="
+				op = "~="
+			}
+			if !versionClause(version, op, c[2]) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package detector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractPHPVersion_Constraints(t *testing.T) {
+	cases := map[string]string{
+		">=8.3":            "8.3",
+		"^8.1 || ^8.2":     "8.3",
+		"^7.4|^8.0":        "8.3",
+		">=8.1 <8.3":       "8.2",
+		">=8.1,<8.3":       "8.2",
+		"~8.1.0":           "8.1",
+		"~8.1":             "8.3",
+		"8.2.*":            "8.2",
+		"8.0 - 8.2":        "8.2",
+		">=7.2 <8.0":       "7.4",
+		"^8.4":             "8.4",
+		"^9.0":             "8.4",
+		"^9":               "8.4",
+		"~8":               "8.3",
+		"~8 <8.2":          "8.1",
+		"~7":               "7.4",
+		"*":                "8.3",
+		"not a constraint": DefaultPHP,
+	}
+	for in, want := range cases {
+		if got := ExtractPHPVersion(in); got != want {
+			t.Errorf("ExtractPHPVersion(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDetectPHP_PlatformConfigWins(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"composer.json": `{"require":{"php":">=8.1"},"config":{"platform":{"php":"8.1.27"}}}`,
+	})
+	pd := NewProjectDetector(dir)
+	_ = pd.Detect()
+	if php := pd.Dependencies.PHP; php.Version != "8.1" || php.Source != "composer.json config.platform.php" {
+		t.Fatalf("unexpected php version %q from %q", php.Version, php.Source)
+	}
+}
+
+func TestDetectPHP_LockExtensions(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		// predis would map to redis by name; the lock says what is really needed
+		"composer.json": `{"require":{"php":"^8.2","predis/predis":"^2.0","ext-intl":"*"}}`,
+		"composer.lock": `{
+			"packages":[{"name":"predis/predis","require":{"php":"^7.2 || ^8.0"}},
+			            {"name":"doctrine/dbal","require":{"ext-pdo":"*"}}],
+			"packages-dev":[],
+			"platform":{"php":"^8.2","ext-intl":"*","ext-zend-opcache":"*"},
+			"platform-dev":[]
+		}`,
+	})
+	pd := NewProjectDetector(dir)
+	_ = pd.Detect()
+	php := pd.Dependencies.PHP
+	if php.Version != "8.3" || php.Source != "composer.json require.php ^8.2" {
+		t.Fatalf("unexpected php version %q from %q", php.Version, php.Source)
+	}
+	if want := []string{"intl", "opcache", "pdo"}; !reflect.DeepEqual(php.Extensions, want) {
+		t.Fatalf("extensions = %v, want %v", php.Extensions, want)
+	}
+}
+
+func TestDetectPHP_Symfony(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"composer.json":    `{"require":{"php":">=8.2","symfony/framework-bundle":"7.1.*"}}`,
+		"symfony.lock":     `{}`,
+		"public/index.php": "<?php\n",
+	})
+	pd := NewProjectDetector(dir)
+	_ = pd.Detect()
+	if pd.Type != TypePHPSymfony || pd.Framework != "Symfony" || pd.Version != "7.1.*" {
+		t.Fatalf("expected symfony detection, got %s %q %q", pd.Type, pd.Framework, pd.Version)
+	}
+}
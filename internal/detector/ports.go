@@ -0,0 +1,113 @@
+package detector
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"mitl/internal/dotenv"
+)
+
+// portFlagPattern matches port flags in dev/start scripts, e.g. "--port 4000",
+// "--port=4000", "-p 4000" or "PORT=4000 next dev"
+var portFlagPattern = regexp.MustCompile(`(?:--port[= ]|-p |\bPORT=)(\d{2,5})\b`)
+
+// InferPorts returns the container ports the project's dev server is expected
+// to listen on, most likely first. A PORT in .env wins over framework defaults.
+// Projects without a known server (CLI tools, Go modules) yield nil.
+func (pd *ProjectDetector) InferPorts() []int {
+	if p := dotEnvPort(pd.Root); p > 0 {
+		return []int{p}
+	}
+	switch {
+	case pd.Type == TypeNodeNext, pd.Type == TypeNodeNuxt:
+		if p := pd.scriptPort(); p > 0 {
+			return []int{p}
+		}
+		return []int{3000}
+	case strings.HasPrefix(string(pd.Type), "node"):
+		if p := pd.scriptPort(); p > 0 {
+			return []int{p}
+		}
+		if pd.hasNodePackage("vite") {
+			return []int{5173}
+		}
+		return []int{3000}
+	case strings.HasPrefix(string(pd.Type), "php"):
+		// artisan serve and php -S both default to 8000 in mitl
+		return []int{8000}
+	case pd.Type == TypePythonDjango:
+		return []int{8000}
+	case pd.Type == TypePythonFlask:
+		return []int{5000}
+	case strings.HasPrefix(string(pd.Type), "python"):
+		return []int{8000}
+	case pd.Type == TypeRubyRails:
+		return []int{3000}
+	case pd.Type == TypeRubyGeneric && fileExists(filepath.Join(pd.Root, "config.ru")):
+		// rackup's default
+		return []int{9292}
+	case strings.HasPrefix(string(pd.Type), "java"):
+		// Spring Boot and Quarkus both listen on 8080 unless configured
+		if p := pd.jvmServerPort(); p > 0 {
+			return []int{p}
+		}
+		return []int{8080}
+	}
+	return nil
+}
+
+// scriptPort looks for an explicit port in the dev or start script
+func (pd *ProjectDetector) scriptPort() int {
+	pkg, ok := pd.Metadata["package.json"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	scripts, ok := pkg["scripts"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	for _, name := range []string{"dev", "start"} {
+		s, _ := scripts[name].(string)
+		if m := portFlagPattern.FindStringSubmatch(s); m != nil {
+			if p := validPort(m[1]); p > 0 {
+				return p
+			}
+		}
+	}
+	return 0
+}
+
+// hasNodePackage reports whether package.json depends on name
+func (pd *ProjectDetector) hasNodePackage(name string) bool {
+	pkg, ok := pd.Metadata["package.json"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for _, key := range []string{"dependencies", "devDependencies"} {
+		if deps, ok := pkg[key].(map[string]interface{}); ok {
+			if _, ok := deps[name]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dotEnvPort reads PORT from the project's .env file
+func dotEnvPort(root string) int {
+	vars, err := dotenv.ReadFile(filepath.Join(root, ".env"), nil)
+	if err != nil {
+		return 0
+	}
+	return validPort(dotenv.Map(vars)["PORT"])
+}
+
+func validPort(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 65535 {
+		return 0
+	}
+	return n
+}
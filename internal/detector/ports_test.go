@@ -0,0 +1,38 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInferPorts(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []int
+	}{
+		{"next default", map[string]string{"package.json": `{"dependencies":{"next":"14"}}`}, []int{3000}},
+		{"next script port", map[string]string{"package.json": `{"dependencies":{"next":"14"},"scripts":{"dev":"next dev -p 4000"}}`}, []int{4000}},
+		{"vite", map[string]string{"package.json": `{"devDependencies":{"vite":"5"}}`}, []int{5173}},
+		{"laravel", map[string]string{"artisan": "", "composer.json": `{"require":{"laravel/framework":"^11"}}`}, []int{8000}},
+		{"django", map[string]string{"manage.py": "", "requirements.txt": "django\n"}, []int{8000}},
+		{"flask", map[string]string{"app.py": "from flask import Flask\n"}, []int{5000}},
+		{"dotenv wins", map[string]string{"package.json": `{"dependencies":{"next":"14"}}`, ".env": "APP=x\nexport PORT=\"8080\"\n"}, []int{8080}},
+		{"go has none", map[string]string{"go.mod": "module x\n"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+			}
+			d := NewProjectDetector(dir)
+			d.Detect()
+			if got := d.InferPorts(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("InferPorts() = %v, want %v (type %s)", got, tt.want, d.Type)
+			}
+		})
+	}
+}
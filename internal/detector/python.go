@@ -0,0 +1,243 @@
+package detector
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Python installers, chosen from the project's lockfile
+const (
+	PythonInstallerPip    = "pip"
+	PythonInstallerPoetry = "poetry"
+	PythonInstallerPipenv = "pipenv"
+	PythonInstallerUV     = "uv"
+	PythonInstallerPDM    = "pdm"
+)
+
+// DefaultPython is used when neither .python-version nor requires-python
+// constrains the interpreter
+const DefaultPython = "3.11"
+
+// pythonImageVersions are the interpreter lines a requires-python constraint
+// is resolved against, newest to oldest. DefaultPython must be one of them.
+var pythonImageVersions = []string{"3.13", "3.12", "3.11", "3.10", "3.9", "3.8"}
+
+// pythonNativeDeps maps packages with C extensions to the Alpine packages
+// they build against and load at runtime
+var pythonNativeDeps = map[string]struct{ build, runtime []string }{
+	"psycopg2":    {[]string{"postgresql-dev"}, []string{"libpq"}},
+	"psycopg":     {nil, []string{"libpq"}},
+	"mysqlclient": {[]string{"mariadb-dev", "pkgconf"}, []string{"mariadb-connector-c"}},
+	"pillow":      {[]string{"jpeg-dev", "zlib-dev", "freetype-dev"}, []string{"jpeg", "zlib", "freetype"}},
+}
+
+var (
+	pythonVersionNum   = regexp.MustCompile(`\d+\.\d+(?:\.\d+)?`)
+	requirementName    = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._\-]*)`)
+	djangoSettings     = regexp.MustCompile(`DJANGO_SETTINGS_MODULE["']\s*,\s*["']([\w.]+)\.settings`)
+	fastAPIApp         = regexp.MustCompile(`(?m)^(\w+)\s*(?::\s*\w+\s*)?=\s*(?:fastapi\.)?FastAPI\(`)
+	flaskApp           = regexp.MustCompile(`(?m)^(\w+)\s*=\s*(?:flask\.)?Flask\(`)
+	pythonAppCandidate = []string{"main.py", "app.py", "app/main.py", "src/main.py", "api/main.py", "wsgi.py"}
+)
+
+// analyzePythonDependencies picks the installer from the lockfile, resolves
+// the interpreter version and collects native package requirements
+func (pd *ProjectDetector) analyzePythonDependencies() {
+	py := PythonDependencies{}
+	pyproject := readFileString(filepath.Join(pd.Root, "pyproject.toml"))
+
+	switch {
+	case fileExists(filepath.Join(pd.Root, "uv.lock")):
+		py.Installer, py.Lockfile = PythonInstallerUV, "uv.lock"
+	case fileExists(filepath.Join(pd.Root, "poetry.lock")):
+		py.Installer, py.Lockfile = PythonInstallerPoetry, "poetry.lock"
+	case fileExists(filepath.Join(pd.Root, "Pipfile.lock")):
+		py.Installer, py.Lockfile = PythonInstallerPipenv, "Pipfile.lock"
+	case fileExists(filepath.Join(pd.Root, "pdm.lock")):
+		py.Installer, py.Lockfile = PythonInstallerPDM, "pdm.lock"
+	case tomlHasTable(pyproject, "tool.poetry"):
+		py.Installer = PythonInstallerPoetry
+	case fileExists(filepath.Join(pd.Root, "Pipfile")):
+		py.Installer = PythonInstallerPipenv
+	case tomlHasTable(pyproject, "tool.pdm"):
+		py.Installer = PythonInstallerPDM
+	default:
+		py.Installer = PythonInstallerPip
+	}
+	py.UsesPoetry = py.Installer == PythonInstallerPoetry
+	py.UsesPipenv = py.Installer == PythonInstallerPipenv
+	py.UsesVenv = fileExists(filepath.Join(pd.Root, ".venv"))
+
+	if v, src := managedVersion(pd.Root, "python"); v != "" {
+		py.Version, py.Source = v, src
+	} else if v, src := versionFile(pd.Root, "python", ".python-version"); v != "" {
+		py.Version, py.Source = v, src
+	} else if c := pythonConstraint(pd.Root, pyproject); c != "" {
+		py.Version, py.Source = ResolvePythonVersion(c), "requires-python "+c
+	} else {
+		py.Version, py.Source = DefaultPython, "default"
+	}
+
+	packages := pythonPackages(pd.Root, pyproject)
+	for name, deps := range pythonNativeDeps {
+		if packages[name] {
+			py.SystemDeps = append(py.SystemDeps, deps.build...)
+			py.RuntimeDeps = append(py.RuntimeDeps, deps.runtime...)
+		}
+	}
+	sort.Strings(py.SystemDeps)
+	sort.Strings(py.RuntimeDeps)
+	py.SystemDeps = UniqueStrings(py.SystemDeps)
+	py.RuntimeDeps = UniqueStrings(py.RuntimeDeps)
+	for _, server := range []string{"gunicorn", "uvicorn"} {
+		if packages[server] {
+			py.Servers = append(py.Servers, server)
+		}
+	}
+
+	switch pd.Type {
+	case TypePythonDjango:
+		pd.Framework = "Django"
+		if m := djangoSettings.FindStringSubmatch(readFileString(filepath.Join(pd.Root, "manage.py"))); m != nil {
+			py.AppModule = m[1] + ".wsgi:application"
+		}
+	case TypePythonFlask:
+		pd.Framework = "Flask"
+		py.AppModule = findPythonApp(pd.Root, flaskApp)
+	case TypePythonFastAPI:
+		pd.Framework = "FastAPI"
+		py.AppModule = findPythonApp(pd.Root, fastAPIApp)
+	}
+	pd.Dependencies.Python = py
+}
+
+// findPythonApp returns "module:variable" for the first candidate file that
+// instantiates the framework's application object
+func findPythonApp(root string, re *regexp.Regexp) string {
+	for _, f := range pythonAppCandidate {
+		if m := re.FindStringSubmatch(readFileString(filepath.Join(root, f))); m != nil {
+			return strings.ReplaceAll(strings.TrimSuffix(f, ".py"), "/", ".") + ":" + m[1]
+		}
+	}
+	return ""
+}
+
+// isFastAPIApp reports whether one of the usual entry modules creates a
+// FastAPI application
+func isFastAPIApp(root string) bool {
+	return findPythonApp(root, fastAPIApp) != ""
+}
+
+// pythonConstraint returns requires-python, or Poetry's python dependency
+func pythonConstraint(root, pyproject string) string {
+	if c := tomlString(pyproject, "project", "requires-python"); c != "" {
+		return c
+	}
+	if c := tomlString(pyproject, "tool.poetry.dependencies", "python"); c != "" {
+		return c
+	}
+	pipfile := readFileString(filepath.Join(root, "Pipfile"))
+	return tomlString(pipfile, "requires", "python_version")
+}
+
+// pythonPackages collects normalized dependency names from requirements
+// files, pyproject (PEP 621 and Poetry), Pipfile and the lockfiles
+func pythonPackages(root, pyproject string) map[string]bool {
+	out := map[string]bool{}
+	add := func(spec string) {
+		if m := requirementName.FindStringSubmatch(strings.TrimSpace(spec)); m != nil {
+			out[normalizePythonName(m[1])] = true
+		}
+	}
+	for _, f := range []string{"requirements.txt", "requirements-dev.txt"} {
+		for _, line := range strings.Split(readFileString(filepath.Join(root, f)), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "-") {
+				add(line)
+			}
+		}
+	}
+	for _, spec := range tomlStrings(pyproject, "project", "dependencies") {
+		add(spec)
+	}
+	pipfile := readFileString(filepath.Join(root, "Pipfile"))
+	tomlLines(pyproject+"\n"+pipfile, func(table, key, _ string) bool {
+		if table == "tool.poetry.dependencies" || table == "packages" || table == "dev-packages" ||
+			(strings.HasPrefix(table, "tool.poetry.group.") && strings.HasSuffix(table, ".dependencies")) {
+			if key != "python" {
+				add(key)
+			}
+		}
+		return true
+	})
+	for _, lock := range []string{"uv.lock", "poetry.lock", "pdm.lock"} {
+		for _, name := range tomlTableStrings(readFileString(filepath.Join(root, lock)), "package", "name") {
+			add(name)
+		}
+	}
+	var pipLock map[string]map[string]any
+	if json.Unmarshal([]byte(readFileString(filepath.Join(root, "Pipfile.lock"))), &pipLock) == nil {
+		for _, section := range []string{"default", "develop"} {
+			for name := range pipLock[section] {
+				add(name)
+			}
+		}
+	}
+	return out
+}
+
+// normalizePythonName applies PEP 503 normalization
+func normalizePythonName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "-", ".", "-").Replace(name))
+}
+
+// ResolvePythonVersion picks the interpreter line for a version constraint
+// such as ">=3.10,<3.13", "^3.11" or "~=3.9". The default wins when it
+// satisfies the constraint, otherwise the newest line that does.
+func ResolvePythonVersion(constraint string) string {
+	if pythonSatisfies(DefaultPython, constraint) {
+		return DefaultPython
+	}
+	for _, v := range pythonImageVersions {
+		if pythonSatisfies(v, constraint) {
+			return v
+		}
+	}
+	if v := pythonVersionNum.FindString(constraint); v != "" {
+		return v
+	}
+	return DefaultPython
+}
+
+// pythonSatisfies checks a major.minor line against a PEP 440 or Poetry
+// constraint. Patch levels are ignored: images track the latest patch.
+func pythonSatisfies(version, constraint string) bool {
+	for _, alt := range strings.Split(constraint, "||") {
+		clauses := versionClausePattern.FindAllStringSubmatch(alt, -1)
+		if len(clauses) == 0 {
+			continue
+		}
+		ok := true
+		for _, c := range clauses {
+			if !versionClause(version, c[1], c[2]) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func readFileString(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -0,0 +1,109 @@
+package detector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectPython_InstallerFromLockfile(t *testing.T) {
+	cases := []struct {
+		name      string
+		files     map[string]string
+		installer string
+		lockfile  string
+	}{
+		{"uv", map[string]string{"pyproject.toml": "[project]\nname = \"x\"\n", "uv.lock": "version = 1\n"}, PythonInstallerUV, "uv.lock"},
+		{"poetry", map[string]string{"pyproject.toml": "[tool.poetry]\nname = \"x\"\n", "poetry.lock": ""}, PythonInstallerPoetry, "poetry.lock"},
+		{"pipenv", map[string]string{"Pipfile": "[packages]\nflask = \"*\"\n", "Pipfile.lock": "{}"}, PythonInstallerPipenv, "Pipfile.lock"},
+		{"pdm", map[string]string{"pyproject.toml": "[project]\nname = \"x\"\n[tool.pdm]\n", "pdm.lock": ""}, PythonInstallerPDM, "pdm.lock"},
+		{"unlocked poetry", map[string]string{"pyproject.toml": "[tool.poetry]\nname = \"x\"\n"}, PythonInstallerPoetry, ""},
+		{"pip", map[string]string{"requirements.txt": "requests\n"}, PythonInstallerPip, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, c.files)
+			pd := NewProjectDetector(dir)
+			_ = pd.Detect()
+			py := pd.Dependencies.Python
+			if py.Installer != c.installer || py.Lockfile != c.lockfile {
+				t.Fatalf("got %s (%s), want %s (%s)", py.Installer, py.Lockfile, c.installer, c.lockfile)
+			}
+			if py.UsesPoetry != (c.installer == PythonInstallerPoetry) || py.UsesPipenv != (c.installer == PythonInstallerPipenv) {
+				t.Fatalf("uses flags out of sync: %+v", py)
+			}
+		})
+	}
+}
+
+func TestDetectPython_FastAPIWithNativeDeps(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"pyproject.toml": "[project]\nname = \"api\"\nrequires-python = \">=3.12,<3.14\"\ndependencies = [\n  \"fastapi[standard]>=0.110\",\n  \"psycopg2>=2.9\",\n  \"Pillow\",\n]\n",
+		"app/main.py":    "from fastapi import FastAPI\n\napi: FastAPI = FastAPI()\n",
+	})
+	pd := NewProjectDetector(dir)
+	_ = pd.Detect()
+	if pd.Type != TypePythonFastAPI || pd.Framework != "FastAPI" {
+		t.Fatalf("expected fastapi, got %s (%s)", pd.Type, pd.Framework)
+	}
+	py := pd.Dependencies.Python
+	if py.AppModule != "app.main:api" {
+		t.Fatalf("app module = %q", py.AppModule)
+	}
+	if py.Version != "3.13" || py.Source != "requires-python >=3.12,<3.14" {
+		t.Fatalf("version = %s from %s", py.Version, py.Source)
+	}
+	if want := []string{"freetype-dev", "jpeg-dev", "postgresql-dev", "zlib-dev"}; !reflect.DeepEqual(py.SystemDeps, want) {
+		t.Fatalf("system deps = %v, want %v", py.SystemDeps, want)
+	}
+	if !ContainsString(py.RuntimeDeps, "libpq") {
+		t.Fatalf("runtime deps = %v", py.RuntimeDeps)
+	}
+}
+
+func TestDetectPython_DjangoGunicorn(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"requirements.txt": "Django==5.0\ngunicorn==21.2  # prod server\nmysqlclient\n",
+		"manage.py":        "os.environ.setdefault(\"DJANGO_SETTINGS_MODULE\", \"shop.settings\")\n",
+		".python-version":  "3.12.2\n",
+	})
+	pd := NewProjectDetector(dir)
+	_ = pd.Detect()
+	py := pd.Dependencies.Python
+	if pd.Type != TypePythonDjango || py.AppModule != "shop.wsgi:application" || !ContainsString(py.Servers, "gunicorn") {
+		t.Fatalf("unexpected django detection %s %+v", pd.Type, py)
+	}
+	if py.Version != "3.12.2" || py.Source != ".python-version" {
+		t.Fatalf("version = %s from %s", py.Version, py.Source)
+	}
+	if !ContainsString(py.SystemDeps, "mariadb-dev") {
+		t.Fatalf("system deps = %v", py.SystemDeps)
+	}
+}
+
+func TestResolvePythonVersion(t *testing.T) {
+	cases := map[string]string{
+		">=3.8":           DefaultPython,
+		">=3.12":          "3.13",
+		">=3.9,<3.11":     "3.10",
+		"^3.12":           "3.13",
+		"~3.10":           "3.10",
+		"~=3.9":           DefaultPython,
+		"~=3.9.2":         "3.9",
+		">=3.10 <3.11":    "3.10",
+		"==3.12.*":        "3.12",
+		">3.11":           "3.13",
+		"^3.8 || ^3.9":    DefaultPython,
+		"<3.11.4":         DefaultPython,
+		"!=3.11.*,>=3.10": "3.13",
+		"3.9":             "3.9",
+		">=4.0":           "4.0",
+	}
+	for c, want := range cases {
+		if got := ResolvePythonVersion(c); got != want {
+			t.Errorf("ResolvePythonVersion(%q) = %s, want %s", c, got, want)
+		}
+	}
+}
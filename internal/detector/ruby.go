@@ -0,0 +1,144 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RubyDependencies for Bundler projects
+type RubyDependencies struct {
+	Version      string   `json:"version"`                 // Ruby for the image tag, e.g. "3.3.0" or "3.2"
+	Source       string   `json:"source,omitempty"`        // where Version came from
+	Bundler      string   `json:"bundler,omitempty"`       // BUNDLED WITH version from Gemfile.lock
+	Locked       bool     `json:"locked,omitempty"`        // Gemfile.lock present; install frozen
+	Assets       bool     `json:"assets,omitempty"`        // Rails asset pipeline (sprockets, propshaft, *bundling)
+	BuildDeps    []string `json:"build_deps,omitempty"`    // Alpine -dev packages native gems compile against
+	RuntimeDeps  []string `json:"runtime_deps,omitempty"`  // Alpine libraries native gems load at runtime
+	NodeRequired bool     `json:"node_required,omitempty"` // jsbundling/cssbundling need node for assets
+}
+
+// DefaultRuby is used when neither .ruby-version nor Gemfile names one
+const DefaultRuby = "3.3"
+
+// rubyNativeDeps maps gems with C extensions to the Alpine packages they
+// build against and load at runtime
+var rubyNativeDeps = map[string]struct{ build, runtime []string }{
+	"pg":       {[]string{"postgresql-dev"}, []string{"libpq"}},
+	"mysql2":   {[]string{"mariadb-dev"}, []string{"mariadb-connector-c"}},
+	"nokogiri": {[]string{"libxml2-dev", "libxslt-dev"}, []string{"libxml2", "libxslt"}},
+	"sassc":    {[]string{"libffi-dev"}, []string{"libstdc++"}},
+	"sqlite3":  {[]string{"sqlite-dev"}, []string{"sqlite-libs"}},
+}
+
+var (
+	// top-level specs in Gemfile.lock are indented four spaces; their
+	// dependencies six
+	gemLockSpec    = regexp.MustCompile(`^    ([A-Za-z0-9_.\-]+) \(([^)]+)\)\s*$`)
+	gemfileGem     = regexp.MustCompile(`(?m)^\s*gem\s+["']([^"']+)["']`)
+	gemfileRuby    = regexp.MustCompile(`(?m)^\s*ruby\s+["']([^"']+)["']`)
+	gemfileRubyRef = regexp.MustCompile(`(?m)^\s*ruby\s+file:`)
+	lockRuby       = regexp.MustCompile(`RUBY VERSION\s+ruby (\d+\.\d+(?:\.\d+)?)`)
+	lockBundler    = regexp.MustCompile(`BUNDLED WITH\s+(\S+)`)
+	rubyVersionNum = regexp.MustCompile(`\d+\.\d+(?:\.\d+)?`)
+)
+
+// gemfileGems returns the gems a project uses with their locked versions.
+// Gemfile.lock is authoritative; without one the Gemfile's gem lines are
+// used and versions are left empty.
+func gemfileGems(root string) map[string]string {
+	gems := map[string]string{}
+	if b, err := os.ReadFile(filepath.Join(root, "Gemfile.lock")); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			if m := gemLockSpec.FindStringSubmatch(line); m != nil {
+				gems[m[1]] = m[2]
+			}
+		}
+		return gems
+	}
+	if b, err := os.ReadFile(filepath.Join(root, "Gemfile")); err == nil {
+		for _, m := range gemfileGem.FindAllStringSubmatch(string(b), -1) {
+			gems[m[1]] = ""
+		}
+	}
+	return gems
+}
+
+// isRailsApp reports whether a Bundler project is a Rails application
+func isRailsApp(root string, gems map[string]string) bool {
+	if _, ok := gems["rails"]; ok {
+		return true
+	}
+	_, ok := gems["railties"]
+	return ok || fileExists(filepath.Join(root, "config", "application.rb"))
+}
+
+// analyzeRubyDependencies reads the Ruby version, Bundler version and the
+// native gems that need Alpine packages
+func (pd *ProjectDetector) analyzeRubyDependencies() {
+	rd := RubyDependencies{}
+	gemfile, _ := os.ReadFile(filepath.Join(pd.Root, "Gemfile"))
+	lock, lockErr := os.ReadFile(filepath.Join(pd.Root, "Gemfile.lock"))
+	rd.Locked = lockErr == nil
+
+	// mise/asdf and .ruby-version win; Gemfile `ruby file: ".ruby-version"`
+	// points at it anyway, and a plain `ruby "x"` directive must agree with
+	// it for bundler to run
+	if v, src := managedVersion(pd.Root, "ruby"); v != "" {
+		rd.Version, rd.Source = v, src
+	} else if v, src := versionFile(pd.Root, "ruby", ".ruby-version"); v != "" {
+		rd.Version, rd.Source = v, src
+	}
+	if rd.Version == "" && !gemfileRubyRef.Match(gemfile) {
+		if m := gemfileRuby.FindSubmatch(gemfile); m != nil {
+			// "~> 3.2" or ">= 3.1" pin the minor line the image tag follows
+			if v := rubyVersionNum.Find(m[1]); v != nil {
+				rd.Version, rd.Source = string(v), "Gemfile"
+			}
+		}
+	}
+	if rd.Version == "" {
+		if m := lockRuby.FindSubmatch(lock); m != nil {
+			rd.Version, rd.Source = string(m[1]), "Gemfile.lock"
+		}
+	}
+	if rd.Version == "" {
+		rd.Version, rd.Source = DefaultRuby, "default"
+	}
+	if m := lockBundler.FindSubmatch(lock); m != nil {
+		rd.Bundler = string(m[1])
+	}
+
+	gems := gemfileGems(pd.Root)
+	for gem, pkgs := range rubyNativeDeps {
+		if _, ok := gems[gem]; ok {
+			rd.BuildDeps = append(rd.BuildDeps, pkgs.build...)
+			rd.RuntimeDeps = append(rd.RuntimeDeps, pkgs.runtime...)
+		}
+	}
+	sort.Strings(rd.BuildDeps)
+	sort.Strings(rd.RuntimeDeps)
+	rd.BuildDeps = UniqueStrings(rd.BuildDeps)
+	rd.RuntimeDeps = UniqueStrings(rd.RuntimeDeps)
+
+	if pd.Type == TypeRubyRails {
+		pd.Framework = "Rails"
+		pd.Version = gems["rails"]
+		if pd.Version == "" {
+			pd.Version = gems["railties"]
+		}
+		for _, g := range []string{"sprockets", "propshaft", "jsbundling-rails", "cssbundling-rails"} {
+			if _, ok := gems[g]; ok {
+				rd.Assets = true
+			}
+		}
+		for _, g := range []string{"jsbundling-rails", "cssbundling-rails"} {
+			if _, ok := gems[g]; ok && fileExists(filepath.Join(pd.Root, "package.json")) {
+				rd.NodeRequired = true
+			}
+		}
+	}
+	pd.Dependencies.Ruby = rd
+}
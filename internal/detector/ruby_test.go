@@ -0,0 +1,79 @@
+package detector
+
+import (
+	"reflect"
+	"testing"
+)
+
+const railsLock = `GEM
+  remote: https://rubygems.org/
+  specs:
+    actionpack (7.1.3)
+      rack (>= 2.2.4)
+    nokogiri (1.16.2-x86_64-linux)
+      racc (~> 1.4)
+    pg (1.5.4)
+    propshaft (0.8.0)
+    rails (7.1.3)
+      actionpack (= 7.1.3)
+
+PLATFORMS
+  x86_64-linux
+
+RUBY VERSION
+   ruby 3.2.2p53
+
+BUNDLED WITH
+   2.5.6
+`
+
+func TestDetectRuby_Rails(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"Gemfile":      "source \"https://rubygems.org\"\nruby \"~> 3.2\"\ngem \"rails\"\ngem \"pg\"\n",
+		"Gemfile.lock": railsLock,
+		"config.ru":    "run Rails.application\n",
+	})
+	pd := NewProjectDetector(dir)
+	_ = pd.Detect()
+	if pd.Type != TypeRubyRails {
+		t.Fatalf("expected ruby-rails, got %s", pd.Type)
+	}
+	if pd.Framework != "Rails" || pd.Version != "7.1.3" {
+		t.Fatalf("framework = %q %q", pd.Framework, pd.Version)
+	}
+	rd := pd.Dependencies.Ruby
+	if rd.Version != "3.2" || rd.Source != "Gemfile" || rd.Bundler != "2.5.6" || !rd.Locked || !rd.Assets {
+		t.Fatalf("unexpected ruby deps %+v", rd)
+	}
+	if want := []string{"libxml2-dev", "libxslt-dev", "postgresql-dev"}; !reflect.DeepEqual(rd.BuildDeps, want) {
+		t.Fatalf("build deps = %v, want %v", rd.BuildDeps, want)
+	}
+	if want := []string{"libpq", "libxml2", "libxslt"}; !reflect.DeepEqual(rd.RuntimeDeps, want) {
+		t.Fatalf("runtime deps = %v, want %v", rd.RuntimeDeps, want)
+	}
+}
+
+func TestDetectRuby_VersionSources(t *testing.T) {
+	cases := []struct {
+		name  string
+		files map[string]string
+		want  string
+		src   string
+	}{
+		{"ruby-version wins", map[string]string{"Gemfile": "ruby \"3.1.4\"\n", ".ruby-version": "ruby-3.3.0\n"}, "3.3.0", ".ruby-version"},
+		{"gemfile file reference", map[string]string{"Gemfile": "ruby file: \".ruby-version\"\n", "Gemfile.lock": railsLock}, "3.2.2", "Gemfile.lock"},
+		{"default", map[string]string{"Gemfile": "source \"https://rubygems.org\"\ngem \"rack\"\n"}, DefaultRuby, "default"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, c.files)
+			pd := NewProjectDetector(dir)
+			_ = pd.Detect()
+			if rd := pd.Dependencies.Ruby; rd.Version != c.want || rd.Source != c.src {
+				t.Fatalf("got %s from %s, want %s from %s", rd.Version, rd.Source, c.want, c.src)
+			}
+		})
+	}
+}
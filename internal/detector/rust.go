@@ -0,0 +1,139 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RustDependencies for Cargo projects
+type RustDependencies struct {
+	Version    string   `json:"version"`               // toolchain for the rust image tag, e.g. "1.78" or "1"
+	Source     string   `json:"source,omitempty"`      // where Version or Channel came from
+	Channel    string   `json:"channel,omitempty"`     // rust-toolchain channel when not a plain version
+	Workspace  bool     `json:"workspace,omitempty"`   // Cargo.toml declares [workspace]
+	Members    []string `json:"members,omitempty"`     // expanded workspace member directories
+	Binaries   []string `json:"binaries,omitempty"`    // binary targets, default first
+	Locked     bool     `json:"locked,omitempty"`      // Cargo.lock present; build with --locked
+	SystemDeps []string `json:"system_deps,omitempty"` // Alpine packages native crates need
+}
+
+var rustVersionPattern = regexp.MustCompile(`^\d+(\.\d+){0,2}$`)
+
+// rustNativeDeps maps crates with C dependencies to the Alpine packages
+// they need at build time
+var rustNativeDeps = map[string][]string{
+	"openssl-sys":    {"openssl-dev", "openssl-libs-static", "pkgconf"},
+	"libsqlite3-sys": {"sqlite-dev"},
+	"pq-sys":         {"postgresql-dev"},
+	"libz-sys":       {"zlib-dev", "zlib-static"},
+	"prost-build":    {"protobuf-dev"},
+}
+
+// validateCargoToml accepts manifests with a [package] or [workspace]
+func (pd *ProjectDetector) validateCargoToml(path string) bool {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	content := string(b)
+	return tomlHasTable(content, "package") || tomlHasTable(content, "workspace")
+}
+
+// analyzeRustDependencies reads Cargo.toml, workspace members and the
+// rust-toolchain file
+func (pd *ProjectDetector) analyzeRustDependencies() {
+	rd := RustDependencies{Version: "1", Source: "default"}
+	b, _ := os.ReadFile(filepath.Join(pd.Root, "Cargo.toml"))
+	content := string(b)
+
+	if channel, file := rustToolchainChannel(pd.Root); channel != "" {
+		rd.Source = file
+		if rustVersionPattern.MatchString(channel) {
+			rd.Version = channel
+		} else {
+			// stable/beta/nightly-YYYY-MM-DD: rustup in the image installs it
+			// from the toolchain file on first cargo invocation
+			rd.Channel = channel
+		}
+	} else if v, src := managedVersion(pd.Root, "rust"); v != "" {
+		rd.Version, rd.Source = v, src
+	}
+
+	rd.Workspace = tomlHasTable(content, "workspace")
+	if tomlHasTable(content, "package") {
+		rd.Binaries = append(rd.Binaries, crateBinaries(pd.Root, content)...)
+	}
+	if rd.Workspace {
+		rd.Members = expandWorkspaceMembers(pd.Root, tomlStrings(content, "workspace", "members"))
+		for _, m := range rd.Members {
+			mb, err := os.ReadFile(filepath.Join(pd.Root, m, "Cargo.toml"))
+			if err != nil {
+				continue
+			}
+			rd.Binaries = append(rd.Binaries, crateBinaries(filepath.Join(pd.Root, m), string(mb))...)
+		}
+	}
+	rd.Binaries = UniqueStrings(rd.Binaries)
+
+	if lock, err := os.ReadFile(filepath.Join(pd.Root, "Cargo.lock")); err == nil {
+		rd.Locked = true
+		for crate, pkgs := range rustNativeDeps {
+			if strings.Contains(string(lock), `name = "`+crate+`"`) {
+				rd.SystemDeps = append(rd.SystemDeps, pkgs...)
+			}
+		}
+		sort.Strings(rd.SystemDeps)
+		rd.SystemDeps = UniqueStrings(rd.SystemDeps)
+	}
+	pd.Dependencies.Rust = rd
+}
+
+// crateBinaries lists the binary targets of one crate: explicit [[bin]]
+// entries, else the package name when src/main.rs exists
+func crateBinaries(dir, content string) []string {
+	if bins := tomlTableStrings(content, "bin", "name"); len(bins) > 0 {
+		return bins
+	}
+	if name := tomlString(content, "package", "name"); name != "" && fileExists(filepath.Join(dir, "src", "main.rs")) {
+		return []string{name}
+	}
+	return nil
+}
+
+// expandWorkspaceMembers resolves member globs such as "crates/*"
+func expandWorkspaceMembers(root string, patterns []string) []string {
+	var out []string
+	for _, p := range patterns {
+		matches, err := filepath.Glob(filepath.Join(root, p))
+		if err != nil {
+			continue
+		}
+		for _, m := range matches {
+			if fileExists(filepath.Join(m, "Cargo.toml")) {
+				if rel, err := filepath.Rel(root, m); err == nil {
+					out = append(out, filepath.ToSlash(rel))
+				}
+			}
+		}
+	}
+	return UniqueStrings(out)
+}
+
+// rustToolchainChannel reads the channel from rust-toolchain.toml or the
+// legacy single-line rust-toolchain file, and names the file it came from
+func rustToolchainChannel(root string) (channel, file string) {
+	if b, err := os.ReadFile(filepath.Join(root, "rust-toolchain.toml")); err == nil {
+		return tomlString(string(b), "toolchain", "channel"), "rust-toolchain.toml"
+	}
+	if b, err := os.ReadFile(filepath.Join(root, "rust-toolchain")); err == nil {
+		content := string(b)
+		if c := tomlString(content, "toolchain", "channel"); c != "" {
+			return c, "rust-toolchain"
+		}
+		return strings.TrimSpace(content), "rust-toolchain"
+	}
+	return "", ""
+}
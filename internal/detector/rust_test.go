@@ -0,0 +1,73 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDetectRust_Package(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"Cargo.toml":          "[package]\nname = \"api\" # the server\nversion = \"0.1.0\"\n",
+		"src/main.rs":         "fn main() {}\n",
+		"Cargo.lock":          "[[package]]\nname = \"openssl-sys\"\nversion = \"0.9.0\"\n",
+		"rust-toolchain.toml": "[toolchain]\nchannel = \"1.78.0\"\ncomponents = [\"clippy\"]\n",
+	})
+	pd := NewProjectDetector(dir)
+	_ = pd.Detect()
+	if pd.Type != TypeRustCargo {
+		t.Fatalf("expected rust, got %s", pd.Type)
+	}
+	rd := pd.Dependencies.Rust
+	if rd.Version != "1.78.0" || rd.Channel != "" || !rd.Locked || rd.Workspace {
+		t.Fatalf("unexpected rust deps %+v", rd)
+	}
+	if !reflect.DeepEqual(rd.Binaries, []string{"api"}) {
+		t.Fatalf("binaries = %v", rd.Binaries)
+	}
+	if !ContainsString(rd.SystemDeps, "openssl-dev") {
+		t.Fatalf("expected openssl-dev for openssl-sys, got %v", rd.SystemDeps)
+	}
+}
+
+func TestDetectRust_Workspace(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"Cargo.toml":              "[workspace]\nmembers = [\n  \"crates/*\",\n  \"tools/cli\",\n]\nresolver = \"2\"\n",
+		"crates/core/Cargo.toml":  "[package]\nname = \"core\"\n",
+		"crates/core/src/lib.rs":  "",
+		"crates/web/Cargo.toml":   "[package]\nname = \"web\"\n\n[[bin]]\nname = \"web-server\"\npath = \"src/main.rs\"\n",
+		"tools/cli/Cargo.toml":    "[package]\nname = \"cli\"\n",
+		"tools/cli/src/main.rs":   "fn main() {}\n",
+		"rust-toolchain":          "nightly-2024-05-01\n",
+		"crates/notacrate/README": "",
+	})
+	pd := NewProjectDetector(dir)
+	_ = pd.Detect()
+	rd := pd.Dependencies.Rust
+	if pd.Type != TypeRustCargo || !rd.Workspace || rd.Locked {
+		t.Fatalf("unexpected detection %s %+v", pd.Type, rd)
+	}
+	if !reflect.DeepEqual(rd.Members, []string{"crates/core", "crates/web", "tools/cli"}) {
+		t.Fatalf("members = %v", rd.Members)
+	}
+	if !reflect.DeepEqual(rd.Binaries, []string{"web-server", "cli"}) {
+		t.Fatalf("binaries = %v", rd.Binaries)
+	}
+	if rd.Version != "1" || rd.Channel != "nightly-2024-05-01" {
+		t.Fatalf("toolchain = %s/%s", rd.Version, rd.Channel)
+	}
+}
@@ -0,0 +1,145 @@
+package detector
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Minimal TOML readers for the handful of keys the detector needs from
+// Cargo.toml, rust-toolchain.toml and pyproject.toml. They understand
+// [table] and [[array-of-tables]] headers, "key = value" pairs and
+// (multi-line) string arrays; anything fancier is ignored.
+
+var tomlQuoted = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+
+// tomlLines yields (table, key, value) for every key/value pair. Values of
+// multi-line arrays are joined onto one line.
+func tomlLines(content string, fn func(table, key, value string) bool) {
+	table := ""
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(stripTOMLComment(lines[i]))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			table = strings.TrimSpace(strings.Trim(line, "[]"))
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if strings.HasPrefix(v, "[") {
+			for tomlArrayOpen(v) && i+1 < len(lines) {
+				i++
+				v += " " + strings.TrimSpace(stripTOMLComment(lines[i]))
+			}
+		}
+		if !fn(table, strings.Trim(strings.TrimSpace(k), `"'`), v) {
+			return
+		}
+	}
+}
+
+// tomlString returns the string value of key in table ("" for the root)
+func tomlString(content, table, key string) string {
+	out := ""
+	tomlLines(content, func(t, k, v string) bool {
+		if t == table && k == key {
+			out = tomlUnquote(v)
+			return false
+		}
+		return true
+	})
+	return out
+}
+
+// tomlStrings returns the string array value of key in table
+func tomlStrings(content, table, key string) []string {
+	var out []string
+	tomlLines(content, func(t, k, v string) bool {
+		if t == table && k == key {
+			for _, m := range tomlQuoted.FindAllStringSubmatch(v, -1) {
+				out = append(out, m[1]+m[2])
+			}
+			return false
+		}
+		return true
+	})
+	return out
+}
+
+// tomlHasTable reports whether content declares [table] or [[table]]
+func tomlHasTable(content, table string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(stripTOMLComment(line))
+		if strings.HasPrefix(line, "[") && strings.TrimSpace(strings.Trim(line, "[]")) == table {
+			return true
+		}
+	}
+	return false
+}
+
+// tomlTableStrings returns key from every [[table]] entry, e.g. all
+// [[bin]] names
+func tomlTableStrings(content, table, key string) []string {
+	var out []string
+	tomlLines(content, func(t, k, v string) bool {
+		if t == table && k == key {
+			out = append(out, tomlUnquote(v))
+		}
+		return true
+	})
+	return out
+}
+
+// tomlArrayOpen reports whether v has unbalanced brackets outside of
+// quotes, i.e. an array continuing on the next line
+func tomlArrayOpen(v string) bool {
+	depth := 0
+	inQuote := byte(0)
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			}
+		case c == '"' || c == '\'':
+			inQuote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+func tomlUnquote(v string) string {
+	if m := tomlQuoted.FindStringSubmatch(v); m != nil {
+		return m[1] + m[2]
+	}
+	return strings.TrimSpace(v)
+}
+
+// stripTOMLComment drops a trailing # comment outside of quotes
+func stripTOMLComment(line string) string {
+	inQuote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			}
+		case c == '"' || c == '\'':
+			inQuote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
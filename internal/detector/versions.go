@@ -0,0 +1,122 @@
+package detector
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Runtime versions are pinned in several places. Each analyzer consults them
+// in the same order and records the winner's file as the version's Source:
+//
+//  1. mise.toml / .mise.toml [tools]
+//  2. .tool-versions (asdf; mise reads it too)
+//  3. the runtime's own file: .nvmrc, .node-version, .python-version,
+//     .ruby-version
+//  4. the project manifest: engines.node, requires-python, Gemfile ruby, ...
+//  5. a default
+//
+// go.mod's toolchain line and rust-toolchain(.toml) come first for Go and
+// Rust: the go command and rustup enforce them whatever the version manager
+// installed. A runtime pinned in mitl.yaml overrides all of these.
+
+// versionManagerTools lists the tool names asdf and mise use for a runtime
+var versionManagerTools = map[string][]string{
+	"node":   {"node", "nodejs"},
+	"python": {"python"},
+	"ruby":   {"ruby"},
+	"go":     {"go", "golang"},
+	"rust":   {"rust"},
+}
+
+var (
+	// image tags follow plain release numbers; aliases such as "latest",
+	// "system" or "ref:..." are left to the next source
+	runtimeVersionPattern = regexp.MustCompile(`^v?(\d+(?:\.\d+){0,2})$`)
+	miseInlineVersion     = regexp.MustCompile(`version\s*=\s*["']([^"']+)["']`)
+)
+
+// nodeLTSCodenames maps nvm's lts/<codename> aliases to their major
+var nodeLTSCodenames = map[string]string{
+	"gallium": "16", "hydrogen": "18", "iron": "20", "jod": "22",
+}
+
+// managedVersion returns the version mise or asdf pins for a runtime and the
+// file it came from
+func managedVersion(root, runtime string) (version, source string) {
+	names := versionManagerTools[runtime]
+	for _, f := range []string{"mise.toml", ".mise.toml"} {
+		content := readFileString(filepath.Join(root, f))
+		found := ""
+		tomlLines(content, func(table, key, value string) bool {
+			if table == "tools" && ContainsString(names, key) {
+				found = miseToolVersion(value)
+				return false
+			}
+			return true
+		})
+		if v := normalizeRuntimeVersion(runtime, found); v != "" {
+			return v, f
+		}
+	}
+	for _, line := range strings.Split(readFileString(filepath.Join(root, ".tool-versions")), "\n") {
+		fields := strings.Fields(stripTOMLComment(line))
+		// "nodejs 20.11.0 18.19.0": the first version is the active one
+		if len(fields) >= 2 && ContainsString(names, fields[0]) {
+			if v := normalizeRuntimeVersion(runtime, fields[1]); v != "" {
+				return v, ".tool-versions"
+			}
+		}
+	}
+	return "", ""
+}
+
+// miseToolVersion reads a [tools] value: "20", ["3.12", "3.11"] (first is
+// active) or { version = "3.3" }
+func miseToolVersion(value string) string {
+	if strings.HasPrefix(value, "{") {
+		if m := miseInlineVersion.FindStringSubmatch(value); m != nil {
+			return m[1]
+		}
+		return ""
+	}
+	return tomlUnquote(value)
+}
+
+// versionFile returns the version from the first of files present in root,
+// e.g. .nvmrc before .node-version
+func versionFile(root, runtime string, files ...string) (version, source string) {
+	for _, f := range files {
+		content := strings.TrimSpace(readFileString(filepath.Join(root, f)))
+		if content == "" {
+			continue
+		}
+		// pyenv allows several versions, one per line; the first is active
+		first, _, _ := strings.Cut(content, "\n")
+		if v := normalizeRuntimeVersion(runtime, strings.TrimSpace(first)); v != "" {
+			return v, f
+		}
+	}
+	return "", ""
+}
+
+// normalizeRuntimeVersion turns a version manager's spelling into an image
+// tag version: "v20.11.0" -> "20.11.0", "ruby-3.2.2" -> "3.2.2",
+// "lts/iron" -> "20". Unusable values yield "".
+func normalizeRuntimeVersion(runtime, v string) string {
+	v = strings.TrimSpace(v)
+	switch runtime {
+	case "node":
+		if codename, ok := strings.CutPrefix(strings.ToLower(v), "lts/"); ok {
+			return nodeLTSCodenames[codename]
+		}
+	case "ruby":
+		v = strings.TrimPrefix(v, "ruby-")
+	case "go":
+		v = strings.TrimPrefix(v, "go")
+	}
+	if m := runtimeVersionPattern.FindStringSubmatch(v); m != nil {
+		return m[1]
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package detector
+
+import "testing"
+
+func TestRuntimeVersions_Precedence(t *testing.T) {
+	cases := []struct {
+		name    string
+		files   map[string]string
+		runtime func(*ProjectDetector) (string, string)
+		version string
+		source  string
+	}{
+		{
+			name: "nvmrc beats engines",
+			files: map[string]string{
+				"package.json": `{"engines":{"node":">=18"}}`,
+				".nvmrc":       "v20.11.0\n",
+			},
+			runtime: func(pd *ProjectDetector) (string, string) {
+				return pd.Dependencies.Node.Version, pd.Dependencies.Node.Source
+			},
+			version: "20.11.0", source: ".nvmrc",
+		},
+		{
+			name: "lts codename",
+			files: map[string]string{
+				"package.json":  `{}`,
+				".node-version": "lts/hydrogen\n",
+			},
+			runtime: func(pd *ProjectDetector) (string, string) {
+				return pd.Dependencies.Node.Version, pd.Dependencies.Node.Source
+			},
+			version: "18", source: ".node-version",
+		},
+		{
+			name: "tool-versions beats nvmrc",
+			files: map[string]string{
+				"package.json":   `{}`,
+				".nvmrc":         "18\n",
+				".tool-versions": "# pinned\nnodejs 22.2.0 20.11.0\n",
+			},
+			runtime: func(pd *ProjectDetector) (string, string) {
+				return pd.Dependencies.Node.Version, pd.Dependencies.Node.Source
+			},
+			version: "22.2.0", source: ".tool-versions",
+		},
+		{
+			name: "mise beats tool-versions and python-version",
+			files: map[string]string{
+				"requirements.txt": "flask\n",
+				".python-version":  "3.10\n",
+				".tool-versions":   "python 3.11.9\n",
+				".mise.toml":       "[tools]\npython = [\"3.12\", \"3.11\"]\n",
+			},
+			runtime: func(pd *ProjectDetector) (string, string) {
+				return pd.Dependencies.Python.Version, pd.Dependencies.Python.Source
+			},
+			version: "3.12", source: ".mise.toml",
+		},
+		{
+			name: "alias falls through",
+			files: map[string]string{
+				"Gemfile":        "source \"https://rubygems.org\"\n",
+				".tool-versions": "ruby system\n",
+				".ruby-version":  "ruby-3.2.2\n",
+			},
+			runtime: func(pd *ProjectDetector) (string, string) {
+				return pd.Dependencies.Ruby.Version, pd.Dependencies.Ruby.Source
+			},
+			version: "3.2.2", source: ".ruby-version",
+		},
+		{
+			name: "go toolchain beats mise",
+			files: map[string]string{
+				"go.mod":    "module example.com/app\n\ngo 1.21\n\ntoolchain go1.22.3\n",
+				"mise.toml": "[tools]\ngo = { version = \"1.23\" }\n",
+			},
+			runtime: func(pd *ProjectDetector) (string, string) {
+				return pd.Dependencies.Go.Version, pd.Dependencies.Go.Source
+			},
+			version: "1.22.3", source: "go.mod toolchain",
+		},
+		{
+			name: "mise beats go directive",
+			files: map[string]string{
+				"go.mod":    "module example.com/app\n\ngo 1.21\n",
+				"mise.toml": "[tools]\ngolang = { version = \"1.23\" }\n",
+			},
+			runtime: func(pd *ProjectDetector) (string, string) {
+				return pd.Dependencies.Go.Version, pd.Dependencies.Go.Source
+			},
+			version: "1.23", source: "mise.toml",
+		},
+		{
+			name: "rust toolchain file beats tool-versions",
+			files: map[string]string{
+				"Cargo.toml":          "[package]\nname = \"app\"\n",
+				"rust-toolchain.toml": "[toolchain]\nchannel = \"1.78\"\n",
+				".tool-versions":      "rust 1.80.0\n",
+			},
+			runtime: func(pd *ProjectDetector) (string, string) {
+				return pd.Dependencies.Rust.Version, pd.Dependencies.Rust.Source
+			},
+			version: "1.78", source: "rust-toolchain.toml",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tc.files)
+			pd := NewProjectDetector(dir)
+			_ = pd.Detect()
+			if v, src := tc.runtime(pd); v != tc.version || src != tc.source {
+				t.Fatalf("got %q from %q, want %q from %q", v, src, tc.version, tc.source)
+			}
+		})
+	}
+}
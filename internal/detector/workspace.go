@@ -0,0 +1,417 @@
+package detector
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workspace tools, as reported in Workspace.Tools
+const (
+	WorkspacePNPM     = "pnpm"
+	WorkspaceNPM      = "npm"
+	WorkspaceYarn     = "yarn"
+	WorkspaceBun      = "bun"
+	WorkspaceGo       = "go"
+	WorkspaceCargo    = "cargo"
+	WorkspaceComposer = "composer"
+	WorkspaceNx       = "nx"
+	WorkspaceTurbo    = "turbo"
+)
+
+// Workspace is a monorepo root and the packages it declares. One capsule
+// and one set of dependency volumes serve the whole workspace; commands pick
+// a package with --package.
+type Workspace struct {
+	Root     string             `json:"root"`
+	Tools    []string           `json:"tools"`
+	Packages []WorkspacePackage `json:"packages"`
+}
+
+// WorkspacePackage is one member of a workspace
+type WorkspacePackage struct {
+	Name     string   `json:"name"`               // package.json/composer.json name, module path or crate name
+	Path     string   `json:"path"`               // slash-separated, relative to the workspace root
+	Requires []string `json:"requires,omitempty"` // names of workspace packages it depends on
+}
+
+var (
+	goWorkUse    = regexp.MustCompile(`(?m)^\s*use\s+(?:\(([^)]*)\)|(\S+))`)
+	goModModule  = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+	goModRequire = regexp.MustCompile(`(?m)^\s*(?:require\s+)?([\w.\-]+(?:/[\w.\-~]+)+)\s+v\S+`)
+)
+
+// DiscoverWorkspace reads the workspace declarations in root: pnpm-workspace.yaml,
+// package.json workspaces (npm, Yarn, Bun), go.work, a Cargo [workspace],
+// Composer path repositories and Nx project.json files. Turborepo only
+// orchestrates package manager workspaces and is reported as a tool. It
+// returns nil when root declares no member packages.
+func DiscoverWorkspace(root string) *Workspace {
+	ws := &Workspace{Root: root}
+	members := map[string]*WorkspacePackage{}
+	deps := map[string][]string{}
+	add := func(tool string, dirs []string, read func(dir string) (name string, requires []string, ok bool)) {
+		found := false
+		for _, dir := range dirs {
+			name, requires, ok := read(filepath.Join(root, dir))
+			if !ok {
+				continue
+			}
+			found = true
+			if name == "" {
+				name = filepath.Base(dir)
+			}
+			if m, ok := members[dir]; ok {
+				// e.g. an Nx project that is also an npm workspace
+				deps[dir] = append(deps[dir], requires...)
+				if m.Name == filepath.Base(dir) {
+					m.Name = name
+				}
+				continue
+			}
+			members[dir] = &WorkspacePackage{Name: name, Path: dir}
+			deps[dir] = requires
+		}
+		if found && !ContainsString(ws.Tools, tool) {
+			ws.Tools = append(ws.Tools, tool)
+		}
+	}
+
+	if patterns := pnpmWorkspacePatterns(root); patterns != nil {
+		add(WorkspacePNPM, expandWorkspaceGlobs(root, patterns), readNodePackage)
+	} else if patterns := packageJSONWorkspaces(root); patterns != nil {
+		tool := WorkspaceNPM
+		switch {
+		case anyFileExists(root, Lockfiles(PackageManagerYarn)...):
+			tool = WorkspaceYarn
+		case anyFileExists(root, Lockfiles(PackageManagerBun)...):
+			tool = WorkspaceBun
+		}
+		add(tool, expandWorkspaceGlobs(root, patterns), readNodePackage)
+	}
+	if content := readFileString(filepath.Join(root, "go.work")); content != "" {
+		var dirs []string
+		for _, m := range goWorkUse.FindAllStringSubmatch(content, -1) {
+			dirs = append(dirs, strings.Fields(m[1]+" "+m[2])...)
+		}
+		add(WorkspaceGo, cleanWorkspaceDirs(dirs), readGoModule)
+	}
+	if cargo := readFileString(filepath.Join(root, "Cargo.toml")); tomlHasTable(cargo, "workspace") {
+		add(WorkspaceCargo, expandWorkspaceMembers(root, tomlStrings(cargo, "workspace", "members")), readCrate)
+	}
+	if patterns := composerPathRepositories(root); patterns != nil {
+		add(WorkspaceComposer, expandWorkspaceGlobs(root, patterns), readComposerPackage)
+	}
+	if fileExists(filepath.Join(root, "nx.json")) {
+		add(WorkspaceNx, expandWorkspaceGlobs(root, []string{"apps/*", "libs/*", "packages/*"}), readNxProject)
+		if !ContainsString(ws.Tools, WorkspaceNx) {
+			ws.Tools = append(ws.Tools, WorkspaceNx)
+		}
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	if fileExists(filepath.Join(root, "turbo.json")) {
+		ws.Tools = append(ws.Tools, WorkspaceTurbo)
+	}
+
+	names := map[string]bool{}
+	for _, m := range members {
+		names[m.Name] = true
+	}
+	for dir, m := range members {
+		for _, d := range UniqueStrings(deps[dir]) {
+			if names[d] && d != m.Name {
+				m.Requires = append(m.Requires, d)
+			}
+		}
+		sort.Strings(m.Requires)
+		ws.Packages = append(ws.Packages, *m)
+	}
+	sort.Slice(ws.Packages, func(i, j int) bool { return ws.Packages[i].Path < ws.Packages[j].Path })
+	return ws
+}
+
+// FindWorkspace returns the workspace declared in dir or its nearest
+// ancestor that lists dir as (or inside) a member, or nil
+func FindWorkspace(dir string) *Workspace {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	for cur := abs; ; {
+		if ws := DiscoverWorkspace(cur); ws != nil {
+			if cur == abs {
+				return ws
+			}
+			rel, _ := filepath.Rel(cur, abs)
+			if _, ok := ws.PackageAt(filepath.ToSlash(rel)); ok {
+				return ws
+			}
+		}
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return nil
+		}
+		cur = parent
+	}
+}
+
+// Package looks a member up by name or by its path
+func (w *Workspace) Package(name string) (WorkspacePackage, bool) {
+	clean := strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(name), "./"), "/")
+	for _, p := range w.Packages {
+		if p.Name == name || p.Path == clean {
+			return p, true
+		}
+	}
+	return WorkspacePackage{}, false
+}
+
+// PackageAt returns the member containing the root-relative path
+func (w *Workspace) PackageAt(rel string) (WorkspacePackage, bool) {
+	for _, p := range w.Packages {
+		if rel == p.Path || strings.HasPrefix(rel, p.Path+"/") {
+			return p, true
+		}
+	}
+	return WorkspacePackage{}, false
+}
+
+// Scope returns the paths of a package and every workspace package it
+// depends on, directly or transitively: the directories whose changes can
+// affect it
+func (w *Workspace) Scope(pkg WorkspacePackage) []string {
+	seen := map[string]bool{}
+	var paths []string
+	var visit func(WorkspacePackage)
+	visit = func(p WorkspacePackage) {
+		if seen[p.Name] {
+			return
+		}
+		seen[p.Name] = true
+		paths = append(paths, p.Path)
+		for _, dep := range p.Requires {
+			if d, ok := w.Package(dep); ok {
+				visit(d)
+			}
+		}
+	}
+	visit(pkg)
+	sort.Strings(paths)
+	return paths
+}
+
+// Names lists member names for messages
+func (w *Workspace) Names() []string {
+	names := make([]string, 0, len(w.Packages))
+	for _, p := range w.Packages {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// pnpmWorkspacePatterns returns the packages globs of pnpm-workspace.yaml
+func pnpmWorkspacePatterns(root string) []string {
+	b, err := os.ReadFile(filepath.Join(root, "pnpm-workspace.yaml"))
+	if err != nil {
+		return nil
+	}
+	var cfg struct {
+		Packages []string `yaml:"packages"`
+	}
+	if yaml.Unmarshal(b, &cfg) != nil {
+		return nil
+	}
+	return cfg.Packages
+}
+
+// packageJSONWorkspaces returns npm/Yarn/Bun workspaces globs, either an
+// array or Yarn classic's {"packages": [...]}
+func packageJSONWorkspaces(root string) []string {
+	var pkg struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if json.Unmarshal([]byte(readFileString(filepath.Join(root, "package.json"))), &pkg) != nil || pkg.Workspaces == nil {
+		return nil
+	}
+	var patterns []string
+	if json.Unmarshal(pkg.Workspaces, &patterns) == nil {
+		return patterns
+	}
+	var yarn struct {
+		Packages []string `json:"packages"`
+	}
+	if json.Unmarshal(pkg.Workspaces, &yarn) == nil {
+		return yarn.Packages
+	}
+	return nil
+}
+
+// composerPathRepositories returns the url globs of "path" repositories
+func composerPathRepositories(root string) []string {
+	var composer struct {
+		Repositories json.RawMessage `json:"repositories"`
+	}
+	if json.Unmarshal([]byte(readFileString(filepath.Join(root, "composer.json"))), &composer) != nil {
+		return nil
+	}
+	// repositories is an array or an object keyed by name
+	var list []map[string]interface{}
+	if json.Unmarshal(composer.Repositories, &list) != nil {
+		var named map[string]map[string]interface{}
+		if json.Unmarshal(composer.Repositories, &named) != nil {
+			return nil
+		}
+		for _, r := range named {
+			list = append(list, r)
+		}
+	}
+	var patterns []string
+	for _, r := range list {
+		if t, _ := r["type"].(string); t == "path" {
+			if url, _ := r["url"].(string); url != "" {
+				patterns = append(patterns, url)
+			}
+		}
+	}
+	return patterns
+}
+
+// expandWorkspaceGlobs resolves member globs to directories relative to
+// root. "!" exclusions are applied afterwards; a trailing "/**" is treated
+// as "/*", the usual intent.
+func expandWorkspaceGlobs(root string, patterns []string) []string {
+	var dirs, excluded []string
+	for _, p := range patterns {
+		negate := strings.HasPrefix(p, "!")
+		p = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(p, "!"), "./"), "/")
+		if strings.HasSuffix(p, "/**") {
+			p = strings.TrimSuffix(p, "**") + "*"
+		}
+		matches, err := filepath.Glob(filepath.Join(root, filepath.FromSlash(p)))
+		if err != nil {
+			continue
+		}
+		for _, m := range matches {
+			if info, err := os.Stat(m); err != nil || !info.IsDir() {
+				continue
+			}
+			if rel, err := filepath.Rel(root, m); err == nil {
+				if negate {
+					excluded = append(excluded, filepath.ToSlash(rel))
+				} else {
+					dirs = append(dirs, filepath.ToSlash(rel))
+				}
+			}
+		}
+	}
+	var out []string
+	for _, d := range UniqueStrings(dirs) {
+		if !ContainsString(excluded, d) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// cleanWorkspaceDirs normalizes explicit member directories such as go.work
+// use lines ("./api", "."); the root itself is not a member
+func cleanWorkspaceDirs(dirs []string) []string {
+	var out []string
+	for _, d := range dirs {
+		d = filepath.ToSlash(filepath.Clean(d))
+		if d != "." && !strings.HasPrefix(d, "../") {
+			out = append(out, d)
+		}
+	}
+	return UniqueStrings(out)
+}
+
+func readNodePackage(dir string) (string, []string, bool) {
+	var pkg map[string]interface{}
+	if json.Unmarshal([]byte(readFileString(filepath.Join(dir, "package.json"))), &pkg) != nil {
+		return "", nil, false
+	}
+	name, _ := pkg["name"].(string)
+	var requires []string
+	for _, section := range []string{"dependencies", "devDependencies", "peerDependencies", "optionalDependencies"} {
+		deps, _ := pkg[section].(map[string]interface{})
+		for dep := range deps {
+			requires = append(requires, dep)
+		}
+	}
+	return name, requires, true
+}
+
+func readGoModule(dir string) (string, []string, bool) {
+	content := readFileString(filepath.Join(dir, "go.mod"))
+	if content == "" {
+		return "", nil, false
+	}
+	var requires []string
+	for _, m := range goModRequire.FindAllStringSubmatch(content, -1) {
+		requires = append(requires, m[1])
+	}
+	name := ""
+	if m := goModModule.FindStringSubmatch(content); m != nil {
+		name = m[1]
+	}
+	return name, requires, true
+}
+
+func readCrate(dir string) (string, []string, bool) {
+	content := readFileString(filepath.Join(dir, "Cargo.toml"))
+	if content == "" {
+		return "", nil, false
+	}
+	var requires []string
+	tomlLines(content, func(table, key, _ string) bool {
+		if strings.HasSuffix(table, "dependencies") {
+			requires = append(requires, key)
+		}
+		return true
+	})
+	return tomlString(content, "package", "name"), requires, true
+}
+
+func readComposerPackage(dir string) (string, []string, bool) {
+	var composer map[string]interface{}
+	if json.Unmarshal([]byte(readFileString(filepath.Join(dir, "composer.json"))), &composer) != nil {
+		return "", nil, false
+	}
+	name, _ := composer["name"].(string)
+	var requires []string
+	for _, section := range []string{"require", "require-dev"} {
+		deps, _ := composer[section].(map[string]interface{})
+		for dep := range deps {
+			requires = append(requires, dep)
+		}
+	}
+	return name, requires, true
+}
+
+func readNxProject(dir string) (string, []string, bool) {
+	var project struct {
+		Name                 string   `json:"name"`
+		ImplicitDependencies []string `json:"implicitDependencies"`
+	}
+	if json.Unmarshal([]byte(readFileString(filepath.Join(dir, "project.json"))), &project) != nil {
+		return "", nil, false
+	}
+	return project.Name, project.ImplicitDependencies, true
+}
+
+func anyFileExists(root string, names ...string) bool {
+	for _, n := range names {
+		if fileExists(filepath.Join(root, n)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package detector
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverWorkspace_PNPMAndTurbo(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"package.json":                  `{"name":"root","private":true}`,
+		"pnpm-workspace.yaml":           "packages:\n  - 'apps/*'\n  - 'packages/**'\n  - '!packages/scratch'\n",
+		"pnpm-lock.yaml":                "lockfileVersion: '9.0'\n",
+		"turbo.json":                    `{"tasks":{}}`,
+		"apps/web/package.json":         `{"name":"web","dependencies":{"@acme/ui":"workspace:*","react":"^18"}}`,
+		"packages/ui/package.json":      `{"name":"@acme/ui"}`,
+		"packages/scratch/x.txt":        "not a package",
+		"packages/scratch/package.json": `{"name":"scratch"}`,
+	})
+	ws := DiscoverWorkspace(dir)
+	if ws == nil {
+		t.Fatal("expected a workspace")
+	}
+	if !reflect.DeepEqual(ws.Tools, []string{WorkspacePNPM, WorkspaceTurbo}) {
+		t.Fatalf("unexpected tools %v", ws.Tools)
+	}
+	want := []WorkspacePackage{
+		{Name: "web", Path: "apps/web", Requires: []string{"@acme/ui"}},
+		{Name: "@acme/ui", Path: "packages/ui"},
+	}
+	if !reflect.DeepEqual(ws.Packages, want) {
+		t.Fatalf("unexpected packages %+v", ws.Packages)
+	}
+}
+
+func TestDiscoverWorkspace_NPMScope(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"package.json":               `{"name":"root","workspaces":{"packages":["packages/*"]}}`,
+		"yarn.lock":                  "",
+		"packages/app/package.json":  `{"name":"app","dependencies":{"lib":"*"}}`,
+		"packages/lib/package.json":  `{"name":"lib","devDependencies":{"util":"*"}}`,
+		"packages/util/package.json": `{"name":"util"}`,
+		"packages/docs/package.json": `{"name":"docs"}`,
+	})
+	ws := DiscoverWorkspace(dir)
+	if ws == nil || !reflect.DeepEqual(ws.Tools, []string{WorkspaceYarn}) {
+		t.Fatalf("expected a Yarn workspace, got %+v", ws)
+	}
+	app, ok := ws.Package("app")
+	if !ok {
+		t.Fatal("app not found by name")
+	}
+	if got := ws.Scope(app); !reflect.DeepEqual(got, []string{"packages/app", "packages/lib", "packages/util"}) {
+		t.Fatalf("scope should follow workspace dependencies transitively, got %v", got)
+	}
+	if p, ok := ws.Package("./packages/docs/"); !ok || p.Name != "docs" {
+		t.Fatalf("lookup by path failed: %+v", p)
+	}
+	if _, ok := ws.Package("missing"); ok {
+		t.Fatal("unexpected match for an unknown package")
+	}
+}
+
+func TestDiscoverWorkspace_GoWork(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"go.work":       "go 1.22\n\nuse (\n\t./api\n\t./shared\n)\n",
+		"api/go.mod":    "module example.com/api\n\ngo 1.22\n\nrequire example.com/shared v0.0.0\n",
+		"shared/go.mod": "module example.com/shared\n\ngo 1.22\n",
+	})
+	ws := DiscoverWorkspace(dir)
+	if ws == nil || !reflect.DeepEqual(ws.Tools, []string{WorkspaceGo}) {
+		t.Fatalf("expected a Go workspace, got %+v", ws)
+	}
+	want := []WorkspacePackage{
+		{Name: "example.com/api", Path: "api", Requires: []string{"example.com/shared"}},
+		{Name: "example.com/shared", Path: "shared"},
+	}
+	if !reflect.DeepEqual(ws.Packages, want) {
+		t.Fatalf("unexpected packages %+v", ws.Packages)
+	}
+}
+
+func TestDiscoverWorkspace_CargoComposerNx(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"Cargo.toml":             "[workspace]\nmembers = [\"crates/*\"]\n",
+		"crates/cli/Cargo.toml":  "[package]\nname = \"cli\"\n\n[dependencies]\ncore = { path = \"../core\" }\n",
+		"crates/core/Cargo.toml": "[package]\nname = \"core\"\n",
+	})
+	ws := DiscoverWorkspace(dir)
+	if ws == nil || len(ws.Packages) != 2 || strings.Join(ws.Packages[0].Requires, ",") != "core" {
+		t.Fatalf("unexpected Cargo workspace %+v", ws)
+	}
+
+	dir = t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"composer.json":                  `{"repositories":[{"type":"path","url":"packages/*"},{"type":"vcs","url":"https://example.com/x.git"}],"require":{"acme/billing":"*"}}`,
+		"packages/billing/composer.json": `{"name":"acme/billing"}`,
+	})
+	ws = DiscoverWorkspace(dir)
+	if ws == nil || !reflect.DeepEqual(ws.Packages, []WorkspacePackage{{Name: "acme/billing", Path: "packages/billing"}}) {
+		t.Fatalf("unexpected Composer workspace %+v", ws)
+	}
+
+	dir = t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"nx.json":                  "{}",
+		"apps/shop/project.json":   `{"name":"shop","implicitDependencies":["shared"]}`,
+		"libs/shared/project.json": `{"name":"shared"}`,
+	})
+	ws = DiscoverWorkspace(dir)
+	if ws == nil || !reflect.DeepEqual(ws.Tools, []string{WorkspaceNx}) || len(ws.Packages) != 2 {
+		t.Fatalf("unexpected Nx workspace %+v", ws)
+	}
+
+	if ws := DiscoverWorkspace(t.TempDir()); ws != nil {
+		t.Fatalf("a plain directory is not a workspace: %+v", ws)
+	}
+}
+
+func TestFindWorkspace_FromMember(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"package.json":          `{"workspaces":["apps/*"]}`,
+		"apps/web/package.json": `{"name":"web"}`,
+		"apps/web/src/index.js": "",
+		"tools/seed.js":         "",
+	})
+	for _, sub := range []string{"apps/web", "apps/web/src"} {
+		ws := FindWorkspace(filepath.Join(dir, sub))
+		if ws == nil || ws.Root != dir {
+			t.Fatalf("%s: expected the enclosing workspace at %s, got %+v", sub, dir, ws)
+		}
+	}
+	if ws := FindWorkspace(filepath.Join(dir, "tools")); ws != nil {
+		t.Fatalf("a directory outside every member should not resolve, got %+v", ws)
+	}
+}
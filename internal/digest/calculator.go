@@ -4,7 +4,9 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/fs"
 	"os"
@@ -26,6 +28,8 @@ type Calculator struct {
 	algorithm   HashAlgorithm
 	parallel    bool
 	maxWorkers  int
+	selectFile  func(relPath string) bool
+	index       *FileIndex
 	bufferPool  sync.Pool
 }
 
@@ -46,12 +50,19 @@ type CalculatorOptions struct {
 	MaxWorkers  int
 	Normalizer  *Normalizer
 	IgnoreRules *IgnoreRules
+	// Select keeps only the files it returns true for, before any is read;
+	// nil keeps every file the ignore rules allow
+	Select func(relPath string) bool
+	// Index reuses the hashes of files whose stamp is unchanged; nil hashes
+	// every file
+	Index *FileIndex
 }
 
 // workItem is an internal unit of work for hashing
 type workItem struct {
 	path  string
 	index int
+	oid   string // git blob ID of a clean tracked file, if known
 }
 
 // CalcFileInfo contains metadata about a processed file.
@@ -61,6 +72,12 @@ type CalcFileInfo struct {
 	Hash         string
 	IsNormalized bool
 	Error        error
+	Cached       bool          // Hash came from the index
+	HashTime     time.Duration // time spent hashing, or saved on a cache hit
+
+	stamp   fileStamp
+	notText bool
+	oid     string
 }
 
 // CalcResult contains the complete digest calculation results.
@@ -72,6 +89,7 @@ type CalcResult struct {
 	TotalSize    int64
 	IgnoredFiles int
 	Errors       []error
+	Cache        CacheStats // zero without an index
 }
 
 // NewCalculator creates a new digest calculator with default settings.
@@ -103,6 +121,8 @@ func NewCalculatorWithOptions(opts CalculatorOptions) *Calculator {
 		algorithm:   opts.Algorithm,
 		parallel:    opts.Parallel,
 		maxWorkers:  opts.MaxWorkers,
+		selectFile:  opts.Select,
+		index:       opts.Index,
 		bufferPool: sync.Pool{
 			New: func() interface{} {
 				buf := make([]byte, 32*1024) // 32KB buffer
@@ -125,7 +145,32 @@ func (c *Calculator) CalculateDirectory(ctx context.Context, rootDir string) (*C
 	sort.Strings(files)
 
 	// Process files
-	return c.processFiles(ctx, rootDir, files)
+	return c.finishDirectory(c.processFiles(ctx, rootDir, files, nil))
+}
+
+// calculateGit is CalculateDirectory for a git checkout: git-ignored files
+// are left out, and clean tracked files are looked up by blob ID, so a
+// file's hash survives branch switches and fresh checkouts that change its
+// timestamps. File hashes are the same as a walk's.
+func (c *Calculator) calculateGit(ctx context.Context, rootDir string, repo *gitRepo) (*CalcResult, error) {
+	files, oids, err := c.collectGitFiles(rootDir, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect files: %w", err)
+	}
+	sort.Strings(files)
+	return c.finishDirectory(c.processFiles(ctx, rootDir, files, oids))
+}
+
+// finishDirectory updates and saves the index after a directory run
+func (c *Calculator) finishDirectory(result *CalcResult, err error) (*CalcResult, error) {
+	if err != nil || c.index == nil {
+		return result, err
+	}
+	// Only a walk that saw every file knows which entries are gone. Saving
+	// is best effort: a read-only checkout just goes without the index.
+	c.index.finish(c.selectFile == nil)
+	_ = c.index.Save()
+	return result, nil
 }
 
 // CalculateFiles computes digest for specific files.
@@ -135,7 +180,7 @@ func (c *Calculator) CalculateFiles(ctx context.Context, files []string) (*CalcR
 	copy(sortedFiles, files)
 	sort.Strings(sortedFiles)
 
-	return c.processFiles(ctx, "", sortedFiles)
+	return c.processFiles(ctx, "", sortedFiles, nil)
 }
 
 // CalculateFile computes digest for a single file with normalization.
@@ -144,7 +189,9 @@ func (c *Calculator) CalculateFile(filePath string) (string, error) {
 	return c.calculateFileWithContext(context.Background(), filePath)
 }
 
-// calculateFileWithContext reads file content in chunks, allowing context cancellation.
+// calculateFileWithContext streams the file through the normalizer into the
+// hash, so large files are never held in memory, checking for cancellation
+// between reads.
 func (c *Calculator) calculateFileWithContext(ctx context.Context, filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -152,46 +199,51 @@ func (c *Calculator) calculateFileWithContext(ctx context.Context, filePath stri
 	}
 	defer file.Close()
 
-	// Read file content in chunks to allow cancellation checks
-	var buf []byte
-	chunk := make([]byte, 32*1024)
-	for {
-		if ctx != nil {
-			select {
-			case <-ctx.Done():
+	bufp := c.bufferPool.Get().(*[]byte)
+	defer c.bufferPool.Put(bufp)
+	hasher := c.newHash()
+	src := &contextReader{ctx: ctx, r: file}
+	if err := c.normalizer.NormalizeStream(hasher, src, *bufp); err != nil {
+		if src.err != nil {
+			if ctx != nil && ctx.Err() != nil {
 				return "", ctx.Err()
-			default:
 			}
-			if d, ok := ctx.Deadline(); ok {
-				// If deadline is extremely soon, yield briefly to allow cancellation to propagate
-				if rem := time.Until(d); rem > 0 && rem < 2*time.Millisecond {
-					time.Sleep(rem)
-				}
-			}
-		}
-		n, rErr := file.Read(chunk)
-		if n > 0 {
-			buf = append(buf, chunk[:n]...)
+			return "", fmt.Errorf("failed to read file %s: %w", filePath, err)
 		}
-		if rErr == io.EOF {
-			break
-		}
-		if rErr != nil {
-			return "", fmt.Errorf("failed to read file %s: %w", filePath, rErr)
-		}
-	}
-
-	// Apply normalization
-	normalized, err := c.normalizer.Normalize(buf)
-	if err != nil {
 		return "", fmt.Errorf("failed to normalize file %s: %w", filePath, err)
 	}
 	if ctx != nil && ctx.Err() != nil {
 		return "", ctx.Err()
 	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
 
-	// Calculate hash
-	return c.hashContent(normalized), nil
+// contextReader stops reading once ctx is done and remembers the error of
+// the underlying reader, telling read failures from normalization ones
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+	err error
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if cr.ctx != nil {
+		if err := cr.ctx.Err(); err != nil {
+			cr.err = err
+			return 0, err
+		}
+		if d, ok := cr.ctx.Deadline(); ok {
+			// If deadline is extremely soon, yield briefly to allow cancellation to propagate
+			if rem := time.Until(d); rem > 0 && rem < 2*time.Millisecond {
+				time.Sleep(rem)
+			}
+		}
+	}
+	n, err := cr.r.Read(p)
+	if err != nil && err != io.EOF {
+		cr.err = err
+	}
+	return n, err
 }
 
 // collectFiles walks the directory tree and returns all file paths.
@@ -218,7 +270,7 @@ func (c *Calculator) collectFiles(rootDir string) ([]string, error) {
 		}
 
 		// Only include regular files
-		if d.Type().IsRegular() {
+		if d.Type().IsRegular() && (c.selectFile == nil || c.selectFile(relPath)) {
 			files = append(files, path)
 		}
 
@@ -229,7 +281,7 @@ func (c *Calculator) collectFiles(rootDir string) ([]string, error) {
 }
 
 // processFiles processes a list of files and computes the combined digest.
-func (c *Calculator) processFiles(ctx context.Context, rootDir string, files []string) (*CalcResult, error) {
+func (c *Calculator) processFiles(ctx context.Context, rootDir string, files []string, oids map[string]string) (*CalcResult, error) {
 	result := &CalcResult{
 		Algorithm: c.algorithm,
 		Files:     make([]CalcFileInfo, 0, len(files)),
@@ -260,7 +312,7 @@ func (c *Calculator) processFiles(ctx context.Context, rootDir string, files []s
 		defer close(workCh)
 		for i, path := range files {
 			select {
-			case workCh <- workItem{path: path, index: i}:
+			case workCh <- workItem{path: path, index: i, oid: oids[path]}:
 			case <-ctx.Done():
 				return
 			}
@@ -275,7 +327,17 @@ func (c *Calculator) processFiles(ctx context.Context, rootDir string, files []s
 
 	// Process results maintaining order
 	fileResults := make([]CalcFileInfo, len(files))
+	now := time.Now()
 	for fileInfo := range resultCh {
+		if c.index != nil && rootDir != "" {
+			c.index.add(fileInfo, now)
+			if fileInfo.Cached {
+				result.Cache.Hits++
+				result.Cache.Saved += fileInfo.HashTime
+			} else {
+				result.Cache.Misses++
+			}
+		}
 		if fileInfo.Error != nil {
 			result.Errors = append(result.Errors, fileInfo.Error)
 		} else {
@@ -307,17 +369,17 @@ func (c *Calculator) worker(ctx context.Context, rootDir string, workCh <-chan w
 		default:
 		}
 
-		fileInfo := c.processFileWithContext(ctx, work.path, rootDir)
+		fileInfo := c.processFileWithContext(ctx, work.path, rootDir, work.oid)
 		resultCh <- fileInfo
 	}
 }
 
 // processFile processes a single file and returns its information.
 func (c *Calculator) processFile(filePath, rootDir string) CalcFileInfo { // retained for compatibility
-	return c.processFileWithContext(context.Background(), filePath, rootDir)
+	return c.processFileWithContext(context.Background(), filePath, rootDir, "")
 }
 
-func (c *Calculator) processFileWithContext(ctx context.Context, filePath, rootDir string) CalcFileInfo {
+func (c *Calculator) processFileWithContext(ctx context.Context, filePath, rootDir, oid string) CalcFileInfo {
 	// Get relative path for result
 	relPath := filePath
 	if rootDir != "" {
@@ -328,6 +390,7 @@ func (c *Calculator) processFileWithContext(ctx context.Context, filePath, rootD
 
 	fileInfo := CalcFileInfo{
 		Path: relPath,
+		oid:  oid,
 	}
 
 	// Get file stats
@@ -337,10 +400,33 @@ func (c *Calculator) processFileWithContext(ctx context.Context, filePath, rootD
 		return fileInfo
 	}
 	fileInfo.Size = stat.Size()
+	fileInfo.stamp = stampOf(stat)
+
+	if c.index != nil && rootDir != "" {
+		entry, ok := c.index.lookup(relPath, fileInfo.stamp)
+		if !ok && oid != "" {
+			entry, ok = c.index.lookupBlob(oid)
+		}
+		if ok {
+			fileInfo.Cached = true
+			fileInfo.HashTime = time.Duration(entry.Nanos)
+			if entry.NotText {
+				fileInfo.notText = true
+				fileInfo.Error = fmt.Errorf("failed to hash file %s: %w", filePath, errNotUTF8)
+				return fileInfo
+			}
+			fileInfo.Hash = entry.Hash
+			fileInfo.IsNormalized = true
+			return fileInfo
+		}
+	}
 
 	// Calculate hash with context support
+	start := time.Now()
 	hash, err := c.calculateFileWithContext(ctx, filePath)
+	fileInfo.HashTime = time.Since(start)
 	if err != nil {
+		fileInfo.notText = errors.Is(err, errNotUTF8)
 		fileInfo.Error = fmt.Errorf("failed to hash file %s: %w", filePath, err)
 		return fileInfo
 	}
@@ -373,6 +459,14 @@ func (c *Calculator) calculateCombinedDigest(files []CalcFileInfo) string {
 	return c.hashContent([]byte(input.String()))
 }
 
+// newHash returns a hash for the configured algorithm
+func (c *Calculator) newHash() hash.Hash {
+	if c.algorithm == Blake3 {
+		return blake3.New()
+	}
+	return sha256.New()
+}
+
 // hashContent calculates hash of content using the configured algorithm.
 func (c *Calculator) hashContent(content []byte) string {
 	switch c.algorithm {
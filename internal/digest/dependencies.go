@@ -0,0 +1,129 @@
+package digest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// dependencyManifests declare dependencies or toolchain versions without
+// pinning them. Not every project commits a lockfile, so they count towards
+// the dependency digest too.
+var dependencyManifests = map[string]bool{
+	"package.json":        true,
+	"pnpm-workspace.yaml": true,
+	".npmrc":              true,
+	".nvmrc":              true,
+	".node-version":       true,
+	"composer.json":       true,
+	"pyproject.toml":      true,
+	"Pipfile":             true,
+	".python-version":     true,
+	"Gemfile":             true,
+	".ruby-version":       true,
+	"Cargo.toml":          true,
+	"rust-toolchain":      true,
+	"rust-toolchain.toml": true,
+	"go.work":             true,
+	"go.work.sum":         true,
+	"build.gradle":        true,
+	"build.gradle.kts":    true,
+	"settings.gradle":     true,
+	"settings.gradle.kts": true,
+	"gradle.properties":   true,
+	".tool-versions":      true,
+}
+
+// isLockfile reports whether name is a file LockfileHasher understands
+func isLockfile(name string) bool {
+	_, ok := (&LockfileHasher{}).hashers()[name]
+	return ok
+}
+
+// IsDependencyFile reports whether a file name is a lockfile or a
+// dependency manifest, the files a dependency digest covers
+func IsDependencyFile(name string) bool {
+	return dependencyManifests[name] || isLockfile(name)
+}
+
+// DependencyDigest computes the identity of a capsule image rather than of
+// the source mounted over it: the lockfiles and dependency manifests in
+// scope plus inputs that are not project files, such as the Dockerfile the
+// capsule is built from and the detector's view of the project. Lockfiles
+// are hashed by LockfileHasher, so reformatting one keeps the digest.
+// Inputs are recorded as "<name>".
+func DependencyDigest(root string, options *Options, inputs map[string][]byte) (*Digest, error) {
+	opts := Options{}
+	if options != nil {
+		opts = *options
+	}
+	opts.LockfilesOnly, opts.DependenciesOnly = false, true
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	d, err := NewProjectCalculator(root, &opts).Calculate(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	hashers := (&LockfileHasher{}).hashers()
+	for i, f := range d.Files {
+		hash := hashers[filepath.Base(f.Path)]
+		if hash == nil {
+			continue
+		}
+		data, rerr := os.ReadFile(filepath.Join(root, f.Path))
+		if rerr != nil {
+			continue
+		}
+		// Unparseable lockfiles keep their content hash
+		if semantic, herr := hash(data); herr == nil {
+			d.Files[i].Hash = semantic
+		}
+	}
+	d.addInputs(inputs)
+	// Sizes are left out: a reformatted lockfile keeps its semantic hash
+	hasher := sha256.New()
+	for _, f := range d.Files {
+		fmt.Fprintf(hasher, "%s\n%s\n", f.Path, f.Hash)
+	}
+	d.Hash = hex.EncodeToString(hasher.Sum(nil))
+	return d, nil
+}
+
+// AddInputs adds inputs that are not project files to a project digest,
+// recorded as "<name>" as DependencyDigest records them, and rehashes it.
+// Baked capsules use it so their Dockerfile and build source count too.
+func (d *Digest) AddInputs(inputs map[string][]byte) {
+	d.addInputs(inputs)
+	d.Hash = (&ProjectCalculator{}).calculateCombinedHash(d.Files)
+}
+
+// addInputs appends the "<name>" entries of inputs to d.Files, keeping them
+// sorted
+func (d *Digest) addInputs(inputs map[string][]byte) {
+	for name, data := range inputs {
+		sum := sha256.Sum256(data)
+		d.Files = append(d.Files, FileDigest{Path: "<" + name + ">", Hash: hex.EncodeToString(sum[:]), Size: int64(len(data))})
+		d.TotalSize += int64(len(data))
+	}
+	sort.Slice(d.Files, func(i, j int) bool { return d.Files[i].Path < d.Files[j].Path })
+	d.FileCount = len(d.Files)
+}
+
+// DependencyTag returns the short (12-char) form of DependencyDigest, the
+// tag of capsules that mount their source
+func DependencyTag(root string, options *Options, inputs map[string][]byte) (string, error) {
+	d, err := DependencyDigest(root, options, inputs)
+	if err != nil {
+		return "", err
+	}
+	if len(d.Hash) < 12 {
+		return "", fmt.Errorf("digest too short")
+	}
+	return d.Hash[:12], nil
+}
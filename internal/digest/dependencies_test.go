@@ -0,0 +1,69 @@
+package digest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDependencyTag(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("package.json", `{"name":"app","dependencies":{"left-pad":"^1.0.0"}}`)
+	write("package-lock.json", `{"packages":{"node_modules/left-pad":{"version":"1.3.0"}}}`)
+	write(".nvmrc", "20\n")
+	write("src/index.js", "console.log(1)")
+	inputs := map[string][]byte{"dockerfile": []byte("FROM node:20\n")}
+	tag := func() string {
+		v, err := DependencyTag(dir, &Options{Algorithm: "sha256"}, inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	base := tag()
+	if len(base) != 12 {
+		t.Fatalf("expected 12-char tag, got %q", base)
+	}
+	write("src/index.js", "console.log(2)")
+	if got := tag(); got != base {
+		t.Fatalf("a source edit changed the dependency digest: %s -> %s", base, got)
+	}
+	write("package-lock.json", "{\n  \"packages\": {\"node_modules/left-pad\": {\"version\": \"1.3.0\"}}\n}\n")
+	if got := tag(); got != base {
+		t.Fatalf("reformatting the lockfile changed the dependency digest: %s -> %s", base, got)
+	}
+	write("package-lock.json", `{"packages":{"node_modules/left-pad":{"version":"1.3.1"}}}`)
+	locked := tag()
+	if locked == base {
+		t.Fatal("a lockfile change must change the dependency digest")
+	}
+	write(".nvmrc", "22\n")
+	if got := tag(); got == locked {
+		t.Fatal("a toolchain version file must change the dependency digest")
+	}
+	inputs["dockerfile"] = []byte("FROM node:22\n")
+	if got := tag(); got == locked {
+		t.Fatal("the Dockerfile must change the dependency digest")
+	}
+}
+
+func TestDependencyDigest_Files(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644)
+	d, err := DependencyDigest(dir, nil, map[string][]byte{"source": []byte("generated")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.FileCount != 2 || d.Files[0].Path != "<source>" || d.Files[1].Path != "go.mod" {
+		t.Fatalf("unexpected files: %+v", d.Files)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -27,13 +28,14 @@ import (
 // This struct provides complete information about what files were included
 // and how the digest was calculated for transparency and debugging.
 type Digest struct {
-	Hash      string       `json:"hash"`       // The calculated digest value
-	Algorithm string       `json:"algorithm"`  // Hash algorithm used (sha256, blake3)
-	Timestamp time.Time    `json:"timestamp"`  // When the digest was calculated
-	FileCount int          `json:"file_count"` // Number of files included
-	TotalSize int64        `json:"total_size"` // Total size of all files
-	Files     []FileDigest `json:"files"`      // Files included in the digest
-	Options   Options      `json:"options"`    // Configuration used for calculation
+	Hash      string       `json:"hash"`            // The calculated digest value
+	Algorithm string       `json:"algorithm"`       // Hash algorithm used (sha256, blake3)
+	Timestamp time.Time    `json:"timestamp"`       // When the digest was calculated
+	FileCount int          `json:"file_count"`      // Number of files included
+	TotalSize int64        `json:"total_size"`      // Total size of all files
+	Files     []FileDigest `json:"files"`           // Files included in the digest
+	Options   Options      `json:"options"`         // Configuration used for calculation
+	Cache     *CacheStats  `json:"cache,omitempty"` // File index use; nil with NoCache
 }
 
 // FileDigest contains metadata for a single file included in the digest calculation.
@@ -55,12 +57,31 @@ type ProjectCalculator struct {
 
 // Options configures digest calculation behavior to meet different use cases.
 type Options struct {
-	Algorithm      string   `json:"algorithm"`       // Hash algorithm: "sha256", "blake3" (default: "blake3")
-	MaxFileSize    int64    `json:"max_file_size"`   // Skip files larger than this size in bytes (0 = no limit)
-	IncludeHidden  bool     `json:"include_hidden"`  // Include files starting with . (default: false)
-	LockfilesOnly  bool     `json:"lockfiles_only"`  // Only process lockfiles (default: false)
+	Algorithm     string `json:"algorithm"`      // Hash algorithm: "sha256", "blake3" (default: "blake3")
+	MaxFileSize   int64  `json:"max_file_size"`  // Skip files larger than this size in bytes (0 = no limit)
+	IncludeHidden bool   `json:"include_hidden"` // Include files starting with . (default: false)
+	LockfilesOnly bool   `json:"lockfiles_only"` // Only process lockfiles (default: false)
+	// DependenciesOnly hashes lockfiles and the manifests that declare
+	// dependencies or toolchain versions, such as package.json. Other files
+	// are never read.
+	DependenciesOnly bool `json:"dependencies_only,omitempty"`
+	// NoCache hashes every file instead of reusing unchanged files' hashes
+	// from the index under .mitl/cache
+	NoCache bool `json:"no_cache,omitempty"`
+	// Mode is ModeWalk (the default) or ModeGit, which takes tracked files'
+	// state from the git index and also honors .gitignore. Outside a git
+	// checkout ModeGit walks; the digest records the mode used.
+	Mode           string   `json:"mode,omitempty"`
 	IncludePattern []string `json:"include_pattern"` // Only hash files matching these patterns
 	ExcludePattern []string `json:"exclude_pattern"` // Skip files matching these patterns
+	// Paths scopes the digest to a workspace package: only files under these
+	// root-relative directories, plus root-level files such as the shared
+	// lockfile, are hashed. Empty hashes the whole tree.
+	Paths []string `json:"paths,omitempty"`
+	// ExtraFiles are hashed along with the tree, keyed by the name the digest
+	// records for them. They cover build inputs outside the project or in
+	// ignored directories, such as Dockerfile templates.
+	ExtraFiles map[string]string `json:"extra_files,omitempty"`
 }
 
 // NewProjectCalculator creates a digest calculator for the specified root directory.
@@ -100,6 +121,16 @@ func NewProjectCalculator(root string, options *Options) *ProjectCalculator {
 		Normalizer:  NewNormalizer(),
 		IgnoreRules: ignoreMatcher,
 	}
+	if !options.NoCache {
+		calcOpts.Index = LoadFileIndex(root, indexKey(algorithm, calcOpts.Normalizer, ignoreMatcher))
+	}
+	// Skip reading files the filters would drop anyway
+	switch {
+	case options.LockfilesOnly:
+		calcOpts.Select = func(path string) bool { return isLockfile(filepath.Base(path)) }
+	case options.DependenciesOnly:
+		calcOpts.Select = func(path string) bool { return IsDependencyFile(filepath.Base(path)) }
+	}
 
 	internalCalc := NewCalculatorWithOptions(calcOpts)
 
@@ -127,7 +158,19 @@ func (c *ProjectCalculator) Calculate(ctx context.Context) (*Digest, error) {
 		err error
 	}
 	ch := make(chan res, 1)
+	options := c.options
+	var repo *gitRepo
+	if options.Mode == ModeGit {
+		if repo = openGitRepo(c.root); repo == nil {
+			options.Mode = ModeWalk
+		}
+	}
 	go func() {
+		if repo != nil {
+			r, e := c.internalCalc.calculateGit(ctx, c.root, repo)
+			ch <- res{dr: r, err: e}
+			return
+		}
 		r, e := c.internalCalc.CalculateDirectory(ctx, c.root)
 		ch <- res{dr: r, err: e}
 	}()
@@ -160,6 +203,18 @@ func (c *ProjectCalculator) Calculate(ctx context.Context) (*Digest, error) {
 		})
 	}
 
+	for name, path := range c.options.ExtraFiles {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		hash, err := c.internalCalc.CalculateFile(path)
+		if err != nil {
+			continue
+		}
+		files = append(files, FileDigest{Path: name, Hash: hash, Size: info.Size()})
+	}
+
 	// Sort files for deterministic ordering
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].Path < files[j].Path
@@ -168,22 +223,33 @@ func (c *ProjectCalculator) Calculate(ctx context.Context) (*Digest, error) {
 	// Calculate final combined hash
 	finalHash := c.calculateCombinedHash(files)
 
-	return &Digest{
+	d := &Digest{
 		Hash:      finalHash,
 		Algorithm: c.options.Algorithm,
 		Timestamp: time.Now().UTC(),
 		FileCount: len(files),
 		TotalSize: result.TotalSize,
 		Files:     files,
-		Options:   c.options,
-	}, nil
+		Options:   options,
+	}
+	if !c.options.NoCache {
+		stats := result.Cache
+		d.Cache = &stats
+	}
+	return d, nil
 }
 
 // filterFiles applies the configured file filters to the result set.
 func (c *ProjectCalculator) filterFiles(files []CalcFileInfo) []CalcFileInfo {
+	if len(c.options.Paths) > 0 {
+		files = c.filterPaths(files)
+	}
 	if c.options.LockfilesOnly {
 		return c.filterLockfilesOnly(files)
 	}
+	if c.options.DependenciesOnly {
+		return c.filterDependenciesOnly(files)
+	}
 
 	filtered := make([]CalcFileInfo, 0, len(files))
 	for _, file := range files {
@@ -194,27 +260,45 @@ func (c *ProjectCalculator) filterFiles(files []CalcFileInfo) []CalcFileInfo {
 	return filtered
 }
 
+// filterPaths keeps root-level files and files under Paths. Root-level
+// files (lockfiles, workspace and tool configuration) are shared by every
+// package, so they always count.
+func (c *ProjectCalculator) filterPaths(files []CalcFileInfo) []CalcFileInfo {
+	filtered := make([]CalcFileInfo, 0, len(files))
+	for _, file := range files {
+		path := filepath.ToSlash(file.Path)
+		keep := !strings.Contains(path, "/")
+		for _, dir := range c.options.Paths {
+			dir = strings.TrimSuffix(filepath.ToSlash(dir), "/")
+			if path == dir || strings.HasPrefix(path, dir+"/") {
+				keep = true
+				break
+			}
+		}
+		if keep {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
+
 // filterLockfilesOnly returns only lockfiles from the file list.
 func (c *ProjectCalculator) filterLockfilesOnly(files []CalcFileInfo) []CalcFileInfo {
-	// Known lockfile patterns
-	lockfileNames := map[string]bool{
-		"composer.lock":     true,
-		"package-lock.json": true,
-		"pnpm-lock.yaml":    true,
-		"yarn.lock":         true,
-		"go.sum":            true,
-		"go.mod":            true,
-		"Gemfile.lock":      true,
-		"requirements.txt":  true,
-		"poetry.lock":       true,
-		"Pipfile.lock":      true,
-		"Cargo.lock":        true,
+	filtered := make([]CalcFileInfo, 0)
+	for _, file := range files {
+		if isLockfile(filepath.Base(file.Path)) {
+			filtered = append(filtered, file)
+		}
 	}
+	return filtered
+}
 
+// filterDependenciesOnly returns only lockfiles and dependency manifests
+// from the file list.
+func (c *ProjectCalculator) filterDependenciesOnly(files []CalcFileInfo) []CalcFileInfo {
 	filtered := make([]CalcFileInfo, 0)
 	for _, file := range files {
-		filename := filepath.Base(file.Path)
-		if lockfileNames[filename] {
+		if IsDependencyFile(filepath.Base(file.Path)) {
 			filtered = append(filtered, file)
 		}
 	}
@@ -289,6 +373,11 @@ func (o *Options) Validate() error {
 	default:
 		return fmt.Errorf("unsupported algorithm: %s", o.Algorithm)
 	}
+	switch o.Mode {
+	case "", ModeWalk, ModeGit:
+	default:
+		return fmt.Errorf("unsupported digest mode: %s", o.Mode)
+	}
 	return nil
 }
 
@@ -0,0 +1,87 @@
+package digest
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Change groups of an Explanation, in the order they are reported
+const (
+	GroupLockfile   = "lockfile"
+	GroupManifest   = "manifest"
+	GroupDockerfile = "dockerfile"
+	GroupSource     = "source"
+)
+
+// Groups lists the change groups in report order
+var Groups = []string{GroupLockfile, GroupManifest, GroupDockerfile, GroupSource}
+
+// FileChange is one added, modified or removed digest input
+type FileChange struct {
+	Path     string       `json:"path"`
+	Change   string       `json:"change"`             // "added", "modified" or "removed"
+	Packages *PackageDiff `json:"packages,omitempty"` // lockfiles whose packages are known
+}
+
+// Explanation tells why two capsules differ: their digest comparison with
+// the changed inputs grouped by what they are
+type Explanation struct {
+	Old        *CapsuleRecord          `json:"old"`
+	New        *CapsuleRecord          `json:"new"`
+	Comparison *Comparison             `json:"comparison"`
+	Changes    map[string][]FileChange `json:"changes"` // by group
+}
+
+// Explain compares the capsule built from old with the one built from new
+func Explain(old, newRecord *CapsuleRecord) *Explanation {
+	x := &Explanation{
+		Old:        old,
+		New:        newRecord,
+		Comparison: Compare(old.Digest, newRecord.Digest),
+		Changes:    map[string][]FileChange{},
+	}
+	add := func(paths []string, change string) {
+		for _, p := range paths {
+			fc := FileChange{Path: p, Change: change}
+			oldSet, hadOld := old.Packages[p]
+			newSet, hasNew := newRecord.Packages[p]
+			if hadOld || hasNew {
+				fc.Packages = DiffPackages(oldSet, newSet)
+			}
+			group := ChangeGroup(p)
+			x.Changes[group] = append(x.Changes[group], fc)
+		}
+	}
+	add(x.Comparison.Added, "added")
+	add(x.Comparison.Modified, "modified")
+	add(x.Comparison.Removed, "removed")
+	for _, changes := range x.Changes {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	}
+	return x
+}
+
+// ChangeGroup classifies a digest input. Inputs recorded as "<name>" are
+// not project files: the Dockerfile and build source count as Dockerfile
+// changes, the detector's view of the project as a manifest change.
+func ChangeGroup(path string) string {
+	switch path {
+	case "<dockerfile>", "<source>":
+		return GroupDockerfile
+	case "<detector>":
+		return GroupManifest
+	}
+	base := filepath.Base(path)
+	switch {
+	case base == "Dockerfile" || base == "Containerfile" || strings.HasSuffix(base, ".Dockerfile") ||
+		strings.HasSuffix(base, ".Containerfile") || strings.HasSuffix(base, ".Dockerfile.tmpl") ||
+		base == ".dockerignore" || base == ".containerignore":
+		return GroupDockerfile
+	case base == "mitl.yaml" || base == "mitl.yml" || base == "mitl.lock" || dependencyManifests[base]:
+		return GroupManifest
+	case isLockfile(base):
+		return GroupLockfile
+	}
+	return GroupSource
+}
@@ -0,0 +1,181 @@
+package digest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	lockV1 = `{"lockfileVersion":3,"packages":{"":{"name":"app"},"node_modules/react":{"version":"18.2.0"},"node_modules/left-pad":{"version":"1.3.0"},"node_modules/lodash":{"version":"4.17.21"},"node_modules/a/node_modules/lodash":{"version":"3.10.1"}}}`
+	lockV2 = `{"lockfileVersion":3,"packages":{"":{"name":"app"},"node_modules/react":{"version":"18.3.1"},"node_modules/lodash":{"version":"4.17.20"},"node_modules/zod":{"version":"3.23.8"},"node_modules/a/node_modules/lodash":{"version":"3.10.1"}}}`
+)
+
+func TestExplain_GroupsChanges(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record := func(dockerfile string) *CapsuleRecord {
+		d, err := DependencyDigest(dir, &Options{Algorithm: "sha256", NoCache: true}, map[string][]byte{"dockerfile": []byte(dockerfile)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewCapsuleRecord(dir, "", d.Hash[:12], d)
+	}
+	write("package.json", `{"name":"app"}`)
+	write("package-lock.json", lockV1)
+	write("src/index.js", "console.log(1)")
+	old := record("FROM node:20")
+
+	write("package.json", `{"name":"app","private":true}`)
+	write("package-lock.json", lockV2)
+	write("src/index.js", "console.log(2)")
+	x := Explain(old, record("FROM node:22"))
+
+	if x.Comparison.Identical {
+		t.Fatal("expected changes")
+	}
+	if got := x.Changes[GroupDockerfile]; len(got) != 1 || got[0].Path != "<dockerfile>" {
+		t.Fatalf("dockerfile changes = %+v", got)
+	}
+	if got := x.Changes[GroupManifest]; len(got) != 1 || got[0].Path != "package.json" {
+		t.Fatalf("manifest changes = %+v", got)
+	}
+	if len(x.Changes[GroupSource]) != 0 {
+		t.Fatalf("the dependency digest doesn't cover source files: %+v", x.Changes[GroupSource])
+	}
+	lock := x.Changes[GroupLockfile]
+	if len(lock) != 1 || lock[0].Packages == nil {
+		t.Fatalf("lockfile changes = %+v", lock)
+	}
+	p := lock[0].Packages
+	if len(p.Added) != 1 || p.Added[0].Name != "zod" ||
+		len(p.Removed) != 1 || p.Removed[0].Name != "left-pad" ||
+		len(p.Upgraded) != 1 || p.Upgraded[0] != (PackageChange{Name: "react", From: "18.2.0", To: "18.3.1"}) ||
+		len(p.Downgraded) != 1 || p.Downgraded[0].Name != "lodash" {
+		t.Fatalf("package diff = %+v", p)
+	}
+}
+
+func TestChangeGroup(t *testing.T) {
+	tests := map[string]string{
+		"<source>":                             GroupDockerfile,
+		"<detector>":                           GroupManifest,
+		"docker/dev.Dockerfile":                GroupDockerfile,
+		".mitl/templates/node.Dockerfile.tmpl": GroupDockerfile,
+		"mitl.lock":                            GroupManifest,
+		"web/package.json":                     GroupManifest,
+		"web/pnpm-lock.yaml":                   GroupLockfile,
+		"go.sum":                               GroupLockfile,
+		"main.go":                              GroupSource,
+	}
+	for path, want := range tests {
+		if got := ChangeGroup(path); got != want {
+			t.Errorf("ChangeGroup(%q) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestCapsuleHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	project := t.TempDir()
+	if recs, err := ProjectHistory(project, ""); err != nil || len(recs) != 0 {
+		t.Fatalf("expected no history, got %v, %v", recs, err)
+	}
+	d := &Digest{Hash: strings.Repeat("a", 64)}
+	older := NewCapsuleRecord(project, "", "mitl-capsule:aaaaaaaaaaaa", d)
+	older.BuiltAt = time.Now().Add(-time.Hour)
+	newer := NewCapsuleRecord(project, "", "bbbbbbbbbbbb", d)
+	other := NewCapsuleRecord(project, "web", "cccccccccccc", d)
+	for _, rec := range []*CapsuleRecord{older, newer, other} {
+		if err := SaveCapsuleRecord(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recs, err := ProjectHistory(project, "")
+	if err != nil || len(recs) != 2 || recs[0].Tag != "bbbbbbbbbbbb" || recs[1].Tag != "aaaaaaaaaaaa" {
+		t.Fatalf("history = %+v, %v", recs, err)
+	}
+	if rec, err := LoadCapsuleRecord(project, "web", "mitl-capsule:cccccccccccc"); err != nil || rec.Package != "web" {
+		t.Fatalf("load = %+v, %v", rec, err)
+	}
+	if _, err := LoadCapsuleRecord(project, "", "../x"); err == nil {
+		t.Fatal("expected an invalid tag error")
+	}
+
+	// Another checkout building the same capsule keeps its own record
+	clone := t.TempDir()
+	if err := SaveCapsuleRecord(NewCapsuleRecord(clone, "", "bbbbbbbbbbbb", d)); err != nil {
+		t.Fatal(err)
+	}
+	if rec, err := LoadCapsuleRecord(project, "", "bbbbbbbbbbbb"); err != nil || rec.Project != project {
+		t.Fatalf("load = %+v, %v", rec, err)
+	}
+}
+
+func TestCapsuleHistory_Prunes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	project := t.TempDir()
+	d := &Digest{Hash: strings.Repeat("a", 64)}
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < HistoryLimit+3; i++ {
+		rec := NewCapsuleRecord(project, "", fmt.Sprintf("%012d", i), d)
+		rec.BuiltAt = start.Add(time.Duration(i) * time.Minute)
+		if err := SaveCapsuleRecord(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recs, err := ProjectHistory(project, "")
+	if err != nil || len(recs) != HistoryLimit {
+		t.Fatalf("expected %d records, got %d (%v)", HistoryLimit, len(recs), err)
+	}
+	if recs[len(recs)-1].Tag != "000000000003" {
+		t.Fatalf("expected the oldest builds to be pruned, oldest kept is %s", recs[len(recs)-1].Tag)
+	}
+}
+
+func TestCapsuleHistory_MigratesTagKeyedRecords(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	project := t.TempDir()
+	rec := NewCapsuleRecord(project, "", "dddddddddddd", &Digest{Hash: strings.Repeat("d", 64)})
+	data, _ := json.Marshal(rec)
+	os.MkdirAll(HistoryDir(), 0o755)
+	os.WriteFile(filepath.Join(HistoryDir(), "dddddddddddd.json"), data, 0o600)
+
+	recs, err := ProjectHistory(project, "")
+	if err != nil || len(recs) != 1 || recs[0].Tag != "dddddddddddd" {
+		t.Fatalf("history = %+v, %v", recs, err)
+	}
+	if _, err := os.Stat(filepath.Join(HistoryDir(), "dddddddddddd.json")); !os.IsNotExist(err) {
+		t.Fatal("expected the record to move into the project's directory")
+	}
+}
+
+func TestCapsuleRecord_ReadsFormat1Packages(t *testing.T) {
+	old := `{"tag":"aaaaaaaaaaaa","project":"/p","built_at":"2026-01-02T00:00:00Z","digest":{"hash":"` + strings.Repeat("a", 64) + `"},
+		"packages":{"package-lock.json":{"react":"18.2.0","left-pad":"1.3.0"}}}`
+	var rec CapsuleRecord
+	if err := json.Unmarshal([]byte(old), &rec); err != nil {
+		t.Fatal(err)
+	}
+	set := rec.Packages["package-lock.json"]
+	if rec.Format != RecordFormat || set == nil || set.Ecosystem != EcosystemNPM || pinned(set) != "left-pad=1.3.0 react=18.2.0" {
+		t.Fatalf("record = %+v, packages = %+v", rec, set)
+	}
+
+	// Current records round-trip
+	data, _ := json.Marshal(&rec)
+	var again CapsuleRecord
+	if err := json.Unmarshal(data, &again); err != nil || pinned(again.Packages["package-lock.json"]) != pinned(set) {
+		t.Fatalf("round trip = %+v, %v", again, err)
+	}
+}
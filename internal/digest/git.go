@@ -0,0 +1,359 @@
+package digest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Digest modes choose how ProjectCalculator finds and hashes files
+const (
+	ModeWalk = "walk" // walk the tree and hash every file (default)
+	ModeGit  = "git"  // use the git index where the project is a checkout
+)
+
+// gitEntry is a stage-0 file in the git index
+type gitEntry struct {
+	oid       string // blob object ID, hex
+	size      uint32
+	mtimeSec  uint32
+	mtimeNsec uint32
+	regular   bool // a regular file, not a symlink or submodule
+}
+
+// gitRepo is a checkout's index as seen from a project root inside it
+type gitRepo struct {
+	workTree   string
+	prefix     string              // root relative to the work tree, slash-separated; "" at the top
+	entries    map[string]gitEntry // tracked files by root-relative path
+	dirs       map[string]bool     // root-relative directories holding tracked files
+	indexMTime time.Time
+	ignore     *IgnoreRules // .gitignore rules, matched against work-tree paths
+}
+
+// openGitRepo reads the git index of the checkout enclosing root. It
+// returns nil when root is not inside a work tree or the index can't be
+// read, so callers fall back to walking the tree. The git binary is not
+// used.
+func openGitRepo(root string) *gitRepo {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	workTree, gitDir := findGitDir(abs)
+	if gitDir == "" {
+		return nil
+	}
+	indexPath := filepath.Join(gitDir, "index")
+	info, err := os.Stat(indexPath)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil
+	}
+	entries, err := parseGitIndex(data, gitHashSize(gitDir))
+	if err != nil {
+		return nil
+	}
+	prefix, err := filepath.Rel(workTree, abs)
+	if err != nil {
+		return nil
+	}
+	prefix = filepath.ToSlash(prefix)
+	if prefix == "." {
+		prefix = ""
+	}
+
+	repo := &gitRepo{
+		workTree:   workTree,
+		prefix:     prefix,
+		entries:    map[string]gitEntry{},
+		dirs:       map[string]bool{},
+		indexMTime: info.ModTime(),
+		ignore:     &IgnoreRules{cache: make(map[string]bool)},
+	}
+	for name, e := range entries {
+		rel := name
+		if prefix != "" {
+			if !strings.HasPrefix(name, prefix+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(name, prefix+"/")
+		}
+		repo.entries[rel] = e
+		for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+			repo.dirs[dir] = true
+		}
+	}
+
+	// Rules above root apply too: info/exclude, then .gitignore files from
+	// the top of the work tree down to root
+	if excl, err := os.ReadFile(filepath.Join(gitDir, "info", "exclude")); err == nil {
+		addGitignore(repo.ignore, "", excl)
+	}
+	repo.loadGitignore("")
+	if prefix != "" {
+		dir := ""
+		for _, part := range strings.Split(prefix, "/") {
+			dir = path.Join(dir, part)
+			repo.loadGitignore(dir)
+		}
+	}
+	return repo
+}
+
+// findGitDir returns the work tree enclosing dir and its git directory.
+// Linked worktrees and submodules have a .git file pointing elsewhere.
+func findGitDir(dir string) (workTree, gitDir string) {
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return dir, dotGit
+			}
+			data, rerr := os.ReadFile(dotGit)
+			if rerr != nil {
+				return "", ""
+			}
+			target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+			if !ok {
+				return "", ""
+			}
+			target = strings.TrimSpace(target)
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(dir, target)
+			}
+			return dir, target
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// gitHashSize is 32 for SHA-256 repositories, 20 otherwise. Linked
+// worktrees keep their config in the common directory.
+func gitHashSize(gitDir string) int {
+	configDir := gitDir
+	if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		c := strings.TrimSpace(string(common))
+		if !filepath.IsAbs(c) {
+			c = filepath.Join(gitDir, c)
+		}
+		configDir = c
+	}
+	f, err := os.Open(filepath.Join(configDir, "config"))
+	if err != nil {
+		return 20
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "objectformat") && strings.TrimSpace(value) == "sha256" {
+			return 32
+		}
+	}
+	return 20
+}
+
+// parseGitIndex reads the stage-0 entries of an index file (versions 2
+// to 4). Extensions are skipped. Entries in a merge conflict are left out,
+// so their files are hashed from disk.
+func parseGitIndex(data []byte, hashSize int) (map[string]gitEntry, error) {
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("not a git index")
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported git index version %d", version)
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+	entries := make(map[string]gitEntry, count)
+	off := 12
+	prev := ""
+	// ctime, mtime, dev, ino, mode, uid, gid and size are 32-bit fields
+	fixed := 40 + hashSize + 2
+	for i := uint32(0); i < count; i++ {
+		start := off
+		if off+fixed > len(data) {
+			return nil, fmt.Errorf("truncated git index")
+		}
+		field := func(n int) uint32 { return binary.BigEndian.Uint32(data[off+4*n:]) }
+		mode := field(6)
+		e := gitEntry{
+			mtimeSec:  field(2),
+			mtimeNsec: field(3),
+			size:      field(9),
+			oid:       hex.EncodeToString(data[off+40 : off+40+hashSize]),
+			regular:   mode&0o170000 == 0o100000,
+		}
+		flags := binary.BigEndian.Uint16(data[off+40+hashSize:])
+		off += fixed
+		if flags&0x4000 != 0 && version >= 3 {
+			off += 2 // extended flags
+		}
+
+		var name string
+		if version == 4 {
+			// The name drops a number of trailing bytes of the previous
+			// name, then appends a NUL-terminated suffix
+			strip, n := binary.Uvarint(data[off:])
+			if n <= 0 || int(strip) > len(prev) {
+				return nil, fmt.Errorf("corrupt git index entry")
+			}
+			off += n
+			end := bytes.IndexByte(data[off:], 0)
+			if end < 0 {
+				return nil, fmt.Errorf("truncated git index")
+			}
+			name = prev[:len(prev)-int(strip)] + string(data[off:off+end])
+			off += end + 1
+		} else {
+			end := bytes.IndexByte(data[off:], 0)
+			if end < 0 {
+				return nil, fmt.Errorf("truncated git index")
+			}
+			name = string(data[off : off+end])
+			// Entries are NUL-padded to a multiple of eight bytes
+			off = start + (off-start+end+8)&^7
+		}
+		prev = name
+		if stage := (flags >> 12) & 0x3; stage == 0 {
+			entries[name] = e
+		} else {
+			delete(entries, name)
+		}
+	}
+	return entries, nil
+}
+
+// addGitignore adds the rules of the .gitignore in dir (work-tree
+// relative, "" for the top) to rules, rewritten to match work-tree paths.
+// Deeper files are added later, so their rules win, as in git.
+func addGitignore(rules *IgnoreRules, dir string, content []byte) {
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(line, "!")
+		line = strings.TrimPrefix(line, "!")
+		line = strings.TrimPrefix(line, `\`) // \# and \! escapes
+		dirOnly := strings.HasSuffix(line, "/")
+		line = strings.TrimSuffix(line, "/")
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+
+		var patterns []string
+		switch {
+		case dir == "" && anchored:
+			patterns = []string{"/" + line}
+		case dir == "":
+			patterns = []string{line}
+		case anchored:
+			patterns = []string{"/" + dir + "/" + line}
+		default:
+			patterns = []string{"/" + dir + "/" + line, "/" + dir + "/**/" + line}
+		}
+		for _, p := range patterns {
+			if dirOnly {
+				p += "/"
+			}
+			if negate {
+				p = "!" + p
+			}
+			_ = rules.AddPattern(p)
+		}
+	}
+}
+
+// loadGitignore adds dir's .gitignore, dir being work-tree relative
+func (g *gitRepo) loadGitignore(dir string) {
+	if data, err := os.ReadFile(filepath.Join(g.workTree, filepath.FromSlash(dir), ".gitignore")); err == nil {
+		addGitignore(g.ignore, dir, data)
+	}
+}
+
+// ignored reports whether git ignores the root-relative path
+func (g *gitRepo) ignored(rel string, isDir bool) bool {
+	return g.ignore.ShouldIgnore(path.Join(g.prefix, rel), isDir)
+}
+
+// clean reports whether a tracked file still has the content of its blob:
+// size and modification time match the index and the entry is not racy,
+// i.e. the file was not changed in the same instant the index was written.
+func (g *gitRepo) clean(e gitEntry, info os.FileInfo) bool {
+	if !e.regular || uint32(info.Size()) != e.size {
+		return false
+	}
+	mtime := info.ModTime()
+	if uint32(mtime.Unix()) != e.mtimeSec || (e.mtimeNsec != 0 && uint32(mtime.Nanosecond()) != e.mtimeNsec) {
+		return false
+	}
+	return mtime.Before(g.indexMTime)
+}
+
+// collectGitFiles walks rootDir like collectFiles, but skips directories
+// git ignores unless they hold tracked files, and files git ignores unless
+// they are tracked. It returns the clean tracked files' blob IDs by path.
+func (c *Calculator) collectGitFiles(rootDir string, repo *gitRepo) ([]string, map[string]string, error) {
+	var files []string
+	oids := map[string]string{}
+	err := filepath.WalkDir(rootDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(rootDir, p)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		rel := filepath.ToSlash(relPath)
+		if c.ignoreRules.ShouldIgnore(relPath, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if rel == "." {
+				return nil
+			}
+			if repo.ignored(rel, true) && !repo.dirs[rel] {
+				return filepath.SkipDir
+			}
+			repo.loadGitignore(path.Join(repo.prefix, rel))
+			return nil
+		}
+		if !d.Type().IsRegular() || (c.selectFile != nil && !c.selectFile(relPath)) {
+			return nil
+		}
+		e, tracked := repo.entries[rel]
+		if !tracked && repo.ignored(rel, false) {
+			return nil
+		}
+		files = append(files, p)
+		if tracked {
+			if info, ierr := d.Info(); ierr == nil && repo.clean(e, info) {
+				oids[p] = e.oid
+			}
+		}
+		return nil
+	})
+	return files, oids, err
+}
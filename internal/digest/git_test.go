@@ -0,0 +1,203 @@
+package digest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gitRepoFixture creates a checkout with tracked, untracked, ignored and
+// force-added files
+func gitRepoFixture(t *testing.T) (dir string, git func(args ...string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir = t.TempDir()
+	git = func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	for name, content := range map[string]string{
+		".gitignore":     "/dist/\n*.log\n",
+		"a.txt":          "alpha\n",
+		"src/b.txt":      "bravo\r\n",
+		"src/.gitignore": "tmp\n",
+		"keep.log":       "tracked despite *.log\n",
+		"dist/out.js":    "built\n",
+		"src/x/tmp":      "scratch\n",
+		"src/x/c.txt":    "charlie\n",
+		"notes.md":       "untracked\n",
+		"logo.png":       "\x89PNG\r\n",
+		"debug.log":      "ignored\n",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "-q")
+	git("add", ".gitignore", "a.txt", "src", "logo.png")
+	git("add", "-f", "keep.log")
+	git("commit", "-q", "-m", "init")
+	return dir, git
+}
+
+func digestPaths(d *Digest) string {
+	paths := make([]string, 0, len(d.Files))
+	for _, f := range d.Files {
+		paths = append(paths, filepath.ToSlash(f.Path))
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
+
+func TestGitMode_HonorsGitignore(t *testing.T) {
+	dir, _ := gitRepoFixture(t)
+	opts := &Options{Algorithm: "sha256", IncludeHidden: true, Mode: ModeGit}
+	d, err := NewProjectCalculator(dir, opts).Calculate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Options.Mode != ModeGit {
+		t.Fatalf("expected git mode, got %q", d.Options.Mode)
+	}
+	want := ".gitignore,a.txt,keep.log,notes.md,src/.gitignore,src/b.txt,src/x/c.txt"
+	if got := digestPaths(d); got != want {
+		t.Fatalf("files = %s, want %s", got, want)
+	}
+
+	// The walk hashes ignored files too, so the modes differ while there
+	// are any and agree once they are gone
+	walk := func() *Digest {
+		w, err := NewProjectCalculator(dir, &Options{Algorithm: "sha256", IncludeHidden: true, NoCache: true}).Calculate(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+	if w := walk(); w.Hash == d.Hash || !strings.Contains(digestPaths(w), "dist/out.js") {
+		t.Fatalf("expected the walk to hash ignored files: %s", digestPaths(w))
+	}
+	for _, name := range []string{"dist", "src/x/tmp", "debug.log"} {
+		os.RemoveAll(filepath.Join(dir, filepath.FromSlash(name)))
+	}
+	if w := walk(); w.Hash != d.Hash {
+		t.Fatalf("git and walk digests differ: %s vs %s", d.Hash, w.Hash)
+	}
+}
+
+func TestGitMode_IgnoredDirectoryWithTrackedFiles(t *testing.T) {
+	dir, git := gitRepoFixture(t)
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("build/\n"), 0o644)
+	os.MkdirAll(filepath.Join(dir, "build"), 0o755)
+	os.WriteFile(filepath.Join(dir, "build", "keep.txt"), []byte("tracked\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "build", "out.o"), []byte("object\n"), 0o644)
+	git("add", "-f", "build/keep.txt")
+
+	d, err := NewProjectCalculator(dir, &Options{Algorithm: "sha256", Mode: ModeGit}).Calculate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := digestPaths(d)
+	if !strings.Contains(got, "build/keep.txt") || strings.Contains(got, "build/out.o") {
+		t.Fatalf("expected only the tracked file under build/: %s", got)
+	}
+}
+
+func TestGitMode_ReusesBlobHashes(t *testing.T) {
+	old := racyWindow
+	racyWindow = 0
+	defer func() { racyWindow = old }()
+
+	dir, git := gitRepoFixture(t)
+	calc := func() *Digest {
+		d, err := NewProjectCalculator(dir, &Options{Algorithm: "sha256", Mode: ModeGit}).Calculate(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	first := calc()
+
+	// Rewriting a file with the same content, as a checkout does, changes
+	// its stamp but not its blob
+	past := time.Now().Add(-time.Hour)
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("alpha\n"), 0o644)
+	os.Chtimes(filepath.Join(dir, "a.txt"), past, past)
+	time.Sleep(10 * time.Millisecond)
+	git("update-index", "--refresh")
+	second := calc()
+	if second.Hash != first.Hash || second.Cache.Misses != 0 {
+		t.Fatalf("expected the blob hash to be reused: %+v", second.Cache)
+	}
+
+	// A dirty tracked file is hashed from disk
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("ALPHA\n"), 0o644)
+	third := calc()
+	if third.Hash == first.Hash || third.Cache.Misses != 1 {
+		t.Fatalf("an edit must be rehashed: %+v", third.Cache)
+	}
+}
+
+func TestGitMode_FallsBackToWalk(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("alpha"), 0o644)
+	d, err := NewProjectCalculator(dir, &Options{Algorithm: "sha256", Mode: ModeGit}).Calculate(context.Background())
+	if err != nil || d.Options.Mode != ModeWalk || d.FileCount != 1 {
+		t.Fatalf("expected a walk outside a checkout: %+v, %v", d, err)
+	}
+	if err := (&Options{Mode: "svn"}).Validate(); err == nil {
+		t.Fatal("expected an unsupported mode error")
+	}
+}
+
+func TestGitMode_Subdirectory(t *testing.T) {
+	dir, _ := gitRepoFixture(t)
+	d, err := NewProjectCalculator(filepath.Join(dir, "src"), &Options{Algorithm: "sha256", IncludeHidden: true, Mode: ModeGit}).Calculate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := digestPaths(d); got != ".gitignore,b.txt,x/c.txt" {
+		t.Fatalf("files = %s", got)
+	}
+}
+
+func TestParseGitIndex_Versions(t *testing.T) {
+	dir, git := gitRepoFixture(t)
+	for _, version := range []string{"2", "3", "4"} {
+		git("update-index", "--index-version", version)
+		data, err := os.ReadFile(filepath.Join(dir, ".git", "index"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err := parseGitIndex(data, 20)
+		if err != nil {
+			t.Fatalf("v%s: %v", version, err)
+		}
+		names := make([]string, 0, len(entries))
+		for name := range entries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if got := strings.Join(names, ","); got != ".gitignore,a.txt,keep.log,logo.png,src/.gitignore,src/b.txt,src/x/c.txt" {
+			t.Fatalf("v%s entries = %s", version, got)
+		}
+		if e := entries["a.txt"]; !e.regular || e.size != 6 || len(e.oid) != 40 {
+			t.Fatalf("v%s a.txt = %+v", version, e)
+		}
+	}
+	if _, err := parseGitIndex([]byte("DIRC\x00\x00\x00\x09\x00\x00\x00\x00"), 20); err == nil {
+		t.Fatal("expected an unsupported version error")
+	}
+}
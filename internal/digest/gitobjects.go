@@ -0,0 +1,612 @@
+package digest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// gitObjects reads commits, trees and blobs straight from a repository's
+// object store, loose or packed, like openGitRepo reads the index: no git
+// binary is needed.
+type gitObjects struct {
+	gitDir    string // per-worktree: HEAD
+	commonDir string // shared: objects, refs, packed-refs
+	hashSize  int
+	packs     []*gitPack
+}
+
+// gitPack is a pack file and its version 2 index
+type gitPack struct {
+	path    string
+	names   []byte // sorted object IDs, hashSize bytes each
+	offsets []uint64
+}
+
+// ReadGitFile returns the content of path at rev in the checkout enclosing
+// dir. rev is anything git rev-parse takes for a commit in the common
+// forms: a branch, tag or remote branch name, HEAD, a full or abbreviated
+// object ID, each optionally followed by ~N or ^N. path is relative to dir;
+// the error wraps os.ErrNotExist when rev has no such file.
+func ReadGitFile(dir, rev, path string) ([]byte, error) {
+	objs, prefix, err := openGitObjects(dir)
+	if err != nil {
+		return nil, err
+	}
+	return objs.readFile(rev, joinGitPath(prefix, path))
+}
+
+// ReadGitFiles is ReadGitFile for several paths at one rev. Paths rev has
+// no file at are left out of the result.
+func ReadGitFiles(dir, rev string, paths []string) (map[string][]byte, error) {
+	objs, prefix, err := openGitObjects(dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := objs.resolve(rev); err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	for _, p := range paths {
+		data, err := objs.readFile(rev, joinGitPath(prefix, p))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files[p] = data
+	}
+	return files, nil
+}
+
+// openGitObjects opens the object store of the checkout enclosing dir and
+// returns dir's path inside the work tree
+func openGitObjects(dir string) (*gitObjects, string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	workTree, gitDir := findGitDir(abs)
+	if gitDir == "" {
+		return nil, "", fmt.Errorf("%s is not in a git checkout", dir)
+	}
+	prefix, err := filepath.Rel(workTree, abs)
+	if err != nil {
+		return nil, "", err
+	}
+	objs := &gitObjects{gitDir: gitDir, commonDir: gitDir, hashSize: gitHashSize(gitDir)}
+	if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		c := strings.TrimSpace(string(common))
+		if !filepath.IsAbs(c) {
+			c = filepath.Join(gitDir, c)
+		}
+		objs.commonDir = c
+	}
+	idxs, _ := filepath.Glob(filepath.Join(objs.commonDir, "objects", "pack", "*.idx"))
+	for _, idx := range idxs {
+		if p, err := loadGitPack(idx, objs.hashSize); err == nil {
+			objs.packs = append(objs.packs, p)
+		}
+	}
+	return objs, filepath.ToSlash(prefix), nil
+}
+
+// joinGitPath joins a work-tree prefix and a path relative to it
+func joinGitPath(prefix, p string) string {
+	joined := path.Join(prefix, filepath.ToSlash(p))
+	if joined == "." {
+		return ""
+	}
+	return joined
+}
+
+// readFile returns the blob at the work-tree path p in rev's tree
+func (g *gitObjects) readFile(rev, p string) ([]byte, error) {
+	oid, err := g.resolve(rev)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := g.commitTree(oid)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(p, "/") {
+		if name == "" {
+			continue
+		}
+		if tree, err = g.treeEntry(tree, name); err != nil {
+			return nil, fmt.Errorf("%s:%s: %w", rev, p, err)
+		}
+	}
+	kind, data, err := g.object(tree)
+	if err != nil {
+		return nil, err
+	}
+	if kind != "blob" {
+		return nil, fmt.Errorf("%s:%s is a %s, not a file", rev, p, kind)
+	}
+	return data, nil
+}
+
+// resolve turns a revision into a commit ID
+func (g *gitObjects) resolve(rev string) (string, error) {
+	base, suffix := rev, ""
+	if i := strings.IndexAny(rev, "~^"); i > 0 {
+		base, suffix = rev[:i], rev[i:]
+	}
+	oid, err := g.resolveName(base)
+	if err != nil {
+		return "", err
+	}
+	if oid, err = g.peel(oid); err != nil {
+		return "", err
+	}
+	for suffix != "" {
+		op := suffix[0]
+		suffix = suffix[1:]
+		digits := 0
+		for digits < len(suffix) && suffix[digits] >= '0' && suffix[digits] <= '9' {
+			digits++
+		}
+		n := 1
+		if digits > 0 {
+			n, _ = strconv.Atoi(suffix[:digits])
+			suffix = suffix[digits:]
+		}
+		if op == '~' {
+			for ; n > 0; n-- {
+				if oid, err = g.parent(oid, 1); err != nil {
+					return "", fmt.Errorf("%s: %w", rev, err)
+				}
+			}
+		} else if op == '^' {
+			if n == 0 {
+				continue
+			}
+			if oid, err = g.parent(oid, n); err != nil {
+				return "", fmt.Errorf("%s: %w", rev, err)
+			}
+		} else {
+			return "", fmt.Errorf("unsupported revision %q", rev)
+		}
+	}
+	return oid, nil
+}
+
+// resolveName looks a name up as git does: HEAD, a full object ID, a ref
+// under refs/, refs/tags, refs/heads or refs/remotes, then an abbreviated
+// object ID
+func (g *gitObjects) resolveName(name string) (string, error) {
+	if name == "HEAD" || name == "@" {
+		return g.readRef(g.gitDir, "HEAD", 0)
+	}
+	if len(name) == 2*g.hashSize && isHex(name) {
+		return strings.ToLower(name), nil
+	}
+	for _, ref := range []string{name, "refs/" + name, "refs/tags/" + name, "refs/heads/" + name, "refs/remotes/" + name, "refs/remotes/" + name + "/HEAD"} {
+		if oid, err := g.readRef(g.commonDir, ref, 0); err == nil {
+			return oid, nil
+		}
+	}
+	if len(name) >= 4 && isHex(name) {
+		return g.expand(strings.ToLower(name))
+	}
+	return "", fmt.Errorf("unknown revision %q", name)
+}
+
+// readRef reads a loose or packed ref, following symbolic refs
+func (g *gitObjects) readRef(dir, ref string, depth int) (string, error) {
+	if depth > 5 {
+		return "", fmt.Errorf("symbolic ref loop at %s", ref)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref))); err == nil {
+		value := strings.TrimSpace(string(data))
+		if target, ok := strings.CutPrefix(value, "ref:"); ok {
+			return g.readRef(g.commonDir, strings.TrimSpace(target), depth+1)
+		}
+		if isHex(value) && len(value) == 2*g.hashSize {
+			return value, nil
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(g.commonDir, "packed-refs")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if oid, name, ok := strings.Cut(strings.TrimSpace(line), " "); ok && name == ref {
+				return oid, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unknown ref %s", ref)
+}
+
+// expand finds the one object an abbreviated ID names
+func (g *gitObjects) expand(prefix string) (string, error) {
+	found := map[string]bool{}
+	entries, _ := os.ReadDir(filepath.Join(g.commonDir, "objects", prefix[:2]))
+	for _, e := range entries {
+		if oid := prefix[:2] + e.Name(); strings.HasPrefix(oid, prefix) {
+			found[oid] = true
+		}
+	}
+	for _, p := range g.packs {
+		for i := 0; i < len(p.offsets); i++ {
+			if oid := hex.EncodeToString(p.names[i*g.hashSize : (i+1)*g.hashSize]); strings.HasPrefix(oid, prefix) {
+				found[oid] = true
+			}
+		}
+	}
+	if len(found) > 1 {
+		return "", fmt.Errorf("abbreviated object ID %s is ambiguous", prefix)
+	}
+	for oid := range found {
+		return oid, nil
+	}
+	return "", fmt.Errorf("unknown revision %q", prefix)
+}
+
+// peel follows annotated tags to the object they tag
+func (g *gitObjects) peel(oid string) (string, error) {
+	for i := 0; i < 10; i++ {
+		kind, data, err := g.object(oid)
+		if err != nil {
+			return "", err
+		}
+		if kind != "tag" {
+			return oid, nil
+		}
+		target, ok := headerField(data, "object")
+		if !ok {
+			return "", fmt.Errorf("malformed tag %s", oid)
+		}
+		oid = target
+	}
+	return "", fmt.Errorf("tag chain too long at %s", oid)
+}
+
+// parent returns a commit's nth parent, counting from 1
+func (g *gitObjects) parent(oid string, n int) (string, error) {
+	kind, data, err := g.object(oid)
+	if err != nil {
+		return "", err
+	}
+	if kind != "commit" {
+		return "", fmt.Errorf("%s is a %s, not a commit", oid, kind)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break
+		}
+		if p, ok := strings.CutPrefix(line, "parent "); ok {
+			if n--; n == 0 {
+				return p, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("commit %s has no such parent", oid[:12])
+}
+
+// commitTree returns the tree of a commit
+func (g *gitObjects) commitTree(oid string) (string, error) {
+	kind, data, err := g.object(oid)
+	if err != nil {
+		return "", err
+	}
+	if kind != "commit" {
+		return "", fmt.Errorf("%s is a %s, not a commit", oid, kind)
+	}
+	tree, ok := headerField(data, "tree")
+	if !ok {
+		return "", fmt.Errorf("malformed commit %s", oid)
+	}
+	return tree, nil
+}
+
+// treeEntry returns the object a tree holds under name
+func (g *gitObjects) treeEntry(tree, name string) (string, error) {
+	kind, data, err := g.object(tree)
+	if err != nil {
+		return "", err
+	}
+	if kind != "tree" {
+		return "", fmt.Errorf("not a directory")
+	}
+	// Entries are "<mode> <name>\0<raw object ID>"
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp < 0 || nul < sp || nul+1+g.hashSize > len(data) {
+			return "", fmt.Errorf("malformed tree %s", tree)
+		}
+		if string(data[sp+1:nul]) == name {
+			return hex.EncodeToString(data[nul+1 : nul+1+g.hashSize]), nil
+		}
+		data = data[nul+1+g.hashSize:]
+	}
+	return "", os.ErrNotExist
+}
+
+// headerField returns the first value of a commit or tag header
+func headerField(data []byte, key string) (string, bool) {
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, key+" "); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// object returns an object's type and content
+func (g *gitObjects) object(oid string) (string, []byte, error) {
+	raw, err := hex.DecodeString(oid)
+	if err != nil || len(raw) != g.hashSize {
+		return "", nil, fmt.Errorf("invalid object ID %q", oid)
+	}
+	if f, err := os.Open(filepath.Join(g.commonDir, "objects", oid[:2], oid[2:])); err == nil {
+		defer f.Close()
+		return readLooseObject(f)
+	}
+	for _, p := range g.packs {
+		if off, ok := p.find(raw, g.hashSize); ok {
+			return g.packObject(p, off, 0)
+		}
+	}
+	return "", nil, fmt.Errorf("object %s not found", oid)
+}
+
+// readLooseObject inflates "<type> <size>\0<content>"
+func readLooseObject(r io.Reader) (string, []byte, error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return "", nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, err
+	}
+	nul := bytes.IndexByte(data, 0)
+	if nul < 0 {
+		return "", nil, fmt.Errorf("malformed object")
+	}
+	kind, _, _ := strings.Cut(string(data[:nul]), " ")
+	return kind, data[nul+1:], nil
+}
+
+// loadGitPack reads a version 2 pack index
+func loadGitPack(idxPath string, hashSize int) (*gitPack, error) {
+	data, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8+256*4 || string(data[:4]) != "\xfftOc" || binary.BigEndian.Uint32(data[4:8]) != 2 {
+		return nil, fmt.Errorf("unsupported pack index %s", idxPath)
+	}
+	count := int(binary.BigEndian.Uint32(data[8+255*4:]))
+	namesAt := 8 + 256*4
+	offsetsAt := namesAt + count*hashSize + count*4
+	largeAt := offsetsAt + count*4
+	if largeAt > len(data) {
+		return nil, fmt.Errorf("truncated pack index %s", idxPath)
+	}
+	p := &gitPack{
+		path:    strings.TrimSuffix(idxPath, ".idx") + ".pack",
+		names:   data[namesAt : namesAt+count*hashSize],
+		offsets: make([]uint64, count),
+	}
+	for i := 0; i < count; i++ {
+		off := binary.BigEndian.Uint32(data[offsetsAt+i*4:])
+		if off&0x80000000 == 0 {
+			p.offsets[i] = uint64(off)
+			continue
+		}
+		at := largeAt + int(off&0x7fffffff)*8
+		if at+8 > len(data) {
+			return nil, fmt.Errorf("truncated pack index %s", idxPath)
+		}
+		p.offsets[i] = binary.BigEndian.Uint64(data[at:])
+	}
+	return p, nil
+}
+
+// find returns the pack offset of an object
+func (p *gitPack) find(raw []byte, hashSize int) (uint64, bool) {
+	lo, hi := 0, len(p.offsets)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		switch bytes.Compare(p.names[mid*hashSize:(mid+1)*hashSize], raw) {
+		case 0:
+			return p.offsets[mid], true
+		case -1:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return 0, false
+}
+
+// Pack entry types
+const (
+	packCommit   = 1
+	packTree     = 2
+	packBlob     = 3
+	packTag      = 4
+	packOfsDelta = 6
+	packRefDelta = 7
+)
+
+// packObject reads the object at off, applying deltas to their bases
+func (g *gitObjects) packObject(p *gitPack, off uint64, depth int) (string, []byte, error) {
+	if depth > 64 {
+		return "", nil, fmt.Errorf("delta chain too long in %s", p.path)
+	}
+	f, err := os.Open(p.path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(int64(off), io.SeekStart); err != nil {
+		return "", nil, err
+	}
+	// Reading ahead is fine: nothing else is read from f
+	br := bufio.NewReader(f)
+	b, err := br.ReadByte()
+	if err != nil {
+		return "", nil, err
+	}
+	// The type is in bits 4-6 of the first byte, the size (unused) follows
+	kind := (b >> 4) & 7
+	for b&0x80 != 0 {
+		if b, err = br.ReadByte(); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var baseKind string
+	var base []byte
+	switch kind {
+	case packOfsDelta:
+		b, err = br.ReadByte()
+		if err != nil {
+			return "", nil, err
+		}
+		back := uint64(b & 0x7f)
+		for b&0x80 != 0 {
+			if b, err = br.ReadByte(); err != nil {
+				return "", nil, err
+			}
+			back = (back+1)<<7 | uint64(b&0x7f)
+		}
+		if baseKind, base, err = g.packObject(p, off-back, depth+1); err != nil {
+			return "", nil, err
+		}
+	case packRefDelta:
+		raw := make([]byte, g.hashSize)
+		if _, err := io.ReadFull(br, raw); err != nil {
+			return "", nil, err
+		}
+		if baseKind, base, err = g.object(hex.EncodeToString(raw)); err != nil {
+			return "", nil, err
+		}
+	}
+
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return "", nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, err
+	}
+	switch kind {
+	case packCommit:
+		return "commit", data, nil
+	case packTree:
+		return "tree", data, nil
+	case packBlob:
+		return "blob", data, nil
+	case packTag:
+		return "tag", data, nil
+	case packOfsDelta, packRefDelta:
+		out, err := applyGitDelta(base, data)
+		return baseKind, out, err
+	}
+	return "", nil, fmt.Errorf("unknown pack entry type %d", kind)
+}
+
+// applyGitDelta rebuilds an object from its base and a delta: the base and
+// result sizes, then copy-from-base and insert instructions
+func applyGitDelta(base, delta []byte) ([]byte, error) {
+	varint := func() (uint64, error) {
+		var v uint64
+		for shift := 0; ; shift += 7 {
+			if len(delta) == 0 {
+				return 0, fmt.Errorf("truncated delta")
+			}
+			b := delta[0]
+			delta = delta[1:]
+			v |= uint64(b&0x7f) << shift
+			if b&0x80 == 0 {
+				return v, nil
+			}
+		}
+	}
+	srcSize, err := varint()
+	if err != nil {
+		return nil, err
+	}
+	if srcSize != uint64(len(base)) {
+		return nil, fmt.Errorf("delta base size mismatch")
+	}
+	dstSize, err := varint()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, dstSize)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		if op&0x80 == 0 {
+			n := int(op)
+			if n == 0 || n > len(delta) {
+				return nil, fmt.Errorf("malformed delta")
+			}
+			out = append(out, delta[:n]...)
+			delta = delta[n:]
+			continue
+		}
+		var offset, size uint64
+		for i := 0; i < 7; i++ {
+			if op&(1<<i) == 0 {
+				continue
+			}
+			if len(delta) == 0 {
+				return nil, fmt.Errorf("truncated delta")
+			}
+			if i < 4 {
+				offset |= uint64(delta[0]) << (8 * i)
+			} else {
+				size |= uint64(delta[0]) << (8 * (i - 4))
+			}
+			delta = delta[1:]
+		}
+		if size == 0 {
+			size = 0x10000
+		}
+		if offset+size > uint64(len(base)) {
+			return nil, fmt.Errorf("delta copies past its base")
+		}
+		out = append(out, base[offset:offset+size]...)
+	}
+	if uint64(len(out)) != dstSize {
+		return nil, fmt.Errorf("delta result size mismatch")
+	}
+	return out, nil
+}
+
+// isHex reports whether s is non-empty and all hex digits
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
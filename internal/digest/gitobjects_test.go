@@ -0,0 +1,67 @@
+package digest
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadGitFile(t *testing.T) {
+	dir, git := gitRepoFixture(t)
+	rev := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+		if err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	// Enough shared content for git gc to store the second version as a delta
+	first := strings.Repeat("line of a lockfile\n", 200)
+	os.WriteFile(filepath.Join(dir, "src", "b.txt"), []byte(first), 0o644)
+	git("commit", "-q", "-am", "first")
+	git("tag", "-a", "v1", "-m", "v1")
+	git("checkout", "-q", "-b", "feature")
+	os.WriteFile(filepath.Join(dir, "src", "b.txt"), []byte(first+"one more\n"), 0o644)
+	git("commit", "-q", "-am", "second")
+	abbrev := rev("rev-parse", "--short", "HEAD~1")
+
+	check := func(stage string) {
+		t.Helper()
+		tests := []struct{ rev, path, want string }{
+			{"HEAD", "src/b.txt", first + "one more\n"},
+			{"feature", "src/b.txt", first + "one more\n"},
+			{"HEAD~1", "src/b.txt", first},
+			{"HEAD^", "a.txt", "alpha\n"},
+			{"v1", "src/b.txt", first},
+			{"refs/tags/v1", "src/b.txt", first},
+			{abbrev, "src/b.txt", first},
+			{"HEAD~2", "src/b.txt", "bravo\r\n"},
+		}
+		for _, tt := range tests {
+			got, err := ReadGitFile(dir, tt.rev, tt.path)
+			if err != nil || string(got) != tt.want {
+				t.Errorf("%s: %s:%s = %.20q, %v", stage, tt.rev, tt.path, got, err)
+			}
+		}
+		// Paths are relative to the directory given
+		if got, err := ReadGitFile(filepath.Join(dir, "src"), "HEAD~1", "b.txt"); err != nil || string(got) != first {
+			t.Errorf("%s: subdirectory read = %.20q, %v", stage, got, err)
+		}
+		if _, err := ReadGitFile(dir, "HEAD", "missing.lock"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: expected a missing file, got %v", stage, err)
+		}
+		if _, err := ReadGitFile(dir, "no-such-branch", "a.txt"); err == nil {
+			t.Errorf("%s: expected an unknown revision error", stage)
+		}
+	}
+	check("loose")
+	git("gc", "-q", "--aggressive")
+	if packs, _ := filepath.Glob(filepath.Join(dir, ".git", "objects", "pack", "*.pack")); len(packs) == 0 {
+		t.Fatal("git gc wrote no pack")
+	}
+	check("packed")
+}
@@ -0,0 +1,279 @@
+package digest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CapsuleRecord is what a capsule was built from: the digest that tagged it
+// and the packages its lockfiles pinned. mitl hydrate keeps one per build
+// and project in HistoryDir so mitl digest explain can tell why the next
+// capsule differs.
+type CapsuleRecord struct {
+	Format  int       `json:"format"`            // RecordFormat when written
+	Tag     string    `json:"tag"`               // digest part of mitl-capsule:<tag>
+	Project string    `json:"project"`           // absolute project (or workspace) root
+	Package string    `json:"package,omitempty"` // workspace package for --package builds
+	BuiltAt time.Time `json:"built_at"`
+	Digest  *Digest   `json:"digest"`
+	// Packages holds what each lockfile in the digest pinned, by path
+	Packages map[string]*PackageSet `json:"packages,omitempty"`
+}
+
+// RecordFormat versions the CapsuleRecord layout. Format 1 records (no
+// format field) stored Packages as lockfile path -> name -> version.
+const RecordFormat = 2
+
+// UnmarshalJSON reads current records and converts format 1 package maps
+// into package sets, so records written before the upgrade still explain
+func (r *CapsuleRecord) UnmarshalJSON(data []byte) error {
+	type record CapsuleRecord
+	var raw struct {
+		*record
+		Packages json.RawMessage `json:"packages,omitempty"`
+	}
+	raw.record = (*record)(r)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	r.Packages = nil
+	if len(raw.Packages) == 0 {
+		return nil
+	}
+	if r.Format >= RecordFormat {
+		return json.Unmarshal(raw.Packages, &r.Packages)
+	}
+	var legacy map[string]map[string]string
+	if err := json.Unmarshal(raw.Packages, &legacy); err != nil {
+		return err
+	}
+	r.Packages = make(map[string]*PackageSet, len(legacy))
+	for path, versions := range legacy {
+		set := &PackageSet{Ecosystem: LockfileEcosystem(path)}
+		for name, version := range versions {
+			set.Packages = append(set.Packages, LockedPackage{Name: name, Version: version})
+		}
+		sort.Slice(set.Packages, func(i, j int) bool { return set.Packages[i].Name < set.Packages[j].Name })
+		r.Packages[path] = set
+	}
+	r.Format = RecordFormat
+	return nil
+}
+
+// HistoryDir returns the directory capsule records are kept in,
+// ~/.mitl/digests
+func HistoryDir() string {
+	home := os.Getenv("HOME")
+	if home == "" {
+		home, _ = os.UserHomeDir()
+	}
+	return filepath.Join(home, ".mitl", "digests")
+}
+
+// NewCapsuleRecord records d as the digest of the capsule tagged tag,
+// reading the package versions of the lockfiles it covers from root
+func NewCapsuleRecord(root, pkg, tag string, d *Digest) *CapsuleRecord {
+	rec := &CapsuleRecord{
+		Format:   RecordFormat,
+		Tag:      strings.TrimPrefix(tag, "mitl-capsule:"),
+		Project:  root,
+		Package:  pkg,
+		BuiltAt:  time.Now().UTC(),
+		Digest:   d,
+		Packages: map[string]*PackageSet{},
+	}
+	if abs, err := filepath.Abs(root); err == nil {
+		rec.Project = abs
+	}
+	for _, f := range d.Files {
+		if strings.HasPrefix(f.Path, "<") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root, f.Path))
+		if err != nil {
+			continue
+		}
+		if set, err := ParseLockfile(f.Path, data); err == nil {
+			rec.Packages[filepath.ToSlash(f.Path)] = set
+		}
+	}
+	return rec
+}
+
+// HistoryLimit is how many capsule records are kept per project and
+// workspace package; older ones are pruned when hydrate records a build
+const HistoryLimit = 20
+
+// historyKey names the HistoryDir subdirectory of a project's records
+func historyKey(project, pkg string) string {
+	h := sha256.Sum256([]byte(project + "\x00" + pkg))
+	return hex.EncodeToString(h[:8])
+}
+
+// projectHistoryDir returns the directory holding the records of project
+// (absolute) and workspace package pkg
+func projectHistoryDir(project, pkg string) string {
+	return filepath.Join(HistoryDir(), historyKey(project, pkg))
+}
+
+// SaveCapsuleRecord writes rec to HistoryDir as <project key>/<tag>.json,
+// replacing the record of an earlier build of the same capsule for the
+// same project, and prunes the project's history to HistoryLimit records
+func SaveCapsuleRecord(rec *CapsuleRecord) error {
+	if rec == nil || rec.Tag == "" || rec.Digest == nil || rec.Project == "" {
+		return fmt.Errorf("incomplete capsule record")
+	}
+	if !validTag(rec.Tag) {
+		return fmt.Errorf("invalid capsule tag %q", rec.Tag)
+	}
+	dir := projectHistoryDir(rec.Project, rec.Package)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create digest history: %w", err)
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal capsule record: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, rec.Tag+".json"), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write capsule record: %w", err)
+	}
+	return pruneHistory(rec.Project, rec.Package, HistoryLimit)
+}
+
+// LoadCapsuleRecord reads the record of the capsule tagged tag, with or
+// without the mitl-capsule: prefix, built for project and package pkg
+func LoadCapsuleRecord(project, pkg, tag string) (*CapsuleRecord, error) {
+	tag = strings.TrimPrefix(tag, "mitl-capsule:")
+	if !validTag(tag) {
+		return nil, fmt.Errorf("invalid capsule tag %q", tag)
+	}
+	if abs, err := filepath.Abs(project); err == nil {
+		project = abs
+	}
+	rec, err := readCapsuleRecord(filepath.Join(projectHistoryDir(project, pkg), tag+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("no record of capsule %s for %s: %w", tag, project, err)
+	}
+	return rec, nil
+}
+
+// ProjectHistory returns the records of a project's capsules, newest
+// first. project is matched as an absolute path; pkg selects the
+// workspace package ("" for the whole project).
+func ProjectHistory(project, pkg string) ([]*CapsuleRecord, error) {
+	if abs, err := filepath.Abs(project); err == nil {
+		project = abs
+	}
+	stored, err := projectRecords(project, pkg)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*CapsuleRecord, len(stored))
+	for i, s := range stored {
+		records[i] = s.rec
+	}
+	return records, nil
+}
+
+// storedRecord is a capsule record and the file it was read from
+type storedRecord struct {
+	path string
+	rec  *CapsuleRecord
+}
+
+// projectRecords reads the records in a project's history directory,
+// newest first. Unreadable files are skipped.
+func projectRecords(project, pkg string) ([]storedRecord, error) {
+	migrateHistory()
+	dir := projectHistoryDir(project, pkg)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read digest history: %w", err)
+	}
+	var records []storedRecord
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		p := filepath.Join(dir, entry.Name())
+		rec, err := readCapsuleRecord(p)
+		if err != nil || rec.Project != project || rec.Package != pkg {
+			continue
+		}
+		records = append(records, storedRecord{path: p, rec: rec})
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].rec.BuiltAt.After(records[j].rec.BuiltAt) })
+	return records, nil
+}
+
+// migrateHistory moves records kept as HistoryDir/<tag>.json, keyed by
+// tag alone, into their project's directory. Records that can't be read
+// stay where they are.
+func migrateHistory() {
+	entries, err := os.ReadDir(HistoryDir())
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		p := filepath.Join(HistoryDir(), entry.Name())
+		rec, err := readCapsuleRecord(p)
+		if err != nil || rec.Project == "" || !validTag(rec.Tag) {
+			continue
+		}
+		dir := projectHistoryDir(rec.Project, rec.Package)
+		target := filepath.Join(dir, rec.Tag+".json")
+		if _, err := os.Stat(target); err == nil {
+			// a newer build of the same capsule was recorded since
+			_ = os.Remove(p)
+			continue
+		}
+		if os.MkdirAll(dir, 0o755) == nil {
+			_ = os.Rename(p, target)
+		}
+	}
+}
+
+// pruneHistory removes all but the newest keep records of a project
+func pruneHistory(project, pkg string, keep int) error {
+	records, err := projectRecords(project, pkg)
+	if err != nil {
+		return err
+	}
+	for i := keep; i < len(records); i++ {
+		if err := os.Remove(records[i].path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to prune digest history: %w", err)
+		}
+	}
+	return nil
+}
+
+// readCapsuleRecord decodes one record file
+func readCapsuleRecord(path string) (*CapsuleRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec CapsuleRecord
+	if err := json.Unmarshal(data, &rec); err != nil || rec.Digest == nil {
+		return nil, fmt.Errorf("failed to parse capsule record %s", filepath.Base(path))
+	}
+	return &rec, nil
+}
+
+// validTag reports whether tag can name a record file
+func validTag(tag string) bool {
+	return tag != "" && !strings.ContainsAny(tag, `/\`) && tag != "." && tag != ".."
+}
@@ -52,6 +52,10 @@ func NewIgnoreRules() *IgnoreRules {
 		"*.swo",
 		"*~",
 		".mitl/",
+		// Local env files hold secrets and must not influence capsule tags
+		".env",
+		".env.*",
+		"!.env.example",
 		// Prevent self-influence when users redirect `mitl digest` output
 		// as done by preflight checks (e.g., digest1.txt, digest2.txt, ...)
 		"digest*.txt",
@@ -219,14 +223,15 @@ func (r *IgnoreRules) evaluatePatterns(path string, isDir bool) bool {
 	ignored := false
 
 	for _, pattern := range r.patterns {
-		// Skip directory-only patterns for files
-		if pattern.dirOnly && !isDir {
-			continue
+		// Directory-only patterns match directories, and files and
+		// directories below a matching directory
+		var matches bool
+		if pattern.dirOnly {
+			matches = (isDir && r.matchesPattern(pattern, path, true)) || r.matchesParent(pattern, path)
+		} else {
+			matches = r.matchesPattern(pattern, path, isDir)
 		}
 
-		// Check if pattern matches
-		matches := r.matchesPattern(pattern, path, isDir)
-
 		if matches {
 			if pattern.negate {
 				ignored = false // Negation pattern un-ignores
@@ -249,12 +254,32 @@ func (r *IgnoreRules) matchesPattern(pattern ignorePattern, path string, isDir b
 		}
 	}
 
-	// Check full path against the compiled glob
-	testPath := path
-	if isDir && !strings.HasSuffix(testPath, "/") {
-		testPath += "/"
+	// Check full path against the compiled glob. Directories are tried
+	// with and without the trailing slash, so "/dist/" and "a/**/b"
+	// match the directory itself.
+	if pattern.glob.Match(path) {
+		return true
+	}
+	if isDir && !strings.HasSuffix(path, "/") {
+		return pattern.glob.Match(path + "/")
+	}
+	return false
+}
+
+// matchesParent checks a pattern against the directories above path, so
+// "build/" ignores build/out.o even when build itself was not skipped
+func (r *IgnoreRules) matchesParent(pattern ignorePattern, path string) bool {
+	for i := strings.Index(path, "/"); i >= 0; {
+		if r.matchesPattern(pattern, path[:i], true) {
+			return true
+		}
+		next := strings.Index(path[i+1:], "/")
+		if next < 0 {
+			break
+		}
+		i += next + 1
 	}
-	return pattern.glob.Match(testPath)
+	return false
 }
 
 // GetPatterns returns a copy of all loaded patterns for inspection.
@@ -20,3 +20,37 @@ func TestIgnoreRules_PatternVariants(t *testing.T) {
 		t.Fatalf("expected 4 patterns")
 	}
 }
+
+func TestIgnoreRules_AnchoredDirectory(t *testing.T) {
+	r := NewIgnoreRules()
+	for _, p := range []string{"/dist/", "docs/**/tmp"} {
+		if err := r.AddPattern(p); err != nil {
+			t.Fatalf("AddPattern %q: %v", p, err)
+		}
+	}
+	if !r.ShouldIgnore("dist", true) || !r.ShouldIgnore("docs/a/tmp", true) {
+		t.Fatal("expected the directories to be ignored")
+	}
+	if r.ShouldIgnore("dist", false) || r.ShouldIgnore("src/dist", true) {
+		t.Fatal("only the top-level dist directory is ignored")
+	}
+}
+
+func TestIgnoreRules_DirectoryOnlyCoversContents(t *testing.T) {
+	r := NewIgnoreRules()
+	for _, p := range []string{"build/", "/out/"} {
+		if err := r.AddPattern(p); err != nil {
+			t.Fatalf("AddPattern %q: %v", p, err)
+		}
+	}
+	for _, p := range []string{"build/out.o", "src/build/a/b.o", "out/x.js"} {
+		if !r.ShouldIgnore(p, false) {
+			t.Errorf("expected %s to be ignored", p)
+		}
+	}
+	for _, p := range []string{"build", "src/out/x.js", "builder/a.o"} {
+		if r.ShouldIgnore(p, false) {
+			t.Errorf("expected %s to be kept", p)
+		}
+	}
+}
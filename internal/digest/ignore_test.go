@@ -42,3 +42,15 @@ func TestIgnoreRules_Basic(t *testing.T) {
 		t.Fatalf("expected cache cleared")
 	}
 }
+
+func TestIgnoreRules_DefaultsExcludeEnvFiles(t *testing.T) {
+	r := NewIgnoreRules()
+	for _, p := range []string{".env", ".env.local", "api/.env"} {
+		if !r.ShouldIgnore(p, false) {
+			t.Fatalf("expected %s to be ignored by default", p)
+		}
+	}
+	if r.ShouldIgnore(".env.example", false) {
+		t.Fatalf("expected .env.example to stay in the digest")
+	}
+}
@@ -0,0 +1,249 @@
+package digest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// IndexDir holds the file hash index, relative to the project root. The
+// default ignore rules skip .mitl/, so the index never hashes itself.
+const IndexDir = ".mitl/cache"
+
+// IndexFileName is the index file inside IndexDir
+const IndexFileName = "digest-index.json"
+
+// indexVersion is bumped when the index layout or the meaning of a file
+// hash changes, dropping every entry written before
+const indexVersion = 1
+
+// racyWindow keeps files changed this recently out of the index: a second
+// edit within the filesystem's timestamp granularity would leave the stamp
+// unchanged and the stale hash in use
+var racyWindow = 2 * time.Second
+
+// fileStamp identifies a version of a file without reading it. Inode and
+// change time are zero where the platform doesn't report them.
+type fileStamp struct {
+	Size  int64  `json:"size"`
+	MTime int64  `json:"mtime"`
+	CTime int64  `json:"ctime,omitempty"`
+	Inode uint64 `json:"inode,omitempty"`
+}
+
+// stampOf returns the stamp of a stat result
+func stampOf(info os.FileInfo) fileStamp {
+	st := fileStamp{Size: info.Size(), MTime: info.ModTime().UnixNano()}
+	statStamp(&st, info)
+	return st
+}
+
+// indexEntry is a file's hash as of its stamp
+type indexEntry struct {
+	fileStamp
+	Hash    string `json:"hash,omitempty"`
+	NotText bool   `json:"not_text,omitempty"` // not UTF-8, so left out of digests
+	Nanos   int64  `json:"nanos"`              // hashing time, saved on every hit
+}
+
+// indexData is the on-disk layout
+type indexData struct {
+	Version int                   `json:"version"`
+	Key     string                `json:"key"`
+	Entries map[string]indexEntry `json:"entries"`
+	// Blobs maps git blob IDs to file hashes for the git digest mode.
+	// Their stamps are unused: a blob's content never changes.
+	Blobs map[string]indexEntry `json:"blobs,omitempty"`
+}
+
+// FileIndex persists file hashes between runs, keyed by path and stamp, so
+// unchanged files are not read again. Entries are only trusted under the
+// key they were written with, which covers the algorithm, the normalizer
+// and the ignore rules.
+type FileIndex struct {
+	path    string
+	key     string
+	entries map[string]indexEntry // read-only while files are hashed
+	blobs   map[string]indexEntry // likewise, by git blob ID
+	fresh   map[string]indexEntry // this run's entries
+	fBlobs  map[string]indexEntry // this run's blobs
+	dirty   bool
+}
+
+// LoadFileIndex opens the index under root for key. A missing, unreadable
+// or outdated index starts empty; it is a cache, never an error.
+func LoadFileIndex(root, key string) *FileIndex {
+	ix := &FileIndex{
+		path:    filepath.Join(root, filepath.FromSlash(IndexDir), IndexFileName),
+		key:     key,
+		entries: map[string]indexEntry{},
+		blobs:   map[string]indexEntry{},
+		fresh:   map[string]indexEntry{},
+		fBlobs:  map[string]indexEntry{},
+	}
+	data, err := os.ReadFile(ix.path)
+	if err != nil {
+		return ix
+	}
+	var stored indexData
+	if json.Unmarshal(data, &stored) != nil || stored.Version != indexVersion || stored.Key != key {
+		ix.dirty = true
+		return ix
+	}
+	if stored.Entries != nil {
+		ix.entries = stored.Entries
+	}
+	if stored.Blobs != nil {
+		ix.blobs = stored.Blobs
+	}
+	return ix
+}
+
+// Path returns the index file
+func (ix *FileIndex) Path() string {
+	return ix.path
+}
+
+// lookup returns the entry for path if its stamp still matches
+func (ix *FileIndex) lookup(path string, st fileStamp) (indexEntry, bool) {
+	e, ok := ix.entries[filepath.ToSlash(path)]
+	if !ok || e.fileStamp != st {
+		return indexEntry{}, false
+	}
+	return e, true
+}
+
+// lookupBlob returns the hash recorded for a git blob
+func (ix *FileIndex) lookupBlob(oid string) (indexEntry, bool) {
+	e, ok := ix.blobs[oid]
+	return e, ok
+}
+
+// add records a processed file for this run. Files that changed within
+// racyWindow of now are left for the next run to hash again.
+func (ix *FileIndex) add(info CalcFileInfo, now time.Time) {
+	if info.stamp == (fileStamp{}) || (info.Hash == "" && !info.notText) {
+		return
+	}
+	path := filepath.ToSlash(info.Path)
+	if info.oid != "" {
+		// Clean tracked files match their blob whatever their timestamps
+		e := indexEntry{Hash: info.Hash, NotText: info.notText, Nanos: info.HashTime.Nanoseconds()}
+		if old, ok := ix.blobs[info.oid]; ok && info.Cached {
+			e = old
+		} else if !ok {
+			ix.dirty = true
+		}
+		ix.fBlobs[info.oid] = e
+	}
+	if info.Cached {
+		if e, ok := ix.entries[path]; ok && e.fileStamp == info.stamp {
+			ix.fresh[path] = e
+			return
+		}
+	}
+	limit := now.Add(-racyWindow).UnixNano()
+	if info.stamp.MTime > limit || info.stamp.CTime > limit {
+		return
+	}
+	ix.fresh[path] = indexEntry{fileStamp: info.stamp, Hash: info.Hash, NotText: info.notText, Nanos: info.HashTime.Nanoseconds()}
+	ix.dirty = true
+}
+
+// finish folds this run's entries into the index. prune drops the entries
+// of files the run did not see, which is only right after a full walk.
+func (ix *FileIndex) finish(prune bool) {
+	if prune {
+		if len(ix.fresh) != len(ix.entries) || len(ix.fBlobs) != len(ix.blobs) {
+			ix.dirty = true
+		}
+		ix.entries, ix.blobs = ix.fresh, ix.fBlobs
+	} else {
+		for path, e := range ix.fresh {
+			ix.entries[path] = e
+		}
+		for oid, e := range ix.fBlobs {
+			ix.blobs[oid] = e
+		}
+	}
+	ix.fresh, ix.fBlobs = map[string]indexEntry{}, map[string]indexEntry{}
+}
+
+// Save writes the index if it changed. The write goes through a temporary
+// file so concurrent runs never see a partial index.
+func (ix *FileIndex) Save() error {
+	if !ix.dirty {
+		return nil
+	}
+	dir := filepath.Dir(ix.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	// Keep the cache out of version control without touching .gitignore
+	ignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		_ = os.WriteFile(ignore, []byte("*\n"), 0o644)
+	}
+	data, err := json.Marshal(indexData{Version: indexVersion, Key: ix.key, Entries: ix.entries, Blobs: ix.blobs})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, IndexFileName+".*")
+	if err != nil {
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), ix.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	ix.dirty = false
+	return nil
+}
+
+// indexKey fingerprints what a file hash depends on besides the file: the
+// algorithm and the normalizer. The ignore rules are included too, so
+// editing .mitlignore starts a clean index.
+func indexKey(algorithm HashAlgorithm, n *Normalizer, rules *IgnoreRules) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "v%d\nalgorithm=%d\nbom=%t\nline-endings=%t\nutf8=%t\n",
+		indexVersion, algorithm, n.stripBOM, n.normalizeLineEndings, n.validateUTF8)
+	if rules != nil {
+		fmt.Fprintf(h, "ignore=%s\n", strings.Join(rules.GetPatterns(), "\x00"))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// CacheStats reports how much of a digest calculation the file index
+// answered
+type CacheStats struct {
+	Hits   int           `json:"hits"`
+	Misses int           `json:"misses"`
+	Saved  time.Duration `json:"saved"` // hashing time the hits skipped
+}
+
+// HitRate returns the share of files answered by the index, 0 to 1
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
@@ -0,0 +1,76 @@
+package digest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileIndex_ReusesUnchangedFiles(t *testing.T) {
+	old := racyWindow
+	racyWindow = 0
+	defer func() { racyWindow = old }()
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.txt", "alpha")
+	write("b.txt", "bravo")
+	write("logo.png", "\x89PNG\r\n")
+	calc := func() *Digest {
+		d, err := NewProjectCalculator(dir, &Options{Algorithm: "sha256"}).Calculate(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	first := calc()
+	if first.Cache == nil || first.Cache.Hits != 0 || first.Cache.Misses != 3 {
+		t.Fatalf("expected a cold index, got %+v", first.Cache)
+	}
+	if _, err := os.Stat(filepath.Join(dir, IndexDir, IndexFileName)); err != nil {
+		t.Fatalf("index not written: %v", err)
+	}
+	second := calc()
+	if second.Hash != first.Hash || second.Cache.Hits != 3 || second.Cache.Misses != 0 {
+		t.Fatalf("expected every file from the index: %+v", second.Cache)
+	}
+	if second.FileCount != 2 {
+		t.Fatalf("cached binary files must stay out of the digest, got %d files", second.FileCount)
+	}
+
+	write("b.txt", "BRAVO")
+	third := calc()
+	if third.Hash == first.Hash || third.Cache.Misses != 1 {
+		t.Fatalf("an edit must be rehashed: %+v", third.Cache)
+	}
+	nocache, err := NewProjectCalculator(dir, &Options{Algorithm: "sha256", NoCache: true}).Calculate(context.Background())
+	if err != nil || nocache.Hash != third.Hash || nocache.Cache != nil {
+		t.Fatalf("cached and uncached digests differ: %s vs %s (%v)", third.Hash, nocache.Hash, err)
+	}
+
+	os.Remove(filepath.Join(dir, "a.txt"))
+	calc()
+	if len(LoadFileIndex(dir, indexKey(SHA256, NewNormalizer(), mustIgnoreRules(t, dir))).entries) != 2 {
+		t.Fatal("a full walk must prune deleted files")
+	}
+
+	write(".mitlignore", "*.log\n")
+	if d := calc(); d.Cache.Hits != 0 {
+		t.Fatalf("changed ignore rules must invalidate the index, got %+v", d.Cache)
+	}
+}
+
+func mustIgnoreRules(t *testing.T, dir string) *IgnoreRules {
+	t.Helper()
+	rules, err := LoadIgnoreRulesFromProject(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
@@ -6,6 +6,7 @@
 package digest
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -31,35 +32,54 @@ func NewLockfileHasher(root string) *LockfileHasher {
 	}
 }
 
+// hashers maps lockfile names to their specialized hash functions. Names
+// are matched exactly, so case matters on case-sensitive filesystems.
+func (lh *LockfileHasher) hashers() map[string]func([]byte) (string, error) {
+	return map[string]func([]byte) (string, error){
+		"composer.lock":       lh.hashComposerLock,
+		"package-lock.json":   lh.hashPackageLock,
+		"pnpm-lock.yaml":      lh.hashPnpmLock,
+		"yarn.lock":           lh.hashYarnLock,
+		"bun.lock":            lh.hashBunLock,
+		"bun.lockb":           lh.hashBunLock,
+		"npm-shrinkwrap.json": lh.hashPackageLock,
+		"go.sum":              lh.hashGoSum,
+		"go.mod":              lh.hashGoMod,
+		"Gemfile.lock":        lh.hashGemfileLock,
+		"requirements.txt":    lh.hashRequirements,
+		"poetry.lock":         lh.hashPoetryLock,
+		"Pipfile.lock":        lh.hashPipfileLock,
+		"uv.lock":             lh.hashPoetryLock,
+		"pdm.lock":            lh.hashPoetryLock,
+		"Cargo.lock":          lh.hashCargoLock,
+		"gradle.lockfile":     lh.hashGradleLockfile,
+		"pom.xml":             lh.hashPomXML,
+		"mitl.yaml":           lh.hashManifest,
+		"mitl.yml":            lh.hashManifest,
+		"mitl.lock":           lh.hashManifest,
+	}
+}
+
+// LockfileNames lists the files HashLockfiles considers, sorted
+func LockfileNames() []string {
+	var names []string
+	for name := range (&LockfileHasher{}).hashers() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // HashLockfiles computes a combined hash of all lockfiles in the project.
 // It looks for common lockfile types and applies specialized hashing to each.
 func (lh *LockfileHasher) HashLockfiles() (string, error) {
-	// Map of lockfile names to their specialized hash functions
-	lockfiles := map[string]func([]byte) (string, error){
-		"composer.lock":     lh.hashComposerLock,
-		"package-lock.json": lh.hashPackageLock,
-		"pnpm-lock.yaml":    lh.hashPnpmLock,
-		"yarn.lock":         lh.hashYarnLock,
-		"go.sum":            lh.hashGoSum,
-		"go.mod":            lh.hashGoMod,
-		"Gemfile.lock":      lh.hashGemfileLock,
-		"requirements.txt":  lh.hashRequirements,
-		"poetry.lock":       lh.hashPoetryLock,
-		"Pipfile.lock":      lh.hashPipfileLock,
-		"cargo.lock":        lh.hashCargoLock,
-	}
+	lockfiles := lh.hashers()
 
 	hasher := sha256.New()
 	found := false
 
 	// Process each lockfile type in alphabetical order for determinism
-	var lockfileNames []string
-	for name := range lockfiles {
-		lockfileNames = append(lockfileNames, name)
-	}
-	sort.Strings(lockfileNames)
-
-	for _, filename := range lockfileNames {
+	for _, filename := range LockfileNames() {
 		hashFunc := lockfiles[filename]
 		path := filepath.Join(lh.root, filename)
 		data, err := os.ReadFile(path)
@@ -236,91 +256,45 @@ func (lh *LockfileHasher) extractNpmDependencies(deps map[string]map[string]inte
 }
 
 // hashPnpmLock handles pnpm-lock.yaml files.
-// Extracts package versions while ignoring volatile metadata.
+// Hashes the resolved dependencies and packages while ignoring volatile
+// metadata; lockfiles that don't parse are hashed as-is.
 func (lh *LockfileHasher) hashPnpmLock(data []byte) (string, error) {
-	content := string(data)
-	lines := strings.Split(content, "\n")
-	hasher := sha256.New()
-
-	// Extract lockfile version
-	lockfileVersionRegex := regexp.MustCompile(`^lockfileVersion:\s*['"]?(\d+(?:\.\d+)?)['"]?`)
-
-	// Extract package specifications (name and version)
-	packageRegex := regexp.MustCompile(`^\s*['"]?([^:'"]+)['"]?:\s*(['"]?)([^'"]+)['"]?\s*$`)
-
-	var packages []string
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		// Capture lockfile version
-		if match := lockfileVersionRegex.FindStringSubmatch(line); len(match) > 1 {
-			fmt.Fprintf(hasher, "lockfileVersion:%s\n", match[1])
-			continue
-		}
-
-		// Capture package dependencies (skip comments and empty lines)
-		if strings.HasPrefix(line, "#") || line == "" {
-			continue
-		}
-
-		if match := packageRegex.FindStringSubmatch(line); len(match) > 3 {
-			name := match[1]
-			version := match[3]
-
-			// Filter out non-package lines (like registries, settings)
-			if !strings.Contains(name, "registry") && !strings.Contains(name, "settings") &&
-				!strings.Contains(name, "specifiers") {
-				packages = append(packages, fmt.Sprintf("%s@%s", name, version))
-			}
-		}
+	lock, err := parsePnpmLock(data)
+	if err != nil {
+		return lh.hashRaw(data), nil
 	}
-
-	// Sort packages for deterministic output
-	sort.Strings(packages)
-	for _, pkg := range packages {
-		fmt.Fprintln(hasher, pkg)
+	hasher := sha256.New()
+	for _, line := range lock.hashLines() {
+		fmt.Fprintln(hasher, line)
 	}
-
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
-// hashYarnLock handles yarn.lock files.
+// hashYarnLock handles yarn.lock files, Classic and Berry.
+// Hashes each descriptor's resolved version; lockfiles that don't parse
+// are hashed as-is.
 func (lh *LockfileHasher) hashYarnLock(data []byte) (string, error) {
-	content := string(data)
-	lines := strings.Split(content, "\n")
-	hasher := sha256.New()
-
-	// Extract yarn lockfile version
-	if len(lines) > 0 && strings.HasPrefix(lines[0], "# yarn lockfile v") {
-		fmt.Fprintln(hasher, lines[0])
+	entries, err := parseYarnLock(data)
+	if err != nil {
+		return lh.hashRaw(data), nil
 	}
-
-	// Parse package entries
-	packageRegex := regexp.MustCompile(`^"?([^"@]+)@([^"]+)"?:`)
-	versionRegex := regexp.MustCompile(`^\s+version\s+"([^"]+)"`)
-
 	var packages []string
-	var currentPackage string
-
-	for _, line := range lines {
-		// Check for package declaration
-		if match := packageRegex.FindStringSubmatch(line); len(match) > 2 {
-			currentPackage = fmt.Sprintf("%s@%s", match[1], match[2])
-		}
-
-		// Check for version line
-		if match := versionRegex.FindStringSubmatch(line); len(match) > 1 && currentPackage != "" {
-			packages = append(packages, fmt.Sprintf("%s=%s", currentPackage, match[1]))
-			currentPackage = ""
+	for _, e := range entries {
+		for _, d := range e.descriptors {
+			packages = append(packages, fmt.Sprintf("%s=%s", d, e.version))
 		}
 	}
-
 	sort.Strings(packages)
+
+	hasher := sha256.New()
+	if strings.Contains(string(data), "__metadata:") {
+		fmt.Fprintln(hasher, "yarn berry")
+	} else {
+		fmt.Fprintln(hasher, "yarn lockfile v1")
+	}
 	for _, pkg := range packages {
 		fmt.Fprintln(hasher, pkg)
 	}
-
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
@@ -330,6 +304,11 @@ func (lh *LockfileHasher) hashGoSum(data []byte) (string, error) {
 	return lh.hashRaw(data), nil
 }
 
+// hashBunLock handles bun.lock (text) and bun.lockb (binary) files.
+func (lh *LockfileHasher) hashBunLock(data []byte) (string, error) {
+	return lh.hashRaw(data), nil
+}
+
 // hashGoMod handles Go go.mod files.
 func (lh *LockfileHasher) hashGoMod(data []byte) (string, error) {
 	// go.mod is deterministic, but we can extract just the require statements
@@ -424,7 +403,8 @@ func (lh *LockfileHasher) hashRequirements(data []byte) (string, error) {
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
-// hashPoetryLock handles Python poetry.lock files.
+// hashPoetryLock handles Python poetry.lock files. uv.lock and pdm.lock use
+// the same [[package]] name/version layout.
 func (lh *LockfileHasher) hashPoetryLock(data []byte) (string, error) {
 	// Poetry lock files are TOML format, but we can extract the essential info
 	content := string(data)
@@ -536,6 +516,52 @@ func (lh *LockfileHasher) hashCargoLock(data []byte) (string, error) {
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
+// hashGradleLockfile handles Gradle dependency locking files. Each line is
+// "group:artifact:version=configurations"; the header comments Gradle writes
+// are skipped and entries sorted so regeneration order doesn't matter.
+func (lh *LockfileHasher) hashGradleLockfile(data []byte) (string, error) {
+	var entries []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	sort.Strings(entries)
+	hasher := sha256.New()
+	for _, e := range entries {
+		fmt.Fprintln(hasher, e)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+var (
+	xmlComment    = regexp.MustCompile(`(?s)<!--.*?-->`)
+	xmlWhitespace = regexp.MustCompile(`>\s+<`)
+)
+
+// hashPomXML handles Maven's pom.xml. Maven has no lockfile, so the POM is
+// the closest thing: comments and formatting are dropped so only changes to
+// coordinates, versions, properties and plugins invalidate the cache.
+func (lh *LockfileHasher) hashPomXML(data []byte) (string, error) {
+	normalized := xmlComment.ReplaceAll(data, nil)
+	normalized = xmlWhitespace.ReplaceAll(normalized, []byte("><"))
+	return lh.hashRaw(bytes.TrimSpace(normalized)), nil
+}
+
+// hashManifest handles the mitl.yaml project manifest and the mitl.lock
+// base image pins. They are not dependency lockfiles, but they pin versions,
+// packages and images that shape the capsule, so changing them must
+// invalidate the cache just like a dependency change.
+func (lh *LockfileHasher) hashManifest(data []byte) (string, error) {
+	normalized, err := DefaultNormalize(data)
+	if err != nil {
+		return lh.hashRaw(data), nil
+	}
+	return lh.hashRaw(normalized), nil
+}
+
 // hashRaw provides a fallback raw hash for unrecognized or unparseable files.
 func (lh *LockfileHasher) hashRaw(data []byte) string {
 	hash := sha256.Sum256(data)
@@ -76,3 +76,39 @@ func TestLockfileHasher_PipfileAndCargo(t *testing.T) {
 		t.Fatalf("unexpected: %q %v", sum, err)
 	}
 }
+
+func TestLockfileHasher_CargoLockCaseSensitive(t *testing.T) {
+	dir := t.TempDir()
+	h := NewLockfileHasher(dir)
+	os.WriteFile(filepath.Join(dir, "Cargo.lock"), []byte("name = \"rand\"\nversion = \"0.8.5\"\n"), 0o644)
+	first, _ := h.HashLockfiles()
+	if first == "no-lockfiles" {
+		t.Fatalf("Cargo.lock was not picked up")
+	}
+	os.WriteFile(filepath.Join(dir, "Cargo.lock"), []byte("name = \"rand\"\nversion = \"0.8.6\"\n"), 0o644)
+	if second, _ := h.HashLockfiles(); second == first {
+		t.Fatalf("expected Cargo.lock change to alter the hash")
+	}
+}
+
+func TestLockfileHasher_PomIgnoresFormatting(t *testing.T) {
+	h := NewLockfileHasher(t.TempDir())
+	a, _ := h.hashPomXML([]byte("<project>\n  <!-- app -->\n  <version>1.0</version>\n</project>\n"))
+	b, _ := h.hashPomXML([]byte("<project><version>1.0</version></project>"))
+	c, _ := h.hashPomXML([]byte("<project><version>1.1</version></project>"))
+	if a != b {
+		t.Fatalf("comments and whitespace should not change the pom hash")
+	}
+	if b == c {
+		t.Fatalf("version change should alter the pom hash")
+	}
+}
+
+func TestLockfileHasher_GradleLockfileOrder(t *testing.T) {
+	h := NewLockfileHasher(t.TempDir())
+	a, _ := h.hashGradleLockfile([]byte("# header\ncom.a:a:1.0=compileClasspath\ncom.b:b:2.0=runtimeClasspath\nempty=\n"))
+	b, _ := h.hashGradleLockfile([]byte("# other header\ncom.b:b:2.0=runtimeClasspath\ncom.a:a:1.0=compileClasspath\nempty=\n"))
+	if a != b {
+		t.Fatalf("entry order and comments should not change the gradle.lockfile hash")
+	}
+}
@@ -0,0 +1,563 @@
+package digest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ecosystems of the packages a lockfile pins
+const (
+	EcosystemNPM      = "npm"
+	EcosystemComposer = "composer"
+	EcosystemGo       = "go"
+	EcosystemRubyGems = "rubygems"
+	EcosystemPyPI     = "pypi"
+	EcosystemCargo    = "cargo"
+	EcosystemMaven    = "maven"
+)
+
+// lockfileEcosystems maps the lockfiles ParseLockfile reads to their
+// ecosystem
+var lockfileEcosystems = map[string]string{
+	"package-lock.json":   EcosystemNPM,
+	"npm-shrinkwrap.json": EcosystemNPM,
+	"pnpm-lock.yaml":      EcosystemNPM,
+	"yarn.lock":           EcosystemNPM,
+	"composer.lock":       EcosystemComposer,
+	"go.sum":              EcosystemGo,
+	"go.mod":              EcosystemGo,
+	"Gemfile.lock":        EcosystemRubyGems,
+	"poetry.lock":         EcosystemPyPI,
+	"uv.lock":             EcosystemPyPI,
+	"pdm.lock":            EcosystemPyPI,
+	"Pipfile.lock":        EcosystemPyPI,
+	"requirements.txt":    EcosystemPyPI,
+	"Cargo.lock":          EcosystemCargo,
+	"gradle.lockfile":     EcosystemMaven,
+}
+
+// ErrNoPackageList is returned for lockfiles LockfileHasher hashes without
+// listing packages, such as bun.lockb and pom.xml
+var ErrNoPackageList = errors.New("lockfile format has no package list")
+
+// LockedPackage is one package version a lockfile pins
+type LockedPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Dev     bool   `json:"dev,omitempty"` // only a development dependency
+}
+
+// PackageSet is what a lockfile pins. A name can appear with several
+// versions, e.g. nested npm dependencies.
+type PackageSet struct {
+	Ecosystem string          `json:"ecosystem"`
+	Packages  []LockedPackage `json:"packages"` // by name, then version
+}
+
+// Versions returns the versions pinned for each package name
+func (s *PackageSet) Versions() map[string][]string {
+	versions := map[string][]string{}
+	if s == nil {
+		return versions
+	}
+	for _, p := range s.Packages {
+		versions[p.Name] = append(versions[p.Name], p.Version)
+	}
+	return versions
+}
+
+// add records a package, ignoring entries without a name or version
+func (s *PackageSet) add(name, version string, dev bool) {
+	if name != "" && version != "" {
+		s.Packages = append(s.Packages, LockedPackage{Name: name, Version: version, Dev: dev})
+	}
+}
+
+// finish sorts the packages and drops duplicates. A package pinned both as
+// a development and a runtime dependency counts as runtime.
+func (s *PackageSet) finish() *PackageSet {
+	sort.Slice(s.Packages, func(i, j int) bool {
+		a, b := s.Packages[i], s.Packages[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return !a.Dev && b.Dev
+	})
+	out := s.Packages[:0]
+	for _, p := range s.Packages {
+		if n := len(out); n > 0 && out[n-1].Name == p.Name && out[n-1].Version == p.Version {
+			continue
+		}
+		out = append(out, p)
+	}
+	s.Packages = out
+	return s
+}
+
+// LockfileEcosystem returns the ecosystem of a lockfile name, "" for files
+// ParseLockfile doesn't read
+func LockfileEcosystem(name string) string {
+	return lockfileEcosystems[filepath.Base(name)]
+}
+
+// ParseLockfile returns the packages a lockfile pins, choosing the format
+// by file name. It returns ErrNoPackageList for lockfiles without one and
+// an error for content that doesn't parse.
+func ParseLockfile(name string, data []byte) (*PackageSet, error) {
+	name = filepath.Base(name)
+	set := &PackageSet{Ecosystem: lockfileEcosystems[name]}
+	var err error
+	switch name {
+	case "package-lock.json", "npm-shrinkwrap.json":
+		err = parseNpmLock(set, data)
+	case "pnpm-lock.yaml":
+		var lock *pnpmLock
+		if lock, err = parsePnpmLock(data); err == nil {
+			set.Packages = lock.packages
+		}
+	case "yarn.lock":
+		var entries []yarnEntry
+		if entries, err = parseYarnLock(data); err == nil {
+			for _, e := range entries {
+				set.add(e.name, e.version, false)
+			}
+		}
+	case "composer.lock":
+		err = parseComposerLock(set, data)
+	case "Pipfile.lock":
+		err = parsePipfileLock(set, data)
+	case "poetry.lock", "uv.lock", "pdm.lock", "Cargo.lock":
+		parseTomlPackages(set, data)
+	case "Gemfile.lock":
+		parseGemfileLock(set, data)
+	case "go.sum":
+		parseGoSum(set, data)
+	case "go.mod":
+		parseGoModRequires(set, data)
+	case "requirements.txt":
+		parseRequirements(set, data)
+	case "gradle.lockfile":
+		parseGradleLockfile(set, data)
+	default:
+		return nil, ErrNoPackageList
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return set.finish(), nil
+}
+
+// parseNpmLock reads package-lock.json: the packages map of lockfile
+// versions 2 and 3, else the nested dependencies of version 1
+func parseNpmLock(set *PackageSet, data []byte) error {
+	type legacyDep struct {
+		Version      string                `json:"version"`
+		Dev          bool                  `json:"dev"`
+		Dependencies map[string]*legacyDep `json:"dependencies"`
+	}
+	var lock struct {
+		Packages map[string]struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+			Dev     bool   `json:"dev"`
+			Link    bool   `json:"link"`
+		} `json:"packages"`
+		Dependencies map[string]*legacyDep `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return err
+	}
+	if lock.Packages != nil {
+		for path, pkg := range lock.Packages {
+			// "" is the project and paths outside node_modules are
+			// workspace packages; links point at those
+			i := strings.LastIndex(path, "node_modules/")
+			if i < 0 || pkg.Link {
+				continue
+			}
+			name := pkg.Name
+			if name == "" {
+				name = path[i+len("node_modules/"):]
+			}
+			set.add(name, pkg.Version, pkg.Dev)
+		}
+		return nil
+	}
+	var walk func(map[string]*legacyDep)
+	walk = func(deps map[string]*legacyDep) {
+		for name, dep := range deps {
+			if dep == nil {
+				continue
+			}
+			set.add(name, dep.Version, dep.Dev)
+			walk(dep.Dependencies)
+		}
+	}
+	walk(lock.Dependencies)
+	return nil
+}
+
+// parseComposerLock reads composer.lock's packages and packages-dev
+func parseComposerLock(set *PackageSet, data []byte) error {
+	type pkg struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	var lock struct {
+		Packages    []pkg `json:"packages"`
+		PackagesDev []pkg `json:"packages-dev"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return err
+	}
+	for _, p := range lock.Packages {
+		set.add(p.Name, p.Version, false)
+	}
+	for _, p := range lock.PackagesDev {
+		set.add(p.Name, p.Version, true)
+	}
+	return nil
+}
+
+// parsePipfileLock reads Pipfile.lock's default and develop sections.
+// Versions are pinned as "==x.y.z".
+func parsePipfileLock(set *PackageSet, data []byte) error {
+	var lock struct {
+		Default map[string]struct {
+			Version string `json:"version"`
+		} `json:"default"`
+		Develop map[string]struct {
+			Version string `json:"version"`
+		} `json:"develop"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return err
+	}
+	for name, p := range lock.Default {
+		set.add(name, strings.TrimPrefix(p.Version, "=="), false)
+	}
+	for name, p := range lock.Develop {
+		set.add(name, strings.TrimPrefix(p.Version, "=="), true)
+	}
+	return nil
+}
+
+// parseTomlPackages reads the [[package]] tables of poetry.lock, uv.lock,
+// pdm.lock and Cargo.lock. Only their name, version and, for older Poetry
+// lockfiles, category keys are needed, so this is not a full TOML parser.
+func parseTomlPackages(set *PackageSet, data []byte) {
+	var name, version, category string
+	inPackage := false
+	flush := func() {
+		if inPackage {
+			set.add(name, version, category == "dev")
+		}
+		name, version, category = "", "", ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			// Sub-tables such as [package.dependencies] belong to the
+			// package; any other table ends it
+			if line == "[[package]]" {
+				flush()
+				inPackage = true
+			} else if !strings.HasPrefix(line, "[package.") && !strings.HasPrefix(line, "[[package.") {
+				flush()
+				inPackage = false
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || !inPackage {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		switch strings.TrimSpace(key) {
+		case "name":
+			if name == "" {
+				name = value
+			}
+		case "version":
+			if version == "" {
+				version = value
+			}
+		case "category":
+			category = value
+		}
+	}
+	flush()
+}
+
+// parseGemfileLock reads the specs of Gemfile.lock's GEM, GIT and PATH
+// sections. Gems sit at four spaces, their requirements at six; platform
+// gems keep the platform in the version, as in nokogiri (1.16.5-x86_64-linux).
+func parseGemfileLock(set *PackageSet, data []byte) {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "     ") {
+			continue
+		}
+		name, rest, ok := strings.Cut(strings.TrimSpace(line), " (")
+		if ok && strings.HasSuffix(rest, ")") {
+			set.add(name, strings.TrimSuffix(rest, ")"), false)
+		}
+	}
+}
+
+// parseGoSum reads go.sum. Each module version has a line for its content
+// and one for its go.mod; versions only listed for their go.mod took part
+// in version selection without being downloaded, so they are left out.
+func parseGoSum(set *PackageSet, data []byte) {
+	for _, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 3 && !strings.HasSuffix(f[1], "/go.mod") {
+			set.add(f[0], f[1], false)
+		}
+	}
+}
+
+// parseGoModRequires reads go.mod's require directives, single-line and
+// blocks
+func parseGoModRequires(set *PackageSet, data []byte) {
+	inRequire := false
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "require (":
+			inRequire = true
+			continue
+		case inRequire && line == ")":
+			inRequire = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimPrefix(line, "require ")
+		case !inRequire:
+			continue
+		}
+		if f := strings.Fields(line); len(f) >= 2 {
+			set.add(f[0], f[1], false)
+		}
+	}
+}
+
+// parseRequirements reads the exact pins of requirements.txt: name==1.2.3,
+// with extras and environment markers dropped. Ranges pin nothing.
+func parseRequirements(set *PackageSet, data []byte) {
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		line, _, _ = strings.Cut(line, ";")
+		line = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), "\\"))
+		name, version, ok := strings.Cut(line, "==")
+		if !ok || strings.HasPrefix(line, "-") {
+			continue
+		}
+		name, _, _ = strings.Cut(strings.TrimSpace(name), "[")
+		version, _, _ = strings.Cut(strings.TrimSpace(version), " ")
+		set.add(strings.ToLower(name), version, false)
+	}
+}
+
+// parseGradleLockfile reads "group:artifact:version=configurations" lines
+func parseGradleLockfile(set *PackageSet, data []byte) {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		coords, _, _ := strings.Cut(line, "=")
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "empty=") {
+			continue
+		}
+		if i := strings.LastIndex(coords, ":"); i > 0 {
+			set.add(coords[:i], coords[i+1:], false)
+		}
+	}
+}
+
+// PackageChange is a package whose pinned version differs. From is empty
+// for added packages, To for removed ones.
+type PackageChange struct {
+	Name string `json:"name"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// PackageDiff compares the packages two versions of a lockfile pin
+type PackageDiff struct {
+	Added      []PackageChange `json:"added,omitempty"`
+	Removed    []PackageChange `json:"removed,omitempty"`
+	Upgraded   []PackageChange `json:"upgraded,omitempty"`
+	Downgraded []PackageChange `json:"downgraded,omitempty"`
+}
+
+// Empty reports whether no package changed, e.g. after a reformat
+func (p *PackageDiff) Empty() bool {
+	return len(p.Added)+len(p.Removed)+len(p.Upgraded)+len(p.Downgraded) == 0
+}
+
+// DiffPackages compares two package sets by name. Either may be nil. A
+// package that swapped one version for another is upgraded or downgraded;
+// when several of its versions changed, as nested npm dependencies can,
+// the versions only one side pins are reported as added or removed.
+func DiffPackages(old, newSet *PackageSet) *PackageDiff {
+	d := &PackageDiff{}
+	oldVersions, newVersions := old.Versions(), newSet.Versions()
+	for name, from := range oldVersions {
+		to, ok := newVersions[name]
+		if !ok {
+			for _, v := range from {
+				d.Removed = append(d.Removed, PackageChange{Name: name, From: v})
+			}
+			continue
+		}
+		gone, added := versionsOnlyIn(from, to), versionsOnlyIn(to, from)
+		switch {
+		case len(gone) == 1 && len(added) == 1:
+			c := PackageChange{Name: name, From: gone[0], To: added[0]}
+			if compareVersions(c.From, c.To) > 0 {
+				d.Downgraded = append(d.Downgraded, c)
+			} else {
+				d.Upgraded = append(d.Upgraded, c)
+			}
+		default:
+			for _, v := range gone {
+				d.Removed = append(d.Removed, PackageChange{Name: name, From: v})
+			}
+			for _, v := range added {
+				d.Added = append(d.Added, PackageChange{Name: name, To: v})
+			}
+		}
+	}
+	for name, to := range newVersions {
+		if _, ok := oldVersions[name]; !ok {
+			for _, v := range to {
+				d.Added = append(d.Added, PackageChange{Name: name, To: v})
+			}
+		}
+	}
+	for _, list := range [][]PackageChange{d.Added, d.Removed, d.Upgraded, d.Downgraded} {
+		sort.Slice(list, func(i, j int) bool {
+			a, b := list[i], list[j]
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			if a.From != b.From {
+				return compareVersions(a.From, b.From) < 0
+			}
+			return compareVersions(a.To, b.To) < 0
+		})
+	}
+	return d
+}
+
+// versionsOnlyIn returns the versions in a that b lacks
+func versionsOnlyIn(a, b []string) []string {
+	var only []string
+	for _, v := range a {
+		found := false
+		for _, w := range b {
+			if v == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			only = append(only, v)
+		}
+	}
+	return only
+}
+
+// LockfileDiff compares two versions of a lockfile. Packages is nil when
+// either side has no package list or doesn't parse; Note says why.
+type LockfileDiff struct {
+	Lockfile  string       `json:"lockfile"`
+	Ecosystem string       `json:"ecosystem,omitempty"`
+	Status    string       `json:"status"` // "added", "removed", "modified" or "unchanged"
+	Packages  *PackageDiff `json:"packages,omitempty"`
+	Note      string       `json:"note,omitempty"`
+}
+
+// DiffLockfile compares two versions of a lockfile; a nil side means the
+// lockfile doesn't exist there
+func DiffLockfile(name string, old, newData []byte) LockfileDiff {
+	d := LockfileDiff{Lockfile: filepath.ToSlash(name), Ecosystem: LockfileEcosystem(name), Status: "modified"}
+	switch {
+	case old == nil && newData == nil:
+		d.Status = "unchanged"
+		return d
+	case old == nil:
+		d.Status = "added"
+	case newData == nil:
+		d.Status = "removed"
+	case string(old) == string(newData):
+		d.Status = "unchanged"
+	}
+	var sets [2]*PackageSet
+	for i, data := range [][]byte{old, newData} {
+		if data == nil {
+			continue
+		}
+		set, err := ParseLockfile(name, data)
+		if err != nil {
+			d.Note = err.Error()
+			return d
+		}
+		sets[i] = set
+	}
+	d.Packages = DiffPackages(sets[0], sets[1])
+	return d
+}
+
+// compareVersions orders versions by their dot-separated parts, numerically
+// where both parts are numbers. A leading v and build metadata are ignored,
+// and a pre-release sorts before its release.
+func compareVersions(a, b string) int {
+	split := func(v string) (parts []string, pre string) {
+		v = strings.TrimPrefix(strings.TrimPrefix(v, "v"), "V")
+		v, _, _ = strings.Cut(v, "+")
+		v, pre, _ = strings.Cut(v, "-")
+		return strings.Split(v, "."), pre
+	}
+	pa, preA := split(a)
+	pb, preB := split(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		x, y := "0", "0"
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		nx, errX := strconv.Atoi(x)
+		ny, errY := strconv.Atoi(y)
+		switch {
+		case errX == nil && errY == nil && nx != ny:
+			if nx < ny {
+				return -1
+			}
+			return 1
+		case (errX != nil || errY != nil) && x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	return strings.Compare(preA, preB)
+}
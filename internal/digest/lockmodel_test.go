@@ -0,0 +1,314 @@
+package digest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// pinned formats a package set as "name=version" pairs
+func pinned(set *PackageSet) string {
+	var out []string
+	for _, p := range set.Packages {
+		out = append(out, p.Name+"="+p.Version)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestParseLockfile(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"package-lock.json", lockV1,
+			"left-pad=1.3.0 lodash=3.10.1 lodash=4.17.21 react=18.2.0"},
+		{"package-lock.json", `{"lockfileVersion":1,"dependencies":{"react":{"version":"16.14.0","dependencies":{"loose-envify":{"version":"1.4.0"}}}}}`,
+			"loose-envify=1.4.0 react=16.14.0"},
+		{"composer.lock", `{"packages":[{"name":"laravel/framework","version":"v11.0.0"}],"packages-dev":[{"name":"phpunit/phpunit","version":"11.1.0"}]}`,
+			"laravel/framework=v11.0.0 phpunit/phpunit=11.1.0"},
+		{"Pipfile.lock", `{"default":{"requests":{"version":"==2.32.3"}},"develop":{"pytest":{"version":"==8.2.0"}}}`,
+			"pytest=8.2.0 requests=2.32.3"},
+		{"poetry.lock", "[[package]]\nname = \"flask\"\nversion = \"3.0.3\"\n\n[package.dependencies]\nclick = \">=8.1.3\"\n\n[metadata]\nlock-version = \"2.0\"\n",
+			"flask=3.0.3"},
+		{"Cargo.lock", "version = 3\n\n[[package]]\nname = \"serde\"\nversion = \"1.0.203\"\n",
+			"serde=1.0.203"},
+		{"Gemfile.lock", "GEM\n  specs:\n    rails (7.1.3)\n      actionpack (= 7.1.3)\n    rack (3.0.11)\n\nPLATFORMS\n",
+			"rack=3.0.11 rails=7.1.3"},
+		{"go.sum", "golang.org/x/sys v0.20.0 h1:abc=\ngolang.org/x/sys v0.20.0/go.mod h1:def=\ngolang.org/x/text v0.14.0/go.mod h1:ghi=\n",
+			"golang.org/x/sys=v0.20.0"},
+		{"go.mod", "module x\n\nrequire github.com/a/b v1.0.0\n\nrequire (\n\tgolang.org/x/sys v0.20.0 // indirect\n)\n",
+			"github.com/a/b=v1.0.0 golang.org/x/sys=v0.20.0"},
+		{"requirements.txt", "Flask[async]==3.0.3 ; python_version >= \"3.8\"\nrequests>=2\n",
+			"flask=3.0.3"},
+	}
+	for _, tt := range tests {
+		set, err := ParseLockfile(tt.name, []byte(tt.data))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := pinned(set); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if set, _ := ParseLockfile("web/composer.lock", []byte(`{"packages":[]}`)); set == nil || set.Ecosystem != EcosystemComposer {
+		t.Fatalf("expected the composer ecosystem, got %+v", set)
+	}
+	if _, err := ParseLockfile("bun.lockb", []byte{0}); !errors.Is(err, ErrNoPackageList) {
+		t.Fatalf("binary lockfiles have no package list, got %v", err)
+	}
+	if _, err := ParseLockfile("package-lock.json", []byte("{")); err == nil {
+		t.Fatal("expected a parse error")
+	}
+}
+
+func TestParseLockfile_Pnpm(t *testing.T) {
+	tests := map[string]string{
+		"5.4": `lockfileVersion: 5.4
+
+specifiers:
+  react: ^18.2.0
+
+dependencies:
+  react: 18.2.0
+
+devDependencies:
+  '@types/react': 18.2.79
+
+packages:
+
+  /@types/react/18.2.79:
+    resolution: {integrity: sha512-abc}
+    dev: true
+
+  /react/18.2.0_loose-envify@1.4.0:
+    resolution: {integrity: sha512-def}
+    dev: false
+`,
+		"6.0": `lockfileVersion: '6.0'
+
+dependencies:
+  react:
+    specifier: ^18.2.0
+    version: 18.2.0
+
+devDependencies:
+  '@types/react':
+    specifier: ^18.2.0
+    version: 18.2.79
+
+packages:
+
+  /@types/react@18.2.79:
+    resolution: {integrity: sha512-abc}
+    dev: true
+
+  /react@18.2.0(loose-envify@1.4.0):
+    resolution: {integrity: sha512-def}
+    dev: false
+`,
+		"9.0": `lockfileVersion: '9.0'
+
+importers:
+
+  .:
+    dependencies:
+      react:
+        specifier: ^18.2.0
+        version: 18.2.0
+    devDependencies:
+      '@types/react':
+        specifier: ^18.2.0
+        version: 18.2.79
+
+packages:
+
+  '@types/react@18.2.79':
+    resolution: {integrity: sha512-abc}
+
+  react@18.2.0:
+    resolution: {integrity: sha512-def}
+
+snapshots:
+
+  react@18.2.0(loose-envify@1.4.0): {}
+`,
+	}
+	for version, data := range tests {
+		lock, err := parsePnpmLock([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: %v", version, err)
+		}
+		if lock.version != version {
+			t.Errorf("%s: lockfileVersion = %q", version, lock.version)
+		}
+		if deps := lock.importers["."]; deps["react"] != "18.2.0" || deps["@types/react"] != "18.2.79" {
+			t.Errorf("%s: importer dependencies = %v", version, deps)
+		}
+		set, err := ParseLockfile("pnpm-lock.yaml", []byte(data))
+		if err != nil {
+			t.Fatalf("%s: %v", version, err)
+		}
+		if got := pinned(set); got != "@types/react=18.2.79 react=18.2.0" {
+			t.Errorf("%s: packages = %s", version, got)
+		}
+	}
+	if _, err := ParseLockfile("pnpm-lock.yaml", []byte("packages: [")); err == nil {
+		t.Fatal("expected a parse error")
+	}
+}
+
+func TestParseLockfile_Yarn(t *testing.T) {
+	classic := `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.22.13":
+  version "7.24.2"
+  resolved "https://registry.yarnpkg.com/@babel/code-frame/-/code-frame-7.24.2.tgz"
+  dependencies:
+    picocolors "^1.0.0"
+
+left-pad@^1.0.0, left-pad@~1.3.0:
+  version "1.3.0"
+
+picocolors@^1.0.0:
+  version "1.0.1"
+`
+	berry := `# This file is generated by running "yarn install" inside your project.
+
+__metadata:
+  version: 8
+  cacheKey: 10c0
+
+"@babel/code-frame@npm:^7.0.0, @babel/code-frame@npm:^7.22.13":
+  version: 7.24.2
+  resolution: "@babel/code-frame@npm:7.24.2"
+  dependencies:
+    picocolors: "npm:^1.0.0"
+
+"app@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "app@workspace:."
+
+"left-pad@npm:^1.0.0":
+  version: 1.3.0
+  resolution: "left-pad@npm:1.3.0"
+
+"picocolors@npm:^1.0.0":
+  version: 1.0.1
+  resolution: "picocolors@npm:1.0.1"
+`
+	for name, data := range map[string]string{"classic": classic, "berry": berry} {
+		set, err := ParseLockfile("yarn.lock", []byte(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := pinned(set); got != "@babel/code-frame=7.24.2 left-pad=1.3.0 picocolors=1.0.1" {
+			t.Errorf("%s: packages = %s", name, got)
+		}
+	}
+	entries, err := parseYarnLock([]byte(classic))
+	if err != nil || len(entries) != 3 || len(entries[0].descriptors) != 2 || entries[1].descriptors[1] != "left-pad@~1.3.0" {
+		t.Fatalf("entries = %+v, %v", entries, err)
+	}
+	if _, err := parseYarnLock([]byte("left-pad@^1.0.0:\n  resolved \"x\"\n")); err == nil {
+		t.Fatal("expected an error for an entry without a version")
+	}
+}
+
+func TestLockfileHasher_NodeLockfilesIgnoreMetadata(t *testing.T) {
+	lh := NewLockfileHasher(t.TempDir())
+	pnpm := "lockfileVersion: '9.0'\n\nsettings:\n  autoInstallPeers: true\n\npackages:\n\n  react@18.2.0:\n    resolution: {integrity: sha512-def}\n"
+	a, _ := lh.hashPnpmLock([]byte(pnpm))
+	b, _ := lh.hashPnpmLock([]byte(strings.Replace(pnpm, "autoInstallPeers: true", "autoInstallPeers: false", 1)))
+	c, _ := lh.hashPnpmLock([]byte(strings.ReplaceAll(pnpm, "18.2.0", "18.3.1")))
+	if a != b || a == c {
+		t.Fatalf("pnpm hashes: settings %s/%s, upgrade %s", a, b, c)
+	}
+
+	yarn := "# yarn lockfile v1\n\nleft-pad@^1.0.0:\n  version \"1.3.0\"\n  resolved \"https://registry.yarnpkg.com/a\"\n"
+	a, _ = lh.hashYarnLock([]byte(yarn))
+	b, _ = lh.hashYarnLock([]byte(strings.Replace(yarn, "registry.yarnpkg.com", "registry.npmjs.org", 1)))
+	c, _ = lh.hashYarnLock([]byte(strings.Replace(yarn, "1.3.0", "1.3.1", 1)))
+	if a != b || a == c {
+		t.Fatalf("yarn hashes: registry %s/%s, upgrade %s", a, b, c)
+	}
+}
+
+func TestDiffPackages(t *testing.T) {
+	oldSet, _ := ParseLockfile("package-lock.json", []byte(lockV1))
+	newSet, _ := ParseLockfile("package-lock.json", []byte(lockV2))
+	d := DiffPackages(oldSet, newSet)
+	want := &PackageDiff{
+		Added:      []PackageChange{{Name: "zod", To: "3.23.8"}},
+		Removed:    []PackageChange{{Name: "left-pad", From: "1.3.0"}},
+		Upgraded:   []PackageChange{{Name: "react", From: "18.2.0", To: "18.3.1"}},
+		Downgraded: []PackageChange{{Name: "lodash", From: "4.17.21", To: "4.17.20"}},
+	}
+	if got, exp := formatDiff(d), formatDiff(want); got != exp {
+		t.Fatalf("diff = %s, want %s", got, exp)
+	}
+	if !DiffPackages(newSet, newSet).Empty() {
+		t.Fatal("identical sets must not differ")
+	}
+	if d := DiffPackages(nil, newSet); len(d.Added) != len(newSet.Packages) {
+		t.Fatalf("everything is added to an empty set: %+v", d)
+	}
+
+	// Two versions of a name changing at once can't be paired up
+	twoOld := &PackageSet{Packages: []LockedPackage{{Name: "lodash", Version: "3.10.1"}, {Name: "lodash", Version: "4.17.21"}}}
+	twoNew := &PackageSet{Packages: []LockedPackage{{Name: "lodash", Version: "3.10.2"}, {Name: "lodash", Version: "4.17.22"}}}
+	d = DiffPackages(twoOld, twoNew)
+	if len(d.Added) != 2 || len(d.Removed) != 2 || len(d.Upgraded) != 0 || d.Removed[0].From != "3.10.1" {
+		t.Fatalf("diff = %s", formatDiff(d))
+	}
+}
+
+func formatDiff(d *PackageDiff) string {
+	var out []string
+	for _, group := range [][]PackageChange{d.Added, d.Removed, d.Upgraded, d.Downgraded} {
+		var s []string
+		for _, c := range group {
+			s = append(s, c.Name+":"+c.From+">"+c.To)
+		}
+		out = append(out, strings.Join(s, ","))
+	}
+	return strings.Join(out, " | ")
+}
+
+func TestDiffLockfile(t *testing.T) {
+	d := DiffLockfile("web/package-lock.json", []byte(lockV1), []byte(lockV2))
+	if d.Status != "modified" || d.Ecosystem != EcosystemNPM || d.Packages == nil || len(d.Packages.Upgraded) != 1 {
+		t.Fatalf("modified = %+v", d)
+	}
+	if d := DiffLockfile("go.sum", nil, []byte("a v1.0.0 h1:x=\n")); d.Status != "added" || len(d.Packages.Added) != 1 {
+		t.Fatalf("added = %+v", d)
+	}
+	if d := DiffLockfile("go.sum", []byte("a v1.0.0 h1:x=\n"), nil); d.Status != "removed" || len(d.Packages.Removed) != 1 {
+		t.Fatalf("removed = %+v", d)
+	}
+	if d := DiffLockfile("Cargo.lock", []byte("x"), []byte("x")); d.Status != "unchanged" || !d.Packages.Empty() {
+		t.Fatalf("unchanged = %+v", d)
+	}
+	if d := DiffLockfile("bun.lockb", []byte{0}, []byte{1}); d.Packages != nil || d.Note == "" {
+		t.Fatalf("binary lockfiles can't be diffed: %+v", d)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.10.0", -1},
+		{"v1.2.0", "1.2", 0},
+		{"2.0.0-rc.1", "2.0.0", -1},
+		{"1.0.0+build.5", "1.0.0", 0},
+		{"0.9", "0.10", -1},
+		{"1.0.0-beta", "1.0.0-alpha", 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
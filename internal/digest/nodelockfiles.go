@@ -0,0 +1,269 @@
+package digest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pnpmLock is the part of pnpm-lock.yaml that decides what gets installed
+type pnpmLock struct {
+	version   string
+	importers map[string]map[string]string // project path -> dependency -> resolved version
+	packages  []LockedPackage
+}
+
+// pnpmDependency is a dependency of an importer: a bare version up to
+// lockfile version 5, {specifier, version} from version 6 on
+type pnpmDependency struct {
+	Version string
+}
+
+func (d *pnpmDependency) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		d.Version = n.Value
+		return nil
+	}
+	var v struct {
+		Version string `yaml:"version"`
+	}
+	if err := n.Decode(&v); err != nil {
+		return err
+	}
+	d.Version = v.Version
+	return nil
+}
+
+// pnpmImporter lists a workspace project's dependencies
+type pnpmImporter struct {
+	Dependencies         map[string]pnpmDependency `yaml:"dependencies"`
+	DevDependencies      map[string]pnpmDependency `yaml:"devDependencies"`
+	OptionalDependencies map[string]pnpmDependency `yaml:"optionalDependencies"`
+}
+
+// parsePnpmLock reads pnpm-lock.yaml in lockfile versions 5 to 9.
+// Single-project lockfiles list the project's dependencies at the top
+// level, workspaces under importers; package keys are /name/1.0.0_peer
+// before version 6, /name@1.0.0(peer) in 6 and name@1.0.0 from 9 on.
+func parsePnpmLock(data []byte) (*pnpmLock, error) {
+	var raw struct {
+		LockfileVersion string                  `yaml:"lockfileVersion"`
+		Importers       map[string]pnpmImporter `yaml:"importers"`
+		Root            pnpmImporter            `yaml:",inline"` // before version 5.4
+		Packages        map[string]struct {
+			Name    string `yaml:"name"`
+			Version string `yaml:"version"`
+			Dev     bool   `yaml:"dev"`
+		} `yaml:"packages"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.LockfileVersion == "" {
+		return nil, fmt.Errorf("no lockfileVersion")
+	}
+	lock := &pnpmLock{version: raw.LockfileVersion, importers: map[string]map[string]string{}}
+	importers := raw.Importers
+	if importers == nil {
+		importers = map[string]pnpmImporter{".": raw.Root}
+	}
+	for path, imp := range importers {
+		deps := map[string]string{}
+		for _, group := range []map[string]pnpmDependency{imp.Dependencies, imp.DevDependencies, imp.OptionalDependencies} {
+			for name, dep := range group {
+				deps[name] = dep.Version
+			}
+		}
+		lock.importers[path] = deps
+	}
+
+	legacy := strings.HasPrefix(raw.LockfileVersion, "5") || strings.HasPrefix(raw.LockfileVersion, "4")
+	for key, pkg := range raw.Packages {
+		name, version := pnpmPackageKey(key, legacy)
+		// Tarball and git dependencies carry their own name and version
+		if pkg.Name != "" {
+			name = pkg.Name
+		}
+		if pkg.Version != "" {
+			version = pkg.Version
+		}
+		if name != "" && version != "" {
+			lock.packages = append(lock.packages, LockedPackage{Name: name, Version: version, Dev: pkg.Dev})
+		}
+	}
+	return lock, nil
+}
+
+// pnpmPackageKey splits a packages key into name and version, dropping
+// the peer dependency suffix
+func pnpmPackageKey(key string, legacy bool) (name, version string) {
+	key = strings.TrimPrefix(key, "/")
+	if legacy {
+		i := strings.LastIndex(key, "/")
+		if i <= 0 {
+			return "", ""
+		}
+		name, version = key[:i], key[i+1:]
+		version, _, _ = strings.Cut(version, "_")
+		return name, version
+	}
+	key, _, _ = strings.Cut(key, "(")
+	i := strings.LastIndex(key, "@")
+	if i <= 0 {
+		return "", ""
+	}
+	return key[:i], key[i+1:]
+}
+
+// yarnEntry is a resolved package of yarn.lock and the descriptors
+// (name@range) that resolve to it
+type yarnEntry struct {
+	descriptors []string
+	name        string
+	version     string
+}
+
+// parseYarnLock reads yarn.lock: the v1 format of Yarn Classic or the YAML
+// of Yarn 2 and later, whose workspace entries are left out
+func parseYarnLock(data []byte) ([]yarnEntry, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if strings.HasPrefix(text, "__metadata:") || strings.Contains(text, "\n__metadata:") {
+		return parseYarnBerry(data)
+	}
+	var entries []yarnEntry
+	for n, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		switch {
+		case indent == 0:
+			keys, ok := strings.CutSuffix(trimmed, ":")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected a package entry", n+1)
+			}
+			descriptors, err := splitYarnDescriptors(keys)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			entries = append(entries, yarnEntry{descriptors: descriptors, name: yarnDescriptorName(descriptors[0])})
+		case len(entries) == 0:
+			return nil, fmt.Errorf("line %d: field outside a package entry", n+1)
+		case indent == 2:
+			// Fields are "key value", both optionally quoted; deeper
+			// lines belong to dependency maps
+			key, value, _ := strings.Cut(trimmed, " ")
+			if key == "version" || key == `"version"` {
+				v, err := unquoteYarn(strings.TrimSpace(value))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", n+1, err)
+				}
+				entries[len(entries)-1].version = v
+			}
+		}
+	}
+	for _, e := range entries {
+		if e.version == "" {
+			return nil, fmt.Errorf("%s has no version", e.descriptors[0])
+		}
+	}
+	return entries, nil
+}
+
+// parseYarnBerry reads the YAML lockfile of Yarn 2 and later
+func parseYarnBerry(data []byte) ([]yarnEntry, error) {
+	var raw map[string]struct {
+		Version    string `yaml:"version"`
+		Resolution string `yaml:"resolution"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	var entries []yarnEntry
+	for key, e := range raw {
+		if key == "__metadata" || strings.Contains(e.Resolution, "@workspace:") {
+			continue
+		}
+		descriptors, err := splitYarnDescriptors(key)
+		if err != nil {
+			return nil, err
+		}
+		if e.Version == "" {
+			return nil, fmt.Errorf("%s has no version", key)
+		}
+		entries = append(entries, yarnEntry{descriptors: descriptors, name: yarnDescriptorName(descriptors[0]), version: e.Version})
+	}
+	return entries, nil
+}
+
+// splitYarnDescriptors splits an entry key: descriptors separated by
+// commas, each optionally quoted
+func splitYarnDescriptors(keys string) ([]string, error) {
+	var descriptors []string
+	for len(keys) > 0 {
+		keys = strings.TrimLeft(keys, " ,")
+		if keys == "" {
+			break
+		}
+		var d string
+		if keys[0] == '"' {
+			end := 1
+			for end < len(keys) && (keys[end] != '"' || keys[end-1] == '\\') {
+				end++
+			}
+			if end == len(keys) {
+				return nil, fmt.Errorf("unterminated quote in %q", keys)
+			}
+			var err error
+			if d, err = strconv.Unquote(keys[:end+1]); err != nil {
+				return nil, err
+			}
+			keys = keys[end+1:]
+		} else {
+			d, keys, _ = strings.Cut(keys, ",")
+			d = strings.TrimSpace(d)
+		}
+		descriptors = append(descriptors, d)
+	}
+	if len(descriptors) == 0 {
+		return nil, fmt.Errorf("empty package entry")
+	}
+	return descriptors, nil
+}
+
+// unquoteYarn returns a field value without its quotes
+func unquoteYarn(v string) (string, error) {
+	if strings.HasPrefix(v, `"`) {
+		return strconv.Unquote(v)
+	}
+	return v, nil
+}
+
+// yarnDescriptorName returns the package name of name@range, scoped or
+// not; patch: and npm: protocols follow the first @ after the name
+func yarnDescriptorName(descriptor string) string {
+	if i := strings.Index(descriptor[min(1, len(descriptor)):], "@"); i >= 0 {
+		return descriptor[:i+1]
+	}
+	return descriptor
+}
+
+// hashLines returns the sorted lines hashPnpmLock hashes: the lockfile
+// version, each project's resolved dependencies and each package
+func (l *pnpmLock) hashLines() []string {
+	lines := []string{"lockfileVersion:" + l.version}
+	for path, deps := range l.importers {
+		for name, version := range deps {
+			lines = append(lines, fmt.Sprintf("importer:%s:%s@%s", path, name, version))
+		}
+	}
+	for _, p := range l.packages {
+		lines = append(lines, fmt.Sprintf("%s@%s", p.Name, p.Version))
+	}
+	sort.Strings(lines[1:])
+	return lines
+}
@@ -2,11 +2,17 @@ package digest
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"unicode/utf8"
 )
 
+// errNotUTF8 rejects content that is not text; such files stay out of
+// digests
+var errNotUTF8 = errors.New("content is not valid UTF-8")
+
 // Normalizer provides content normalization for deterministic hashing.
 // It handles line ending normalization, BOM removal, and encoding validation
 // to ensure consistent digest generation across different platforms and editors.
@@ -54,7 +60,7 @@ func (n *Normalizer) Normalize(content []byte) ([]byte, error) {
 	// Validate UTF-8 if enabled
 	if n.validateUTF8 {
 		if !utf8.Valid(result) {
-			return nil, fmt.Errorf("content is not valid UTF-8")
+			return nil, errNotUTF8
 		}
 	}
 
@@ -66,6 +72,81 @@ func (n *Normalizer) Normalize(content []byte) ([]byte, error) {
 	return result, nil
 }
 
+// NormalizeStream copies src to dst with the enabled normalizations applied,
+// writing the bytes Normalize would return without holding the content in
+// memory. buf is the read buffer. Invalid UTF-8 is only reported once src is
+// exhausted, so dst must be discarded on error.
+func (n *Normalizer) NormalizeStream(dst io.Writer, src io.Reader, buf []byte) error {
+	// A BOM is at most four bytes
+	head := make([]byte, 4)
+	k, err := io.ReadFull(src, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	head = head[:k]
+	if n.stripBOM {
+		head = n.stripByteOrderMark(head)
+	}
+	r := io.MultiReader(bytes.NewReader(head), src)
+
+	var pending []byte // incomplete UTF-8 sequence held for the next read
+	invalid, prevCR := false, false
+	out := make([]byte, 0, len(buf))
+	for {
+		k, rerr := r.Read(buf)
+		data := buf[:k]
+		if n.validateUTF8 && !invalid && k > 0 {
+			check := data
+			if len(pending) > 0 {
+				check = append(pending, data...)
+			}
+			cut := len(check)
+			for i := len(check) - 1; i >= 0 && i >= len(check)-utf8.UTFMax; i-- {
+				if utf8.RuneStart(check[i]) {
+					if !utf8.FullRune(check[i:]) {
+						cut = i
+					}
+					break
+				}
+			}
+			invalid = !utf8.Valid(check[:cut])
+			pending = append([]byte(nil), check[cut:]...)
+		}
+		if n.normalizeLineEndings {
+			// CRLF and lone CR become LF, as in normalizeLineEndingsImpl
+			out = out[:0]
+			for _, b := range data {
+				switch {
+				case b == '\r':
+					out = append(out, '\n')
+					prevCR = true
+					continue
+				case b == '\n' && prevCR:
+				default:
+					out = append(out, b)
+				}
+				prevCR = false
+			}
+			data = out
+		}
+		if len(data) > 0 {
+			if _, werr := dst.Write(data); werr != nil {
+				return werr
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+	if invalid || len(pending) > 0 {
+		return errNotUTF8
+	}
+	return nil
+}
+
 // stripByteOrderMark removes UTF-8, UTF-16BE, UTF-16LE, UTF-32BE, and UTF-32LE BOMs.
 func (n *Normalizer) stripByteOrderMark(content []byte) []byte {
 	// UTF-8 BOM: EF BB BF
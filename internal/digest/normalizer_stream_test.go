@@ -0,0 +1,35 @@
+package digest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeStream_MatchesNormalize(t *testing.T) {
+	inputs := []string{
+		"",
+		"a",
+		"line1\r\nline2\rline3\n",
+		"\r\r\n\r",
+		"\xEF\xBB\xBFwith bom\r\n",
+		"\xFF\xFEab",
+		"héllo wörld ✓ 🚀\r\n",
+		"bad \xff byte",
+		"truncated \xe2\x9c",
+		strings.Repeat("x\r\n€", 1000),
+	}
+	for _, in := range inputs {
+		want, wantErr := NewNormalizer().Normalize([]byte(in))
+		for _, size := range []int{1, 2, 3, 5, 32 * 1024} {
+			var got bytes.Buffer
+			err := NewNormalizer().NormalizeStream(&got, strings.NewReader(in), make([]byte, size))
+			if (err != nil) != (wantErr != nil) {
+				t.Fatalf("%q with %d-byte reads: error %v, want %v", in, size, err, wantErr)
+			}
+			if err == nil && !bytes.Equal(got.Bytes(), want) {
+				t.Fatalf("%q with %d-byte reads: got %q, want %q", in, size, got.Bytes(), want)
+			}
+		}
+	}
+}
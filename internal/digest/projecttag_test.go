@@ -19,3 +19,66 @@ func TestProjectTag_Basic(t *testing.T) {
 		t.Fatalf("expected 12-char tag, got %q", tag)
 	}
 }
+
+func TestProjectTag_PathsScopeToPackage(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("pnpm-lock.yaml", "lockfileVersion: '9.0'\n")
+	write("apps/web/index.js", "web")
+	write("packages/ui/index.js", "ui")
+	write("apps/admin/index.js", "admin")
+	opts := &Options{Algorithm: "sha256", Paths: []string{"apps/web", "packages/ui"}}
+	tag := func() string {
+		v, err := ProjectTag(dir, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	base := tag()
+	write("apps/admin/index.js", "admin v2")
+	if got := tag(); got != base {
+		t.Fatalf("an unrelated package changed the digest: %s -> %s", base, got)
+	}
+	write("packages/ui/index.js", "ui v2")
+	dep := tag()
+	if dep == base {
+		t.Fatal("a workspace dependency must change the digest")
+	}
+	write("pnpm-lock.yaml", "lockfileVersion: '9.0'\n# changed\n")
+	if got := tag(); got == dep {
+		t.Fatal("the shared lockfile must change the digest")
+	}
+}
+
+func TestProjectTag_ExtraFiles(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0o644)
+	tmpl := filepath.Join(outside, "go.Dockerfile.tmpl")
+	os.WriteFile(tmpl, []byte("FROM a"), 0o644)
+	opts := &Options{Algorithm: "sha256", ExtraFiles: map[string]string{"~/.mitl/templates/go.Dockerfile.tmpl": tmpl}}
+
+	base, err := ProjectTag(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := ProjectTag(dir, &Options{Algorithm: "sha256"})
+	if plain == base {
+		t.Fatal("extra files must count toward the digest")
+	}
+	os.WriteFile(tmpl, []byte("FROM b"), 0o644)
+	if got, _ := ProjectTag(dir, opts); got == base {
+		t.Fatal("editing an extra file must change the digest")
+	}
+	os.Remove(tmpl)
+	if got, _ := ProjectTag(dir, opts); got != plain {
+		t.Fatal("missing extra files are skipped")
+	}
+}
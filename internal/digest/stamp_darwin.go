@@ -0,0 +1,14 @@
+package digest
+
+import (
+	"os"
+	"syscall"
+)
+
+// statStamp adds the inode and change time to a file's stamp
+func statStamp(st *fileStamp, info os.FileInfo) {
+	if sys, ok := info.Sys().(*syscall.Stat_t); ok {
+		st.Inode = sys.Ino
+		st.CTime = sys.Ctimespec.Nano()
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"mitl/internal/detector"
+	"mitl/internal/manifest"
 )
 
 // execCommand enables test stubbing.
@@ -270,11 +273,12 @@ func (c *CacheHealthCheck) Run() CheckResult {
 	return CheckResult{Status: StatusOK, Message: fmt.Sprintf("Cache healthy: %d capsules", count)}
 }
 
-// PnpmOptimizationCheck ensures pnpm is being used
+// PnpmOptimizationCheck verifies the Node project's lockfiles agree with the
+// package manager policy (respect, enforce-pnpm or a fixed manager)
 type PnpmOptimizationCheck struct{}
 
 func (p *PnpmOptimizationCheck) Name() string        { return "Node.js" }
-func (p *PnpmOptimizationCheck) Description() string { return "Checking pnpm optimization" }
+func (p *PnpmOptimizationCheck) Description() string { return "Checking package manager" }
 func (p *PnpmOptimizationCheck) CanAutoFix() bool    { return false }
 func (p *PnpmOptimizationCheck) Fix() error          { return nil }
 func (p *PnpmOptimizationCheck) Severity() Severity  { return SeverityMedium }
@@ -283,13 +287,40 @@ func (p *PnpmOptimizationCheck) Run() CheckResult {
 	if _, err := os.Stat("package.json"); err != nil {
 		return CheckResult{Status: StatusOK, Message: "Not a Node.js project"}
 	}
-	if _, err := exec.LookPath("pnpm"); err != nil {
-		return CheckResult{Status: StatusWarning, Message: "pnpm not installed (70% space savings)", FixCommand: "npm install -g pnpm", Impact: "Using 3x more disk space"}
+	pd := detector.NewProjectDetector("")
+	_ = pd.Detect()
+	m, err := manifest.Load(pd.Root)
+	if err != nil {
+		return CheckResult{Status: StatusWarning, Message: "Cannot read package manager policy", Details: err.Error(), FixCommand: "mitl inspect"}
+	}
+	m.Apply(pd)
+	policy, perr := m.PackageManagerPolicy()
+	if perr != nil {
+		return CheckResult{Status: StatusWarning, Message: "Invalid package manager policy", Details: perr.Error(), FixCommand: "unset " + detector.PackageManagerEnv}
+	}
+	detected := pd.Dependencies.Node.PackageManager
+	pd.ApplyPackageManagerPolicy(policy)
+	manager := pd.Dependencies.Node.PackageManager
+
+	present := pd.PresentLockfiles()
+	if len(present) > 1 {
+		return CheckResult{Status: StatusWarning, Message: "Multiple lockfiles: " + strings.Join(present, ", "),
+			Details: fmt.Sprintf("%s is used; stale lockfiles drift and confuse tools", manager),
+			Impact:  "Inconsistent installs across machines"}
+	}
+	if manager != detected && len(present) > 0 {
+		fix := manager + " install"
+		if manager == detector.PackageManagerPNPM {
+			fix = "pnpm import"
+		}
+		return CheckResult{Status: StatusWarning,
+			Message:    fmt.Sprintf("Project uses %s but policy %q installs with %s", detected, policy, manager),
+			FixCommand: fix, Impact: "Lockfile churn; set node.package_manager: respect in mitl.yaml to keep " + detected}
 	}
-	if _, err := os.Stat("pnpm-lock.yaml"); err != nil {
-		return CheckResult{Status: StatusWarning, Message: "Project not using pnpm", FixCommand: "pnpm import && rm package-lock.json", Impact: "Missing performance gains"}
+	if manager == detector.PackageManagerPNPM {
+		return CheckResult{Status: StatusOK, Message: "Using pnpm (optimal)"}
 	}
-	return CheckResult{Status: StatusOK, Message: "Using pnpm (optimal)"}
+	return CheckResult{Status: StatusOK, Message: fmt.Sprintf("Using %s (%s policy)", manager, policy)}
 }
 
 // Fix attempts automatic fixes for checks that support it.
@@ -0,0 +1,240 @@
+// Package dotenv parses .env files.
+//
+// Supported syntax:
+//
+//	# comments and blank lines
+//	KEY=value                 # inline comments after unquoted values
+//	export KEY=value
+//	KEY='literal $NOT_EXPANDED'
+//	KEY="escapes\n and ${OTHER} or $OTHER"
+//	KEY=${OTHER:-fallback}
+//	KEY="multi
+//	line"
+//
+// Unquoted and double-quoted values are interpolated against keys defined
+// earlier in the file first, then the lookup function (usually os.LookupEnv).
+package dotenv
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Var is a single KEY=value assignment
+type Var struct {
+	Key   string
+	Value string
+}
+
+// LookupFunc resolves variables not defined in the file
+type LookupFunc func(string) (string, bool)
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// ReadFile parses the .env file at path
+func ReadFile(path string, lookup LookupFunc) ([]Var, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := Parse(string(data), lookup)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// Parse parses .env content. Later assignments of the same key win, but the
+// key keeps the position of its first assignment.
+func Parse(content string, lookup LookupFunc) ([]Var, error) {
+	if lookup == nil {
+		lookup = func(string) (string, bool) { return "", false }
+	}
+	p := &parser{src: strings.ReplaceAll(content, "\r\n", "\n"), lookup: lookup, index: map[string]int{}}
+	return p.parse()
+}
+
+// Map converts parsed vars to a map
+func Map(vars []Var) map[string]string {
+	m := make(map[string]string, len(vars))
+	for _, v := range vars {
+		m[v.Key] = v.Value
+	}
+	return m
+}
+
+type parser struct {
+	src    string
+	pos    int
+	line   int
+	lookup LookupFunc
+	vars   []Var
+	index  map[string]int
+}
+
+func (p *parser) parse() ([]Var, error) {
+	p.line = 1
+	for p.pos < len(p.src) {
+		p.skipBlank()
+		if p.pos >= len(p.src) {
+			break
+		}
+		if p.src[p.pos] == '#' {
+			p.skipLine()
+			continue
+		}
+		line := p.line
+		key := p.readKey()
+		if strings.HasPrefix(key, "export ") {
+			key = strings.TrimSpace(strings.TrimPrefix(key, "export "))
+		}
+		if !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", line, key)
+		}
+		if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+			return nil, fmt.Errorf("line %d: expected '=' after %s", line, key)
+		}
+		p.pos++
+		value, err := p.readValue()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		p.set(key, value)
+	}
+	return p.vars, nil
+}
+
+func (p *parser) set(key, value string) {
+	if i, ok := p.index[key]; ok {
+		p.vars[i].Value = value
+		return
+	}
+	p.index[key] = len(p.vars)
+	p.vars = append(p.vars, Var{Key: key, Value: value})
+}
+
+func (p *parser) skipBlank() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\n':
+			p.line++
+		case ' ', '\t':
+		default:
+			return
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) skipLine() {
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+// readKey reads up to '=' or end of line
+func (p *parser) readKey() string {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] != '=' && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+	return strings.TrimSpace(p.src[start:p.pos])
+}
+
+func (p *parser) readValue() (string, error) {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return "", nil
+	}
+	switch p.src[p.pos] {
+	case '\'':
+		raw, err := p.readQuoted('\'')
+		return raw, err
+	case '"':
+		raw, err := p.readQuoted('"')
+		if err != nil {
+			return "", err
+		}
+		return p.expand(unescape(raw)), nil
+	}
+	start := p.pos
+	p.skipLine()
+	raw := p.src[start:p.pos]
+	// An unquoted # starts a comment when preceded by whitespace
+	if i := strings.Index(raw, " #"); i >= 0 {
+		raw = raw[:i]
+	} else if i := strings.Index(raw, "\t#"); i >= 0 {
+		raw = raw[:i]
+	}
+	return p.expand(strings.TrimSpace(raw)), nil
+}
+
+// readQuoted reads a quoted value, which may span lines, then discards the
+// rest of the line (typically a comment)
+func (p *parser) readQuoted(q byte) (string, error) {
+	p.pos++ // opening quote
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '\\' && q == '"' && p.pos+1 < len(p.src) {
+			b.WriteByte(c)
+			b.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+			continue
+		}
+		if c == q {
+			p.pos++
+			p.skipLine()
+			return b.String(), nil
+		}
+		if c == '\n' {
+			p.line++
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+	return "", fmt.Errorf("unterminated %c quote", q)
+}
+
+func unescape(s string) string {
+	r := strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`, `\$`, "\x00")
+	return r.Replace(s)
+}
+
+var refPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:?-[^}]*)?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// expand substitutes ${VAR}, ${VAR:-default}, ${VAR-default} and $VAR.
+// An escaped \$ (marked with NUL by unescape) is restored as a literal $.
+func (p *parser) expand(s string) string {
+	out := refPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := refPattern.FindStringSubmatch(ref)
+		name, def := m[1], m[2]
+		if name == "" {
+			name = m[3]
+		}
+		val, ok := p.resolve(name)
+		switch {
+		case strings.HasPrefix(def, ":-"):
+			if val == "" {
+				return def[2:]
+			}
+		case strings.HasPrefix(def, "-"):
+			if !ok {
+				return def[1:]
+			}
+		}
+		return val
+	})
+	return strings.ReplaceAll(out, "\x00", "$")
+}
+
+func (p *parser) resolve(name string) (string, bool) {
+	if i, ok := p.index[name]; ok {
+		return p.vars[i].Value, true
+	}
+	return p.lookup(name)
+}
@@ -0,0 +1,69 @@
+package dotenv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	content := `# comment
+APP_NAME=mitl
+export APP_ENV=local
+EMPTY=
+SPACED = padded value   # trailing comment
+HASH=abc#def
+SINGLE='literal $APP_NAME # not a comment'
+DOUBLE="line1\nline2 \"quoted\" ${APP_NAME}"
+MULTI="first
+second"
+REF=$APP_NAME-${APP_ENV}
+DEFAULT=${MISSING:-fallback}
+HOST=${HOME_DIR}
+ESCAPED="cost \$5"
+APP_NAME=override
+`
+	lookup := func(k string) (string, bool) {
+		if k == "HOME_DIR" {
+			return "/home/dev", true
+		}
+		return "", false
+	}
+	vars, err := Parse(content, lookup)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	got := Map(vars)
+	want := map[string]string{
+		"APP_NAME": "override",
+		"APP_ENV":  "local",
+		"EMPTY":    "",
+		"SPACED":   "padded value",
+		"HASH":     "abc#def",
+		"SINGLE":   "literal $APP_NAME # not a comment",
+		"DOUBLE":   "line1\nline2 \"quoted\" mitl",
+		"MULTI":    "first\nsecond",
+		"REF":      "mitl-local",
+		"DEFAULT":  "fallback",
+		"HOST":     "/home/dev",
+		"ESCAPED":  "cost $5",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Parse mismatch:\n got: %#v\nwant: %#v", got, want)
+	}
+	// Redefined keys keep their first position
+	if vars[0].Key != "APP_NAME" {
+		t.Fatalf("expected APP_NAME first, got %s", vars[0].Key)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, content := range []string{
+		"NOVALUE\n",
+		"1BAD=x\n",
+		"OPEN=\"never closed\n",
+	} {
+		if _, err := Parse(content, nil); err == nil {
+			t.Fatalf("expected error for %q", content)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+// Package manifest loads the per-project mitl.yaml manifest.
+//
+// The manifest is checked into the project and pins settings that would
+// otherwise be guessed by the detector: project type, language versions,
+// PHP extensions, system packages, environment, ports and mounts. Detector
+// output is only used as a default for anything the manifest leaves unset.
+//
+// Example:
+//
+//	version: 1
+//	type: php-laravel
+//	runtimes:
+//	  php: "8.2"
+//	  node: "20"
+//	php:
+//	  extensions: [intl, redis]
+//	node:
+//	  package_manager: respect
+//	go:
+//	  target: ./cmd/api
+//	build:
+//	  dockerfile: docker/dev.Dockerfile
+//	  target: dev
+//	  args:
+//	    NODE_ENV: development
+//	system_packages: [imagemagick]
+//	env:
+//	  APP_ENV: local
+//	forward_env: [AWS_PROFILE]
+//	ports: ["8000:8000"]
+//	mounts: ["./storage:/app/storage"]
+//	shell: /bin/zsh
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"mitl/internal/build"
+	"mitl/internal/detector"
+	"mitl/internal/ports"
+
+	e "mitl/pkg/errors"
+)
+
+// FileName is the canonical manifest file name
+const FileName = "mitl.yaml"
+
+// CurrentVersion is the newest manifest schema version understood by mitl
+const CurrentVersion = 1
+
+// FileNames lists accepted manifest file names in lookup order
+var FileNames = []string{FileName, "mitl.yml"}
+
+// Manifest holds project settings pinned in mitl.yaml
+type Manifest struct {
+	Version        int               `yaml:"version,omitempty"`
+	Type           string            `yaml:"type,omitempty"`
+	Runtimes       Runtimes          `yaml:"runtimes,omitempty"`
+	PHP            PHPSettings       `yaml:"php,omitempty"`
+	Node           NodeSettings      `yaml:"node,omitempty"`
+	Go             GoSettings        `yaml:"go,omitempty"`
+	Build          BuildSettings     `yaml:"build,omitempty"`
+	SystemPackages []string          `yaml:"system_packages,omitempty"`
+	Env            map[string]string `yaml:"env,omitempty"`
+	ForwardEnv     []string          `yaml:"forward_env,omitempty"`
+	Ports          []string          `yaml:"ports,omitempty"`
+	Mounts         []string          `yaml:"mounts,omitempty"`
+	Shell          string            `yaml:"shell,omitempty"`
+
+	// Path is the file the manifest was loaded from
+	Path string `yaml:"-"`
+}
+
+// Runtimes pins language versions (e.g. "8.2", "20", "3.12")
+type Runtimes struct {
+	PHP    string `yaml:"php,omitempty"`
+	Node   string `yaml:"node,omitempty"`
+	Python string `yaml:"python,omitempty"`
+	Go     string `yaml:"go,omitempty"`
+	Ruby   string `yaml:"ruby,omitempty"`
+	Rust   string `yaml:"rust,omitempty"`
+	Java   string `yaml:"java,omitempty"`
+}
+
+// PHPSettings holds PHP-specific overrides
+type PHPSettings struct {
+	Extensions []string `yaml:"extensions,omitempty"`
+}
+
+// NodeSettings holds Node-specific overrides
+type NodeSettings struct {
+	// PackageManager is the policy: respect (default), enforce-pnpm, or a
+	// fixed manager (npm, yarn, pnpm, bun)
+	PackageManager string `yaml:"package_manager,omitempty"`
+}
+
+// GoSettings holds Go-specific overrides
+type GoSettings struct {
+	// Target is the main package the capsule builds, e.g. ./cmd/api
+	Target string `yaml:"target,omitempty"`
+}
+
+// BuildSettings chooses what the capsule is built from
+type BuildSettings struct {
+	// Mode is auto (the project's Dockerfile or Containerfile when present,
+	// else generate), dockerfile or generate
+	Mode string `yaml:"mode,omitempty"`
+	// Dockerfile is a project Dockerfile to build from, relative to the root
+	Dockerfile string `yaml:"dockerfile,omitempty"`
+	// Target is the stage to build
+	Target string `yaml:"target,omitempty"`
+	// Args are passed as --build-arg
+	Args map[string]string `yaml:"args,omitempty"`
+	// Source is mounted (default: the capsule is tagged by its dependencies
+	// and the project is mounted over /app) or baked (tagged by every file)
+	Source string `yaml:"source,omitempty"`
+}
+
+// BakedSource reports whether capsules are tagged by the full project
+// digest rather than the dependency digest
+func (m *Manifest) BakedSource() bool {
+	return m != nil && m.Build.Source == build.SourceBaked
+}
+
+var (
+	versionPattern   = regexp.MustCompile(`^\d+(\.\d+){0,2}$`)
+	extensionPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+	envKeyPattern    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	packagePattern   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9+._=~-]*$`)
+	shellPattern     = regexp.MustCompile(`^(/[A-Za-z0-9._-]+)+$|^[A-Za-z0-9._-]+$`)
+	stagePattern     = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._-]*$`)
+)
+
+// Find returns the path of the manifest in root, or "" when there is none
+func Find(root string) string {
+	for _, name := range FileNames {
+		p := filepath.Join(root, name)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// Load reads and validates the manifest in root. It returns (nil, nil) when
+// the project has no manifest.
+func Load(root string) (*Manifest, error) {
+	path := Find(root)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, e.Wrap(err, e.ErrPermissionDenied, "Failed to read "+filepath.Base(path)).
+			WithContext("file", path)
+	}
+	m, perr := Parse(data)
+	if perr != nil {
+		return nil, perr.WithContext("file", path)
+	}
+	m.Path = path
+	if verr := m.Validate(); verr != nil {
+		return nil, verr
+	}
+	return m, nil
+}
+
+// Parse decodes manifest YAML. Unknown keys are rejected so typos surface
+// instead of being silently ignored.
+func Parse(data []byte) (*Manifest, *e.MitlError) {
+	var m Manifest
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, e.New(e.ErrInvalidManifest, "Invalid "+FileName+": "+yamlMessage(err)).WithCause(err)
+	}
+	return &m, nil
+}
+
+// yamlMessage strips the library prefix from YAML decode errors
+func yamlMessage(err error) string {
+	msg := err.Error()
+	msg = strings.TrimPrefix(msg, "yaml: unmarshal errors:\n")
+	msg = strings.TrimPrefix(msg, "yaml: ")
+	return strings.TrimSpace(msg)
+}
+
+// Validate checks every field and reports all problems in a single error
+func (m *Manifest) Validate() *e.MitlError {
+	if m == nil {
+		return nil
+	}
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if m.Version < 0 || m.Version > CurrentVersion {
+		add("unsupported version %d (supported: %d)", m.Version, CurrentVersion)
+	}
+	if m.Type != "" && !detector.IsKnownType(detector.ProjectType(m.Type)) {
+		add("unknown type %q", m.Type)
+	}
+	runtimes := m.Runtimes.byName()
+	for _, name := range runtimeNames {
+		if v := runtimes[name]; v != "" && !versionPattern.MatchString(v) {
+			add("runtimes.%s: invalid version %q (expected e.g. \"8.2\")", name, v)
+		}
+	}
+	for _, ext := range m.PHP.Extensions {
+		if !extensionPattern.MatchString(ext) {
+			add("php.extensions: invalid extension %q", ext)
+		}
+	}
+	if _, err := detector.ParsePackageManagerPolicy(m.Node.PackageManager); err != nil {
+		add("node.package_manager: %v", err)
+	}
+	if t := m.Go.Target; t != "" && relativePath(t) == "" {
+		add("go.target: %q must be a package directory inside the module, e.g. ./cmd/api", t)
+	}
+	if b := m.Build; b.Mode != "" || b.Dockerfile != "" || b.Target != "" || len(b.Args) > 0 || b.Source != "" {
+		switch b.Mode {
+		case "", build.ModeAuto, build.ModeDockerfile:
+		case build.ModeGenerate:
+			if b.Dockerfile != "" {
+				add("build.dockerfile: not used with mode generate")
+			}
+		default:
+			add("build.mode: unknown mode %q (expected %s)", b.Mode, strings.Join(build.Modes, ", "))
+		}
+		if b.Dockerfile != "" && relativePath(b.Dockerfile) == "" {
+			add("build.dockerfile: %q must be a path inside the project", b.Dockerfile)
+		}
+		if b.Target != "" && !stagePattern.MatchString(b.Target) {
+			add("build.target: invalid stage name %q", b.Target)
+		}
+		for _, k := range slices.Sorted(maps.Keys(b.Args)) {
+			if !envKeyPattern.MatchString(k) {
+				add("build.args: invalid argument name %q", k)
+			}
+		}
+		switch b.Source {
+		case "", build.SourceMounted, build.SourceBaked:
+		default:
+			add("build.source: unknown value %q (expected %s)", b.Source, strings.Join(build.SourceHandlings, ", "))
+		}
+	}
+	for _, pkg := range m.SystemPackages {
+		if !packagePattern.MatchString(pkg) {
+			add("system_packages: invalid package %q", pkg)
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(m.Env)) {
+		if !envKeyPattern.MatchString(k) {
+			add("env: invalid variable name %q", k)
+		}
+	}
+	for _, k := range m.ForwardEnv {
+		if !envKeyPattern.MatchString(k) {
+			add("forward_env: invalid variable name %q", k)
+		}
+	}
+	for _, p := range m.Ports {
+		if _, err := ports.Parse(p); err != nil {
+			add("ports: %q: %v", p, err)
+		}
+	}
+	for _, v := range m.Mounts {
+		if err := validateMount(v); err != nil {
+			add("mounts: %q: %v", v, err)
+		}
+	}
+
+	if m.Shell != "" && !shellPattern.MatchString(m.Shell) {
+		add("shell: invalid shell %q (expected a path like /bin/zsh or a name like zsh)", m.Shell)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	name := FileName
+	if m.Path != "" {
+		name = filepath.Base(m.Path)
+	}
+	err := e.New(e.ErrInvalidManifest, fmt.Sprintf("Invalid %s: %s", name, problems[0])).
+		WithDetails("- " + strings.Join(problems, "\n- "))
+	if m.Path != "" {
+		err = err.WithContext("file", m.Path)
+	}
+	return err
+}
+
+// runtimeNames fixes the order runtimes are validated and reported in
+var runtimeNames = []string{"php", "node", "python", "go", "ruby", "rust", "java"}
+
+// byName returns pinned runtimes keyed by language name
+func (r Runtimes) byName() map[string]string {
+	return map[string]string{
+		"php":    r.PHP,
+		"node":   r.Node,
+		"python": r.Python,
+		"go":     r.Go,
+		"ruby":   r.Ruby,
+		"rust":   r.Rust,
+		"java":   r.Java,
+	}
+}
+
+// validateMount accepts source:/container/path[:ro|:rw]
+func validateMount(spec string) error {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fmt.Errorf("expected source:/container/path[:ro]")
+	}
+	if parts[0] == "" {
+		return fmt.Errorf("empty source")
+	}
+	if !strings.HasPrefix(parts[1], "/") {
+		return fmt.Errorf("container path must be absolute")
+	}
+	if len(parts) == 3 && parts[2] != "ro" && parts[2] != "rw" {
+		return fmt.Errorf("unsupported mode %q", parts[2])
+	}
+	return nil
+}
+
+// Apply overlays manifest values on detector output. Fields left unset in
+// the manifest keep whatever the detector found.
+func (m *Manifest) Apply(pd *detector.ProjectDetector) {
+	if m == nil || pd == nil {
+		return
+	}
+	if m.Type != "" && detector.ProjectType(m.Type) != pd.Type {
+		pd.Type = detector.ProjectType(m.Type)
+		pd.AnalyzeDependencies()
+	}
+	runtimes := m.Runtimes.byName()
+	for _, name := range slices.Sorted(maps.Keys(runtimes)) {
+		if v := runtimes[name]; v != "" {
+			setRuntimeVersion(pd, name, v)
+		}
+	}
+	if t := relativePath(m.Go.Target); t != "" {
+		pd.Dependencies.Go.Target = t
+	}
+	if len(m.PHP.Extensions) > 0 {
+		pd.Dependencies.PHP.Extensions = detector.UniqueStrings(m.PHP.Extensions)
+	}
+	if len(m.SystemPackages) > 0 {
+		pd.Dependencies.System = detector.UniqueStrings(m.SystemPackages)
+	}
+	if pd.Metadata == nil {
+		pd.Metadata = make(map[string]interface{})
+	}
+	pd.Metadata["manifest"] = m.Path
+}
+
+// relativePath cleans a go.target or build.dockerfile value to a
+// root-relative path ("." or "cmd/api"). Absolute paths and paths leaving
+// the project yield "".
+func relativePath(t string) string {
+	if t == "" || path.IsAbs(t) || strings.Contains(t, `\`) {
+		return ""
+	}
+	clean := path.Clean(t)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return ""
+	}
+	return clean
+}
+
+// PackageManagerPolicy returns the Node package manager policy: the
+// MITL_PACKAGE_MANAGER environment variable, then node.package_manager,
+// then respect. It is safe to call on a nil manifest.
+func (m *Manifest) PackageManagerPolicy() (detector.PackageManagerPolicy, error) {
+	if v := os.Getenv(detector.PackageManagerEnv); v != "" {
+		p, err := detector.ParsePackageManagerPolicy(v)
+		if err != nil {
+			return detector.PolicyRespect, fmt.Errorf("%s: %w", detector.PackageManagerEnv, err)
+		}
+		return p, nil
+	}
+	if m == nil {
+		return detector.PolicyRespect, nil
+	}
+	return detector.ParsePackageManagerPolicy(m.Node.PackageManager)
+}
+
+// setRuntimeVersion pins a runtime version from the manifest: on the
+// ecosystem's dependencies, which the templates read, and on the matching
+// language entry
+func setRuntimeVersion(pd *detector.ProjectDetector, name, version string) {
+	d := &pd.Dependencies
+	switch name {
+	case "php":
+		d.PHP.Version, d.PHP.Source = version, FileName
+	case "node":
+		d.Node.Version, d.Node.Source = version, FileName
+	case "python":
+		d.Python.Version, d.Python.Source = version, FileName
+	case "go":
+		d.Go.Version, d.Go.Source = version, FileName
+	case "ruby":
+		d.Ruby.Version, d.Ruby.Source = version, FileName
+	case "rust":
+		d.Rust.Version, d.Rust.Source, d.Rust.Channel = version, FileName, ""
+	case "java":
+		d.Java.Version, d.Java.Source = version, FileName
+	}
+	found := false
+	for i := range pd.Languages {
+		if pd.Languages[i].Name == name {
+			pd.Languages[i].Version = version
+			found = true
+		}
+	}
+	if !found {
+		pd.Languages = append(pd.Languages, detector.Language{
+			Name:    name,
+			Version: version,
+			Primary: strings.HasPrefix(string(pd.Type), name),
+		})
+	}
+}
+
+// MountArgs returns container run -v flags for the manifest's mounts.
+// Relative mount sources resolve against root. Env and ports are resolved
+// by the caller together with flags and .env files.
+func (m *Manifest) MountArgs(root string) []string {
+	if m == nil {
+		return nil
+	}
+	args := []string{}
+	for _, v := range m.Mounts {
+		args = append(args, "-v", resolveMount(v, root))
+	}
+	return args
+}
+
+// resolveMount turns ./ and ~/ sources into absolute host paths. Bare names
+// are left alone so they keep referring to named volumes.
+func resolveMount(spec, root string) string {
+	src, rest, _ := strings.Cut(spec, ":")
+	switch {
+	case src == "." || strings.HasPrefix(src, "./") || strings.HasPrefix(src, "../"):
+		src = filepath.Join(root, src)
+	case strings.HasPrefix(src, "~/"):
+		if home := os.Getenv("HOME"); home != "" {
+			src = filepath.Join(home, src[2:])
+		}
+	}
+	return src + ":" + rest
+}
@@ -0,0 +1,163 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mitl/internal/detector"
+
+	e "mitl/pkg/errors"
+)
+
+func writeManifest(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(content), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	m, err := Load(t.TempDir())
+	if err != nil || m != nil {
+		t.Fatalf("expected nil manifest and nil error, got %v, %v", m, err)
+	}
+	// nil manifests are safe to use
+	m.Apply(detector.NewProjectDetector(t.TempDir()))
+	if args := m.MountArgs("/tmp"); len(args) != 0 {
+		t.Fatalf("expected no run args, got %v", args)
+	}
+}
+
+func TestLoad_Valid(t *testing.T) {
+	dir := t.TempDir()
+	writeManifest(t, dir, `version: 1
+type: php-laravel
+runtimes:
+  php: "8.2"
+  node: "18"
+  go: "1.22"
+php:
+  extensions: [intl, redis]
+go:
+  target: ./cmd/api/
+build:
+  dockerfile: ./docker/dev.Dockerfile
+  target: dev
+  args:
+    NODE_ENV: development
+system_packages: [imagemagick]
+env:
+  APP_ENV: local
+  APP_DEBUG: "true"
+ports: ["8000:8000", "127.0.0.1:5173:5173/tcp"]
+mounts: ["./storage:/app/storage", "cache:/cache:ro"]
+shell: /bin/zsh
+`)
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if m.Path != filepath.Join(dir, FileName) {
+		t.Fatalf("unexpected path %s", m.Path)
+	}
+
+	d := detector.NewProjectDetector(dir)
+	_ = d.Detect()
+	m.Apply(d)
+	if d.Type != detector.TypePHPLaravel {
+		t.Fatalf("expected type override, got %s", d.Type)
+	}
+	if d.Dependencies.PHP.Version != "8.2" || d.Dependencies.Node.Version != "18" {
+		t.Fatalf("expected pinned versions, got php=%s node=%s", d.Dependencies.PHP.Version, d.Dependencies.Node.Version)
+	}
+	if strings.Join(d.Dependencies.PHP.Extensions, ",") != "intl,redis" {
+		t.Fatalf("expected pinned extensions, got %v", d.Dependencies.PHP.Extensions)
+	}
+	if d.Dependencies.Go.Target != "cmd/api" {
+		t.Fatalf("expected go target cmd/api, got %q", d.Dependencies.Go.Target)
+	}
+	if strings.Join(d.Dependencies.System, ",") != "imagemagick" {
+		t.Fatalf("expected system packages, got %v", d.Dependencies.System)
+	}
+	goPinned := false
+	for _, l := range d.Languages {
+		if l.Name == "go" && l.Version == "1.22" {
+			goPinned = true
+		}
+	}
+	if !goPinned {
+		t.Fatalf("expected go language version, got %+v", d.Languages)
+	}
+	if d.Dependencies.Go.Version != "1.22" || d.Dependencies.Go.Source != FileName {
+		t.Fatalf("expected the go dependency pinned by %s, got %+v", FileName, d.Dependencies.Go)
+	}
+
+	if m.Build.Dockerfile != "./docker/dev.Dockerfile" || m.Build.Target != "dev" || m.Build.Args["NODE_ENV"] != "development" {
+		t.Fatalf("unexpected build settings %+v", m.Build)
+	}
+
+	args := strings.Join(m.MountArgs(dir), " ")
+	want := "-v " +
+		filepath.Join(dir, "storage") + ":/app/storage -v cache:/cache:ro"
+	if args != want {
+		t.Fatalf("run args mismatch:\n got: %s\nwant: %s", args, want)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", "typo: 1\n", "field typo not found"},
+		{"bad yaml", "type: [\n", "Invalid mitl.yaml"},
+		{"unknown type", "type: cobol\n", `unknown type "cobol"`},
+		{"bad version", "runtimes:\n  php: latest\n", "runtimes.php"},
+		{"bad port", "ports: [\"99999\"]\n", "invalid port"},
+		{"bad mount", "mounts: [\"./data:relative\"]\n", "container path must be absolute"},
+		{"bad env", "env:\n  1BAD: x\n", "invalid variable name"},
+		{"bad forward_env", "forward_env: [\"A-B\"]\n", "forward_env"},
+		{"bad package manager", "node:\n  package_manager: deno\n", "node.package_manager"},
+		{"bad shell", "shell: \"bash -l\"\n", "shell"},
+		{"bad go target", "go:\n  target: ../other\n", "go.target"},
+		{"bad build mode", "build:\n  mode: always\n", "build.mode"},
+		{"generate with dockerfile", "build:\n  mode: generate\n  dockerfile: Dockerfile\n", "build.dockerfile"},
+		{"dockerfile outside project", "build:\n  dockerfile: /etc/Dockerfile\n", "build.dockerfile"},
+		{"bad build target", "build:\n  target: \"dev stage\"\n", "build.target"},
+		{"bad build arg", "build:\n  args:\n    1X: y\n", "build.args"},
+		{"bad build source", "build:\n  source: copied\n", "build.source"},
+		{"future schema", "version: 9\n", "unsupported version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeManifest(t, dir, tt.content)
+			_, err := Load(dir)
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			me, ok := err.(*e.MitlError)
+			if !ok || me.Code != e.ErrInvalidManifest {
+				t.Fatalf("expected INVALID_MANIFEST, got %T %v", err, err)
+			}
+			if !strings.Contains(me.Message, tt.want) {
+				t.Fatalf("expected %q in %q", tt.want, me.Message)
+			}
+		})
+	}
+}
+
+func TestFind_PrefersYAML(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "mitl.yml"), []byte("type: go\n"), 0o644)
+	if got := Find(dir); filepath.Base(got) != "mitl.yml" {
+		t.Fatalf("expected mitl.yml, got %s", got)
+	}
+	writeManifest(t, dir, "type: node\n")
+	if got := Find(dir); filepath.Base(got) != FileName {
+		t.Fatalf("expected %s, got %s", FileName, got)
+	}
+}
@@ -0,0 +1,150 @@
+// Package ports parses container port mappings and resolves host port
+// collisions before a capsule is started.
+package ports
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Mapping is a parsed [ip:][host:]container[/proto] publish spec
+type Mapping struct {
+	IP        string
+	Host      int // 0 means "same as container"
+	Container int
+	Proto     string // tcp (default) or udp
+
+	// Requested is the host port asked for before collision handling
+	Requested int
+}
+
+// Available reports whether a TCP host port can be bound. It is a variable
+// so tests can simulate busy ports.
+var Available = func(ip string, port int) bool {
+	l, err := net.Listen("tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	_ = l.Close()
+	return true
+}
+
+// Parse parses a publish spec such as "3000", "8080:80" or
+// "127.0.0.1:5173:5173/tcp"
+func Parse(spec string) (Mapping, error) {
+	var m Mapping
+	rest, proto, hasProto := strings.Cut(spec, "/")
+	m.Proto = "tcp"
+	if hasProto {
+		if proto != "tcp" && proto != "udp" {
+			return m, fmt.Errorf("unsupported protocol %q", proto)
+		}
+		m.Proto = proto
+	}
+	parts := strings.Split(rest, ":")
+	switch len(parts) {
+	case 1:
+	case 2:
+		m.Host = -1
+	case 3:
+		m.IP = parts[0]
+		if net.ParseIP(m.IP) == nil {
+			return m, fmt.Errorf("invalid IP %q", m.IP)
+		}
+		parts = parts[1:]
+		m.Host = -1
+	default:
+		return m, fmt.Errorf("expected [host:]container")
+	}
+	var err error
+	if m.Container, err = parsePort(parts[len(parts)-1]); err != nil {
+		return m, err
+	}
+	if m.Host == -1 {
+		if m.Host, err = parsePort(parts[0]); err != nil {
+			return m, err
+		}
+	}
+	m.Requested = m.Host
+	return m, nil
+}
+
+func parsePort(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return n, nil
+}
+
+// String renders the mapping as a runtime -p argument
+func (m Mapping) String() string {
+	host := m.Host
+	if host == 0 {
+		host = m.Container
+	}
+	s := fmt.Sprintf("%d:%d", host, m.Container)
+	if m.IP != "" {
+		s = m.IP + ":" + s
+	}
+	if m.Proto != "" && m.Proto != "tcp" {
+		s += "/" + m.Proto
+	}
+	return s
+}
+
+// Remapped reports whether collision handling moved the host port
+func (m Mapping) Remapped() bool {
+	want := m.Requested
+	if want == 0 {
+		want = m.Container
+	}
+	return m.Host != want
+}
+
+// URL returns the address a browser can reach the mapping on
+func (m Mapping) URL() string {
+	host := "localhost"
+	if m.IP != "" && m.IP != "0.0.0.0" && m.IP != "127.0.0.1" {
+		host = m.IP
+	}
+	return fmt.Sprintf("http://%s:%d", host, m.Host)
+}
+
+// Resolve assigns a free host port to every TCP mapping. A mapping whose
+// host port is already bound (or claimed by an earlier mapping) moves to the
+// next free port above it.
+func Resolve(ms []Mapping) []Mapping {
+	out := make([]Mapping, len(ms))
+	claimed := make(map[int]bool)
+	for i, m := range ms {
+		if m.Host == 0 {
+			m.Host = m.Container
+		}
+		if m.Proto == "tcp" || m.Proto == "" {
+			if claimed[m.Host] || !Available(m.IP, m.Host) {
+				m.Host = freePort(m.IP, m.Host, claimed)
+			}
+		}
+		claimed[m.Host] = true
+		out[i] = m
+	}
+	return out
+}
+
+// freePort scans upward from start, then falls back to an OS-assigned port
+func freePort(ip string, start int, claimed map[int]bool) int {
+	for p := start + 1; p <= start+100 && p <= 65535; p++ {
+		if !claimed[p] && Available(ip, p) {
+			return p
+		}
+	}
+	l, err := net.Listen("tcp", net.JoinHostPort(ip, "0"))
+	if err != nil {
+		return start
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
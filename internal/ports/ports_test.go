@@ -0,0 +1,57 @@
+package ports
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{"3000", "3000:3000", false},
+		{"8080:80", "8080:80", false},
+		{"127.0.0.1:5173:5173/tcp", "127.0.0.1:5173:5173", false},
+		{"53:53/udp", "53:53/udp", false},
+		{"99999", "", true},
+		{"80/sctp", "", true},
+		{"a:b:c:d", "", true},
+		{"nothost:80:80", "", true},
+	}
+	for _, tt := range tests {
+		m, err := Parse(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("Parse(%q) err = %v, wantErr %v", tt.spec, err, tt.wantErr)
+		}
+		if err == nil && m.String() != tt.want {
+			t.Fatalf("Parse(%q) = %s, want %s", tt.spec, m.String(), tt.want)
+		}
+	}
+}
+
+func TestResolve_PicksFreePortOnCollision(t *testing.T) {
+	orig := Available
+	defer func() { Available = orig }()
+	busy := map[int]bool{3000: true, 3001: true}
+	Available = func(ip string, port int) bool { return !busy[port] }
+
+	in := []Mapping{}
+	for _, s := range []string{"3000", "8000:8000", "8000:9000"} {
+		m, _ := Parse(s)
+		in = append(in, m)
+	}
+	out := Resolve(in)
+
+	if out[0].Host != 3002 || !out[0].Remapped() {
+		t.Fatalf("expected 3000 to move to 3002, got %+v", out[0])
+	}
+	if out[1].Host != 8000 || out[1].Remapped() {
+		t.Fatalf("expected 8000 to stay, got %+v", out[1])
+	}
+	// The second request for host 8000 collides with the first mapping
+	if out[2].Host != 8001 || out[2].String() != "8001:9000" {
+		t.Fatalf("expected duplicate host port to move to 8001, got %+v", out[2])
+	}
+	if out[0].URL() != "http://localhost:3002" {
+		t.Fatalf("unexpected URL %s", out[0].URL())
+	}
+}
@@ -0,0 +1,137 @@
+// Package service tracks long-running capsule containers started by `mitl up`.
+// State lives in ~/.mitl/services.json next to the volume metadata so that
+// ps/logs/down/restart can find a project's container from any of its
+// subdirectories.
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Service describes a detached capsule container
+type Service struct {
+	Name      string    `json:"name"`         // container name
+	Project   string    `json:"project_path"` // absolute project root
+	Tag       string    `json:"tag"`          // capsule image tag
+	Runtime   string    `json:"runtime"`      // CLI used to start it
+	Ports     []string  `json:"ports,omitempty"`
+	Command   []string  `json:"command,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Store persists services keyed by project root
+type Store struct {
+	mu       sync.RWMutex
+	path     string
+	services map[string]Service
+}
+
+// NewStore opens the service state file under $HOME/.mitl
+func NewStore() *Store {
+	home := os.Getenv("HOME")
+	if home == "" {
+		home, _ = os.Getwd()
+	}
+	metaDir := filepath.Join(home, ".mitl")
+	_ = os.MkdirAll(metaDir, 0o755)
+	s := &Store{
+		path:     filepath.Join(metaDir, "services.json"),
+		services: make(map[string]Service),
+	}
+	s.load()
+	return s
+}
+
+// LabelProjectHash is set on service containers so they can be found by
+// project even when the state file is missing
+const LabelProjectHash = "run.mitl.project-hash"
+
+// ProjectHash returns the identifier used for a project root, matching the
+// hash volume names are derived from
+func ProjectHash(projectRoot string) string {
+	h := sha256.Sum256([]byte(projectRoot))
+	return hex.EncodeToString(h[:])
+}
+
+// ContainerName returns the deterministic container name for a project root
+func ContainerName(projectRoot string) string {
+	return "mitl-" + ProjectHash(projectRoot)[:12]
+}
+
+// Get returns the service registered for exactly this project root
+func (s *Store) Get(projectRoot string) (Service, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	svc, ok := s.services[projectRoot]
+	return svc, ok
+}
+
+// FindForDir returns the service whose project contains dir, preferring the
+// innermost project when services are nested.
+func (s *Store) FindForDir(dir string) (Service, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if svc, ok := s.services[d]; ok {
+			return svc, true
+		}
+		if parent := filepath.Dir(d); parent == d {
+			return Service{}, false
+		}
+	}
+}
+
+// List returns all services ordered by project path
+func (s *Store) List() []Service {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Service, 0, len(s.services))
+	for _, svc := range s.services {
+		out = append(out, svc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Project < out[j].Project })
+	return out
+}
+
+// Put records a service and saves the state file
+func (s *Store) Put(svc Service) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.services[svc.Project] = svc
+	return s.save()
+}
+
+// Remove forgets the service for a project root and saves the state file
+func (s *Store) Remove(projectRoot string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.services, projectRoot)
+	return s.save()
+}
+
+func (s *Store) load() {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(b, &s.services)
+	if s.services == nil {
+		s.services = make(map[string]Service)
+	}
+}
+
+// save writes the state file; callers hold the lock
+func (s *Store) save() error {
+	b, err := json.MarshalIndent(s.services, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o600)
+}
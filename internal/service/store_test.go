@@ -0,0 +1,62 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_PersistAndFindFromSubdir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := filepath.Join(t.TempDir(), "app")
+
+	s := NewStore()
+	svc := Service{Name: ContainerName(root), Project: root, Tag: "mitl-capsule:abc", Ports: []string{"8000:8000"}, StartedAt: time.Now()}
+	if err := s.Put(svc); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	// A fresh store reads the same file
+	s2 := NewStore()
+	got, ok := s2.FindForDir(filepath.Join(root, "src", "components"))
+	if !ok || got.Name != svc.Name || got.Tag != svc.Tag {
+		t.Fatalf("expected service from subdir lookup, got %+v ok=%v", got, ok)
+	}
+	if _, ok := s2.FindForDir(filepath.Dir(root)); ok {
+		t.Fatalf("parent directory must not match a child project")
+	}
+
+	if err := s2.Remove(root); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if len(NewStore().List()) != 0 {
+		t.Fatalf("expected empty store after remove")
+	}
+}
+
+func TestStore_FindPrefersInnermostProject(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	outer := t.TempDir()
+	inner := filepath.Join(outer, "packages", "web")
+
+	s := NewStore()
+	_ = s.Put(Service{Name: "outer", Project: outer})
+	_ = s.Put(Service{Name: "inner", Project: inner})
+
+	if got, _ := s.FindForDir(filepath.Join(inner, "src")); got.Name != "inner" {
+		t.Fatalf("expected inner service, got %s", got.Name)
+	}
+	if got, _ := s.FindForDir(filepath.Join(outer, "docs")); got.Name != "outer" {
+		t.Fatalf("expected outer service, got %s", got.Name)
+	}
+}
+
+func TestContainerName_Deterministic(t *testing.T) {
+	a := ContainerName("/src/app")
+	if a != ContainerName("/src/app") || a == ContainerName("/src/other") {
+		t.Fatalf("container names must be stable per project")
+	}
+	if len(a) != len("mitl-")+12 {
+		t.Fatalf("unexpected name %q", a)
+	}
+}
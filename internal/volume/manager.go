@@ -30,18 +30,29 @@ type Manager struct {
 	metadata     map[string]VolumeMetadata // Volume tracking
 	metadataPath string                    // Path to metadata file
 	pnpmStore    string                    // Global pnpm store volume name
+	node         detector.NodeDependencies // Resolved Node package manager
+	workspace    *detector.Workspace       // Monorepo members sharing the project's volumes
 }
 
 // VolumeType represents different dependency types
 type VolumeType string
 
 const (
-	VolumeTypeVendor      VolumeType = "vendor"       // PHP Composer
-	VolumeTypePnpmStore   VolumeType = "pnpm-store"   // Global pnpm store
-	VolumeTypePnpmModules VolumeType = "pnpm-modules" // Project node_modules
-	VolumeTypePythonVenv  VolumeType = "venv"         // Python virtualenv
-	VolumeTypeGoBuild     VolumeType = "go-build"     // Go build cache
-	VolumeTypeRubyGems    VolumeType = "gems"         // Ruby gems
+	VolumeTypeVendor        VolumeType = "vendor"         // PHP Composer
+	VolumeTypePnpmStore     VolumeType = "pnpm-store"     // Global pnpm store
+	VolumeTypePnpmModules   VolumeType = "pnpm-modules"   // Project node_modules (pnpm)
+	VolumeTypeNpmModules    VolumeType = "npm-modules"    // Project node_modules (npm)
+	VolumeTypeYarnModules   VolumeType = "yarn-modules"   // Project node_modules (Yarn)
+	VolumeTypeBunModules    VolumeType = "bun-modules"    // Project node_modules (Bun)
+	VolumeTypePythonVenv    VolumeType = "venv"           // Python virtualenv
+	VolumeTypeGoBuild       VolumeType = "go-build"       // Go build cache
+	VolumeTypeGoMod         VolumeType = "go-mod"         // Global Go module cache
+	VolumeTypeRubyGems      VolumeType = "gems"           // Ruby gems
+	VolumeTypeCargoTarget   VolumeType = "cargo-target"   // Rust target/ directory
+	VolumeTypeCargoRegistry VolumeType = "cargo-registry" // Global cargo registry
+	VolumeTypeCargoGit      VolumeType = "cargo-git"      // Global cargo git checkouts
+	VolumeTypeMavenRepo     VolumeType = "maven-repo"     // Global ~/.m2 (Maven)
+	VolumeTypeGradleHome    VolumeType = "gradle-home"    // Global ~/.gradle (Gradle)
 )
 
 // VolumeMetadata tracks volume information
@@ -111,6 +122,62 @@ func (vm *Manager) ensurePnpmStore() error {
 	return nil
 }
 
+// SetNodeDependencies selects the package manager Node mounts are built
+// for. Without it the manager defaults to pnpm.
+func (vm *Manager) SetNodeDependencies(nd detector.NodeDependencies) {
+	vm.node = nd
+}
+
+// SetWorkspace records the project's workspace members so package
+// managers that install per-package node_modules get a volume for each
+func (vm *Manager) SetWorkspace(ws *detector.Workspace) {
+	vm.workspace = ws
+}
+
+// nodeWorkspaceMembers returns the paths of members of a pnpm, npm, Yarn or
+// Bun workspace
+func (vm *Manager) nodeWorkspaceMembers() []string {
+	if vm.workspace == nil {
+		return nil
+	}
+	node := false
+	for _, tool := range []string{detector.WorkspacePNPM, detector.WorkspaceNPM, detector.WorkspaceYarn, detector.WorkspaceBun} {
+		node = node || detector.ContainsString(vm.workspace.Tools, tool)
+	}
+	if !node {
+		return nil
+	}
+	var paths []string
+	for _, p := range vm.workspace.Packages {
+		if _, err := os.Stat(filepath.Join(vm.projectRoot, p.Path, "package.json")); err == nil {
+			paths = append(paths, p.Path)
+		}
+	}
+	return paths
+}
+
+// nodeManager returns the resolved Node package manager
+func (vm *Manager) nodeManager() string {
+	if vm.node.PackageManager == "" {
+		return detector.PackageManagerPNPM
+	}
+	return vm.node.PackageManager
+}
+
+// nodeModulesVolumeType names node_modules volumes after the package manager
+// so switching managers never reuses a tree laid out by another one
+func nodeModulesVolumeType(manager string) VolumeType {
+	switch manager {
+	case detector.PackageManagerNPM:
+		return VolumeTypeNpmModules
+	case detector.PackageManagerYarn:
+		return VolumeTypeYarnModules
+	case detector.PackageManagerBun:
+		return VolumeTypeBunModules
+	}
+	return VolumeTypePnpmModules
+}
+
 // GetMounts returns volume and env flags for container run
 func (vm *Manager) GetMounts(projectType detector.ProjectType) []string {
 	mounts := []string{}
@@ -126,23 +193,48 @@ func (vm *Manager) GetMounts(projectType detector.ProjectType) []string {
 		mounts = append(mounts, vm.getPythonMounts()...)
 	case strings.HasPrefix(string(projectType), "go"):
 		mounts = append(mounts, vm.getGoMounts()...)
+	case strings.HasPrefix(string(projectType), "ruby"):
+		mounts = append(mounts, vm.getRubyMounts()...)
+	case projectType == detector.TypeRustCargo:
+		mounts = append(mounts, vm.getRustMounts()...)
+	case projectType == detector.TypeJavaMaven:
+		mounts = append(mounts, "-v", vm.ensureSharedVolume("mitl-maven-repo", VolumeTypeMavenRepo)+":/root/.m2")
+	case projectType == detector.TypeJavaGradle:
+		mounts = append(mounts, "-v", vm.ensureSharedVolume("mitl-gradle-home", VolumeTypeGradleHome)+":/root/.gradle")
 	}
 	return mounts
 }
 
-// getNodeMounts returns mounts for Node.js projects (enforcing pnpm)
+// getNodeMounts returns mounts for Node.js projects: a node_modules volume
+// keyed by package manager and lockfile, plus the global store for pnpm.
+// Yarn Plug'n'Play keeps dependencies in .yarn/ and needs no volume.
 func (vm *Manager) getNodeMounts() []string {
+	manager := vm.nodeManager()
+	if manager == detector.PackageManagerYarn && vm.node.YarnPnP {
+		return nil
+	}
 	mounts := []string{}
-	// Global pnpm store
-	mounts = append(mounts, "-v", fmt.Sprintf("%s:/root/.local/share/pnpm/store", vm.pnpmStore))
+	if manager == detector.PackageManagerPNPM {
+		// Global pnpm store
+		mounts = append(mounts, "-v", fmt.Sprintf("%s:/root/.local/share/pnpm/store", vm.pnpmStore))
+	}
 	// Project-specific node_modules
-	modulesVolume := vm.getOrCreateVolume(VolumeTypePnpmModules)
-	mounts = append(mounts,
-		"-v", fmt.Sprintf("%s:/app/node_modules", modulesVolume),
-		// Env to force pnpm store
-		"-e", "PNPM_STORE_DIR=/root/.local/share/pnpm/store",
-		"-e", "PNPM_PACKAGE_IMPORT_METHOD=hard-link",
-	)
+	modulesVolume := vm.getOrCreateVolume(nodeModulesVolumeType(manager))
+	mounts = append(mounts, "-v", fmt.Sprintf("%s:/app/node_modules", modulesVolume))
+	// Workspace members get their own node_modules (pnpm links every
+	// member's dependencies there; npm/Yarn/Bun put unhoisted versions there)
+	// so the host tree never sees Linux binaries
+	for _, member := range vm.nodeWorkspaceMembers() {
+		memberVolume := vm.getOrCreateMemberVolume(nodeModulesVolumeType(manager), member)
+		mounts = append(mounts, "-v", fmt.Sprintf("%s:/app/%s/node_modules", memberVolume, member))
+	}
+	if manager == detector.PackageManagerPNPM {
+		mounts = append(mounts,
+			// Env to force pnpm store
+			"-e", "PNPM_STORE_DIR=/root/.local/share/pnpm/store",
+			"-e", "PNPM_PACKAGE_IMPORT_METHOD=hard-link",
+		)
+	}
 	return mounts
 }
 
@@ -168,16 +260,77 @@ func (vm *Manager) getPythonMounts() []string {
 func (vm *Manager) getGoMounts() []string {
 	// Go build cache can be shared; keep per-project for simplicity
 	goVol := vm.getOrCreateVolume(VolumeTypeGoBuild)
-	return []string{"-v", fmt.Sprintf("%s:/root/.cache/go-build", goVol)}
+	return []string{
+		// The module cache is content-addressed, so every project shares it
+		"-v", vm.ensureSharedVolume("mitl-go-mod", VolumeTypeGoMod) + ":/go/pkg/mod",
+		"-v", fmt.Sprintf("%s:/root/.cache/go-build", goVol),
+	}
+}
+
+// getRubyMounts mounts the gems volume over the image's BUNDLE_PATH. A new
+// volume is seeded with the gems baked into the capsule, and `mitl run
+// bundle install` adds to it without rebuilding.
+func (vm *Manager) getRubyMounts() []string {
+	gemsVolume := vm.getOrCreateVolume(VolumeTypeRubyGems)
+	return []string{"-v", fmt.Sprintf("%s:/usr/local/bundle", gemsVolume)}
+}
+
+// getRustMounts returns the shared cargo registry and git checkouts and a
+// per-project target volume. Both caches are content-addressed, so every
+// project shares them like the pnpm store; target/ is keyed on Cargo.lock
+// and the toolchain.
+func (vm *Manager) getRustMounts() []string {
+	targetVolume := vm.getOrCreateVolume(VolumeTypeCargoTarget)
+	return []string{
+		"-v", vm.ensureSharedVolume("mitl-cargo-registry", VolumeTypeCargoRegistry) + ":/usr/local/cargo/registry",
+		"-v", vm.ensureSharedVolume("mitl-cargo-git", VolumeTypeCargoGit) + ":/usr/local/cargo/git",
+		"-v", fmt.Sprintf("%s:/app/target", targetVolume),
+	}
+}
+
+// ensureSharedVolume creates a volume shared by every project, such as the
+// Maven repository or Gradle home. Artifacts there are keyed by coordinates
+// and version, so unlike node_modules they never need per-lockfile copies.
+func (vm *Manager) ensureSharedVolume(name string, vt VolumeType) string {
+	if ok, _ := vm.volumeExists(name); ok {
+		vm.updateLastUsed(name)
+		vm.saveMetadata()
+		return name
+	}
+	if err := execCommand(vm.runtime, "volume", "create", name).Run(); err != nil {
+		// Runtimes without named volumes create it implicitly on run
+		return name
+	}
+	vm.mu.Lock()
+	vm.metadata[name] = VolumeMetadata{
+		Name:      name,
+		Type:      vt,
+		CreatedAt: time.Now(),
+		LastUsed:  time.Now(),
+		Runtime:   vm.runtime,
+	}
+	vm.mu.Unlock()
+	vm.saveMetadata()
+	return name
 }
 
 // getOrCreateVolume creates a volume if needed and returns its name
 func (vm *Manager) getOrCreateVolume(volType VolumeType) string {
+	return vm.getOrCreateMemberVolume(volType, "")
+}
+
+// getOrCreateMemberVolume is getOrCreateVolume for a workspace member's
+// directory. Member volumes share the workspace lockfile key and are told
+// apart by a hash of the member path; "" is the project itself.
+func (vm *Manager) getOrCreateMemberVolume(volType VolumeType, member string) string {
 	lockfileHash := vm.calculateLockfileHash(volType)
 	if lockfileHash == "" {
 		lockfileHash = vm.projectHash[:12]
 	}
 	volumeName := fmt.Sprintf("mitl-%s-%s-%s", vm.projectHash[:8], volType, lockfileHash[:8])
+	if member != "" {
+		volumeName += "-" + generateProjectHash(member)[:8]
+	}
 
 	vm.mu.Lock()
 	defer vm.mu.Unlock()
@@ -210,15 +363,24 @@ func (vm *Manager) calculateLockfileHash(volType VolumeType) string {
 		files = []string{"composer.lock"}
 	case VolumeTypePnpmModules:
 		files = []string{"pnpm-lock.yaml", "package.json"}
+	case VolumeTypeNpmModules:
+		files = append(detector.Lockfiles(detector.PackageManagerNPM), "package.json")
+	case VolumeTypeYarnModules:
+		files = append(detector.Lockfiles(detector.PackageManagerYarn), "package.json")
+	case VolumeTypeBunModules:
+		files = append(detector.Lockfiles(detector.PackageManagerBun), "package.json")
 	case VolumeTypePnpmStore:
 		// Global store not tied to project lockfiles; no hash input
 		files = nil
 	case VolumeTypePythonVenv:
-		files = []string{"requirements.txt", "Pipfile.lock", "poetry.lock"}
+		files = []string{"requirements.txt", "Pipfile.lock", "poetry.lock", "uv.lock", "pdm.lock", ".python-version"}
 	case VolumeTypeGoBuild:
 		files = []string{"go.sum", "go.mod"}
 	case VolumeTypeRubyGems:
-		files = []string{"Gemfile.lock"}
+		// native extensions are built against one Ruby ABI
+		files = []string{"Gemfile.lock", ".ruby-version"}
+	case VolumeTypeCargoTarget:
+		files = []string{"Cargo.lock", "rust-toolchain.toml", "rust-toolchain"}
 	}
 	if len(files) == 0 {
 		return ""
@@ -233,59 +395,79 @@ func (vm *Manager) calculateLockfileHash(volType VolumeType) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
-// InterceptNodeCommand converts npm/yarn commands to pnpm and ensures corepack activation
+// InterceptNodeCommand rewrites npm/yarn/pnpm/bun invocations to the
+// project's package manager when the policy asks for it. Under the respect
+// policy commands run exactly as typed.
 func (vm *Manager) InterceptNodeCommand(args []string) []string {
-	if len(args) == 0 {
+	if len(args) == 0 || !vm.node.Policy.Rewrites() {
 		return args
 	}
-	if strings.Contains(args[0], "pnpm") {
+	target := vm.nodeManager()
+	if args[0] == target || !detector.ContainsString(detector.PackageManagers, args[0]) {
 		return args
 	}
-	// helper to wrap into sh -lc with corepack
-	wrap := func(cmd string) []string {
-		msg := fmt.Sprintf("🔄 Enforcing pnpm: %s", cmd)
-		fmt.Println(msg)
-		// Ensure every 'pnpm ' invocation is prefixed with 'corepack '
-		patched := strings.ReplaceAll(cmd, "pnpm ", "corepack pnpm ")
-		full := "corepack prepare pnpm@latest --activate && " + patched
-		return []string{"sh", "-lc", full}
-	}
-	switch args[0] {
-	case "npm":
-		if len(args) > 1 {
-			sub := args[1]
-			switch sub {
-			case "ci":
-				return wrap("pnpm install --frozen-lockfile || pnpm install --no-frozen-lockfile")
-			case "install", "i":
-				return wrap("pnpm install")
-			case "run":
-				return wrap("pnpm run " + strings.Join(args[2:], " "))
-			case "test":
-				return wrap("pnpm test")
-			default:
-				return wrap("pnpm " + strings.Join(args[1:], " "))
-			}
+	cmd := translateNodeCommand(args[1:], target, vm.node.YarnBerry, vm.hasLockfile(target))
+	fmt.Printf("🔄 Using %s (%s policy): %s\n", target, vm.node.Policy, cmd)
+	if target == detector.PackageManagerPNPM || target == detector.PackageManagerYarn {
+		// corepack provides pnpm/yarn even when the capsule has not enabled it
+		cmd = strings.ReplaceAll(cmd, target+" ", "corepack "+target+" ")
+	}
+	return []string{"sh", "-lc", cmd}
+}
+
+// hasLockfile reports whether the project has a lockfile of manager
+func (vm *Manager) hasLockfile(manager string) bool {
+	for _, name := range detector.Lockfiles(manager) {
+		if _, err := os.Stat(filepath.Join(vm.projectRoot, name)); err == nil {
+			return true
 		}
-		return wrap("pnpm install")
-	case "yarn":
-		if len(args) > 1 {
-			sub := args[1]
-			switch sub {
-			case "install":
-				return wrap("pnpm install")
-			case "add":
-				return wrap("pnpm add " + strings.Join(args[2:], " "))
-			case "remove":
-				return wrap("pnpm remove " + strings.Join(args[2:], " "))
-			default:
-				return wrap("pnpm " + strings.Join(args[1:], " "))
-			}
+	}
+	return false
+}
+
+// translateNodeCommand maps a package manager subcommand onto target's
+// equivalent, e.g. "npm ci" -> "pnpm install --frozen-lockfile". Without
+// target's lockfile (locked false) "ci" becomes a plain install, since a
+// frozen install has nothing to check against; with one it stays frozen, so
+// lockfile drift still fails.
+func translateNodeCommand(args []string, target string, berry, locked bool) string {
+	rest := strings.Join(args, " ")
+	with := func(sub string) string {
+		return strings.TrimSpace(target + " " + sub + " " + strings.Join(args[1:], " "))
+	}
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	switch {
+	case sub == "" || (sub == "install" || sub == "i") && len(args) == 1:
+		return target + " install"
+	case sub == "ci" && !locked:
+		return target + " install"
+	case sub == "ci":
+		frozen := map[string]string{
+			detector.PackageManagerNPM:  "npm ci",
+			detector.PackageManagerPNPM: "pnpm install --frozen-lockfile",
+			detector.PackageManagerYarn: "yarn install --frozen-lockfile",
+			detector.PackageManagerBun:  "bun install --frozen-lockfile",
+		}[target]
+		if target == detector.PackageManagerYarn && berry {
+			frozen = "yarn install --immutable"
 		}
-		return wrap("pnpm install")
-	default:
-		return args
+		return frozen
+	case sub == "add" || sub == "install" || sub == "i":
+		if target == detector.PackageManagerNPM {
+			return with("install")
+		}
+		return with("add")
+	case sub == "remove" || sub == "rm" || sub == "uninstall" || sub == "un":
+		if target == detector.PackageManagerNPM {
+			return with("uninstall")
+		}
+		return with("remove")
 	}
+	// run, test, exec and the rest share their spelling across managers
+	return target + " " + rest
 }
 
 // GetOrCreateVolume gets existing or creates new volume for dependencies
@@ -3,10 +3,13 @@ package volume
 import (
 	"strings"
 	"testing"
+
+	"mitl/internal/detector"
 )
 
 func TestManager_InterceptNodeCommand_More(t *testing.T) {
 	vm := NewManager("true", t.TempDir())
+	vm.SetNodeDependencies(detector.NodeDependencies{PackageManager: "pnpm", Policy: detector.PolicyEnforcePnpm})
 	// npm test -> pnpm test
 	out := vm.InterceptNodeCommand([]string{"npm", "test"})
 	s := sliceToString(out)
@@ -2,6 +2,7 @@ package volume
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -35,13 +36,21 @@ func TestManager_Mounts_NodeAndPHP(t *testing.T) {
 }
 
 func TestManager_InterceptNodeCommand(t *testing.T) {
-	vm := NewManager("true", t.TempDir())
-	// npm ci -> pnpm install with fallback
+	dir := t.TempDir()
+	vm := NewManager("true", dir)
+	vm.SetNodeDependencies(detector.NodeDependencies{PackageManager: "pnpm", Policy: detector.PolicyEnforcePnpm})
+	// npm ci -> a plain pnpm install until pnpm-lock.yaml exists
 	out := vm.InterceptNodeCommand([]string{"npm", "ci"})
 	s := strings.Join(out, " ")
-	if !strings.Contains(s, "pnpm install") {
+	if !strings.HasSuffix(s, "corepack pnpm install") {
 		t.Fatalf("expected pnpm install, got %s", s)
 	}
+	// then a frozen install alone, so lockfile drift fails
+	os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), []byte("lockfileVersion: 9"), 0o644)
+	s = strings.Join(vm.InterceptNodeCommand([]string{"npm", "ci"}), " ")
+	if !strings.HasSuffix(s, "corepack pnpm install --frozen-lockfile") || strings.Contains(s, "||") {
+		t.Fatalf("expected a frozen pnpm install, got %s", s)
+	}
 	// yarn add -> pnpm add
 	out = vm.InterceptNodeCommand([]string{"yarn", "add", "leftpad"})
 	s = strings.Join(out, " ")
@@ -67,6 +76,119 @@ func TestManager_InterceptNodeCommand(t *testing.T) {
 	}
 }
 
+func TestManager_InterceptNodeCommand_Policies(t *testing.T) {
+	vm := NewManager("true", t.TempDir())
+	// respect leaves every command as typed
+	vm.SetNodeDependencies(detector.NodeDependencies{PackageManager: "npm", Policy: detector.PolicyRespect})
+	if out := vm.InterceptNodeCommand([]string{"yarn", "add", "x"}); strings.Join(out, " ") != "yarn add x" {
+		t.Fatalf("respect should not rewrite, got %v", out)
+	}
+
+	tests := []struct {
+		nd   detector.NodeDependencies
+		args []string
+		want string
+	}{
+		{detector.NodeDependencies{PackageManager: "npm", Policy: "npm"}, []string{"yarn", "add", "-D", "x"}, "npm install -D x"},
+		{detector.NodeDependencies{PackageManager: "npm", Policy: "npm"}, []string{"pnpm", "remove", "x"}, "npm uninstall x"},
+		{detector.NodeDependencies{PackageManager: "yarn", YarnBerry: true, Policy: "yarn"}, []string{"npm", "ci"}, "corepack yarn install"},
+		{detector.NodeDependencies{PackageManager: "bun", Policy: "bun"}, []string{"npm", "run", "dev"}, "bun run dev"},
+		{detector.NodeDependencies{PackageManager: "bun", Policy: "bun"}, []string{"bun", "install"}, "bun install"},
+	}
+	for _, tt := range tests {
+		vm.SetNodeDependencies(tt.nd)
+		if got := strings.Join(vm.InterceptNodeCommand(tt.args), " "); !strings.HasSuffix(got, tt.want) {
+			t.Fatalf("%v with %s: got %v, want %q", tt.args, tt.nd.PackageManager, got, tt.want)
+		}
+	}
+}
+
+func TestManager_NodeMountsFollowPackageManager(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "package-lock.json"), []byte("{}"), 0o644)
+	vm := NewManager("true", dir)
+
+	vm.SetNodeDependencies(detector.NodeDependencies{PackageManager: "npm"})
+	npm := strings.Join(vm.GetMounts(detector.TypeNodeGeneric), " ")
+	if !strings.Contains(npm, "-npm-modules-") || strings.Contains(npm, "pnpm/store") {
+		t.Fatalf("expected npm node_modules volume without pnpm store, got %s", npm)
+	}
+
+	vm.SetNodeDependencies(detector.NodeDependencies{PackageManager: "yarn", YarnBerry: true, YarnPnP: true})
+	pnp := strings.Join(vm.GetMounts(detector.TypeNodeGeneric), " ")
+	if strings.Contains(pnp, "node_modules") {
+		t.Fatalf("Plug'n'Play projects need no node_modules volume, got %s", pnp)
+	}
+}
+
+func TestManager_NodeWorkspaceMemberMounts(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), []byte("lockfileVersion: 9"), 0o644)
+	for _, member := range []string{"apps/web", "packages/ui"} {
+		os.MkdirAll(filepath.Join(dir, member), 0o755)
+		os.WriteFile(filepath.Join(dir, member, "package.json"), []byte(`{}`), 0o644)
+	}
+	vm := NewManager("true", dir)
+	vm.SetNodeDependencies(detector.NodeDependencies{PackageManager: "pnpm"})
+	vm.SetWorkspace(&detector.Workspace{
+		Root:  dir,
+		Tools: []string{detector.WorkspacePNPM},
+		Packages: []detector.WorkspacePackage{
+			{Name: "web", Path: "apps/web"}, {Name: "ui", Path: "packages/ui"}, {Name: "api", Path: "services/api"},
+		},
+	})
+	mounts := strings.Join(vm.GetMounts(detector.TypeNodeGeneric), " ")
+	for _, target := range []string{":/app/node_modules", ":/app/apps/web/node_modules", ":/app/packages/ui/node_modules"} {
+		if !strings.Contains(mounts, target) {
+			t.Fatalf("expected %s mount, got %s", target, mounts)
+		}
+	}
+	if strings.Contains(mounts, "services/api") {
+		t.Fatalf("members without package.json need no node_modules volume, got %s", mounts)
+	}
+
+	vm.SetWorkspace(&detector.Workspace{Root: dir, Tools: []string{detector.WorkspaceGo}, Packages: []detector.WorkspacePackage{{Name: "web", Path: "apps/web"}}})
+	if mounts := strings.Join(vm.GetMounts(detector.TypeNodeGeneric), " "); strings.Contains(mounts, "apps/web") {
+		t.Fatalf("only Node workspaces get member volumes, got %s", mounts)
+	}
+}
+
+func TestManager_RustMounts(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Cargo.lock"), []byte("version = 3"), 0o644)
+	vm := NewManager("true", dir)
+	mounts := strings.Join(vm.GetMounts(detector.TypeRustCargo), " ")
+	if !strings.Contains(mounts, "mitl-cargo-registry:/usr/local/cargo/registry") {
+		t.Fatalf("expected shared registry volume, got %s", mounts)
+	}
+	if !strings.Contains(mounts, "mitl-cargo-git:/usr/local/cargo/git") {
+		t.Fatalf("expected shared git checkout volume, got %s", mounts)
+	}
+	if !strings.Contains(mounts, "-cargo-target-") || !strings.Contains(mounts, ":/app/target") {
+		t.Fatalf("expected target volume, got %s", mounts)
+	}
+}
+
+func TestManager_RustSharedVolumesTracked(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	old := execCommand
+	defer func() { execCommand = old }()
+	// No volume exists yet; only create succeeds
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		if len(args) > 1 && args[1] == "create" {
+			return exec.Command("true")
+		}
+		return exec.Command("false")
+	}
+	vm := NewManager("docker", t.TempDir())
+	vm.GetMounts(detector.TypeRustCargo)
+	for name, vt := range map[string]VolumeType{"mitl-cargo-registry": VolumeTypeCargoRegistry, "mitl-cargo-git": VolumeTypeCargoGit} {
+		if md, ok := vm.metadata[name]; !ok || md.Type != vt {
+			t.Fatalf("expected %s recorded as %s, got %+v", name, vt, vm.metadata)
+		}
+	}
+}
+
 func TestManager_LockfileHashing(t *testing.T) {
 	dir := t.TempDir()
 	os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), []byte("a"), 0o644)
@@ -100,6 +222,9 @@ func TestManager_GetMounts_GoPython(t *testing.T) {
 		if !containsFlag(g, "/root/.cache/go-build") {
 			t.Fatalf("expected go-build mount, got %v", g)
 		}
+		if !containsFlag(g, "mitl-go-mod:/go/pkg/mod") {
+			t.Fatalf("expected shared module cache mount, got %v", g)
+		}
 		if !containsFlag(p, "/app/.venv") {
 			t.Fatalf("expected venv mount, got %v", p)
 		}
@@ -126,3 +251,30 @@ func TestManager_VolumePrimitives(t *testing.T) {
 		t.Fatalf("deleteVolume: %v", err)
 	}
 }
+
+func TestManager_JavaMountsShareRepository(t *testing.T) {
+	vm := NewManager("true", t.TempDir())
+	maven := strings.Join(vm.GetMounts(detector.TypeJavaMaven), " ")
+	if !strings.Contains(maven, "mitl-maven-repo:/root/.m2") {
+		t.Fatalf("expected shared maven repo volume, got %s", maven)
+	}
+	gradle := strings.Join(vm.GetMounts(detector.TypeJavaGradle), " ")
+	if !strings.Contains(gradle, "mitl-gradle-home:/root/.gradle") {
+		t.Fatalf("expected shared gradle home volume, got %s", gradle)
+	}
+}
+
+func TestManager_RubyMountsKeyedOnGemfileLock(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Gemfile.lock"), []byte("GEM\n"), 0o644)
+	vm := NewManager("true", dir)
+	mounts := vm.GetMounts(detector.TypeRubyRails)
+	joined := strings.Join(mounts, " ")
+	if !strings.Contains(joined, "-gems-") || !strings.Contains(joined, ":/usr/local/bundle") {
+		t.Fatalf("expected gems volume, got %s", joined)
+	}
+	os.WriteFile(filepath.Join(dir, "Gemfile.lock"), []byte("GEM\n  specs:\n    rack (3.0.0)\n"), 0o644)
+	if again := strings.Join(vm.GetMounts(detector.TypeRubyRails), " "); again == joined {
+		t.Fatalf("expected Gemfile.lock change to select a new gems volume")
+	}
+}
@@ -36,8 +36,12 @@ const (
 	ErrRegistryUnreachable ErrorCode = "REGISTRY_UNREACHABLE"
 
 	// Configuration errors
-	ErrInvalidConfig ErrorCode = "INVALID_CONFIG"
-	ErrMissingConfig ErrorCode = "MISSING_CONFIG"
+	ErrInvalidConfig   ErrorCode = "INVALID_CONFIG"
+	ErrMissingConfig   ErrorCode = "MISSING_CONFIG"
+	ErrInvalidManifest ErrorCode = "INVALID_MANIFEST"
+
+	// Service errors
+	ErrServiceNotFound ErrorCode = "SERVICE_NOT_FOUND"
 
 	// Unknown errors
 	ErrUnknown ErrorCode = "UNKNOWN"
@@ -173,6 +177,8 @@ func isRecoverable(code ErrorCode) bool {
 		ErrPermissionDenied,
 		ErrInvalidConfig,
 		ErrMissingConfig,
+		ErrInvalidManifest,
+		ErrServiceNotFound,
 		ErrUnknown:
 		return false
 	default:
@@ -192,6 +198,8 @@ func getDefaultSuggestion(code ErrorCode) string {
 		ErrPermissionDenied:   "Check file permissions or run with sudo",
 		ErrNetworkTimeout:     "Check internet connection and retry",
 		ErrInvalidConfig:      "Fix config: mitl config validate",
+		ErrInvalidManifest:    "Fix mitl.yaml; run 'mitl inspect' to see detected defaults",
+		ErrServiceNotFound:    "Start the project's capsule with: mitl up",
 	}
 	if s, ok := suggestions[code]; ok {
 		return s
@@ -26,6 +26,18 @@ func IsTerminal() bool {
 	return (fileInfo.Mode() & os.ModeCharDevice) != 0
 }
 
+// IsTTY reports whether f is attached to a terminal
+func IsTTY(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 // Colorize returns text with color codes if terminal supports it
 func Colorize(color, text string) string {
 	if !IsTerminal() || os.Getenv("NO_COLOR") != "" {